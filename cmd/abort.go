@@ -0,0 +1,48 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/spf13/cobra"
+)
+
+// abortCmd represents the abort command
+var abortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Remove the pending blue-green version of the package",
+	Long: `Abort deletes the version of the package deployed with --strategy blue-green
+that was not promoted. The live version is left untouched.`,
+	Run: AbortCmdImp,
+}
+
+func AbortCmdImp(cmd *cobra.Command, args []string) {
+	utils.Flags.Abort = true
+	abortParams := cmdImp.DeployParams{cmdImp.Verbose, cmdImp.ProjectPath, cmdImp.ManifestPath,
+		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive, cmdImp.EnvFile}
+	Deploy(abortParams)
+}
+
+func init() {
+	RootCmd.AddCommand(abortCmd)
+
+	abortCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	abortCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	abortCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+}
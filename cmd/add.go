@@ -19,110 +19,170 @@ package cmd
 
 import (
 	"bufio"
-	"fmt"
 	"os"
+	"path"
 
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
 	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"github.com/spf13/cobra"
 )
 
+// the options of the add commands
+var addFlags struct {
+	runtime  string
+	function string
+	main     string
+	feed     string
+	trigger  string
+	action   string
+	status   string
+	version  string
+	license  string
+	params   []string
+}
+
 // addCmd represents the add command
 var addCmd = &cobra.Command{
 	Use:   "add",
-	Short: "Add an action, feed, trigger or rule to the manifest",
+	Short: "Add an action, trigger, rule or package to the manifest",
+	Long: `Add appends an entry to the manifest without rewriting the rest of it, so
+comments and formatting are kept. Values that are not given as flags are
+asked for.`,
 }
 
 // action represents the `add action` command
 var actionCmd = &cobra.Command{
-	Use:   "action",
-	Short: "add action to the manifest file and create default directory structure.",
+	Use:   "action [name]",
+	Short: "add action to the manifest file and create the directory of its code.",
 	Run: func(cmd *cobra.Command, args []string) {
-		maniyaml := parsers.ReadOrCreateManifest()
-
 		reader := bufio.NewReader(os.Stdin)
-		action := parsers.Action{}
-
-		for {
-			action.Name = utils.Ask(reader, "Name", "")
+		name := argOrAsk(reader, args, "Name")
 
-			// Check action name is unique
-			if _, ok := maniyaml.Package.Actions[action.Name]; !ok {
-				break
-			}
-			fmt.Print(action.Name + " is already used. Pick another action name\n")
+		action := parsers.Action{Runtime: addFlags.runtime, Main: addFlags.main}
+		action.Function = addFlags.function
+		if action.Function == "" {
+			action.Function = utils.Ask(reader, "Function", "src/"+name+".js")
 		}
+		inputs, err := cmdImp.ParseKeyValues(addFlags.params)
+		exitOnError(err)
+		action.Inputs = inputs
 
-		action.Runtime = utils.Ask(reader, "Runtime", "nodejs:6")
-		maniyaml.Package.Actions[action.Name] = action
+		// Create the directory of the code before updating the manifest, as
+		// a way to check the function is a valid path name
+		err = os.MkdirAll(path.Join(cmdImp.ProjectPath, path.Dir(action.Function)), 0777)
+		exitOnError(err)
 
-		// Create directory structure before update manifest, as a way
-		// to check the action name is a valid path name
-		err := os.MkdirAll("actions/"+action.Name, 0777)
-		utils.Check(err)
-
-		parsers.Write(maniyaml, "manifest.yaml")
+		exitOnError(cmdImp.Add(addParams("actions", name, action)))
 	},
 }
 
 // trigger represents the `add trigger` command
 var triggerCmd = &cobra.Command{
-	Use:   "trigger",
+	Use:   "trigger [name]",
 	Short: "add trigger to the manifest file.",
 	Run: func(cmd *cobra.Command, args []string) {
-		maniyaml := parsers.ReadOrCreateManifest()
-
 		reader := bufio.NewReader(os.Stdin)
-		trigger := parsers.Trigger{}
+		name := argOrAsk(reader, args, "Name")
 
-		for {
-			trigger.Name = utils.Ask(reader, "Name", "")
-
-			// Check trigger name is unique
-			if _, ok := maniyaml.Package.Triggers[trigger.Name]; !ok {
-				break
-			}
-			fmt.Print(trigger.Name + " is already used. Pick another trigger name\n")
+		trigger := parsers.Trigger{Source: addFlags.feed}
+		if !cmd.Flags().Changed("feed") {
+			trigger.Source = utils.Ask(reader, "Feed", "")
 		}
+		inputs, err := cmdImp.ParseKeyValues(addFlags.params)
+		exitOnError(err)
+		trigger.Inputs = inputs
 
-		trigger.Feed = utils.Ask(reader, "Feed", "")
-		maniyaml.Package.Triggers[trigger.Name] = trigger
-
-		parsers.Write(maniyaml, "manifest.yaml")
+		exitOnError(cmdImp.Add(addParams("triggers", name, trigger)))
 	},
 }
 
 // rule represents the `add rule` command
 var ruleCmd = &cobra.Command{
-	Use:   "rule",
+	Use:   "rule [name]",
 	Short: "add rule to the manifest file.",
 	Run: func(cmd *cobra.Command, args []string) {
-		maniyaml := parsers.ReadOrCreateManifest()
-
 		reader := bufio.NewReader(os.Stdin)
-		rule := parsers.Rule{}
-
-		for {
-			rule.Rule = utils.Ask(reader, "Rule Name", "")
+		name := argOrAsk(reader, args, "Rule Name")
 
-			// Check rule name is unique
-			if _, ok := maniyaml.Package.Triggers[rule.Rule]; !ok {
-				break
-			}
-			fmt.Print(rule.Rule + " is already used. Pick another rule name\n")
+		rule := parsers.Rule{Trigger: addFlags.trigger, Action: addFlags.action, Status: addFlags.status}
+		if rule.Trigger == "" {
+			rule.Trigger = utils.Ask(reader, "Trigger", "")
+		}
+		if rule.Action == "" {
+			rule.Action = utils.Ask(reader, "Action", "")
 		}
 
-		rule.Action = utils.Ask(reader, "Action", "")
-		rule.Trigger = utils.Ask(reader, "Trigger", "")
-		maniyaml.Package.Rules[rule.Rule] = rule
+		exitOnError(cmdImp.Add(addParams("rules", name, rule)))
+	},
+}
 
-		parsers.Write(maniyaml, "manifest.yaml")
+// package represents the `add package` command
+var packageCmd = &cobra.Command{
+	Use:   "package [name]",
+	Short: "declare the package of a manifest file that has none.",
+	Run: func(cmd *cobra.Command, args []string) {
+		reader := bufio.NewReader(os.Stdin)
+		pkg := parsers.Package{Packagename: argOrAsk(reader, args, "Name")}
+		pkg.Version = addFlags.version
+		if pkg.Version == "" {
+			pkg.Version = askVersion(reader, "")
+		}
+		pkg.License = addFlags.license
+		if pkg.License == "" {
+			pkg.License = askLicense(reader, "")
+		}
+
+		exitOnError(cmdImp.AddPackage(cmdImp.ProjectPath, cmdImp.ManifestPath, pkg))
 	},
 }
 
+func addParams(section string, name string, entry interface{}) cmdImp.AddParams {
+	return cmdImp.AddParams{
+		ProjectPath:  cmdImp.ProjectPath,
+		ManifestPath: cmdImp.ManifestPath,
+		Section:      section,
+		Name:         name,
+		Entry:        entry,
+	}
+}
+
+// the name given as argument, or asked for
+func argOrAsk(reader *bufio.Reader, args []string, question string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return utils.Ask(reader, question, "")
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		utils.Exit(err)
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(addCmd)
 	addCmd.AddCommand(actionCmd)
 	addCmd.AddCommand(triggerCmd)
 	addCmd.AddCommand(ruleCmd)
+	addCmd.AddCommand(packageCmd)
+
+	addCmd.PersistentFlags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	addCmd.PersistentFlags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+
+	actionCmd.Flags().StringVar(&addFlags.function, "function", "", "path to the code of the action")
+	actionCmd.Flags().StringVar(&addFlags.runtime, "runtime", "", "runtime of the action, inferred from the code when empty")
+	actionCmd.Flags().StringVar(&addFlags.main, "main", "", "function of the code to run")
+	actionCmd.Flags().StringSliceVar(&addFlags.params, "param", nil, "input of the action, as key=value")
+
+	triggerCmd.Flags().StringVar(&addFlags.feed, "feed", "", "feed of the trigger, e.g. /whisk.system/alarms/alarm")
+	triggerCmd.Flags().StringSliceVar(&addFlags.params, "param", nil, "input of the trigger, as key=value")
+
+	ruleCmd.Flags().StringVar(&addFlags.trigger, "trigger", "", "trigger of the rule")
+	ruleCmd.Flags().StringVar(&addFlags.action, "action", "", "action of the rule")
+	ruleCmd.Flags().StringVar(&addFlags.status, "status", "", "active or inactive, active by default")
+
+	packageCmd.Flags().StringVar(&addFlags.version, "version", "", "version of the package")
+	packageCmd.Flags().StringVar(&addFlags.license, "license", "", "license of the package")
 }
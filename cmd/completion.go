@@ -0,0 +1,265 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// the shell function completing names from the manifest, given the kind of
+// names as argument
+const manifestNamesFunc = "__wskdeploy_manifest_names"
+
+// the commands whose arguments are names declared in the manifest
+var manifestNameArgs = map[*cobra.Command]string{
+	exportCmd: cmdImp.CompletePackages,
+}
+
+// the flags whose values are names declared in the manifest
+var manifestNameFlags = []struct {
+	cmd  *cobra.Command
+	flag string
+	kind string
+}{
+	{ruleCmd, "action", cmdImp.CompleteActions},
+	{ruleCmd, "trigger", cmdImp.CompleteTriggers},
+	{RootCmd, "project", cmdImp.CompletePackages},
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Generate the shell completion script of wskdeploy",
+	Long: `Completion writes the completion script of wskdeploy for the given shell to
+the standard output. Besides commands and flags it completes the package,
+action and trigger names declared in the manifest of the project, e.g.
+
+    source <(wskdeploy completion bash)
+    wskdeploy completion zsh > "${fpath[1]}/_wskdeploy"
+    wskdeploy completion fish > ~/.config/fish/completions/wskdeploy.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitOnError(utils.WithExitCode(utils.ExitUsage, errors.New("completion needs the shell to complete for: bash, zsh or fish")))
+		}
+		exitOnError(GenCompletion(os.Stdout, args[0]))
+	},
+}
+
+// the completion scripts ask this command for the names in the manifest
+var manifestNamesCmd = &cobra.Command{
+	Use:    "__manifest_names packages|actions|triggers|rules",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			os.Exit(1)
+		}
+		names, err := cmdImp.ManifestNames(cmdImp.ProjectPath, cmdImp.ManifestPath, args[0])
+		if err != nil {
+			os.Exit(1)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	},
+}
+
+// GenCompletion writes the completion script for a shell.
+func GenCompletion(w io.Writer, shell string) error {
+	prepareCompletion()
+	switch shell {
+	case "bash":
+		return RootCmd.GenBashCompletion(w)
+	case "zsh":
+		// zsh runs the bash completion through bashcompinit
+		if _, err := io.WriteString(w, zshCompletionPrelude); err != nil {
+			return err
+		}
+		return RootCmd.GenBashCompletion(w)
+	case "fish":
+		return genFishCompletion(w, RootCmd)
+	}
+	return errors.New("Unsupported shell " + shell + ", use bash, zsh or fish")
+}
+
+const bashCompletionFunc = `__wskdeploy_manifest_names()
+{
+    local project="." manifest="" i
+    for ((i=1; i < ${#words[@]} - 1; i++)); do
+        case "${words[i]}" in
+            -p|--pathpath) project="${words[i+1]}" ;;
+            -m|--manifest) manifest="${words[i+1]}" ;;
+        esac
+    done
+    local names
+    names=$(wskdeploy __manifest_names "$1" -p "${project}" -m "${manifest}" 2>/dev/null)
+    COMPREPLY=( $(compgen -W "${names}" -- "${cur}") )
+}
+
+__custom_func() {
+    case ${last_command} in
+%s    esac
+}
+`
+
+const zshCompletionPrelude = `#compdef wskdeploy
+
+autoload -U +X bashcompinit && bashcompinit
+
+# bash-completion is not loaded in zsh
+if ! type _get_comp_words_by_ref >/dev/null 2>&1; then
+    _get_comp_words_by_ref() {
+        cur="${COMP_WORDS[COMP_CWORD]}"
+        prev="${COMP_WORDS[COMP_CWORD-1]}"
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    }
+fi
+
+`
+
+const fishCompletionFunc = `# fish completion for wskdeploy
+
+function __wskdeploy_manifest_names
+    set -l project .
+    set -l manifest ""
+    set -l tokens (commandline -opc)
+    for i in (seq (math (count $tokens) - 1))
+        switch $tokens[$i]
+            case -p --pathpath
+                set project $tokens[(math $i + 1)]
+            case -m --manifest
+                set manifest $tokens[(math $i + 1)]
+        end
+    end
+    wskdeploy __manifest_names $argv[1] -p $project -m "$manifest" 2>/dev/null
+end
+
+complete -c wskdeploy -f
+`
+
+// cobra has no fish support, write the completions from the command tree
+func genFishCompletion(w io.Writer, root *cobra.Command) error {
+	var script bytes.Buffer
+	script.WriteString(fishCompletionFunc)
+
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		condition := "__fish_seen_subcommand_from " + cmd.Name()
+		if cmd == root {
+			condition = ""
+		}
+		for _, child := range cmd.Commands() {
+			if child.Hidden {
+				continue
+			}
+			parent := "__fish_seen_subcommand_from " + cmd.Name()
+			if cmd == root {
+				parent = "__fish_use_subcommand"
+			}
+			fmt.Fprintf(&script, "complete -c wskdeploy -n '%s' -a %s -d %s\n", parent, child.Name(), fishQuote(child.Short))
+		}
+		cmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Hidden {
+				return
+			}
+			line := "complete -c wskdeploy"
+			if condition != "" {
+				line += " -n '" + condition + "'"
+			}
+			line += " -l " + flag.Name
+			if flag.Shorthand != "" {
+				line += " -s " + flag.Shorthand
+			}
+			if kind := manifestNameFlag(cmd, flag.Name); kind != "" {
+				line += " -x -a '(" + manifestNamesFunc + " " + kind + ")'"
+			} else if flag.Value.Type() != "bool" {
+				line += " -r"
+			}
+			script.WriteString(line + " -d " + fishQuote(flag.Usage) + "\n")
+		})
+		if kind, ok := manifestNameArgs[cmd]; ok {
+			fmt.Fprintf(&script, "complete -c wskdeploy -n '%s' -a '(%s %s)'\n", condition, manifestNamesFunc, kind)
+		} else if len(cmd.ValidArgs) > 0 {
+			fmt.Fprintf(&script, "complete -c wskdeploy -n '%s' -a '%s'\n", condition, strings.Join(cmd.ValidArgs, " "))
+		}
+		for _, child := range cmd.Commands() {
+			if !child.Hidden {
+				visit(child)
+			}
+		}
+	}
+	visit(root)
+
+	_, err := io.WriteString(w, script.String())
+	return err
+}
+
+func manifestNameFlag(cmd *cobra.Command, name string) string {
+	for _, flag := range manifestNameFlags {
+		if flag.cmd == cmd && flag.flag == name {
+			return flag.kind
+		}
+	}
+	return ""
+}
+
+func fishQuote(text string) string {
+	return "'" + strings.Replace(strings.Replace(text, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
+
+// the bash completion of the commands whose arguments are manifest names
+func bashManifestArgs() string {
+	cases := ""
+	for cmd, kind := range manifestNameArgs {
+		cases += "        " + strings.Replace(cmd.CommandPath(), " ", "_", -1) + ")\n"
+		cases += "            " + manifestNamesFunc + " " + kind + "\n"
+		cases += "            return\n            ;;\n"
+	}
+	return cases
+}
+
+// hook the manifest names into the bash completion once all commands and
+// flags are declared
+func prepareCompletion() {
+	RootCmd.BashCompletionFunction = fmt.Sprintf(bashCompletionFunc, bashManifestArgs())
+	for _, flag := range manifestNameFlags {
+		flags := flag.cmd.Flags()
+		if flag.cmd == RootCmd {
+			flags = flag.cmd.PersistentFlags()
+		}
+		flags.SetAnnotation(flag.flag, cobra.BashCompCustom, []string{manifestNamesFunc + " " + flag.kind})
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+	RootCmd.AddCommand(manifestNamesCmd)
+
+	manifestNamesCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	manifestNamesCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+}
@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/spf13/cobra"
+)
+
+var convertParams cmdImp.ConvertParams
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert --from serverless [path]",
+	Short: "Convert a Serverless Framework project to manifest and deployment files",
+	Long: `Convert translates the serverless.yml of a Serverless Framework project of
+the openwhisk provider, given as the file or its directory, into a
+manifest.yaml and a deployment.yaml. The package is named after the service,
+functions become actions or sequences, http events API routes, and trigger
+and schedule events triggers with their rules. The values of parameters are
+bound in deployment.yaml. What has no equivalent in wskdeploy is printed as
+a warning. Existing manifest and deployment files are never overwritten.`,
+	Run: ConvertCmdImp,
+}
+
+func ConvertCmdImp(cmd *cobra.Command, args []string) {
+	convertParams.Path = "."
+	if len(args) > 0 {
+		convertParams.Path = args[0]
+	}
+
+	exitOnError(cmdImp.Convert(os.Stdout, convertParams))
+}
+
+func init() {
+	RootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVar(&convertParams.From, "from", "", "the format of the project to convert: serverless")
+	convertCmd.Flags().StringVarP(&convertParams.OutputPath, "output", "o", "", "the directory to write the manifest and deployment files to, the project directory by default")
+}
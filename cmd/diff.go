@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how the namespace differs from the manifest",
+	Long: `Diff compares the manifest and deployment files with the packages, actions,
+triggers and rules in the namespace and prints the parameter, annotation,
+limit and code hash differences of every entity. It exits with status 1 when
+drift is found, so it can gate a CI pipeline. Nothing is deployed.`,
+	Run: DiffCmdImp,
+}
+
+func DiffCmdImp(cmd *cobra.Command, args []string) {
+	utils.Flags.Diff = true
+	diffParams := cmdImp.DeployParams{cmdImp.Verbose, cmdImp.ProjectPath, cmdImp.ManifestPath,
+		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive, cmdImp.EnvFile}
+	if err := Deploy(diffParams); err != nil {
+		os.Exit(utils.ExitCode(err))
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	diffCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	diffCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+}
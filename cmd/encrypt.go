@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/spf13/cobra"
+)
+
+var generateKey bool
+
+// encryptCmd represents the encrypt command
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt [VALUE]",
+	Short: "Encrypt a value for a manifest or deployment file",
+	Long: `Encrypt prints VALUE, or the first line of stdin, as an ENC[...] value that
+can be committed to git in place of a secret input. wskdeploy decrypts it in
+memory at deploy time with the key in $WSKDEPLOY_KEY or the --key-file file.
+Use --generate-key to create a new key.`,
+	Run: EncryptCmdImp,
+}
+
+func EncryptCmdImp(cmd *cobra.Command, args []string) {
+	if generateKey {
+		key, err := utils.GenerateKey()
+		utils.Check(err)
+		fmt.Println(key)
+		return
+	}
+
+	key, err := utils.EncryptionKey()
+	utils.Check(err)
+
+	value := ""
+	if len(args) > 0 {
+		value = args[0]
+	} else {
+		// a terminal would wait for the value to be typed
+		if info, err := os.Stdin.Stat(); utils.Flags.CI && err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			exitOnError(utils.WithExitCode(utils.ExitPrompt, errors.New("encrypt needs the value as argument or on stdin with --ci")))
+		}
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	encrypted, err := utils.EncryptValue(value, key)
+	utils.Check(err)
+	fmt.Println(encrypted)
+}
+
+func init() {
+	RootCmd.AddCommand(encryptCmd)
+
+	encryptCmd.Flags().BoolVar(&generateKey, "generate-key", false, "print a new random key instead")
+}
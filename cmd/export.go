@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/spf13/cobra"
+)
+
+var exportParams cmdImp.ExportParams
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export PACKAGE",
+	Short: "Generate a manifest from a package deployed on OpenWhisk, or Terraform configuration from the project",
+	Long: `Export reads a package, its actions and sequences, and the rules and
+triggers firing its actions from the namespace in ~/.wskprops, and writes
+a manifest.yaml with the code of the actions to the output directory.
+With --apis the API Gateway routes served by the package are written to an
+OpenAPI 3.0 document, openapi.json, referenced by the manifest.
+
+With --format terraform the project is exported instead: its manifest and
+deployment files are resolved as deploying would and written to main.tf as
+ibm_function resources of the IBM Cloud Terraform provider, the code of the
+actions next to it, e.g.
+
+    wskdeploy export --format terraform -p myproject -o terraform`,
+	Run: ExportCmdImp,
+}
+
+func ExportCmdImp(cmd *cobra.Command, args []string) {
+	if len(args) > 0 {
+		exportParams.PackageName = args[0]
+	}
+	exportParams.Verbose = cmdImp.Verbose
+	exportParams.ProjectPath = cmdImp.ProjectPath
+	exportParams.ManifestPath = cmdImp.ManifestPath
+	exportParams.DeploymentPath = cmdImp.DeploymentPath
+
+	exitOnError(cmdImp.Export(exportParams))
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportParams.OutputPath, "output", "o", ".", "directory to write the manifest and code to")
+	exportCmd.Flags().BoolVar(&exportParams.WithCode, "code", true, "write the code of the actions next to the manifest")
+	exportCmd.Flags().BoolVar(&exportParams.Apis, "apis", false, "write the API Gateway routes of the package as an OpenAPI document")
+	exportCmd.Flags().StringVar(&exportParams.Format, "format", cmdImp.ExportManifest, "the format to export: manifest, or terraform for the project")
+	exportCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	exportCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	exportCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+}
@@ -19,8 +19,10 @@ package cmd
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
 	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
@@ -30,20 +32,30 @@ import (
 
 var initCmd = &cobra.Command{
 	Use:   "init",
-	Short: "Init helps you create a manifest file on OpenWhisk",
+	Short: "Init scaffolds a project with a manifest and a hello world action",
+	Long: `Init asks for the package name, version, license and runtime, then writes a
+manifest.yaml declaring a hello action, its code in src/ and optionally a
+deployment.yaml. Existing files are kept; an existing manifest only gets its
+name, version and license updated.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		maniyaml := parsers.ReadOrCreateManifest()
+		maniyaml, err := parsers.ReadOrCreateManifest()
+		utils.Check(err)
 
 		reader := bufio.NewReader(os.Stdin)
 
-		maniyaml.Package.Packagename = askName(reader, maniyaml.Package.Packagename)
-		maniyaml.Package.Version = askVersion(reader, maniyaml.Package.Version)
-		maniyaml.Package.License = askLicense(reader, maniyaml.Package.License)
-
-		parsers.Write(maniyaml, "manifest.yaml")
+		params := cmdImp.InitParams{ProjectPath: cmdImp.ProjectPath}
+		params.Name = askName(reader, maniyaml.Package.Packagename)
+		params.Version = askVersion(reader, maniyaml.Package.Version)
+		params.License = askLicense(reader, maniyaml.Package.License)
+		if len(maniyaml.Package.Actions) == 0 {
+			params.Runtime = askRuntime(reader)
+		} else {
+			params.Runtime = "nodejs"
+		}
+		params.Deployment = strings.HasPrefix(strings.ToLower(utils.Ask(reader, "Create deployment.yaml (y/n)", "n")), "y")
 
-		// Create directory structure
-		os.Mkdir("actions", 0777)
+		exitOnError(cmdImp.InitProject(params))
+		fmt.Println("Project initialized, deploy it with wskdeploy -p " + filepath.Clean(params.ProjectPath))
 	},
 }
 
@@ -70,6 +82,10 @@ func askLicense(reader *bufio.Reader, def string) string {
 	return utils.Ask(reader, "License", def)
 }
 
+func askRuntime(reader *bufio.Reader) string {
+	return utils.Ask(reader, "Runtime ["+strings.Join(cmdImp.InitRuntimeNames(), ", ")+"]", "nodejs")
+}
+
 // init initializes this package
 func init() {
 	RootCmd.AddCommand(initCmd)
@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/spf13/cobra"
+)
+
+var lintParams cmdImp.LintParams
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Report likely mistakes in a manifest that still deploys",
+	Long: `Lint looks for what validate accepts but is likely a mistake: inputs the
+code of their action never mentions, actions no rule, sequence or API uses,
+missing function files, deprecated runtime kinds and large memory limits.
+Every finding has a severity, error, warning or info, and lint fails on
+errors unless --fail-on says otherwise. With --output json the findings are
+printed as a JSON array for other tools.`,
+	Run: LintCmdImp,
+}
+
+func LintCmdImp(cmd *cobra.Command, args []string) {
+	lintParams.ProjectPath = cmdImp.ProjectPath
+	lintParams.ManifestPath = cmdImp.ManifestPath
+	lintParams.EnvFile = cmdImp.EnvFile
+
+	exitOnError(cmdImp.Lint(os.Stdout, lintParams))
+}
+
+func init() {
+	RootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	lintCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	lintCmd.Flags().StringVarP(&lintParams.Output, "output", "o", cmdImp.LintText, "format of the findings: text or json")
+	lintCmd.Flags().StringVar(&lintParams.FailOn, "fail-on", "error", "severity from which findings fail lint: error, warning or info")
+}
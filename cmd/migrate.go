@@ -0,0 +1,55 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/spf13/cobra"
+)
+
+var migrateParams cmdImp.MigrateParams
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Rewrite the manifest to the current schema version",
+	Long: `Migrate rewrites a manifest written for an older schema-version, or for
+none, to the layout of the current version and sets its schema-version.
+The manifest is edited in place, keeping its comments and formatting, and
+every change is printed. With --dry-run the migrated manifest is printed
+instead. Version 1 routes the exposedUrl of actions in the apis section
+and renames the feed of triggers to source.`,
+	Run: MigrateCmdImp,
+}
+
+func MigrateCmdImp(cmd *cobra.Command, args []string) {
+	migrateParams.ProjectPath = cmdImp.ProjectPath
+	migrateParams.ManifestPath = cmdImp.ManifestPath
+
+	exitOnError(cmdImp.Migrate(os.Stdout, migrateParams))
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	migrateCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	migrateCmd.Flags().BoolVar(&migrateParams.DryRun, "dry-run", false, "print the migrated manifest instead of writing it")
+}
@@ -0,0 +1,49 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/spf13/cobra"
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Make the pending blue-green version of the package live",
+	Long: `Promote points the triggers, rules and API routes of the manifest at the
+version of the package deployed with --strategy blue-green, then repoints the
+binding named after the package to it. The previous version is kept.`,
+	Run: PromoteCmdImp,
+}
+
+func PromoteCmdImp(cmd *cobra.Command, args []string) {
+	utils.Flags.Promote = true
+	promoteParams := cmdImp.DeployParams{cmdImp.Verbose, cmdImp.ProjectPath, cmdImp.ManifestPath,
+		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive, cmdImp.EnvFile}
+	Deploy(promoteParams)
+}
+
+func init() {
+	RootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	promoteCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	promoteCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+}
@@ -28,7 +28,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"strings"
 )
 
@@ -40,8 +39,8 @@ var publishCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// Get registry location
 
-		userHome := utils.GetHomeDirectory()
-		propPath := path.Join(userHome, ".wskprops")
+		propPath, err := utils.WskpropsPath()
+		utils.Check(err)
 
 		configs, err := utils.ReadProps(propPath)
 		utils.Check(err)
@@ -62,11 +61,12 @@ var publishCmd = &cobra.Command{
 
 			}
 			configs["REGISTRY"] = registry
-			utils.WriteProps(propPath, configs)
+			utils.Check(utils.WriteProps(propPath, configs))
 		}
 
 		// Get repo URL
-		maniyaml := parsers.ReadOrCreateManifest()
+		maniyaml, err := parsers.ReadOrCreateManifest()
+		utils.Check(err)
 
 		if len(maniyaml.Package.Repositories) > 0 {
 			repoURL := maniyaml.Package.Repositories[0].Url
@@ -19,8 +19,7 @@ package cmd
 
 import (
 	"fmt"
-
-	"path"
+	"os"
 	"sync"
 
 	"github.com/fatih/color"
@@ -33,6 +32,7 @@ import (
 )
 
 var wskpropsPath string
+var reportFormat string
 
 var client *whisk.Client
 var wg sync.WaitGroup
@@ -43,16 +43,25 @@ var reportCmd = &cobra.Command{
 	Short: "Returns summary of what's been deployed on OpenWhisk in specific namespace",
 	Long: `Command helps user get an overall report about what's been deployed
 on OpenWhisk with specific OpenWhisk namespace. By default it will read the wsk property file
-located under current user home.`,
+located under current user home. With --project it lists only the entities
+deployed for that project, with their versions, runtimes, code sizes and
+deployment times, as a table or as JSON.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// TODO: Work your own magic here
+		var err error
 		if wskpropsPath != "" {
-			client, _ = deployers.NewWhiskClient(wskpropsPath, cmdImp.DeploymentPath, false)
+			client, _, err = deployers.NewWhiskClient(wskpropsPath, cmdImp.DeploymentPath, false)
+			utils.Check(err)
+		}
+		//default to ~/.wskprops, or the profile given with --profile
+		propPath, err := utils.WskpropsPath()
+		utils.Check(err)
+		client, _, err = deployers.NewWhiskClient(propPath, cmdImp.DeploymentPath, false)
+		utils.Check(err)
+		if utils.Flags.Project != "" {
+			exitOnError(printProjectReport(client, utils.Flags.Project))
+			return
 		}
-		userHome := utils.GetHomeDirectory()
-		//default to ~/.wskprops
-		propPath := path.Join(userHome, ".wskprops")
-		client, _ = deployers.NewWhiskClient(propPath, cmdImp.DeploymentPath, false)
 		printDeploymentInfo(client)
 	},
 }
@@ -60,6 +69,7 @@ located under current user home.`,
 func init() {
 	RootCmd.AddCommand(reportCmd)
 	reportCmd.Flags().StringVarP(&wskpropsPath, "wskproppath", "w", ".", "path to wsk property file, default is to ~/.wskprops")
+	reportCmd.Flags().StringVarP(&reportFormat, "output", "o", deployers.ReportTable, "format of the project report: table or json")
 
 	// Here you will define your flags and configuration settings.
 
@@ -75,14 +85,25 @@ func init() {
 
 var boldString = color.New(color.Bold).SprintFunc()
 
+// list what is deployed in the namespace for the project
+func printProjectReport(client *whisk.Client, project string) error {
+	deployer := deployers.NewServiceDeployer()
+	deployer.Client = client
+	deployer.ProjectName = project
+
+	entries, err := deployer.ProjectReport()
+	if err != nil {
+		return err
+	}
+	return deployers.PrintReport(os.Stdout, entries, reportFormat)
+}
+
 func printDeploymentInfo(*whisk.Client) error {
 	//We currently list packages, actions, triggers, rules.
 	wg.Add(4)
 
 	fmt.Println("----==== OpenWhisk Deployment Status ====----")
-	// we set the default package list options
-	pkgoptions := &whisk.PackageListOptions{false, 0, 0, 0, false}
-	packages, _, err := client.Packages.List(pkgoptions)
+	packages, err := deployers.ListAllPackages(client)
 	utils.Check(err)
 
 	// list all packages under current namespace.
@@ -94,9 +115,8 @@ func printDeploymentInfo(*whisk.Client) error {
 	// list all the actions under all the packages.
 	go func() {
 		defer wg.Done()
-		acnoptions := &whisk.ActionListOptions{0, 0, false}
 		for _, pkg := range packages {
-			actions, _, err := client.Actions.List(pkg.Name, acnoptions)
+			actions, err := deployers.ListAllActions(client, pkg.Name)
 			utils.Check(err)
 			printActionList(actions)
 		}
@@ -105,8 +125,7 @@ func printDeploymentInfo(*whisk.Client) error {
 	// list all the triggers under current namespace.
 	go func() {
 		defer wg.Done()
-		troptions := &whisk.TriggerListOptions{0, 0, false}
-		_, _, err := client.Triggers.List(troptions)
+		_, err := deployers.ListAllTriggers(client)
 		utils.Check(err)
 		//printTriggerList(triggers)
 
@@ -115,8 +134,7 @@ func printDeploymentInfo(*whisk.Client) error {
 	// list all the rules under current namespace.
 	go func() {
 		defer wg.Done()
-		roptions := &whisk.RuleListOptions{0, 0, false}
-		rules, _, err := client.Rules.List(roptions)
+		rules, err := deployers.ListAllRules(client)
 		utils.Check(err)
 		printRuleList(rules)
 	}()
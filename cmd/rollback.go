@@ -0,0 +1,52 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/spf13/cobra"
+)
+
+var rollbackParams cmdImp.RollbackParams
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Deploy a previous deployment of the project again",
+	Long: `Every successful deployment is recorded in the history of the project, by
+default .wskdeploy/history, with the code of its actions. Rollback deploys the
+one before the last deployment to the namespace again, or the version given
+with --to. --list shows the recorded versions.
+
+    wskdeploy rollback --list
+    wskdeploy rollback --to 3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rollbackParams.ProjectPath = cmdImp.ProjectPath
+		rollbackParams.DeploymentPath = cmdImp.DeploymentPath
+		exitOnError(cmdImp.Rollback(rollbackParams))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	rollbackCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+	rollbackCmd.Flags().IntVar(&rollbackParams.To, "to", 0, "version to roll back to (default is the one before the last deployment)")
+	rollbackCmd.Flags().BoolVar(&rollbackParams.List, "list", false, "list the recorded deployments instead")
+}
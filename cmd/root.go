@@ -22,7 +22,10 @@ import (
 	"log"
 	"os"
 
+	"github.com/fatih/color"
 	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -33,6 +36,7 @@ import (
 	"errors"
 	"github.com/openwhisk/openwhisk-client-go/wski18n"
 	"strings"
+	"time"
 )
 
 var RootCmd = &cobra.Command{
@@ -42,6 +46,51 @@ var RootCmd = &cobra.Command{
 
 wskdeploy without any commands or flags deploys openwhisk package in the current directory if manifest.yaml exists.
 
+Inputs are read from the manifest, then the deployment file, then --param-file
+and last --param, each overriding the ones before.
+
+Deployment files given with more than one -d are merged in order, e.g. a base
+deployment.yaml and the few values an environment changes:
+
+    wskdeploy -d deployment.yaml -d deployment.prod.yaml
+
+The API host, auth key and namespace are taken from, in order of precedence:
+the --apihost, --auth and --namespace flags, the deployment file or target,
+the WSKDEPLOY_APIHOST, WSKDEPLOY_AUTH and WSKDEPLOY_NAMESPACE environment
+variables, and last the .wskprops file.
+
+The API is reached through the proxy set with HTTP_PROXY or HTTPS_PROXY,
+except for the hosts NO_PROXY lists.
+
+With --strategy blue-green the package is deployed as a new version next to
+the live one, e.g. mypackage-v2, and the actions given with --verify are run
+against it. wskdeploy promote then points the triggers, rules, API routes and
+the mypackage binding at it, and wskdeploy abort removes it instead.
+
+With --watch the project is deployed again whenever the manifest, deployment
+or a source file changes, skipping the actions whose code did not change, for
+a quick loop against a sandbox namespace.
+
+With --ci nothing is colored or prompted for, log lines carry no timestamps,
+and a failure is reported as one key=value line on stderr, exiting with the
+code of its class: 2 usage, 3 invalid manifest or deployment files, 4 a value
+that would be prompted for, 5 network, 6 rejected by the API, 7 drift, and 1
+for anything else.
+
+With --format json the only output on stdout is a JSON document listing every
+entity deployed with the operation performed, how long it took and its error,
+for pipelines to read the result from; the log goes to stderr. With --junit
+the same is written to a JUnit XML file, each entity and each action run by
+--verify being a test case, for Jenkins and GitLab to show.
+
+With --webhook, or webhook in the config file, the URL is posted the project,
+environment, number of entities by operation and duration of the run once it
+succeeds or fails; --webhook-format slack posts it as a Slack message.
+
+With --audit-log, or audit-log in the config file, every entity created,
+updated or deleted is appended to the file as a JSON line with the uuid of the
+auth key, the time, and the SHA-256 of the entity before and after the change.
+
       `,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -50,15 +99,94 @@ wskdeploy without any commands or flags deploys openwhisk package in the current
 
 var Deploy = cmdImp.Deploy
 
+// a flag that can be repeated, keeping every value as given
+type repeatedFlag struct {
+	values *[]string
+}
+
+func (flag repeatedFlag) String() string {
+	return strings.Join(*flag.values, " ")
+}
+
+func (flag repeatedFlag) Set(value string) error {
+	*flag.values = append(*flag.values, value)
+	return nil
+}
+
+func (flag repeatedFlag) Type() string {
+	return "KEY VALUE"
+}
+
+// a -d flag given more than once layers the deployment files in order
+type deploymentFlag struct {
+	path *string
+}
+
+func (flag deploymentFlag) String() string {
+	return *flag.path
+}
+
+func (flag deploymentFlag) Set(value string) error {
+	if *flag.path != "" {
+		value = *flag.path + parsers.DeploymentPathSeparator + value
+	}
+	*flag.path = value
+	return nil
+}
+
+func (flag deploymentFlag) Type() string {
+	return "string"
+}
+
+// --param KEY VALUE takes two arguments as in the wsk CLI, the flag parser is
+// given --param KEY=VALUE instead
+func joinParamArgs(args []string) []string {
+	joined := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return append(joined, args[i:]...)
+		}
+		joined = append(joined, args[i])
+		if args[i] == "--param" && i+2 < len(args) && !strings.Contains(args[i+1], "=") {
+			joined = append(joined, args[i+1]+"="+args[i+2])
+			i += 2
+		}
+	}
+	return joined
+}
+
+// the answers to prompts given on the command line
+var answerFile string
+var answerPairs []string
+
+// the variables given on the command line
+var varPairs []string
+
 func RootCmdImp(cmd *cobra.Command, args []string) {
 	// Set all the parameters passed via the command to the struct of wskdeploy command.
 	deployParams := cmdImp.DeployParams{cmdImp.Verbose, cmdImp.ProjectPath, cmdImp.ManifestPath,
-		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive}
+		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive, cmdImp.EnvFile}
+	if utils.Flags.Watch {
+		exitOnError(cmdImp.Watch(deployParams, watchArgs(os.Args[1:]), utils.Flags.WatchInterval))
+		return
+	}
 	// Call the implementation of wskdeploy command.
 	Deploy(deployParams)
 
 }
 
+// the arguments of the deployments run by --watch: the same without --watch,
+// and without prompts as nobody is there to answer them
+func watchArgs(args []string) []string {
+	deployArgs := make([]string, 0, len(args)+1)
+	for _, arg := range args {
+		if arg != "--watch" && !strings.HasPrefix(arg, "--watch=") {
+			deployArgs = append(deployArgs, arg)
+		}
+	}
+	return append(deployArgs, "--allow-interactive=false")
+}
+
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -70,11 +198,16 @@ func Execute() {
 		}
 	}
 
+	RootCmd.SetArgs(joinParamArgs(os.Args[1:]))
 	if err := RootCmd.Execute(); err != nil {
-		log.Println(err)
 		if utils.Flags.WithinOpenWhisk {
+			log.Println(err)
 			utils.PrintOpenWhiskError(err.Error())
+		} else if utils.Flags.CI {
+			// cobra fails on unknown commands and flags only
+			utils.Exit(utils.WithExitCode(utils.ExitUsage, err))
 		} else {
+			log.Println(err)
 			os.Exit(-1)
 		}
 	} else {
@@ -119,12 +252,59 @@ func init() {
 	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	RootCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
 	RootCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
-	RootCmd.Flags().StringVarP(&cmdImp.DeploymentPath, "deployment", "d", "", "path to deployment file")
+	RootCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
 	RootCmd.PersistentFlags().BoolVarP(&cmdImp.UseInteractive, "allow-interactive", "i", !utils.Flags.WithinOpenWhisk, "allow interactive prompts")
 	RootCmd.PersistentFlags().BoolVarP(&cmdImp.UseDefaults, "allow-defaults", "a", false, "allow defaults")
 	RootCmd.PersistentFlags().BoolVarP(&cmdImp.Verbose, "verbose", "v", false, "verbose output")
+	RootCmd.Flags().BoolVar(&utils.Flags.Watch, "watch", false, "deploy again whenever a file of the project changes, until interrupted")
+	RootCmd.Flags().DurationVar(&utils.Flags.WatchInterval, "watch-interval", utils.DefaultWatchInterval, "how often --watch looks for changed files")
+	RootCmd.Flags().StringVar(&utils.Flags.Format, "format", deployers.ResultsText, "output of the deployment: text, or json for a document of the entities deployed, with operations, durations and errors")
+	RootCmd.Flags().StringVar(&utils.Flags.JUnit, "junit", "", "JUnit XML file to report the entities deployed and the actions verified to, as test cases")
+	RootCmd.Flags().BoolVar(&utils.Flags.RemoveOrphans, "remove-orphans", false, "delete the entities of the project that are no longer in the manifest, once confirmed")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.Force, "force", false, "overwrite entities the project does not manage, and delete those --remove-orphans finds without asking")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.Preview, "preview", false, "show the deployment plan without deploying anything")
+	RootCmd.PersistentFlags().IntVar(&utils.Flags.Concurrency, "concurrency", deployers.DefaultConcurrency, "number of actions to deploy at the same time")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.CACert, "cacert", "", "PEM file of certificate authorities to trust besides the system ones, e.g. of a self-signed gateway")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Cert, "cert", "", "PEM file of the client certificate presented to an API behind mTLS")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Key, "key", "", "PEM file of the key of the client certificate (default is the --cert file)")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.IAMApiKey, "iam-apikey", "", "IBM Cloud API key to authenticate with IAM tokens instead of the auth key")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.IAMEndpoint, "iam-endpoint", utils.DefaultIAMEndpoint, "IAM endpoint the API key is exchanged for tokens at")
+	RootCmd.PersistentFlags().DurationVar(&utils.Flags.ConnectTimeout, "connect-timeout", utils.DefaultConnectTimeout, "time allowed to connect to the API, 0 for no limit")
+	RootCmd.PersistentFlags().DurationVar(&utils.Flags.ReadTimeout, "read-timeout", utils.DefaultReadTimeout, "time allowed for the API to respond once a request is sent, e.g. a large upload, 0 for no limit")
+	RootCmd.PersistentFlags().DurationVar(&utils.Flags.RequestTimeout, "request-timeout", utils.DefaultRequestTimeout, "time allowed for a whole API call, retries included, 0 for no limit")
+	RootCmd.PersistentFlags().Float64Var(&utils.Flags.RateLimit, "rate-limit", 0, "most API requests sent per second, to stay below the throttling of the platform, 0 for no limit")
+	RootCmd.PersistentFlags().IntVar(&utils.Flags.RateBurst, "rate-burst", utils.DefaultRateBurst, "API requests sent at once before --rate-limit applies")
+	RootCmd.PersistentFlags().IntVar(&utils.Flags.Retries, "retries", utils.DefaultRetries, "number of times to retry an API call after a transient error")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Strategy, "strategy", deployers.StrategyInPlace, "how to deploy: in-place, or blue-green to deploy a new version of the package next to the live one")
+	RootCmd.PersistentFlags().Var(repeatedFlag{&utils.Flags.Verify}, "verify", "action of a blue-green version that must run successfully before it can be promoted")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.HistoryDir, "history-dir", "", "directory successful deployments are recorded in for rollback (default is .wskdeploy/history in the project path)")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.RollbackOnFailure, "rollback-on-failure", false, "undo the changes made by a deployment that fails midway")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.UpdateDeps, "update-deps", false, "resolve the github dependencies again and update "+utils.LockFileName)
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.DockerRegistry, "docker-registry", "", "registry the images of docker actions are pushed to (default is $"+utils.DockerRegistryEnv+")")
+	RootCmd.PersistentFlags().IntVar(&utils.Flags.MaxCodeSize, "max-code-size", deployers.DefaultMaxCodeSize, "size in MB the code of an action can have, unless the API host reports its limit")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.RuntimesFile, "runtimes-file", "", "YAML file mapping the runtimes of a custom OpenWhisk: kinds of file extensions, default kinds and code size limits")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.Strict, "strict", false, "fail on unknown or misspelled keys of the manifest and deployment files instead of ignoring them")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.PreProcess, "pre-process", "", "pre-processor the manifest and deployment files go through before they are parsed: "+utils.PreProcessGoTemplate+" runs them as Go templates of the variables")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Project, "project", "", "project name deployed entities are tagged with (default is the package name)")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.KeyFile, "key-file", "", "file holding the key ENC[...] values are decrypted with (default is $WSKDEPLOY_KEY)")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.UseKeychain, "use-keychain", false, "read the auth key from the OS keychain when no other source sets it, storing it there on first use")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Profile, "profile", "", "credential profile in ~/.wskprops.d to use instead of ~/.wskprops")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Environment, "env", "", "environment of the deployment file to deploy to, e.g. dev or prod")
+	RootCmd.PersistentFlags().Var(repeatedFlag{&utils.Flags.Params}, "param", "input of the package or, as ACTION/KEY, of an action, overriding the deployment file and --param-file")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.ParamFile, "param-file", "", "JSON file of inputs, overriding the deployment file")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.NonInteractive, "non-interactive", false, "never prompt, fail when a value is neither given nor defaulted")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Webhook, "webhook", "", "URL to notify when deploy or undeploy completes, with the project, environment, entity counts and duration")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.WebhookFormat, "webhook-format", deployers.NotifyJSON, "body posted to the webhook: json, or slack for a Slack incoming webhook")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Trace, "trace", "", "file to log every API request and response to, with method, URL, status, latency and the start of the bodies, credentials redacted")
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.AuditLog, "audit-log", "", "file to append every entity created, updated or deleted to as a JSON line, with who, when and the hashes before and after")
+	RootCmd.PersistentFlags().BoolVar(&utils.Flags.CI, "ci", false, "run in a CI pipeline: no colors or prompts, failures as key=value lines exiting with a distinct code per class")
+	RootCmd.PersistentFlags().StringVar(&answerFile, "answers", "", "YAML file answering prompts by key, e.g. name: demo or deploy: yes")
+	RootCmd.PersistentFlags().StringSliceVar(&answerPairs, "answer", nil, "answer to a prompt as key=value, e.g. --answer runtime=python")
+	RootCmd.PersistentFlags().Var(repeatedFlag{&varPairs}, "var", "variable for ${NAME} references in the manifest and deployment files as NAME=VALUE, overriding the environment")
+	RootCmd.PersistentFlags().StringVar(&cmdImp.EnvFile, "env-file", "", "file of KEY=VALUE variables used to resolve $VARS (default is .env in the project path)")
 	RootCmd.PersistentFlags().StringVarP(&utils.Flags.ApiHost, "apihost", "", "", wski18n.T("whisk API HOST"))
 	RootCmd.PersistentFlags().StringVarP(&utils.Flags.Auth, "auth", "u", "", wski18n.T("authorization `KEY`"))
+	RootCmd.PersistentFlags().StringVar(&utils.Flags.Namespace, "namespace", "", "namespace to deploy to, overriding the deployment file")
 	RootCmd.PersistentFlags().StringVar(&utils.Flags.ApiVersion, "apiversion", "", wski18n.T("whisk API `VERSION`"))
 }
 
@@ -143,4 +323,65 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
+	if utils.Flags.CACert == "" {
+		// cacert may be set in the config file or as CACERT
+		utils.Flags.CACert = viper.GetString("cacert")
+	}
+	if utils.Flags.IAMApiKey == "" {
+		// so the API key need not be given on the command line
+		utils.Flags.IAMApiKey = viper.GetString("iam-apikey")
+	}
+	// the config file sets the timeouts the command line does not
+	for name, timeout := range map[string]*time.Duration{
+		"connect-timeout": &utils.Flags.ConnectTimeout,
+		"read-timeout":    &utils.Flags.ReadTimeout,
+		"request-timeout": &utils.Flags.RequestTimeout,
+	} {
+		if !RootCmd.PersistentFlags().Lookup(name).Changed && viper.IsSet(name) {
+			*timeout = viper.GetDuration(name)
+		}
+	}
+	if !RootCmd.PersistentFlags().Lookup("rate-limit").Changed && viper.IsSet("rate-limit") {
+		utils.Flags.RateLimit = viper.GetFloat64("rate-limit")
+	}
+	if !RootCmd.PersistentFlags().Lookup("rate-burst").Changed && viper.IsSet("rate-burst") {
+		utils.Flags.RateBurst = viper.GetInt("rate-burst")
+	}
+
+	if utils.Flags.RuntimesFile == "" {
+		// operators set the runtimes of their distribution once
+		utils.Flags.RuntimesFile = viper.GetString("runtimes-file")
+	}
+	if !RootCmd.PersistentFlags().Lookup("strict").Changed && viper.IsSet("strict") {
+		utils.Flags.Strict = viper.GetBool("strict")
+	}
+	if utils.Flags.PreProcess == "" {
+		utils.Flags.PreProcess = viper.GetString("pre-process")
+	}
+	if utils.Flags.Webhook == "" {
+		// teams set their chat webhook once
+		utils.Flags.Webhook = viper.GetString("webhook")
+	}
+	if !RootCmd.PersistentFlags().Lookup("webhook-format").Changed && viper.IsSet("webhook-format") {
+		utils.Flags.WebhookFormat = viper.GetString("webhook-format")
+	}
+	if utils.Flags.AuditLog == "" {
+		// compliance asks for every change to be recorded, not only some runs
+		utils.Flags.AuditLog = viper.GetString("audit-log")
+	}
+
+	if utils.Flags.CI {
+		utils.EnableCIMode()
+		cmdImp.UseInteractive = false
+		color.NoColor = true
+	}
+
+	err := utils.LoadAnswers(answerFile, answerPairs)
+	utils.Check(err)
+
+	err = utils.LoadRuntimes(utils.Flags.RuntimesFile)
+	utils.Check(err)
+
+	err = utils.LoadVars(varPairs)
+	utils.Check(err)
 }
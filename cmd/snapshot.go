@@ -0,0 +1,79 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/spf13/cobra"
+)
+
+var snapshotParams cmdImp.SnapshotParams
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Bundle the resolved deployment and built code of the project into an archive",
+	Long: `Snapshot resolves the manifest and deployment files, builds the code of the
+actions and writes them with their metadata to one archive, by default
+PACKAGE.snapshot.zip. wskdeploy restore deploys the archive unchanged to
+another namespace, promoting the exact bits tested in staging to production:
+
+    wskdeploy snapshot -d deployment.staging.yaml -o release.snapshot.zip
+    wskdeploy restore release.snapshot.zip -d deployment.prod.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		snapshotParams.ProjectPath = cmdImp.ProjectPath
+		snapshotParams.ManifestPath = cmdImp.ManifestPath
+		snapshotParams.DeploymentPath = cmdImp.DeploymentPath
+		exitOnError(cmdImp.Snapshot(snapshotParams))
+	},
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore ARCHIVE",
+	Short: "Deploy a snapshot archive unchanged",
+	Long: `Restore deploys the archive written by wskdeploy snapshot to the namespace of
+the deployment file, the flags or .wskprops. The namespace and API host the
+entities and routes refer to are rewritten; the code and inputs are deployed
+as they were when the snapshot was taken.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exitOnError(errors.New("restore needs the snapshot archive to deploy"))
+		}
+		snapshotParams.ProjectPath = cmdImp.ProjectPath
+		snapshotParams.DeploymentPath = cmdImp.DeploymentPath
+		snapshotParams.File = args[0]
+		exitOnError(cmdImp.Restore(snapshotParams))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(snapshotCmd)
+	RootCmd.AddCommand(restoreCmd)
+
+	snapshotCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	snapshotCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
+	snapshotCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+	snapshotCmd.Flags().StringVarP(&snapshotParams.File, "output", "o", "", "archive to write (default is PACKAGE"+deployers.SnapshotArchiveSuffix+")")
+
+	restoreCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
+	restoreCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+}
@@ -19,6 +19,8 @@ package cmd
 
 import (
 	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -26,14 +28,17 @@ import (
 var undeployCmd = &cobra.Command{
 	Use:   "undeploy",
 	Short: "Undeploy assets from OpenWhisk",
-	Long:  `Undeploy removes deployed assets from the manifest and deployment files`,
-	Run:   UndeployCmdImp,
+	Long: `Undeploy removes deployed assets from the manifest and deployment files.
+
+With --format json the only output on stdout is a JSON document listing every
+entity removed, how long it took and its error; the log goes to stderr.`,
+	Run: UndeployCmdImp,
 }
 
 func UndeployCmdImp(cmd *cobra.Command, args []string) {
 	// Set all the parameters passed via the command to the struct of undeploy command.
 	undeployParams := cmdImp.DeployParams{cmdImp.Verbose, cmdImp.ProjectPath, cmdImp.ManifestPath,
-		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive}
+		cmdImp.DeploymentPath, cmdImp.UseDefaults, cmdImp.UseInteractive, cmdImp.EnvFile}
 	// Call the implementation of wskdeploy command.
 	cmdImp.Undeploy(undeployParams)
 }
@@ -53,5 +58,7 @@ func init() {
 	undeployCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	undeployCmd.Flags().StringVarP(&cmdImp.ProjectPath, "pathpath", "p", ".", "path to serverless project")
 	undeployCmd.Flags().StringVarP(&cmdImp.ManifestPath, "manifest", "m", "", "path to manifest file")
-	undeployCmd.Flags().StringVarP(&cmdImp.DeploymentPath, "deployment", "d", "", "path to deployment file")
+	undeployCmd.Flags().VarP(deploymentFlag{&cmdImp.DeploymentPath}, "deployment", "d", "path to deployment file, repeat it to layer files that override the earlier ones")
+	undeployCmd.Flags().StringVar(&utils.Flags.Format, "format", deployers.ResultsText, "output of the undeployment: text, or json for a document of the entities removed, with durations and errors")
+	undeployCmd.Flags().StringVar(&utils.Flags.JUnit, "junit", "", "JUnit XML file to report the entities removed to, as test cases")
 }
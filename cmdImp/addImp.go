@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// AddParams holds the options of the add commands
+type AddParams struct {
+	ProjectPath  string
+	ManifestPath string
+	// the section of the package to add to, e.g. actions
+	Section string
+	Name    string
+	Entry   interface{}
+}
+
+// Add adds an entry to a section of the manifest package, editing the
+// manifest in place so its comments and formatting are kept.
+func Add(params AddParams) error {
+	if params.Name == "" {
+		return errors.New("missing the name of the entry to add")
+	}
+	manifestPath, content, err := readEditedManifest(params.ProjectPath, params.ManifestPath)
+	if err != nil {
+		return err
+	}
+	edited, err := parsers.AddManifestEntry(content, params.Section, params.Name, params.Entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestPath, edited, 0644)
+}
+
+// AddPackage declares the package of a manifest that has none yet.
+func AddPackage(projectPath string, manifestPath string, pkg parsers.Package) error {
+	if pkg.Packagename == "" {
+		return errors.New("missing the name of the package to add")
+	}
+	manifestPath, content, err := readEditedManifest(projectPath, manifestPath)
+	if err != nil {
+		return err
+	}
+	edited, err := parsers.AddManifestPackage(content, pkg)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestPath, edited, 0644)
+}
+
+// ParseKeyValues parses key=value pairs given on the command line.
+func ParseKeyValues(pairs []string) (map[string]parsers.Parameter, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	params := make(map[string]parsers.Parameter, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.New("parameter " + pair + " must be given as key=value")
+		}
+		params[kv[0]] = parsers.Parameter{Value: kv[1]}
+	}
+	return params, nil
+}
+
+// the manifest of the project, which add creates when missing
+func readEditedManifest(projectPath string, manifestPath string) (string, []byte, error) {
+	if manifestPath == "" {
+		manifestPath = findProjectFile(projectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
+	}
+	if strings.EqualFold(path.Ext(manifestPath), ".json") {
+		return "", nil, errors.New("add edits YAML manifests only, " + manifestPath + " is JSON")
+	}
+	if !utils.FileExists(manifestPath) {
+		return manifestPath, []byte{}, nil
+	}
+	content, err := ioutil.ReadFile(manifestPath)
+	return manifestPath, content, err
+}
@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// the kinds of names the shell completion looks up in the manifest
+const (
+	CompletePackages = "packages"
+	CompleteActions  = "actions"
+	CompleteTriggers = "triggers"
+	CompleteRules    = "rules"
+)
+
+// ManifestNames returns the sorted names of the packages, actions, triggers
+// or rules the manifest of a project declares, for shell completion. The
+// manifest only has to parse, so names complete while it is being written.
+func ManifestNames(projectPath string, manifestPath string, kind string) ([]string, error) {
+	if manifestPath == "" {
+		manifestPath = findProjectFile(projectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
+	}
+	if !utils.FileExists(manifestPath) {
+		return nil, errors.New("missing manifest file " + manifestPath)
+	}
+	manifest, violations := parsers.ValidateManifest(manifestPath)
+	if manifest == nil {
+		return nil, errors.New(violations[0].String())
+	}
+
+	pkg := manifest.Package
+	names := make([]string, 0)
+	switch kind {
+	case CompletePackages:
+		if pkg.Packagename != "" {
+			names = append(names, pkg.Packagename)
+		}
+		for name := range pkg.Dependencies {
+			names = append(names, name)
+		}
+	case CompleteActions:
+		// sequences are actions too
+		for name := range pkg.Actions {
+			names = append(names, name)
+		}
+		for name := range pkg.Sequences {
+			names = append(names, name)
+		}
+	case CompleteTriggers:
+		for name := range pkg.Triggers {
+			names = append(names, name)
+		}
+	case CompleteRules:
+		for name := range pkg.Rules {
+			names = append(names, name)
+		}
+	default:
+		return nil, errors.New("cannot complete " + kind + ", use packages, actions, triggers or rules")
+	}
+	sort.Strings(names)
+	return names, nil
+}
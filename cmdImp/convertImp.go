@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// the project formats convert reads
+const ConvertFromServerless = "serverless"
+
+// ConvertParams holds the options of the convert command
+type ConvertParams struct {
+	// the format of the project, serverless
+	From string
+	// the project file, or its directory
+	Path string
+	// the directory to write the manifest and deployment files to, the one
+	// of the project when empty
+	OutputPath string
+}
+
+// Convert translates the project file of another framework into a
+// manifest.yaml and a deployment.yaml, printing what it could not convert.
+// Existing manifest and deployment files are never overwritten.
+func Convert(w io.Writer, params ConvertParams) error {
+	if params.From != ConvertFromServerless {
+		return errors.New("Unsupported project format " + params.From + ", use " + ConvertFromServerless)
+	}
+	outputPath := params.OutputPath
+	if outputPath == "" {
+		outputPath = params.Path
+		if info, err := os.Stat(params.Path); err == nil && !info.IsDir() {
+			outputPath = path.Dir(params.Path)
+		}
+	}
+
+	manifestPath := path.Join(outputPath, deployers.ManifestFileNameYaml)
+	if utils.FileExists(manifestPath) {
+		return errors.New("manifest file " + manifestPath + " already exists")
+	}
+	deploymentPath := path.Join(outputPath, deployers.DeploymentFileNameYaml)
+	if utils.FileExists(deploymentPath) {
+		return errors.New("deployment file " + deploymentPath + " already exists")
+	}
+
+	manifest, deployment, warnings, err := parsers.ConvertServerless(params.Path, outputPath)
+	if err != nil {
+		return err
+	}
+
+	parser := parsers.NewYAMLParser()
+	manifestContent, err := parser.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	deploymentContent, err := parser.MarshalDeployment(deployment)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(manifestPath, manifestContent, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(deploymentPath, deploymentContent, 0644); err != nil {
+		return err
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintln(w, "Warning: "+warning)
+	}
+	fmt.Fprintln(w, "Service "+manifest.Package.Packagename+" converted to "+manifestPath+" and "+deploymentPath)
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// the formats export writes
+const (
+	ExportManifest  = "manifest"
+	ExportTerraform = "terraform"
+)
+
+// ExportParams holds the options of the export command
+type ExportParams struct {
+	Verbose     bool
+	PackageName string
+	OutputPath  string
+	WithCode    bool
+	Apis        bool
+	// manifest, or terraform to write the project as Terraform configuration
+	Format         string
+	ProjectPath    string
+	ManifestPath   string
+	DeploymentPath string
+}
+
+// Export writes a manifest.yaml describing a deployed package, and the code
+// of its actions, to the output directory. With Apis the API Gateway routes
+// of the package are written to an OpenAPI document the manifest refers to.
+func Export(params ExportParams) error {
+	switch params.Format {
+	case "", ExportManifest:
+	case ExportTerraform:
+		return exportTerraform(params)
+	default:
+		return errors.New("Unsupported export format " + params.Format + ", use " + ExportManifest + " or " + ExportTerraform)
+	}
+	if params.PackageName == "" {
+		return errors.New("missing the name of the package to export")
+	}
+
+	manifestPath := path.Join(params.OutputPath, deployers.ManifestFileNameYaml)
+	if utils.FileExists(manifestPath) {
+		return errors.New("manifest file " + manifestPath + " already exists")
+	}
+
+	apiPath := path.Join(params.OutputPath, deployers.ExportApiFileName)
+	if params.Apis && utils.FileExists(apiPath) {
+		return errors.New("API document " + apiPath + " already exists")
+	}
+
+	propPath, err := utils.WskpropsPath()
+	if err != nil {
+		return err
+	}
+	whiskClient, _, err := deployers.NewWhiskClient(propPath, "", false)
+	if err != nil {
+		return err
+	}
+
+	exporter := deployers.NewExporter(whiskClient)
+	exporter.WithCode = params.WithCode
+
+	if err := os.MkdirAll(params.OutputPath, 0755); err != nil {
+		return err
+	}
+
+	manifest, err := exporter.Export(params.PackageName, params.OutputPath)
+	if err != nil {
+		return err
+	}
+
+	if params.Apis {
+		doc, err := exporter.ExportApis(params.PackageName)
+		if err != nil {
+			return err
+		}
+		content, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(apiPath, content, 0644); err != nil {
+			return err
+		}
+		manifest.Package.Apis.Document = deployers.ExportApiFileName
+		fmt.Println("API routes of package " + params.PackageName + " exported to " + apiPath)
+	}
+
+	if err := parsers.Write(manifest, manifestPath); err != nil {
+		return err
+	}
+
+	if params.Verbose {
+		for _, name := range deployers.ExportedNames(manifest) {
+			fmt.Println("Exported " + name)
+		}
+	}
+	fmt.Println("Package " + params.PackageName + " exported to " + manifestPath)
+	return nil
+}
+
+// exportTerraform writes the deployment of the project, resolved from its
+// manifest and deployment files as deploying would, as Terraform
+// configuration to the output directory.
+func exportTerraform(params ExportParams) error {
+	terraformPath := path.Join(params.OutputPath, deployers.TerraformFileName)
+	if utils.FileExists(terraformPath) {
+		return errors.New("Terraform configuration " + terraformPath + " already exists")
+	}
+
+	deployer, err := resolveDeployment(params.ProjectPath, params.ManifestPath, params.DeploymentPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(params.OutputPath, 0755); err != nil {
+		return err
+	}
+	warnings, err := deployer.Terraform(params.OutputPath)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Println("Warning: " + warning)
+	}
+	fmt.Println("Package " + deployer.RootPackageName + " exported to " + terraformPath)
+	return nil
+}
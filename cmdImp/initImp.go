@@ -0,0 +1,182 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// InitParams holds the answers of the init command
+type InitParams struct {
+	ProjectPath string
+	Name        string
+	Version     string
+	License     string
+	Runtime     string
+	// also write a deployment.yaml setting the inputs of the sample action
+	Deployment bool
+}
+
+// the hello world action written for a runtime
+type initTemplate struct {
+	kind string
+	file string
+	code string
+}
+
+// the runtimes init can write a hello world for, the kind is left out when
+// wskdeploy infers it from the file
+var initRuntimes = map[string]initTemplate{
+	"nodejs": {"nodejs:6", "hello.js", `function main(params) {
+    var name = params.name || "World";
+    return { greeting: "Hello, " + name + "!" };
+}
+`},
+	"python": {"python:3", "hello.py", `def main(params):
+    name = params.get("name", "World")
+    return {"greeting": "Hello, " + name + "!"}
+`},
+	"swift": {"swift:3.1.1", "hello.swift", `func main(args: [String:Any]) -> [String:Any] {
+    let name = args["name"] as? String ?? "World"
+    return [ "greeting" : "Hello, \(name)!" ]
+}
+`},
+	"go": {"", "hello.go", `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	params := map[string]interface{}{}
+	if len(os.Args) > 1 {
+		json.Unmarshal([]byte(os.Args[1]), &params)
+	}
+	name, ok := params["name"].(string)
+	if !ok {
+		name = "World"
+	}
+	result, _ := json.Marshal(map[string]string{"greeting": "Hello, " + name + "!"})
+	fmt.Println(string(result))
+}
+`},
+}
+
+// InitRuntimeNames lists the runtimes init supports
+func InitRuntimeNames() []string {
+	names := make([]string, 0, len(initRuntimes))
+	for name := range initRuntimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InitProject scaffolds a project: a manifest.yaml with a hello action, the
+// action in src/ and optionally a deployment.yaml. An existing manifest only
+// gets its package name, version and license updated, and existing files are
+// never overwritten.
+func InitProject(params InitParams) error {
+	template, ok := initRuntimes[params.Runtime]
+	if !ok {
+		return errors.New("Unsupported runtime " + params.Runtime + ", use one of " + strings.Join(InitRuntimeNames(), ", "))
+	}
+
+	manifestPath := path.Join(params.ProjectPath, deployers.ManifestFileNameYaml)
+	manifest := &parsers.ManifestYAML{}
+	if utils.FileExists(manifestPath) {
+		content, err := ioutil.ReadFile(manifestPath)
+		if err != nil {
+			return err
+		}
+		if err := parsers.NewYAMLParser().Unmarshal(content, manifest); err != nil {
+			return errors.New("Unable to parse " + manifestPath + ": " + err.Error())
+		}
+	} else {
+		manifest.SchemaVersion = strconv.Itoa(parsers.CurrentSchemaVersion)
+	}
+
+	manifest.Package.Packagename = params.Name
+	manifest.Package.Version = params.Version
+	manifest.Package.License = params.License
+
+	if len(manifest.Package.Actions) == 0 {
+		srcDir := path.Join(params.ProjectPath, "src")
+		if err := os.MkdirAll(srcDir, 0755); err != nil {
+			return err
+		}
+		srcPath := path.Join(srcDir, template.file)
+		if !utils.FileExists(srcPath) {
+			if err := ioutil.WriteFile(srcPath, []byte(template.code), 0644); err != nil {
+				return err
+			}
+		}
+
+		manifest.Package.Actions = map[string]parsers.Action{
+			"hello": {
+				Function: path.Join("src", template.file),
+				Runtime:  template.kind,
+				Inputs: map[string]parsers.Parameter{
+					"name": {Type: "string", Description: "name to greet", Default: "World"},
+				},
+			},
+		}
+		manifest.Package.Metadata.Description = "Says hello"
+	}
+
+	output, err := parsers.NewYAMLParser().Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(manifestPath, output, 0644); err != nil {
+		return err
+	}
+
+	deploymentPath := path.Join(params.ProjectPath, deployers.DeploymentFileNameYaml)
+	if !params.Deployment || utils.FileExists(deploymentPath) {
+		return nil
+	}
+	deployment := &parsers.DeploymentYAML{
+		Application: parsers.Application{
+			Name: params.Name,
+			Package: parsers.Package{
+				Packagename: params.Name,
+				Actions: map[string]parsers.Action{
+					"hello": {Inputs: map[string]parsers.Parameter{"name": {Value: "OpenWhisk"}}},
+				},
+			},
+		},
+	}
+	output, err = parsers.NewYAMLParser().MarshalDeployment(deployment)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(deploymentPath, output, 0644)
+}
@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// output formats of lint
+const (
+	LintText = "text"
+	LintJSON = "json"
+)
+
+// the order of the severities lint can fail on
+var lintSeverities = map[string]int{parsers.LintInfo: 0, parsers.LintWarning: 1, parsers.LintError: 2}
+
+// LintParams holds the options of the lint command
+type LintParams struct {
+	ProjectPath  string
+	ManifestPath string
+	EnvFile      string
+	Output       string
+	// the severity from which findings fail the command
+	FailOn string
+}
+
+// Lint prints the lint findings of the manifest of the project, as lines
+// of text or as a JSON array. It fails when a finding is at least as severe
+// as FailOn, error by default.
+func Lint(w io.Writer, params LintParams) error {
+	if params.FailOn == "" {
+		params.FailOn = parsers.LintError
+	}
+	threshold, ok := lintSeverities[params.FailOn]
+	if !ok {
+		return errors.New("lint cannot fail on " + params.FailOn + ", use error, warning or info")
+	}
+	if err := loadEnvFile(params.ProjectPath, params.EnvFile); err != nil {
+		return err
+	}
+
+	if params.ManifestPath == "" {
+		params.ManifestPath = findProjectFile(params.ProjectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
+	}
+	if !utils.FileExists(params.ManifestPath) {
+		return utils.WithExitCode(utils.ExitInvalid, errors.New("missing manifest file "+params.ManifestPath))
+	}
+
+	findings, err := parsers.LintManifest(params.ManifestPath)
+	if err != nil {
+		return err
+	}
+
+	switch params.Output {
+	case LintJSON:
+		output, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(output))
+	case LintText, "":
+		for _, finding := range findings {
+			fmt.Fprintln(w, finding.String())
+		}
+	default:
+		return errors.New("unknown lint output " + params.Output + ", expected text or json")
+	}
+
+	failed := 0
+	for _, finding := range findings {
+		if lintSeverities[finding.Severity] >= threshold {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return utils.WithExitCode(utils.ExitInvalid, errors.New(strconv.Itoa(failed)+" finding(s) of severity "+params.FailOn+" or above"))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// MigrateParams holds the options of the migrate command
+type MigrateParams struct {
+	ProjectPath  string
+	ManifestPath string
+	// print the migrated manifest instead of writing it
+	DryRun bool
+}
+
+// Migrate rewrites the manifest of the project to the current schema
+// version in place, printing what it changed.
+func Migrate(w io.Writer, params MigrateParams) error {
+	manifestPath := params.ManifestPath
+	if manifestPath == "" {
+		manifestPath = findProjectFile(params.ProjectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
+	}
+	if !utils.FileExists(manifestPath) {
+		return errors.New("missing manifest file " + manifestPath)
+	}
+	if strings.EqualFold(path.Ext(manifestPath), ".json") {
+		return errors.New("migrate rewrites YAML manifests only, " + manifestPath + " is JSON")
+	}
+
+	content, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	migrated, changes, err := parsers.MigrateManifest(manifestPath, content)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(w, manifestPath+" is at schema-version "+strconv.Itoa(parsers.CurrentSchemaVersion)+" already")
+		return nil
+	}
+
+	if params.DryRun {
+		_, err := w.Write(migrated)
+		return err
+	}
+	if err := ioutil.WriteFile(manifestPath, migrated, 0644); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		fmt.Fprintln(w, manifestPath+": "+change)
+	}
+	return nil
+}
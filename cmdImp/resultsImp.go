@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// stdout, which only the results are printed to with --format json
+var resultsOutput *os.File
+
+// Start collecting the results of the command when --format json, --junit or
+// --webhook asks for them. With --format json the log and progress printed along the
+// way go to stderr, so that the JSON document is all a pipeline reads from
+// stdout.
+func startResults(command string) (*deployers.Results, error) {
+	switch utils.Flags.Format {
+	case "", deployers.ResultsText:
+		if utils.Flags.JUnit == "" && utils.Flags.Webhook == "" {
+			return nil, nil
+		}
+	case deployers.ResultsJSON:
+		resultsOutput = os.Stdout
+		os.Stdout = os.Stderr
+	default:
+		return nil, utils.WithExitCode(utils.ExitUsage, errors.New("unknown format "+utils.Flags.Format+", expected "+deployers.ResultsText+" or "+deployers.ResultsJSON))
+	}
+	return deployers.NewResults(command), nil
+}
+
+// Print the results with the outcome of the command, if they are collected
+func finishResults(results *deployers.Results, project string, err error) {
+	if results == nil {
+		return
+	}
+	results.Finish(project, err)
+	if utils.Flags.JUnit != "" {
+		if err := writeJUnit(results, utils.Flags.JUnit); err != nil {
+			log.Println("Unable to write the JUnit report " + utils.Flags.JUnit + ": " + err.Error())
+		}
+	}
+	if utils.Flags.Webhook != "" {
+		// a deployment is not failed by its notification
+		notification := deployers.NewNotification(results, utils.Flags.Environment)
+		if err := deployers.PostNotification(utils.Flags.Webhook, utils.Flags.WebhookFormat, notification); err != nil {
+			log.Println("Unable to notify the webhook: " + err.Error())
+		}
+	}
+	if utils.Flags.Format != deployers.ResultsJSON {
+		return
+	}
+	os.Stdout = resultsOutput
+	if err := results.Print(os.Stdout); err != nil {
+		log.Println("Unable to print the results: " + err.Error())
+	}
+}
+
+func writeJUnit(results *deployers.Results, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := results.WriteJUnit(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,104 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// RollbackParams holds the options of the rollback command
+type RollbackParams struct {
+	ProjectPath    string
+	DeploymentPath string
+	// version to roll back to, the one before the last deployment when 0
+	To   int
+	List bool
+}
+
+// Rollback deploys again a deployment recorded in the history of the
+// project, or lists the recorded deployments.
+func Rollback(params RollbackParams) error {
+	projectPath, err := filepath.Abs(params.ProjectPath)
+	if err != nil {
+		return err
+	}
+	dir := historyDir(projectPath)
+	history, err := deployers.ReadHistory(dir)
+	if err != nil {
+		return err
+	}
+
+	if params.List {
+		if len(history) == 0 {
+			fmt.Println("No deployments are recorded in " + dir)
+		}
+		for _, snapshot := range history {
+			fmt.Println(strconv.Itoa(snapshot.Version) + "\t" + snapshot.Time.Format("2006-01-02 15:04:05") + "\t" + snapshot.Package + "\t" + snapshot.ApiHost + "/" + snapshot.Namespace)
+		}
+		return nil
+	}
+
+	deployer, err := newSnapshotDeployer(projectPath, params.DeploymentPath)
+	if err != nil {
+		return err
+	}
+	snapshot, err := deployers.RollbackSnapshot(history, deployer.ClientConfig.Namespace, params.To)
+	if err != nil {
+		return err
+	}
+	if err := deployer.UseSnapshot(snapshot); err != nil {
+		return err
+	}
+	fmt.Println("Rolling back to version " + strconv.Itoa(snapshot.Version) + " deployed " + snapshot.Time.Format("2006-01-02 15:04:05") + " ...")
+	return deployer.Deploy()
+}
+
+// a deployer of snapshots, to the namespace of the deployment file or the
+// flags and .wskprops
+func newSnapshotDeployer(projectPath string, deploymentPath string) (*deployers.ServiceDeployer, error) {
+	if deploymentPath == "" {
+		deploymentPath = findProjectFile(projectPath, deployers.DeploymentFileNameYaml, deployers.DeploymentFileNameYml, deployers.DeploymentFileNameJson)
+	}
+	propPath := ""
+	if !utils.Flags.WithinOpenWhisk {
+		var err error
+		if propPath, err = utils.WskpropsPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.ProjectPath = projectPath
+	deployer.DeploymentPath = deploymentPath
+	deployer.IsInteractive = UseInteractive && !utils.Flags.NonInteractive
+	if utils.Flags.Concurrency > 0 {
+		deployer.Concurrency = utils.Flags.Concurrency
+	}
+	deployer.ProjectName = utils.Flags.Project
+	deployer.HistoryDir = historyDir(projectPath)
+	var err error
+	if deployer.Client, deployer.ClientConfig, err = deployers.NewWhiskClient(propPath, deploymentPath, deployer.IsInteractive); err != nil {
+		return nil, err
+	}
+	return deployer, nil
+}
@@ -2,15 +2,17 @@ package cmdImp
 
 import (
 	"errors"
+	"fmt"
 	"github.com/openwhisk/openwhisk-client-go/whisk"
 	"github.com/openwhisk/openwhisk-client-go/wski18n"
 	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"log"
 	"path"
-	"path/filepath"
-	"regexp"
 	"os"
+	"path/filepath"
+	"strconv"
 )
 
 type DeployParams struct {
@@ -20,21 +22,49 @@ type DeployParams struct {
 	DeploymentPath string
 	UseDefaults    bool
 	UseInteractive bool
+	EnvFile        string
+}
+
+// Find the first of the given file names that exists in the project path,
+// falling back to the first name.
+func findProjectFile(projectPath string, names ...string) string {
+	for _, name := range names {
+		if candidate := path.Join(projectPath, name); utils.FileExists(candidate) {
+			return candidate
+		}
+	}
+	return path.Join(projectPath, names[0])
+}
+
+// Load the variables of the given env file, or of the .env file in the
+// project root if none is given, before any manifest is parsed. The git
+// variables describe the project.
+func loadEnvFile(projectPath string, envFile string) error {
+	utils.GitVarsDir = projectPath
+	if envFile == "" {
+		envFile = path.Join(projectPath, utils.EnvFileName)
+		if !utils.FileExists(envFile) {
+			return nil
+		}
+	}
+	return utils.LoadEnvFile(envFile)
 }
 
 func Deploy(params DeployParams) error {
 
 	whisk.SetVerbose(params.Verbose)
 
+	results, err := startResults("deploy")
+	utils.Check(err)
+
 	projectPath, err := filepath.Abs(params.ProjectPath)
 	utils.Check(err)
 
+	err = loadEnvFile(projectPath, params.EnvFile)
+	utils.Check(err)
+
 	if params.ManifestPath == "" {
-		if ok, _ := regexp.Match(deployers.ManifestFileNameYml, []byte(params.ManifestPath)); ok {
-			params.ManifestPath = path.Join(projectPath, deployers.ManifestFileNameYml)
-		} else {
-			params.ManifestPath = path.Join(projectPath, deployers.ManifestFileNameYaml)
-		}
+		params.ManifestPath = findProjectFile(projectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
 	} else {
 		if _, err := os.Stat(path.Join(projectPath, "manifest.yaml")); err == nil {
 			params.ManifestPath = path.Join(projectPath, deployers.ManifestFileNameYaml)
@@ -44,12 +74,8 @@ func Deploy(params DeployParams) error {
 	}
 
 	if params.DeploymentPath == "" {
-		if ok, _ := regexp.Match(deployers.DeploymentFileNameYml, []byte(params.ManifestPath)); ok {
-			params.DeploymentPath = path.Join(projectPath, deployers.DeploymentFileNameYml)
-		} else {
-			params.DeploymentPath = path.Join(projectPath, deployers.DeploymentFileNameYaml)
-		}
-	} else {
+		params.DeploymentPath = findProjectFile(projectPath, deployers.DeploymentFileNameYaml, deployers.DeploymentFileNameYml, deployers.DeploymentFileNameJson)
+	} else if !parsers.DeploymentExists(params.DeploymentPath) {
 		if _, err := os.Stat(path.Join(projectPath, "deployment.yaml")); err == nil {
 			params.DeploymentPath = path.Join(projectPath, deployers.DeploymentFileNameYaml)
 		} else if _, err := os.Stat(path.Join(projectPath, "deployment.yml")); err == nil {
@@ -59,38 +85,53 @@ func Deploy(params DeployParams) error {
 
 	if utils.MayExists(params.ManifestPath) {
 
-		var deployer = deployers.NewServiceDeployer()
-		deployer.ProjectPath = projectPath
-		deployer.ManifestPath = params.ManifestPath
-		deployer.DeploymentPath = params.DeploymentPath
-		// perform some quick check here.
-		go func() {
-			deployer.Check()
-		}()
-		deployer.IsDefault = params.UseDefaults
+		propPath := ""
+		if !utils.Flags.WithinOpenWhisk {
+			propPath, err = utils.WskpropsPath()
+			utils.Check(err)
+		}
 
-		deployer.IsInteractive = params.UseInteractive
+		deployer := newServiceDeployer(params, projectPath)
+		// the errors of the manifest and deployment file are reported at once
+		if err := deployer.Check(); err != nil {
+			finishResults(results, utils.Flags.Project, err)
+			utils.Check(err)
+			return err
+		}
 
-		// master record of any dependency that has been downloaded
-		deployer.DependencyMaster = make(map[string]utils.DependencyRecord)
+		targets, err := deploymentTargets(params.DeploymentPath)
+		if err == nil && len(targets) > 0 {
+			err = deployToTargets(params, projectPath, propPath, targets, results)
+		}
+		if err != nil || len(targets) > 0 {
+			finishResults(results, utils.Flags.Project, err)
+			utils.Check(err)
+			return err
+		}
 
-		propPath := ""
-		if !utils.Flags.WithinOpenWhisk {
-			userHome := utils.GetHomeDirectory()
-			propPath = path.Join(userHome, ".wskprops")
+		whiskClient, clientConfig, err := deployers.NewWhiskClient(propPath, params.DeploymentPath, deployer.IsInteractive)
+		if err != nil {
+			finishResults(results, deployer.Project(), err)
+			utils.Check(err)
+			return err
 		}
-		whiskClient, clientConfig := deployers.NewWhiskClient(propPath, params.DeploymentPath, deployer.IsInteractive)
 		deployer.Client = whiskClient
 		deployer.ClientConfig = clientConfig
+		deployer.Results = results
 
-		err := deployer.ConstructDeploymentPlan()
+		err = deployer.ConstructDeploymentPlan()
 
 		if err != nil {
+			finishResults(results, deployer.Project(), err)
 			utils.Check(err)
 			return err
 		}
 
 		err = deployer.Deploy()
+		finishResults(results, deployer.Project(), err)
+		if err == deployers.ErrDriftDetected {
+			return err
+		}
 		if err != nil {
 			utils.Check(err)
 			return err
@@ -104,8 +145,108 @@ func Deploy(params DeployParams) error {
 			return errors.New("missing manifest.yaml file")
 		} else {
 			log.Println("missing manifest.yaml file")
-			return errors.New("missing manifest.yaml file")
+			err := utils.WithExitCode(utils.ExitInvalid, errors.New("missing manifest.yaml file"))
+			finishResults(results, utils.Flags.Project, err)
+			return err
+		}
+	}
+
+}
+
+func newServiceDeployer(params DeployParams, projectPath string) *deployers.ServiceDeployer {
+	var deployer = deployers.NewServiceDeployer()
+	deployer.ProjectPath = projectPath
+	deployer.ManifestPath = params.ManifestPath
+	deployer.DeploymentPath = params.DeploymentPath
+	deployer.IsDefault = params.UseDefaults
+
+	deployer.IsInteractive = params.UseInteractive && !utils.Flags.NonInteractive
+	if utils.Flags.Concurrency > 0 {
+		deployer.Concurrency = utils.Flags.Concurrency
+	}
+	deployer.ProjectName = utils.Flags.Project
+	deployer.HistoryDir = historyDir(projectPath)
+
+	overrides, err := deployers.LoadParameterOverrides(utils.Flags.ParamFile, utils.Flags.Params)
+	utils.Check(err)
+	deployer.ParameterOverrides = overrides
+
+	// master record of any dependency that has been downloaded
+	deployer.DependencyMaster = make(map[string]utils.DependencyRecord)
+	return deployer
+}
+
+// the directory deployments of the project are recorded in
+func historyDir(projectPath string) string {
+	if utils.Flags.HistoryDir != "" {
+		return utils.Flags.HistoryDir
+	}
+	return deployers.DefaultHistoryDir(projectPath)
+}
+
+// the targets listed in the deployment file, if any
+func deploymentTargets(deploymentPath string) ([]parsers.Target, error) {
+	if !parsers.DeploymentExists(deploymentPath) {
+		return nil, nil
+	}
+	deployment, err := parsers.NewYAMLParser().ParseDeployment(deploymentPath)
+	if err != nil {
+		return nil, err
+	}
+	return deployment.Application.Targets, nil
+}
+
+// Deploy the project to every target of the deployment file one after the
+// other, carrying on past failures, and report how each of them went.
+func deployToTargets(params DeployParams, projectPath string, propPath string, targets []parsers.Target, entityResults *deployers.Results) error {
+	results := make([]error, len(targets))
+	for i, target := range targets {
+		log.Println("Deploying to target " + targetName(target) + " ...")
+		if entityResults != nil {
+			entityResults.SetTarget(targetName(target))
+		}
+		results[i] = deployToTarget(params, projectPath, propPath, target, entityResults)
+		if results[i] != nil {
+			log.Println("Deployment to target " + targetName(target) + " failed: " + results[i].Error())
+		}
+	}
+
+	failed := 0
+	fmt.Println("\nTargets:")
+	for i, target := range targets {
+		if results[i] != nil {
+			failed++
+			fmt.Println("  " + targetName(target) + ": failed, " + results[i].Error())
+		} else {
+			fmt.Println("  " + targetName(target) + ": succeeded")
 		}
 	}
 
+	if failed > 0 {
+		return errors.New("deployment failed for " + strconv.Itoa(failed) + " of " + strconv.Itoa(len(targets)) + " target(s)")
+	}
+	return nil
+}
+
+func deployToTarget(params DeployParams, projectPath string, propPath string, target parsers.Target, results *deployers.Results) error {
+	deployer := newServiceDeployer(params, projectPath)
+	deployer.Results = results
+	client, clientConfig, err := deployers.NewTargetClient(propPath, target)
+	if err != nil {
+		return err
+	}
+	deployer.Client = client
+	deployer.ClientConfig = clientConfig
+
+	if err := deployer.ConstructDeploymentPlan(); err != nil {
+		return err
+	}
+	return deployer.Deploy()
+}
+
+func targetName(target parsers.Target) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	return target.ApiHost + "/" + target.Namespace
 }
@@ -30,3 +30,4 @@ var DeploymentPath string
 var ManifestPath string
 var UseDefaults bool
 var UseInteractive bool
+var EnvFile string
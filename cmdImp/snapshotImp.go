@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// SnapshotParams holds the options of the snapshot and restore commands
+type SnapshotParams struct {
+	ProjectPath    string
+	ManifestPath   string
+	DeploymentPath string
+	// the archive written by snapshot and read by restore
+	File string
+}
+
+// Snapshot resolves the deployment of the project, the code of its actions
+// built, and bundles it into an archive restore deploys unchanged, e.g. to
+// promote what was tested in staging to production.
+func Snapshot(params SnapshotParams) error {
+	deployer, err := resolveDeployment(params.ProjectPath, params.ManifestPath, params.DeploymentPath)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := deployer.Snapshot()
+	if err != nil {
+		return err
+	}
+	if params.File == "" {
+		params.File = deployer.RootPackageName + deployers.SnapshotArchiveSuffix
+	}
+	if err := deployers.WriteSnapshotArchive(params.File, snapshot, deployer.ManifestPath); err != nil {
+		return err
+	}
+	fmt.Println("Snapshot of package " + deployer.RootPackageName + " written to " + params.File)
+	return nil
+}
+
+// resolveDeployment constructs the deployment plan of the project, as
+// deploying it would, without deploying anything.
+func resolveDeployment(projectPath string, manifestPath string, deploymentPath string) (*deployers.ServiceDeployer, error) {
+	projectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadEnvFile(projectPath, EnvFile); err != nil {
+		return nil, err
+	}
+	if manifestPath == "" {
+		manifestPath = findProjectFile(projectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
+	}
+	if !utils.MayExists(manifestPath) {
+		return nil, errors.New("missing manifest file " + manifestPath)
+	}
+
+	deployer, err := newSnapshotDeployer(projectPath, deploymentPath)
+	if err != nil {
+		return nil, err
+	}
+	whisk.SetVerbose(Verbose)
+	deployer.ManifestPath = manifestPath
+	deployer.IsDefault = UseDefaults
+	overrides, err := deployers.LoadParameterOverrides(utils.Flags.ParamFile, utils.Flags.Params)
+	if err != nil {
+		return nil, err
+	}
+	deployer.ParameterOverrides = overrides
+	if err := deployer.ConstructDeploymentPlan(); err != nil {
+		return nil, err
+	}
+	return deployer, nil
+}
+
+// Restore deploys the snapshot archive of a project to the namespace of the
+// deployment file or the flags and .wskprops, which may not be the one it
+// was taken in.
+func Restore(params SnapshotParams) error {
+	projectPath, err := filepath.Abs(params.ProjectPath)
+	if err != nil {
+		return err
+	}
+	snapshot, err := deployers.ReadSnapshotArchive(params.File)
+	if err != nil {
+		return err
+	}
+
+	deployer, err := newSnapshotDeployer(projectPath, params.DeploymentPath)
+	if err != nil {
+		return err
+	}
+	whisk.SetVerbose(Verbose)
+	snapshot.Retarget(deployer.ClientConfig.Host, deployer.ClientConfig.Namespace)
+	if err := deployer.UseSnapshot(snapshot); err != nil {
+		return err
+	}
+	fmt.Println("Restoring package " + snapshot.Package + " from " + params.File + " to namespace " + snapshot.Namespace + " ...")
+	return deployer.Deploy()
+}
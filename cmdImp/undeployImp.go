@@ -6,30 +6,24 @@ import (
 	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"log"
-	"path"
-	"regexp"
 )
 
 func Undeploy(params DeployParams) error {
 	// TODO: Work your own magic here
 	whisk.SetVerbose(params.Verbose)
 
-	if params.ManifestPath == "" {
-		if ok, _ := regexp.Match(deployers.ManifestFileNameYml, []byte(params.ManifestPath)); ok {
-			params.ManifestPath = path.Join(params.ProjectPath, deployers.ManifestFileNameYml)
-		} else {
-			params.ManifestPath = path.Join(params.ProjectPath, deployers.ManifestFileNameYaml)
-		}
+	results, err := startResults("undeploy")
+	utils.Check(err)
+
+	err = loadEnvFile(params.ProjectPath, params.EnvFile)
+	utils.Check(err)
 
+	if params.ManifestPath == "" {
+		params.ManifestPath = findProjectFile(params.ProjectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
 	}
 
 	if params.DeploymentPath == "" {
-		if ok, _ := regexp.Match(deployers.DeploymentFileNameYml, []byte(params.ManifestPath)); ok {
-			params.DeploymentPath = path.Join(params.ProjectPath, deployers.DeploymentFileNameYml)
-		} else {
-			params.DeploymentPath = path.Join(params.ProjectPath, deployers.DeploymentFileNameYaml)
-		}
-
+		params.DeploymentPath = findProjectFile(params.ProjectPath, deployers.DeploymentFileNameYaml, deployers.DeploymentFileNameYml, deployers.DeploymentFileNameJson)
 	}
 
 	if utils.FileExists(params.ManifestPath) {
@@ -39,18 +33,27 @@ func Undeploy(params DeployParams) error {
 		deployer.ManifestPath = params.ManifestPath
 		deployer.DeploymentPath = params.DeploymentPath
 
-		deployer.IsInteractive = params.UseInteractive
+		deployer.IsInteractive = params.UseInteractive && !utils.Flags.NonInteractive
 		deployer.IsDefault = params.UseDefaults
 
-		userHome := utils.GetHomeDirectory()
-		propPath := path.Join(userHome, ".wskprops")
+		propPath, err := utils.WskpropsPath()
+		utils.Check(err)
 
-		whiskClient, clientConfig := deployers.NewWhiskClient(propPath, params.DeploymentPath, deployer.IsInteractive)
+		whiskClient, clientConfig, err := deployers.NewWhiskClient(propPath, params.DeploymentPath, deployer.IsInteractive)
+		if err != nil {
+			finishResults(results, deployer.Project(), err)
+			utils.Check(err)
+			return err
+		}
 		deployer.Client = whiskClient
 		deployer.ClientConfig = clientConfig
+		deployer.Results = results
 
 		verifiedPlan, err := deployer.ConstructUnDeploymentPlan()
-		err = deployer.UnDeploy(verifiedPlan)
+		if err == nil {
+			err = deployer.UnDeploy(verifiedPlan)
+		}
+		finishResults(results, deployer.Project(), err)
 		if err != nil {
 			utils.Check(err)
 			return err
@@ -60,6 +63,8 @@ func Undeploy(params DeployParams) error {
 
 	} else {
 		log.Println("missing manifest.yaml file")
-		return errors.New("missing manifest.yaml file")
+		err := errors.New("missing manifest.yaml file")
+		finishResults(results, utils.Flags.Project, err)
+		return err
 	}
 }
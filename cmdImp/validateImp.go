@@ -0,0 +1,52 @@
+package cmdImp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// Validate checks the manifest and deployment files of the project and
+// prints every violation found. It never contacts the OpenWhisk API host.
+func Validate(params DeployParams) error {
+	if err := loadEnvFile(params.ProjectPath, params.EnvFile); err != nil {
+		return err
+	}
+
+	if params.ManifestPath == "" {
+		params.ManifestPath = findProjectFile(params.ProjectPath, deployers.ManifestFileNameYaml, deployers.ManifestFileNameYml, deployers.ManifestFileNameJson)
+	}
+
+	if params.DeploymentPath == "" {
+		params.DeploymentPath = findProjectFile(params.ProjectPath, deployers.DeploymentFileNameYaml, deployers.DeploymentFileNameYml, deployers.DeploymentFileNameJson)
+	}
+
+	if !utils.FileExists(params.ManifestPath) {
+		return utils.WithExitCode(utils.ExitInvalid, errors.New("missing manifest file "+params.ManifestPath))
+	}
+
+	manifest, violations := parsers.ValidateManifest(params.ManifestPath)
+	if parsers.DeploymentExists(params.DeploymentPath) {
+		// the layers of the deployment are validated one by one
+		for _, deploymentPath := range parsers.DeploymentFiles(params.DeploymentPath) {
+			violations = append(violations, parsers.ValidateDeployment(deploymentPath, manifest)...)
+		}
+	}
+
+	for _, violation := range violations {
+		fmt.Println(violation.String())
+	}
+
+	if len(violations) > 0 {
+		return utils.WithExitCode(utils.ExitInvalid, errors.New(strconv.Itoa(len(violations))+" problem(s) found"))
+	}
+
+	if params.Verbose {
+		fmt.Println("No problems found in " + params.ManifestPath)
+	}
+	return nil
+}
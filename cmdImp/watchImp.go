@@ -0,0 +1,109 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdImp
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// Watch deploys the project, then deploys it again whenever a file of the
+// project, its manifest or deployment files change, until interrupted.
+// Each deployment runs wskdeploy with args, so a failing one, e.g. of a
+// manifest being edited, is reported without ending the watch. Actions
+// whose code did not change are skipped, which keeps the redeploys quick.
+func Watch(params DeployParams, args []string, interval time.Duration) error {
+	projectPath, err := filepath.Abs(params.ProjectPath)
+	if err != nil {
+		return err
+	}
+	executable, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return err
+	}
+	extra := parsers.DeploymentFiles(params.DeploymentPath)
+	if params.ManifestPath != "" {
+		extra = append(extra, params.ManifestPath)
+	}
+	if interval <= 0 {
+		interval = utils.DefaultWatchInterval
+	}
+
+	deploy := func() (map[string]utils.FileStamp, error) {
+		cmd := exec.Command(executable, args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Println("Deployment failed: " + err.Error())
+		}
+		log.Println("Watching " + projectPath + " for changes, press Ctrl+C to stop ...")
+		// files the deployment itself wrote, e.g. built code, are not changes
+		return utils.ScanFiles(projectPath, extra...)
+	}
+
+	stamps, err := deploy()
+	if err != nil {
+		return err
+	}
+	for {
+		time.Sleep(interval)
+		current, err := utils.ScanFiles(projectPath, extra...)
+		if err != nil {
+			return err
+		}
+		changed := utils.ChangedFiles(stamps, current)
+		if len(changed) == 0 {
+			continue
+		}
+
+		// wait for the editor or build to finish writing
+		for {
+			time.Sleep(interval)
+			settled, err := utils.ScanFiles(projectPath, extra...)
+			if err != nil {
+				return err
+			}
+			if len(utils.ChangedFiles(current, settled)) == 0 {
+				break
+			}
+			current = settled
+		}
+
+		log.Println("\nChanged: " + strings.Join(relativePaths(projectPath, changed), ", "))
+		if stamps, err = deploy(); err != nil {
+			return err
+		}
+	}
+}
+
+func relativePaths(root string, files []string) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file
+		if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+			paths[i] = rel
+		}
+	}
+	return paths
+}
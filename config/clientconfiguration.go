@@ -32,7 +32,9 @@ var ClientConfig *whisk.Config
 // Load configuration will load properties from a file
 func LoadConfiguration(propPath string) ([]string, error) {
 	props, err := ReadProps(propPath)
-	utils.Check(err)
+	if err != nil {
+		return nil, utils.FileErr(propPath, err)
+	}
 	Namespace := props["NAMESPACE"]
 	Apihost := props["APIHOST"]
 	Authtoken := props["AUTH"]
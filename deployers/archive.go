@@ -0,0 +1,215 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// the entries of a snapshot archive: the snapshot with the code of its
+// actions moved to artifacts/PACKAGE/ACTION, and the manifest it was taken
+// from, for reference
+const (
+	SnapshotArchiveEntry  = "snapshot.json"
+	SnapshotArtifactsDir  = "artifacts"
+	SnapshotManifestDir   = "manifest"
+	SnapshotArchiveSuffix = ".snapshot.zip"
+)
+
+// WriteSnapshotArchive bundles a snapshot, the built code of its actions and
+// its manifest into a zip archive that can be deployed unchanged to another
+// namespace. The code is moved out of the snapshot into the archive.
+func WriteSnapshotArchive(file string, snapshot *Snapshot, manifestPath string) error {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	archive := zip.NewWriter(out)
+
+	for _, pack := range snapshot.Deployment.Packages {
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for name, record := range records {
+				if isSequence(record) || record.Action.Exec == nil || record.Action.Exec.Code == nil {
+					continue
+				}
+				if err := writeArchiveEntry(archive, path.Join(SnapshotArtifactsDir, pack.Package.Name, name), []byte(*record.Action.Exec.Code)); err != nil {
+					return err
+				}
+				record.Action.Exec.Code = nil
+			}
+		}
+	}
+
+	if manifestPath != "" {
+		if content, err := utils.Read(manifestPath); err == nil {
+			if err := writeArchiveEntry(archive, path.Join(SnapshotManifestDir, path.Base(manifestPath)), content); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveEntry(archive, SnapshotArchiveEntry, data); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeArchiveEntry(archive *zip.Writer, name string, content []byte) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(content)
+	return err
+}
+
+// ReadSnapshotArchive reads an archive written by WriteSnapshotArchive,
+// putting the code of the actions back and checking it is the one the
+// snapshot was taken with.
+func ReadSnapshotArchive(file string) (*Snapshot, error) {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, errors.New("Unable to open the snapshot archive " + file + ": " + err.Error())
+	}
+	defer archive.Close()
+
+	entries := make(map[string][]byte)
+	for _, entry := range archive.File {
+		if entry.Name != SnapshotArchiveEntry && !strings.HasPrefix(entry.Name, SnapshotArtifactsDir+"/") {
+			continue
+		}
+		reader, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries[entry.Name] = content
+	}
+
+	data, ok := entries[SnapshotArchiveEntry]
+	if !ok {
+		return nil, errors.New(file + " is not a snapshot archive, it has no " + SnapshotArchiveEntry)
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, errors.New("Unable to read the snapshot of " + file + ": " + err.Error())
+	}
+	if snapshot.Deployment == nil {
+		return nil, errors.New("The snapshot of " + file + " holds no deployment")
+	}
+
+	for _, pack := range snapshot.Deployment.Packages {
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for name, record := range records {
+				if isSequence(record) || record.Action.Exec == nil {
+					continue
+				}
+				entryPath := path.Join(pack.Package.Name, name)
+				if content, ok := entries[path.Join(SnapshotArtifactsDir, entryPath)]; ok {
+					code := string(content)
+					record.Action.Exec.Code = &code
+				}
+				if hash, ok := snapshot.Hashes[entryPath]; ok && hash != ActionHash(record.Action) {
+					return nil, errors.New("The code of action " + entryPath + " in " + file + " does not match the snapshot")
+				}
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// Retarget points a snapshot taken in one namespace at another, e.g. from
+// staging to production, rewriting the namespace and API host its entities
+// and routes refer to.
+func (snapshot *Snapshot) Retarget(apihost string, namespace string) {
+	from := snapshot.Namespace
+	if from == namespace && snapshot.ApiHost == apihost {
+		return
+	}
+	ns := func(value string) string {
+		if value == from {
+			return namespace
+		}
+		return value
+	}
+	qualified := func(name string) string {
+		if strings.HasPrefix(name, "/"+from+"/") {
+			return "/" + namespace + strings.TrimPrefix(name, "/"+from)
+		}
+		return name
+	}
+
+	deployment := snapshot.Deployment
+	for _, pack := range deployment.Packages {
+		if pack.Package != nil {
+			pack.Package.Namespace = ns(pack.Package.Namespace)
+		}
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for _, record := range records {
+				record.Action.Namespace = ns(record.Action.Namespace)
+				if isSequence(record) {
+					for i, component := range record.Action.Exec.Components {
+						record.Action.Exec.Components[i] = qualified(component)
+					}
+				}
+			}
+		}
+	}
+	for _, trigger := range deployment.Triggers {
+		trigger.Namespace = ns(trigger.Namespace)
+	}
+	for _, rule := range deployment.Rules {
+		rule.Namespace = ns(rule.Namespace)
+		if name, ok := rule.Trigger.(string); ok {
+			rule.Trigger = qualified(name)
+		}
+		if name, ok := rule.Action.(string); ok {
+			rule.Action = qualified(name)
+		}
+	}
+	for _, api := range deployment.Apis {
+		if api.ApiDoc == nil {
+			continue
+		}
+		api.ApiDoc.Namespace = ns(api.ApiDoc.Namespace)
+		api.ApiDoc.Id = strings.Replace(api.ApiDoc.Id, ":"+from+":", ":"+namespace+":", 1)
+		if action := api.ApiDoc.Action; action != nil {
+			action.Namespace = ns(action.Namespace)
+			action.BackendUrl = "https://" + apihost + "/api/v1/namespaces/" + namespace + "/actions/" + action.Name
+		}
+	}
+	snapshot.ApiHost = apihost
+	snapshot.Namespace = namespace
+}
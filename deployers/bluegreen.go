@@ -0,0 +1,286 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// the strategies a project can be deployed with
+const (
+	StrategyInPlace   = "in-place"
+	StrategyBlueGreen = "blue-green"
+)
+
+// VersionedPackage is the name a version of a package is deployed under by
+// blue/green deployments, e.g. mypackage-v2. The package name itself is a
+// binding to the version that is live.
+func VersionedPackage(pkgname string, version int) string {
+	return pkgname + "-v" + strconv.Itoa(version)
+}
+
+func packageVersion(pkgname string, name string) int {
+	if !strings.HasPrefix(name, pkgname+"-v") {
+		return 0
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(name, pkgname+"-v"))
+	if err != nil || version < 1 {
+		return 0
+	}
+	return version
+}
+
+// packageVersions returns the live version of the package, the one its
+// binding points to, and the latest version deployed, 0 when there is none.
+// A version above the live one is pending promotion.
+func (deployer *ServiceDeployer) packageVersions(pkgname string) (int, int, error) {
+	packages, err := ListAllPackages(deployer.Client)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	live, latest := 0, 0
+	for _, pkg := range packages {
+		if pkg.Name == pkgname {
+			if pkg.Binding == nil || pkg.Binding.Name == "" {
+				return 0, 0, errors.New("Package " + pkgname + " was deployed in place, undeploy it before deploying with --strategy " + StrategyBlueGreen)
+			}
+			live = packageVersion(pkgname, pkg.Binding.Name)
+		} else if version := packageVersion(pkgname, pkg.Name); version > latest {
+			latest = version
+		}
+	}
+	return live, latest, nil
+}
+
+// useVersion renames the package of the deployment to the given version,
+// along with the references the sequences, rules and routes make to it.
+// Entities keep being tagged with the project of the unversioned package.
+func (deployer *ServiceDeployer) useVersion(version int) {
+	pkgname := deployer.RootPackageName
+	versioned := VersionedPackage(pkgname, version)
+	if deployer.ProjectName == "" {
+		deployer.ProjectName = pkgname
+	}
+	rename := func(name string) string {
+		if strings.HasPrefix(name, pkgname+"/") {
+			return versioned + strings.TrimPrefix(name, pkgname)
+		}
+		return name
+	}
+
+	if pack, exists := deployer.Deployment.Packages[pkgname]; exists {
+		delete(deployer.Deployment.Packages, pkgname)
+		deployer.Deployment.Packages[versioned] = pack
+		pack.Package.Name = versioned
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for name, record := range records {
+				record.Packagename = versioned
+				if isSequence(record) {
+					// components are /namespace/package/action
+					for i, component := range record.Action.Exec.Components {
+						if parts := strings.SplitN(component, "/", 3); len(parts) == 3 && parts[0] == "" {
+							record.Action.Exec.Components[i] = "/" + parts[1] + "/" + rename(parts[2])
+						}
+					}
+				}
+				records[name] = record
+			}
+		}
+	}
+
+	for _, rule := range deployer.Deployment.Rules {
+		if action, ok := rule.Action.(string); ok {
+			rule.Action = rename(action)
+		}
+	}
+	for _, api := range deployer.Deployment.Apis {
+		if api.ApiDoc.Action != nil {
+			api.ApiDoc.Action.Name = rename(api.ApiDoc.Action.Name)
+			api.ApiDoc.Action.BackendUrl = strings.Replace(api.ApiDoc.Action.BackendUrl, "/actions/"+pkgname+"/", "/actions/"+versioned+"/", 1)
+		}
+	}
+	deployer.RootPackageName = versioned
+}
+
+// DeployBlueGreen deploys the package as a new version next to the live one,
+// e.g. mypackage-v2, and verifies it. Triggers, rules and API routes are left
+// pointing at the live version until the new one is promoted. Deploying
+// again before then updates the pending version.
+func (deployer *ServiceDeployer) DeployBlueGreen() error {
+	pkgname := deployer.RootPackageName
+	live, latest, err := deployer.packageVersions(pkgname)
+	if err != nil {
+		return err
+	}
+	version := latest + 1
+	if latest > live {
+		version = latest
+	}
+	deployer.useVersion(version)
+
+	if err := deployer.checkPlatformLimits(); err != nil {
+		return err
+	}
+	if err := deployer.checkOwnership(); err != nil {
+		return err
+	}
+
+	if err := deployer.DeployPackages(); err != nil {
+		return err
+	}
+	if err := deployer.DeployDependencies(); err != nil {
+		return err
+	}
+	if err := deployer.DeployActions(); err != nil {
+		return err
+	}
+	if err := deployer.DeploySequences(); err != nil {
+		return err
+	}
+
+	if err := deployer.verifyVersion(); err != nil {
+		return errors.New("Verification of " + deployer.RootPackageName + " failed, run `wskdeploy abort` to remove it: " + err.Error())
+	}
+
+	log.Println("\nVersion " + deployer.RootPackageName + " is deployed and verified. Run `wskdeploy promote` to make it live or `wskdeploy abort` to remove it.")
+	return nil
+}
+
+// verifyVersion checks that every action of the new version was deployed,
+// then invokes the actions given with --verify, which must succeed.
+func (deployer *ServiceDeployer) verifyVersion() error {
+	versioned := deployer.RootPackageName
+	pack, exists := deployer.Deployment.Packages[versioned]
+	if !exists {
+		return nil
+	}
+
+	names := append(sortedActionNames(pack.Actions), sortedActionNames(pack.Sequences)...)
+	for _, name := range names {
+		if _, resp, err := deployer.Client.Actions.Get(versioned + "/" + name); err != nil {
+			if isNotFound(resp) {
+				return errors.New("action " + versioned + "/" + name + " was not deployed")
+			}
+			return err
+		}
+	}
+
+	for _, name := range utils.Flags.Verify {
+		_, inSequences := pack.Sequences[name]
+		if _, inActions := pack.Actions[name]; !inActions && !inSequences {
+			return errors.New("--verify " + name + " is not an action of package " + versioned)
+		}
+		log.Print("Verifying action " + versioned + "/" + name + " ... ")
+		start := time.Now()
+		_, _, err := deployer.Client.Actions.Invoke(versioned+"/"+name, map[string]interface{}{}, true, true)
+		deployer.recordResult("action", versioned+"/"+name, OperationVerify, start, err)
+		if err != nil {
+			return errors.New("action " + versioned + "/" + name + " failed: " + err.Error())
+		}
+		log.Println("Done!")
+	}
+	return nil
+}
+
+// Promote makes the pending version of the package live. The triggers,
+// rules and API routes move to it first, then the binding named after the
+// package is pointed at it, so callers of the package switch at once. The
+// previous version is kept.
+func (deployer *ServiceDeployer) Promote() error {
+	pkgname := deployer.RootPackageName
+	live, latest, err := deployer.packageVersions(pkgname)
+	if err != nil {
+		return err
+	}
+	if latest <= live {
+		return errors.New("No version of package " + pkgname + " is pending, deploy one with --strategy " + StrategyBlueGreen + " first")
+	}
+	deployer.useVersion(latest)
+
+	if err := deployer.checkAlarmTriggers(); err != nil {
+		return err
+	}
+	if err := deployer.checkOwnership(); err != nil {
+		return err
+	}
+
+	if err := deployer.DeployTriggers(); err != nil {
+		return err
+	}
+	if err := deployer.DeployRules(); err != nil {
+		return err
+	}
+	if len(deployer.Deployment.Apis) != 0 {
+		if err := deployer.DeployApis(); err != nil {
+			return err
+		}
+	}
+
+	binding := new(whisk.BindingPackage)
+	binding.Namespace = deployer.ClientConfig.Namespace
+	binding.Name = pkgname
+	pub := false
+	binding.Publish = &pub
+	binding.Binding = whisk.Binding{deployer.ClientConfig.Namespace, deployer.RootPackageName}
+	if err := deployer.createBinding(binding); err != nil {
+		return err
+	}
+
+	log.Println("\nVersion " + deployer.RootPackageName + " of package " + pkgname + " is live.")
+	return nil
+}
+
+// Abort removes the pending version of the package, leaving the live one
+// untouched.
+func (deployer *ServiceDeployer) Abort() error {
+	pkgname := deployer.RootPackageName
+	live, latest, err := deployer.packageVersions(pkgname)
+	if err != nil {
+		return err
+	}
+	if latest <= live {
+		return errors.New("No version of package " + pkgname + " is pending")
+	}
+
+	versioned := VersionedPackage(pkgname, latest)
+	actions, err := ListAllActions(deployer.Client, versioned)
+	if err != nil {
+		return err
+	}
+	for _, action := range actions {
+		log.Print("Removing action " + versioned + "/" + action.Name + " ... ")
+		if _, err := deployer.Client.Actions.Delete(versioned + "/" + action.Name); err != nil {
+			return err
+		}
+		log.Println("Done!")
+	}
+	log.Print("Removing package " + versioned + " ... ")
+	if _, err := deployer.Client.Packages.Delete(versioned); err != nil {
+		return err
+	}
+	log.Println("Done!")
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// annotation wskdeploy keeps its own bookkeeping in
+const ManagedAnnotation = "whisk-managed"
+
+// ActionHash returns a SHA-256 over everything wskdeploy uploads for the
+// action: its exec, limits, parameters and annotations. Parameters and
+// annotations are hashed in key order so the hash does not depend on the
+// order they were read from the manifest.
+func ActionHash(action *whisk.Action) string {
+	content := struct {
+		Exec        *whisk.Exec            `json:"exec,omitempty"`
+		Limits      *whisk.Limits          `json:"limits,omitempty"`
+		Parameters  map[string]interface{} `json:"parameters"`
+		Annotations map[string]interface{} `json:"annotations"`
+	}{action.Exec, action.Limits, keyValueMap(action.Parameters), keyValueMap(action.Annotations)}
+	delete(content.Annotations, ManagedAnnotation)
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FeedParametersHash returns a SHA-256 over the parameters a trigger hands
+// to its feed, in key order, to tell whether the feed must be updated.
+func FeedParametersHash(parameters whisk.KeyValueArr) string {
+	data, err := json.Marshal(keyValueMap(parameters))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ManagedValue returns a field of the whisk-managed annotation
+func ManagedValue(annotations whisk.KeyValueArr, field string) string {
+	for _, annotation := range annotations {
+		if annotation.Key != ManagedAnnotation {
+			continue
+		}
+		if managed, ok := annotation.Value.(map[string]interface{}); ok {
+			if value, ok := managed[field].(string); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// SetManagedValue sets a field of the whisk-managed annotation, keeping the
+// fields already there.
+func SetManagedValue(annotations whisk.KeyValueArr, field string, value string) whisk.KeyValueArr {
+	for i, annotation := range annotations {
+		if annotation.Key != ManagedAnnotation {
+			continue
+		}
+		managed, ok := annotation.Value.(map[string]interface{})
+		if !ok {
+			managed = make(map[string]interface{})
+		}
+		managed[field] = value
+		annotations[i].Value = managed
+		return annotations
+	}
+	return append(annotations, whisk.KeyValue{Key: ManagedAnnotation, Value: map[string]interface{}{field: value}})
+}
+
+func keyValueMap(kvs whisk.KeyValueArr) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, kv := range kvs {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+// deployedHash returns the hash stored on the deployed action, or "" when
+// the action does not exist or was not deployed by wskdeploy.
+func (deployer *ServiceDeployer) deployedHash(pkgname string, name string) string {
+	return ManagedValue(deployer.deployedAnnotations(pkgname)[name], "hash")
+}
+
+// deployedAnnotations returns the annotations of the deployed actions of a
+// package by name. The actions of a package are listed once and cached,
+// since fetching each action would download its code.
+func (deployer *ServiceDeployer) deployedAnnotations(pkgname string) map[string]whisk.KeyValueArr {
+	deployer.mt.Lock()
+	defer deployer.mt.Unlock()
+
+	if deployer.deployedActions == nil {
+		deployer.deployedActions = make(map[string]map[string]whisk.KeyValueArr)
+	}
+
+	annotations, listed := deployer.deployedActions[pkgname]
+	if !listed {
+		annotations = make(map[string]whisk.KeyValueArr)
+		actions, err := ListAllActions(deployer.Client, pkgname)
+		if err != nil {
+			// without a listing every action is uploaded
+			log.Printf("Unable to list deployed actions of %s: %v\n", pkgname, err)
+		}
+		for _, action := range actions {
+			annotations[action.Name] = action.Annotations
+		}
+		deployer.deployedActions[pkgname] = annotations
+	}
+	return annotations
+}
@@ -43,25 +43,31 @@ func (reader *DeploymentReader) HandleYaml() error {
 	dep := reader.serviceDeployer
 
 	deploymentParser := parsers.NewYAMLParser()
-	deployment := deploymentParser.ParseDeployment(dep.DeploymentPath)
+	deployment, err := deploymentParser.ParseDeployment(dep.DeploymentPath)
+	if err != nil {
+		return err
+	}
 
 	reader.DeploymentDescriptor = deployment
 
 	return nil
 }
 
-// Update entities with deployment settings
+// Update entities with deployment settings, reporting the inputs and
+// annotations of every entity that cannot be resolved
 func (reader *DeploymentReader) BindAssets() error {
+	var errs utils.Errors
 
-	reader.bindPackageInputsAndAnnotations()
-	reader.bindActionInputsAndAnnotations()
-	reader.bindTriggerInputsAndAnnotations()
+	errs.Add(reader.bindPackageInputsAndAnnotations())
+	errs.Add(reader.bindActionInputsAndAnnotations())
+	errs.Add(reader.bindTriggerInputsAndAnnotations())
 
-	return nil
+	return errs.Err()
 
 }
 
-func (reader *DeploymentReader) bindPackageInputsAndAnnotations() {
+func (reader *DeploymentReader) bindPackageInputsAndAnnotations() error {
+	var errs utils.Errors
 
 	packArray := make([]parsers.Package, 0)
 
@@ -84,7 +90,12 @@ func (reader *DeploymentReader) bindPackageInputsAndAnnotations() {
 
 				keyVal.Key = name
 
-				keyVal.Value = utils.GetEnvVar(input)
+				value, err := parsers.ResolveParameter(&input)
+				if err != nil {
+					errs.Add(utils.EntityErr("package", pack.Packagename, utils.EntityErr("input", name, err)))
+					continue
+				}
+				keyVal.Value = value
 
 				keyValArr = append(keyValArr, keyVal)
 			}
@@ -103,25 +114,21 @@ func (reader *DeploymentReader) bindPackageInputsAndAnnotations() {
 			serviceDeployPack.Package.Parameters = keyValArr
 		}
 
-		keyValArr = keyValArr[:0]
-
 		if len(pack.Annotations) > 0 {
-			for name, input := range pack.Annotations {
-				var keyVal whisk.KeyValue
-
-				keyVal.Key = name
-				keyVal.Value = utils.GetEnvVar(input)
-
-				keyValArr = append(keyValArr, keyVal)
+			annotations, err := parsers.ComposeAnnotations(pack.Annotations)
+			if err != nil {
+				errs.Add(utils.EntityErr("package", pack.Packagename, err))
+				continue
 			}
-
-			serviceDeployPack.Package.Annotations = keyValArr
+			serviceDeployPack.Package.Annotations = parsers.MergeKeyValues(serviceDeployPack.Package.Annotations, annotations)
 		}
 
 	}
+	return errs.Err()
 }
 
-func (reader *DeploymentReader) bindActionInputsAndAnnotations() {
+func (reader *DeploymentReader) bindActionInputsAndAnnotations() error {
+	var errs utils.Errors
 
 	packArray := make([]parsers.Package, 1)
 
@@ -147,7 +154,12 @@ func (reader *DeploymentReader) bindActionInputsAndAnnotations() {
 
 					keyVal.Key = name
 
-					keyVal.Value = utils.GetEnvVar(input)
+					value, err := parsers.ResolveParameter(&input)
+					if err != nil {
+						errs.Add(utils.EntityErr("action", actionName, utils.EntityErr("input", name, err)))
+						continue
+					}
+					keyVal.Value = value
 
 					keyValArr = append(keyValArr, keyVal)
 				}
@@ -186,9 +198,11 @@ func (reader *DeploymentReader) bindActionInputsAndAnnotations() {
 		}
 
 	}
+	return errs.Err()
 }
 
-func (reader *DeploymentReader) bindTriggerInputsAndAnnotations() {
+func (reader *DeploymentReader) bindTriggerInputsAndAnnotations() error {
+	var errs utils.Errors
 
 	packArray := make([]parsers.Package, 1)
 
@@ -213,7 +227,12 @@ func (reader *DeploymentReader) bindTriggerInputsAndAnnotations() {
 					var keyVal whisk.KeyValue
 
 					keyVal.Key = name
-					keyVal.Value = utils.GetEnvVar(input)
+					value, err := parsers.ResolveParameter(&input)
+					if err != nil {
+						errs.Add(utils.EntityErr("trigger", triggerName, utils.EntityErr("input", name, err)))
+						continue
+					}
+					keyVal.Value = value
 
 					keyValArr = append(keyValArr, keyVal)
 				}
@@ -235,23 +254,19 @@ func (reader *DeploymentReader) bindTriggerInputsAndAnnotations() {
 				}
 			}
 
-			keyValArr = keyValArr[:0]
-
+			// the feed annotation of the manifest is kept
 			if len(trigger.Annotations) > 0 {
-				for name, input := range trigger.Annotations {
-					var keyVal whisk.KeyValue
-
-					keyVal.Key = name
-					keyVal.Value = input
-
-					keyValArr = append(keyValArr, keyVal)
-				}
-
 				if wskTrigger, exists := serviceDeployment.Triggers[triggerName]; exists {
-					wskTrigger.Annotations = keyValArr
+					annotations, err := parsers.ComposeAnnotations(trigger.Annotations)
+					if err != nil {
+						errs.Add(utils.EntityErr("trigger", triggerName, err))
+						continue
+					}
+					wskTrigger.Annotations = parsers.MergeKeyValues(wskTrigger.Annotations, annotations)
 				}
 			}
 		}
 
 	}
+	return errs.Err()
 }
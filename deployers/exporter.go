@@ -0,0 +1,296 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// directory exported action code is written to, relative to the manifest
+const ExportCodeDir = "actions"
+
+// file extension of exported code, by runtime family
+var exportExtensions = map[string]string{
+	"nodejs": ".js",
+	"python": ".py",
+	"swift":  ".swift",
+	"php":    ".php",
+	"java":   ".jar",
+}
+
+// Exporter builds a manifest from a package deployed in a namespace
+type Exporter struct {
+	Client *whisk.Client
+	// write the code of each action next to the manifest
+	WithCode bool
+}
+
+func NewExporter(client *whisk.Client) *Exporter {
+	return &Exporter{Client: client, WithCode: true}
+}
+
+// Export reads the package, its actions and sequences, and the rules and
+// triggers that fire its actions. Action code is written under dir.
+func (exporter *Exporter) Export(pkgname string, dir string) (*parsers.ManifestYAML, error) {
+	pkg, _, err := exporter.Client.Packages.Get(pkgname)
+	if err != nil {
+		return nil, errors.New("Unable to read package " + pkgname + ": " + err.Error())
+	}
+
+	manifest := &parsers.ManifestYAML{SchemaVersion: strconv.Itoa(parsers.CurrentSchemaVersion)}
+	manifest.Package.Packagename = pkgname
+	manifest.Package.Version = pkg.Version
+	manifest.Package.Inputs = exportInputs(pkg.Parameters)
+	manifest.Package.Annotations = exportAnnotations(pkg.Annotations)
+	manifest.Package.Actions = make(map[string]parsers.Action)
+	manifest.Package.Sequences = make(map[string]parsers.Sequence)
+	manifest.Package.Triggers = make(map[string]parsers.Trigger)
+	manifest.Package.Rules = make(map[string]parsers.Rule)
+
+	actions, err := ListAllActions(exporter.Client, pkgname)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, summary := range actions {
+		action, _, err := exporter.Client.Actions.Get(pkgname + "/" + summary.Name)
+		if err != nil {
+			return nil, errors.New("Unable to read action " + pkgname + "/" + summary.Name + ": " + err.Error())
+		}
+
+		if action.Exec != nil && action.Exec.Kind == "sequence" {
+			manifest.Package.Sequences[summary.Name] = parsers.Sequence{
+				Actions:     exportComponents(pkgname, action.Exec.Components),
+				Annotations: exportAnnotations(action.Annotations),
+			}
+			continue
+		}
+
+		exported, err := exporter.exportAction(summary.Name, action, dir)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Package.Actions[summary.Name] = exported
+	}
+
+	if err := exporter.exportRules(manifest, pkgname); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (exporter *Exporter) exportAction(name string, action *whisk.Action, dir string) (parsers.Action, error) {
+	exported := parsers.Action{
+		Version:     action.Version,
+		Inputs:      exportInputs(action.Parameters),
+		Annotations: exportAnnotations(action.Annotations),
+	}
+
+	if webexport, ok := exported.Annotations["web-export"]; ok {
+		if enabled, isBool := webexport.(bool); isBool && enabled {
+			exported.Webexport = "true"
+		}
+		delete(exported.Annotations, "web-export")
+		if len(exported.Annotations) == 0 {
+			exported.Annotations = nil
+		}
+	}
+
+	// compositions keep their conductor annotation, plain conductors use the key
+	if conductor, isBool := exported.Annotations[utils.CONDUCTOR_ANNOT].(bool); isBool {
+		exported.Conductor = conductor
+		delete(exported.Annotations, utils.CONDUCTOR_ANNOT)
+		if len(exported.Annotations) == 0 {
+			exported.Annotations = nil
+		}
+	}
+
+	if action.Limits != nil {
+		exported.Limits = &parsers.Limits{
+			Timeout:    action.Limits.Timeout,
+			MemorySize: action.Limits.Memory,
+			LogSize:    action.Limits.Logsize,
+		}
+	}
+
+	if action.Exec == nil {
+		return exported, nil
+	}
+	exported.Runtime = action.Exec.Kind
+
+	family := strings.Split(action.Exec.Kind, ":")[0]
+	extension, known := exportExtensions[family]
+	binary := action.Exec.Binary != nil && *action.Exec.Binary
+	if binary && family != "java" {
+		extension = ".zip"
+	}
+	if !known && !binary {
+		// docker and other kinds without source code
+		return exported, nil
+	}
+
+	exported.Location = path.Join(ExportCodeDir, name+extension)
+	if !exporter.WithCode || action.Exec.Code == nil {
+		return exported, nil
+	}
+
+	code := []byte(*action.Exec.Code)
+	if binary || family == "java" {
+		decoded, err := base64.StdEncoding.DecodeString(*action.Exec.Code)
+		if err != nil {
+			return exported, errors.New("Unable to decode the code of action " + name + ": " + err.Error())
+		}
+		code = decoded
+	}
+
+	if err := os.MkdirAll(path.Join(dir, ExportCodeDir), 0755); err != nil {
+		return exported, err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, exported.Location), code, 0644); err != nil {
+		return exported, err
+	}
+	return exported, nil
+}
+
+// rules whose action is in the package, and the triggers they use
+func (exporter *Exporter) exportRules(manifest *parsers.ManifestYAML, pkgname string) error {
+	rules, err := ListAllRules(exporter.Client)
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range rules {
+		rule, _, err := exporter.Client.Rules.Get(summary.Name)
+		if err != nil {
+			return errors.New("Unable to read rule " + summary.Name + ": " + err.Error())
+		}
+
+		actionPkg, actionName := refPackageAndName(rule.Action)
+		if actionPkg != pkgname {
+			continue
+		}
+		_, triggerName := refPackageAndName(rule.Trigger)
+		manifest.Package.Rules[rule.Name] = parsers.Rule{Trigger: triggerName, Action: actionName}
+
+		if _, exported := manifest.Package.Triggers[triggerName]; exported {
+			continue
+		}
+		trigger, _, err := exporter.Client.Triggers.Get(triggerName)
+		if err != nil {
+			return errors.New("Unable to read trigger " + triggerName + ": " + err.Error())
+		}
+		exportedTrigger := parsers.Trigger{Annotations: exportAnnotations(trigger.Annotations)}
+		if feed, ok := exportedTrigger.Annotations["feed"].(string); ok {
+			exportedTrigger.Source = feed
+			delete(exportedTrigger.Annotations, "feed")
+			if len(exportedTrigger.Annotations) == 0 {
+				exportedTrigger.Annotations = nil
+			}
+		} else {
+			exportedTrigger.Inputs = exportInputs(trigger.Parameters)
+		}
+		manifest.Package.Triggers[triggerName] = exportedTrigger
+	}
+	return nil
+}
+
+// the package and name of a rule's trigger or action: "/ns/pkg/name",
+// "ns/pkg/name" or a {"path": "ns/pkg", "name": "name"} object
+func refPackageAndName(ref interface{}) (string, string) {
+	full := strings.Trim(qualifiedRef(ref).(string), "/")
+	parts := strings.Split(full, "/")
+	if len(parts) < 3 {
+		return "", parts[len(parts)-1]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+// sequence components relative to the exported package
+func exportComponents(pkgname string, components []string) string {
+	names := make([]string, 0, len(components))
+	for _, component := range components {
+		parts := strings.Split(strings.Trim(component, "/"), "/")
+		switch {
+		case len(parts) >= 3 && parts[len(parts)-2] == pkgname:
+			names = append(names, parts[len(parts)-1])
+		case len(parts) >= 3:
+			names = append(names, strings.Join(parts[len(parts)-2:], "/"))
+		default:
+			names = append(names, parts[len(parts)-1])
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func exportInputs(params whisk.KeyValueArr) map[string]parsers.Parameter {
+	if len(params) == 0 {
+		return nil
+	}
+	inputs := make(map[string]parsers.Parameter)
+	for _, param := range params {
+		inputs[param.Key] = parsers.Parameter{Value: param.Value}
+	}
+	return inputs
+}
+
+// annotations set by the server or by wskdeploy itself are not exported
+func exportAnnotations(annotations whisk.KeyValueArr) map[string]interface{} {
+	exported := make(map[string]interface{})
+	for _, annotation := range annotations {
+		if annotation.Key == "exec" || annotation.Key == ManagedAnnotation {
+			continue
+		}
+		exported[annotation.Key] = annotation.Value
+	}
+	if len(exported) == 0 {
+		return nil
+	}
+	return exported
+}
+
+// ExportedNames lists the action, sequence, trigger and rule names of an
+// exported manifest, for reporting.
+func ExportedNames(manifest *parsers.ManifestYAML) []string {
+	names := make([]string, 0)
+	for name := range manifest.Package.Actions {
+		names = append(names, "action "+name)
+	}
+	for name := range manifest.Package.Sequences {
+		names = append(names, "sequence "+name)
+	}
+	for name := range manifest.Package.Triggers {
+		names = append(names, "trigger "+name)
+	}
+	for name := range manifest.Package.Rules {
+		names = append(names, "rule "+name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -48,14 +48,20 @@ func (reader *FileSystemReader) ReadProjectDirectory(manifest *parsers.ManifestY
 	fmt.Println("Inspecting project directory for actions....")
 
 	projectPathCount, err := reader.getFilePathCount(reader.serviceDeployer.ProjectPath)
-	utils.Check(err)
+	if err != nil {
+		return nil, err
+	}
 
 	actions := make([]utils.ActionRecord, 0)
+	// the files that cannot be read, reported together
+	var errs utils.Errors
 
 	err = filepath.Walk(reader.serviceDeployer.ProjectPath, func(fpath string, f os.FileInfo, err error) error {
 		if fpath != reader.serviceDeployer.ProjectPath {
 			pathCount, err := reader.getFilePathCount(fpath)
-			utils.Check(err)
+			if err != nil {
+				return err
+			}
 
 			if !f.IsDir() {
 				if pathCount-projectPathCount == 1 || strings.HasPrefix(fpath, reader.serviceDeployer.ProjectPath+"/"+FileSystemSourceDirectoryName) {
@@ -73,7 +79,10 @@ func (reader *FileSystemReader) ReadProjectDirectory(manifest *parsers.ManifestY
 
 					if foundFile == true {
 						_, action, err := reader.CreateActionFromFile(reader.serviceDeployer.ManifestPath, fpath)
-						utils.Check(err)
+						if err != nil {
+							errs.Add(utils.FileErr(fpath, err))
+							return nil
+						}
 
 						var record utils.ActionRecord
 						record.Action = action
@@ -96,6 +105,9 @@ func (reader *FileSystemReader) ReadProjectDirectory(manifest *parsers.ManifestY
 	if err != nil {
 		return nil, err
 	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
 
 	return actions, nil
 
@@ -108,21 +120,12 @@ func (reader *FileSystemReader) CreateActionFromFile(manipath, filePath string)
 	action := new(whisk.Action)
 
 	// process source code files
-	if ext == ".swift" || ext == ".js" || ext == ".py" {
-
-		kind := "nodejs:default"
-
-		switch ext {
-		case ".swift":
-			kind = "swift:default"
-		case ".js":
-			kind = "nodejs:default"
-		case ".py":
-			kind = "python"
-		}
+	if kind, ok := utils.KindForExtension(ext); ok && !utils.IsBinaryArtifact(filePath) {
 
 		dat, err := new(utils.ContentReader).LocalReader.ReadLocal(filePath)
-		utils.Check(err)
+		if err != nil {
+			return "", nil, err
+		}
 
 		action.Exec = new(whisk.Exec)
 		code := string(dat)
@@ -0,0 +1,220 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// DefaultHistoryDir is the directory of a project its deployments are
+// recorded in, unless --history-dir is given.
+func DefaultHistoryDir(projectPath string) string {
+	return filepath.Join(projectPath, ".wskdeploy", "history")
+}
+
+// Snapshot records a successful deployment: the resolved deployment plan,
+// code included, and the hashes of its actions, so it can be deployed again.
+// Snapshots hold the resolved inputs of the deployment in clear and are
+// written readable by their owner only.
+type Snapshot struct {
+	Version     int                    `json:"version"`
+	Time        time.Time              `json:"time"`
+	ApiHost     string                 `json:"apihost"`
+	Namespace   string                 `json:"namespace"`
+	Project     string                 `json:"project"`
+	Package     string                 `json:"package"`
+	Manifest    string                 `json:"manifest,omitempty"`
+	ProjectHash string                 `json:"projectHash,omitempty"`
+	Hashes      map[string]string      `json:"hashes"`
+	Deployment  *DeploymentApplication `json:"deployment"`
+}
+
+// Snapshot returns the snapshot of the deployment plan, taken before
+// deploying alters it.
+func (deployer *ServiceDeployer) Snapshot() (*Snapshot, error) {
+	// a copy, as deploying qualifies the names of the plan
+	data, err := json.Marshal(deployer.Deployment)
+	if err != nil {
+		return nil, err
+	}
+	deployment := NewDeploymentApplication()
+	if err := json.Unmarshal(data, deployment); err != nil {
+		return nil, err
+	}
+	for _, api := range deployment.Apis {
+		// the auth key is set again when the snapshot is deployed
+		if api.ApiDoc != nil && api.ApiDoc.Action != nil {
+			api.ApiDoc.Action.Auth = ""
+		}
+	}
+
+	snapshot := &Snapshot{
+		Time:        time.Now().UTC(),
+		Project:     deployer.Project(),
+		Package:     deployer.RootPackageName,
+		Manifest:    deployer.ManifestPath,
+		ProjectHash: deployer.projectHash(),
+		Hashes:      make(map[string]string),
+		Deployment:  deployment,
+	}
+	if deployer.ClientConfig != nil {
+		snapshot.ApiHost = deployer.ClientConfig.Host
+		snapshot.Namespace = deployer.ClientConfig.Namespace
+	}
+	for _, pack := range deployment.Packages {
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for name, record := range records {
+				snapshot.Hashes[deployer.actionPath(pack.Package.Name, name)] = ActionHash(record.Action)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// recordSnapshot writes the snapshot of a successful deployment to the
+// history directory as the next version, e.g. 3.json
+func (deployer *ServiceDeployer) recordSnapshot(snapshot *Snapshot) error {
+	history, err := ReadHistory(deployer.HistoryDir)
+	if err != nil {
+		return err
+	}
+	snapshot.Version = 1
+	if len(history) > 0 {
+		snapshot.Version = history[len(history)-1].Version + 1
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(deployer.HistoryDir, 0755); err != nil {
+		return err
+	}
+	file := filepath.Join(deployer.HistoryDir, strconv.Itoa(snapshot.Version)+".json")
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		return err
+	}
+	log.Println("Deployment recorded as version " + strconv.Itoa(snapshot.Version) + " in " + deployer.HistoryDir + ".")
+	return nil
+}
+
+// ReadHistory returns the snapshots recorded in a history directory, oldest
+// first. A directory that does not exist holds no snapshots.
+func ReadHistory(dir string) ([]*Snapshot, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*Snapshot, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		snapshot, err := ReadSnapshot(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, snapshot)
+	}
+	sort.Sort(byVersion(history))
+	return history, nil
+}
+
+// ReadSnapshot reads a snapshot written by a deployment.
+func ReadSnapshot(file string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, errors.New("Unable to read the snapshot " + file + ": " + err.Error())
+	}
+	if snapshot.Deployment == nil {
+		return nil, errors.New("The snapshot " + file + " holds no deployment")
+	}
+	return snapshot, nil
+}
+
+type byVersion []*Snapshot
+
+func (s byVersion) Len() int           { return len(s) }
+func (s byVersion) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byVersion) Less(i, j int) bool { return s[i].Version < s[j].Version }
+
+// RollbackSnapshot returns the snapshot of version to in the history, or
+// the one before the last deployment to the namespace when to is 0.
+func RollbackSnapshot(history []*Snapshot, namespace string, to int) (*Snapshot, error) {
+	if to == 0 {
+		deployments := make([]*Snapshot, 0, len(history))
+		for _, snapshot := range history {
+			if snapshot.Namespace == namespace {
+				deployments = append(deployments, snapshot)
+			}
+		}
+		if len(deployments) < 2 {
+			return nil, errors.New("No previous deployment to namespace " + namespace + " is recorded to roll back to")
+		}
+		return deployments[len(deployments)-2], nil
+	}
+	for _, snapshot := range history {
+		if snapshot.Version == to {
+			return snapshot, nil
+		}
+	}
+	return nil, errors.New("No deployment version " + strconv.Itoa(to) + " is recorded")
+}
+
+// UseSnapshot sets the deployment plan to the one a snapshot recorded, to
+// deploy it again to the namespace it was deployed to.
+func (deployer *ServiceDeployer) UseSnapshot(snapshot *Snapshot) error {
+	if deployer.ClientConfig != nil && snapshot.Namespace != deployer.ClientConfig.Namespace {
+		return errors.New("Version " + strconv.Itoa(snapshot.Version) + " was deployed to namespace " + snapshot.Namespace + ", not " + deployer.ClientConfig.Namespace)
+	}
+
+	deployer.Deployment = snapshot.Deployment
+	deployer.RootPackageName = snapshot.Package
+	if deployer.ProjectName == "" && snapshot.Project != snapshot.Package {
+		deployer.ProjectName = snapshot.Project
+	}
+	deployer.ManifestPath = snapshot.Manifest
+	deployer.manifestHashOnce.Do(func() {
+		deployer.manifestHash = snapshot.ProjectHash
+	})
+	for _, api := range deployer.Deployment.Apis {
+		if api.ApiDoc != nil && api.ApiDoc.Action != nil && deployer.Client != nil {
+			api.ApiDoc.Action.Auth = deployer.Client.Config.AuthToken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+)
+
+// checkInputs fails before anything is deployed when the values bound to the
+// inputs the manifest declares, by the manifest, the deployment file or
+// --param, do not satisfy them. Actions receive the inputs of their package
+// too. Numbers and booleans bound to string inputs are sent as strings.
+func (deployer *ServiceDeployer) checkInputs(manifest *parsers.ManifestYAML) error {
+	pkg := manifest.Package
+	pack, exists := deployer.Deployment.Packages[pkg.Packagename]
+	if !exists {
+		return nil
+	}
+	var packageParams whisk.KeyValueArr
+	if pack.Package != nil {
+		packageParams = pack.Package.Parameters
+	}
+
+	problems := parsers.CheckInputs("package "+pkg.Packagename, pkg.Inputs, boundValue(pkg.Inputs, packageParams))
+	names := make([]string, 0, len(pkg.Actions))
+	for name := range pkg.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if record, exists := pack.Actions[name]; exists {
+			inputs := pkg.Actions[name].Inputs
+			problems = append(problems, parsers.CheckInputs("action "+deployer.actionPath(pkg.Packagename, name), inputs, boundValue(inputs, record.Action.Parameters, packageParams))...)
+		}
+	}
+
+	names = names[:0]
+	for name := range pkg.Triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if trigger, exists := deployer.Deployment.Triggers[name]; exists {
+			inputs := pkg.Triggers[name].Inputs
+			problems = append(problems, parsers.CheckInputs("trigger "+name, inputs, boundValue(inputs, trigger.Parameters))...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Unsatisfied inputs, bind them in the deployment file or with --param:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// boundValue looks inputs up in the parameters, the first ones taking
+// precedence, turning the numbers and booleans bound to string inputs into
+// strings
+func boundValue(inputs map[string]parsers.Parameter, params ...whisk.KeyValueArr) func(name string) (interface{}, bool) {
+	return func(name string) (interface{}, bool) {
+		for _, keyValues := range params {
+			for i := range keyValues {
+				if keyValues[i].Key != name {
+					continue
+				}
+				switch keyValues[i].Value.(type) {
+				case int, int64, float64, bool:
+					if inputs[name].Type == "string" {
+						keyValues[i].Value = fmt.Sprint(keyValues[i].Value)
+					}
+				}
+				return keyValues[i].Value, true
+			}
+		}
+		return nil, false
+	}
+}
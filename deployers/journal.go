@@ -0,0 +1,216 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// journalEntry records an entity as it was before this run changed it.
+// previous is nil when the run created the entity.
+type journalEntry struct {
+	kind     string
+	name     string
+	previous interface{}
+	feed     string // feed of a created trigger, so rollback can remove it
+}
+
+// Journal keeps track of the entities a deployment changed, so that a
+// failed deployment can be rolled back to the state before the run.
+type Journal struct {
+	mu      sync.Mutex
+	entries []journalEntry
+}
+
+func (journal *Journal) add(entry journalEntry) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	journal.entries = append(journal.entries, entry)
+}
+
+func (journal *Journal) Len() int {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	return len(journal.entries)
+}
+
+// the record* functions are called right before an entity is written, and
+// only when the deployment was asked to roll back on failure
+
+func (deployer *ServiceDeployer) recordPackage(name string) {
+	if deployer.journal == nil {
+		return
+	}
+	existing, resp, err := deployer.Client.Packages.Get(name)
+	deployer.record("package", name, existing, resp, err)
+}
+
+func (deployer *ServiceDeployer) recordAction(name string) {
+	if deployer.journal == nil {
+		return
+	}
+	existing, resp, err := deployer.Client.Actions.Get(name)
+	if err == nil {
+		// restore under the same qualified name
+		existing.Name = name
+	}
+	deployer.record("action", name, existing, resp, err)
+}
+
+func (deployer *ServiceDeployer) recordTrigger(trigger *whisk.Trigger, feed string) {
+	if deployer.journal == nil {
+		return
+	}
+	existing, resp, err := deployer.Client.Triggers.Get(trigger.Name)
+	if err != nil && isNotFound(resp) {
+		deployer.journal.add(journalEntry{kind: "trigger", name: trigger.Name, feed: feed})
+		return
+	}
+	deployer.record("trigger", trigger.Name, existing, resp, err)
+}
+
+func (deployer *ServiceDeployer) recordRule(name string) {
+	if deployer.journal == nil {
+		return
+	}
+	existing, resp, err := deployer.Client.Rules.Get(name)
+	deployer.record("rule", name, existing, resp, err)
+}
+
+func (deployer *ServiceDeployer) record(kind string, name string, existing interface{}, resp *http.Response, err error) {
+	if err != nil {
+		if isNotFound(resp) {
+			deployer.journal.add(journalEntry{kind: kind, name: name})
+			return
+		}
+		log.Printf("Unable to record the state of %s %s, it cannot be rolled back: %v\n", kind, name, err)
+		return
+	}
+	deployer.journal.add(journalEntry{kind: kind, name: name, previous: existing})
+}
+
+// Rollback undoes the changes recorded in the journal, newest first:
+// created entities are deleted and updated entities are written back as
+// they were before the run.
+func (deployer *ServiceDeployer) Rollback() error {
+	if deployer.journal == nil {
+		return nil
+	}
+
+	deployer.journal.mu.Lock()
+	entries := deployer.journal.entries
+	deployer.journal.entries = nil
+	deployer.journal.mu.Unlock()
+
+	failed := make([]string, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if err := deployer.rollbackEntry(entry); err != nil {
+			log.Printf("Unable to roll back %s %s: %v\n", entry.kind, entry.name, err)
+			failed = append(failed, entry.kind+" "+entry.name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New("Rollback did not complete for " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (deployer *ServiceDeployer) rollbackEntry(entry journalEntry) error {
+	var resp *http.Response
+	var err error
+
+	switch entry.kind {
+	case "package":
+		if entry.previous == nil {
+			log.Println("Rolling back: removing package " + entry.name)
+			resp, err = deployer.Client.Packages.Delete(entry.name)
+		} else {
+			log.Println("Rolling back: restoring package " + entry.name)
+			_, _, err = deployer.Client.Packages.Insert(entry.previous.(*whisk.Package), true)
+		}
+
+	case "action":
+		if entry.previous == nil {
+			log.Println("Rolling back: removing action " + entry.name)
+			resp, err = deployer.Client.Actions.Delete(entry.name)
+		} else {
+			log.Println("Rolling back: restoring action " + entry.name)
+			_, _, err = deployer.Client.Actions.Insert(entry.previous.(*whisk.Action), true)
+		}
+
+	case "trigger":
+		if entry.previous == nil {
+			log.Println("Rolling back: removing trigger " + entry.name)
+			trigger := &whisk.Trigger{Name: entry.name}
+			if entry.feed != "" {
+				deployer.deleteFeedAction(trigger, entry.feed)
+			} else {
+				_, resp, err = deployer.Client.Triggers.Delete(entry.name)
+			}
+		} else {
+			log.Println("Rolling back: restoring trigger " + entry.name)
+			_, _, err = deployer.Client.Triggers.Insert(entry.previous.(*whisk.Trigger), true)
+		}
+
+	case "rule":
+		if entry.previous == nil {
+			log.Println("Rolling back: removing rule " + entry.name)
+			if _, resp, err = deployer.Client.Rules.SetState(entry.name, "inactive"); err == nil {
+				resp, err = deployer.Client.Rules.Delete(entry.name)
+			}
+		} else {
+			log.Println("Rolling back: restoring rule " + entry.name)
+			previous := entry.previous.(*whisk.Rule)
+			rule := &whisk.Rule{
+				Name:        previous.Name,
+				Trigger:     qualifiedRef(previous.Trigger),
+				Action:      qualifiedRef(previous.Action),
+				Annotations: previous.Annotations,
+				Publish:     previous.Publish,
+			}
+			if _, _, err = deployer.Client.Rules.Insert(rule, true); err == nil && previous.Status != "" {
+				_, _, err = deployer.Client.Rules.SetState(rule.Name, previous.Status)
+			}
+		}
+	}
+
+	// an entity whose creation failed has nothing to remove
+	if err != nil && entry.previous == nil && isNotFound(resp) {
+		return nil
+	}
+	return err
+}
+
+// the fully qualified name of a rule's trigger or action as returned by the
+// server, either a plain path or a {"name", "path"} object
+func qualifiedRef(ref interface{}) interface{} {
+	if value, ok := ref.(map[string]interface{}); ok {
+		name, _ := value["name"].(string)
+		path, _ := value["path"].(string)
+		return "/" + strings.Trim(path, "/") + "/" + name
+	}
+	return ref
+}
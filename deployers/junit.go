@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// the JUnit XML elements Jenkins and GitLab read test reports from
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report, so that CI servers
+// show a run as tests: each entity deployed or removed and each action
+// verified is a test case of the suite of its target, unchanged entities are
+// skipped, and a last case named after the command fails when the run does.
+func (results *Results) WriteJUnit(w io.Writer) error {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+
+	suites := make([]*junitTestSuite, 0)
+	durations := make([]int64, 0)
+	suiteOf := func(target string) int {
+		name := results.Project
+		if target != "" {
+			name = target
+		}
+		for i, suite := range suites {
+			if suite.Name == name {
+				return i
+			}
+		}
+		suites = append(suites, &junitTestSuite{Name: name})
+		durations = append(durations, 0)
+		return len(suites) - 1
+	}
+	add := func(index int, testCase junitTestCase, duration int64) {
+		suite := suites[index]
+		switch {
+		case testCase.Failure != nil:
+			suite.Failures++
+		case testCase.Skipped != nil:
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+		durations[index] += duration
+	}
+
+	for _, entity := range results.Entities {
+		testCase := junitTestCase{ClassName: entity.Kind, Name: entity.Operation + " " + entity.Name, Time: seconds(entity.Duration)}
+		if entity.Error != "" {
+			testCase.Failure = &junitFailure{Message: entity.Error, Text: entity.Error}
+		} else if entity.Operation == OperationUnchanged {
+			testCase.Skipped = &struct{}{}
+		}
+		add(suiteOf(entity.Target), testCase, entity.Duration)
+	}
+
+	// the run as a whole, failing even when it stopped before any entity
+	run := junitTestCase{ClassName: "wskdeploy", Name: results.Command, Time: seconds(results.Duration)}
+	if !results.Success {
+		run.Failure = &junitFailure{Message: results.Error, Text: results.Error}
+	}
+	add(suiteOf(""), run, 0)
+
+	report := junitTestSuites{Name: "wskdeploy " + results.Command, Time: seconds(results.Duration)}
+	for i, suite := range suites {
+		suite.Time = seconds(durations[i])
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, *suite)
+	}
+
+	output, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, xml.Header+string(output))
+	return err
+}
+
+// a duration in milliseconds as the seconds of JUnit reports
+func seconds(duration int64) string {
+	return strconv.FormatFloat(float64(duration)/1000, 'f', 3, 64)
+}
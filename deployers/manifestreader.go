@@ -20,6 +20,7 @@ package deployers
 import (
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/openwhisk/openwhisk-client-go/whisk"
@@ -43,57 +44,74 @@ func NewManfiestReader(serviceDeployer *ServiceDeployer) *ManifestReader {
 func (deployer *ManifestReader) ParseManifest() (*parsers.ManifestYAML, *parsers.YAMLParser, error) {
 	dep := deployer.serviceDeployer
 	manifestParser := parsers.NewYAMLParser()
-	manifest := manifestParser.ParseManifest(dep.ManifestPath)
+	manifest, err := manifestParser.ParseManifest(dep.ManifestPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dep.PackageName != "" {
+		manifest.Package.Packagename = dep.PackageName
+	}
 
 	return manifest, manifestParser, nil
 }
 
 func (reader *ManifestReader) InitRootPackage(manifestParser *parsers.YAMLParser, manifest *parsers.ManifestYAML) error {
 	packg, err := manifestParser.ComposePackage(manifest)
-	utils.Check(err)
-	reader.SetPackage(packg)
-
-	return nil
+	if err != nil {
+		return err
+	}
+	return reader.SetPackage(packg)
 }
 
-// Wrapper parser to handle yaml dir
+// Wrapper parser to handle yaml dir. The entities of each kind are composed
+// and set independently of the others, and the errors of all of them are
+// reported together.
 func (deployer *ManifestReader) HandleYaml(sdeployer *ServiceDeployer, manifestParser *parsers.YAMLParser, manifest *parsers.ManifestYAML) error {
+	var errs utils.Errors
 
 	deps, err := manifestParser.ComposeDependencies(manifest, deployer.serviceDeployer.ProjectPath)
+	errs.Add(err)
 
 	actions, aubindings, err := manifestParser.ComposeActions(manifest, deployer.serviceDeployer.ManifestPath)
-	utils.Check(err)
+	errs.Add(err)
 
 	sequences, err := manifestParser.ComposeSequences(deployer.serviceDeployer.ClientConfig.Namespace, manifest)
-	utils.Check(err)
+	errs.Add(err)
 
 	triggers, err := manifestParser.ComposeTriggers(manifest)
-	utils.Check(err)
+	errs.Add(err)
 
 	rules, err := manifestParser.ComposeRules(manifest)
-	utils.Check(err)
-
-	err = deployer.SetDependencies(deps)
-	utils.Check(err)
+	errs.Add(err)
 
-	err = deployer.SetActions(actions)
-	utils.Check(err)
+	routes, err := manifestParser.ComposeApiRoutes(manifest)
+	errs.Add(err)
 
-	err = deployer.SetSequences(sequences)
-	utils.Check(err)
+	for _, aubinding := range aubindings {
+		route, err := aubinding.Route(manifest.Package.Packagename)
+		if err != nil {
+			errs.Add(utils.EntityErr("action", aubinding.ActionName, err))
+			continue
+		}
+		routes = append(routes, route)
+	}
 
-	err = deployer.SetTriggers(triggers)
-	utils.Check(err)
+	if len(errs) > 0 {
+		return errs.Err()
+	}
 
-	err = deployer.SetRules(rules)
-	utils.Check(err)
+	errs.Add(deployer.SetDependencies(deps))
+	errs.Add(deployer.SetActions(actions))
+	errs.Add(deployer.SetSequences(sequences))
+	errs.Add(deployer.SetTriggers(triggers))
+	errs.Add(deployer.SetRules(rules))
 
-	//only set api if aubindings
-	if len(aubindings) != 0 {
-		err = deployer.SetApis(sdeployer, aubindings)
+	//only set api if there are routes
+	if len(routes) != 0 {
+		errs.Add(deployer.SetApis(sdeployer, routes))
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func (reader *ManifestReader) SetDependencies(deps map[string]utils.DependencyRecord) error {
@@ -101,9 +119,9 @@ func (reader *ManifestReader) SetDependencies(deps map[string]utils.DependencyRe
 		if !dep.IsBinding && !reader.IsUndeploy {
 			if _, exists := reader.serviceDeployer.DependencyMaster[depName]; !exists {
 				// dependency
-				gitReader := utils.NewGitReader(depName, dep)
-				err := gitReader.CloneDependency()
-				utils.Check(err)
+				if err := reader.cloneDependency(depName, dep); err != nil {
+					return err
+				}
 			} else {
 				// TODO: we should do a check to make sure this dependency is compatible with an already installed one.
 				// If not, we should throw dependency mismatch error.
@@ -119,6 +137,43 @@ func (reader *ManifestReader) SetDependencies(deps map[string]utils.DependencyRe
 	return nil
 }
 
+// clone a github dependency at the commit it is locked to, pinning it to the
+// current commit of its branch or tag when it is not locked yet
+func (reader *ManifestReader) cloneDependency(depName string, dep utils.DependencyRecord) error {
+	deployer := reader.serviceDeployer
+	if deployer.lock == nil {
+		lock, err := utils.ReadLockFile(path.Join(deployer.ProjectPath, utils.LockFileName))
+		if err != nil {
+			return err
+		}
+		deployer.lock = lock
+	}
+
+	gitReader := utils.NewGitReader(depName, dep)
+	if locked, ok := deployer.lock.Locked(depName, dep); ok && !utils.Flags.UpdateDeps {
+		gitReader.Commit = locked.Commit
+		gitReader.Checksum = locked.Sha256
+	} else {
+		commit, err := gitReader.ResolveCommit()
+		if err != nil {
+			return err
+		}
+		gitReader.Commit = commit
+	}
+
+	if err := gitReader.CloneDependency(); err != nil {
+		return err
+	}
+
+	deployer.lock.Lock(depName, utils.LockedDependency{
+		Location: dep.Location,
+		Version:  dep.Version,
+		Commit:   gitReader.Commit,
+		Sha256:   gitReader.Checksum,
+	})
+	return deployer.lock.Save()
+}
+
 func (reader *ManifestReader) SetPackage(pkg *whisk.Package) error {
 
 	dep := reader.serviceDeployer
@@ -155,6 +210,8 @@ func (reader *ManifestReader) SetActions(actions []utils.ActionRecord) error {
 	dep.mt.Lock()
 	defer dep.mt.Unlock()
 
+	// every action is checked, so that all of their problems are reported
+	var errs utils.Errors
 	for _, manifestAction := range actions {
 		existAction, exists := reader.serviceDeployer.Deployment.Packages[manifestAction.Packagename].Actions[manifestAction.Action.Name]
 
@@ -181,23 +238,24 @@ func (reader *ManifestReader) SetActions(actions []utils.ActionRecord) error {
 					existAction.Filepath = manifestAction.Filepath
 				}
 
-				err := reader.checkAction(existAction)
-				utils.Check(err)
+				errs.Add(reader.checkAction(existAction))
 
 			} else {
 				// Action exists, but references two different sources
-				return errors.New("manifestReader. Error: Conflict detected for action named " + existAction.Action.Name + ". Found two locations for source file: " + existAction.Filepath + " and " + manifestAction.Filepath)
+				errs.Add(errors.New("manifestReader. Error: Conflict detected for action named " + existAction.Action.Name + ". Found two locations for source file: " + existAction.Filepath + " and " + manifestAction.Filepath))
 			}
 		} else {
 			// not a new action so to actions in package
 
-			err := reader.checkAction(manifestAction)
-			utils.Check(err)
+			if err := reader.checkAction(manifestAction); err != nil {
+				errs.Add(err)
+				continue
+			}
 			reader.serviceDeployer.Deployment.Packages[manifestAction.Packagename].Actions[manifestAction.Action.Name] = manifestAction
 		}
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func (reader *ManifestReader) checkAction(action utils.ActionRecord) error {
@@ -264,6 +322,7 @@ func (reader *ManifestReader) SetRules(rules []*whisk.Rule) error {
 			existRule.Action = rule.Action
 			existRule.Trigger = rule.Trigger
 			existRule.Status = rule.Status
+			existRule.Annotations = rule.Annotations
 		} else {
 			dep.Deployment.Rules[rule.Name] = rule
 		}
@@ -272,50 +331,46 @@ func (reader *ManifestReader) SetRules(rules []*whisk.Rule) error {
 	return nil
 }
 
-func (reader *ManifestReader) SetApis(deployer *ServiceDeployer, aubs []*utils.ActionExposedURLBinding) error {
+func (reader *ManifestReader) SetApis(deployer *ServiceDeployer, routes []utils.ApiRoute) error {
 	dep := reader.serviceDeployer
-	var apis []*whisk.ApiCreateRequest = make([]*whisk.ApiCreateRequest, 0)
 
 	dep.mt.Lock()
 	defer dep.mt.Unlock()
 
-	for _, aub := range aubs {
-		api := createApiEntity(deployer, aub)
-		apis = append(apis, api)
-	}
-
-	for _, api := range apis {
-		existApi, exist := dep.Deployment.Apis[api.ApiDoc.ApiName]
-		if exist {
-			existApi.ApiDoc.ApiName = api.ApiDoc.ApiName
-		} else {
-			dep.Deployment.Apis[api.ApiDoc.ApiName] = api
+	for _, route := range routes {
+		key := route.Key()
+		api := createApiEntity(deployer, route)
+		if existApi, exist := dep.Deployment.Apis[key]; exist && existApi.ApiDoc.Action.Name != api.ApiDoc.Action.Name {
+			return errors.New("Route " + key + " is bound to more than one action")
 		}
-
+		dep.Deployment.Apis[key] = api
 	}
 	return nil
 }
 
-// create the api entity according to the action definition and deployer.
-func createApiEntity(dp *ServiceDeployer, au *utils.ActionExposedURLBinding) *whisk.ApiCreateRequest {
+// create the api entity according to the route definition and deployer.
+func createApiEntity(dp *ServiceDeployer, route utils.ApiRoute) *whisk.ApiCreateRequest {
 	sendapi := new(whisk.ApiCreateRequest)
 	api := new(whisk.Api)
 	//Compose the api
-	bindingInfo := strings.Split(au.ExposedUrl, "/")
 	api.Namespace = dp.Client.Namespace
-	//api.ApiName = ""
-	api.GatewayBasePath = bindingInfo[1]
-	api.GatewayRelPath = bindingInfo[2]
-	api.GatewayMethod = strings.ToUpper(bindingInfo[0])
-	api.Id = "API" + ":" + dp.ClientConfig.Namespace + ":" + "/" + api.GatewayBasePath
+	api.ApiName = route.BasePath
+	api.GatewayBasePath = route.BasePath
+	api.GatewayRelPath = route.RelPath
+	api.GatewayMethod = route.Verb
+	api.Id = "API" + ":" + dp.ClientConfig.Namespace + ":" + route.BasePath
 	//api.GatewayFullPath = ""
 	//api.Swagger = ""
 	//compose the api action
+	actionName := route.ActionName
+	if dp.DeployActionInPackage && route.Packagename != "" {
+		actionName = route.Packagename + "/" + actionName
+	}
 	api.Action = new(whisk.ApiAction)
-	api.Action.Name = au.ActionName
+	api.Action.Name = actionName
 	api.Action.Namespace = dp.ClientConfig.Namespace
 	api.Action.BackendMethod = "POST"
-	api.Action.BackendUrl = "https://" + dp.ClientConfig.Host + "/api/v1/namespaces/" + dp.ClientConfig.Namespace + "/actions/" + au.ActionName
+	api.Action.BackendUrl = "https://" + dp.ClientConfig.Host + "/api/v1/namespaces/" + dp.ClientConfig.Namespace + "/actions/" + actionName
 	api.Action.Auth = dp.Client.Config.AuthToken
 	sendapi.ApiDoc = api
 	return sendapi
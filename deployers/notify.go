@@ -0,0 +1,149 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// formats of the notification posted to a webhook when a run completes
+const (
+	NotifyJSON  = "json"
+	NotifySlack = "slack"
+)
+
+// time allowed for the webhook to accept a notification
+const notifyTimeout = 10 * time.Second
+
+// Notification tells a webhook how a deploy or undeploy run went. Counts
+// are the number of entities by operation, and of those that failed.
+type Notification struct {
+	Command     string         `json:"command"`
+	Project     string         `json:"project,omitempty"`
+	Environment string         `json:"environment,omitempty"`
+	Success     bool           `json:"success"`
+	Error       string         `json:"error,omitempty"`
+	Duration    int64          `json:"durationMs"`
+	Counts      map[string]int `json:"counts"`
+}
+
+// NewNotification summarizes the results of a run deployed to environment
+func NewNotification(results *Results, environment string) Notification {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+
+	notification := Notification{
+		Command:     results.Command,
+		Project:     results.Project,
+		Environment: environment,
+		Success:     results.Success,
+		Error:       results.Error,
+		Duration:    results.Duration,
+		Counts:      make(map[string]int),
+	}
+	for _, entity := range results.Entities {
+		if entity.Error != "" {
+			notification.Counts["failed"]++
+		} else {
+			notification.Counts[entity.Operation]++
+		}
+	}
+	return notification
+}
+
+// SlackText is the message posted to Slack, e.g.
+// :white_check_mark: wskdeploy deploy of *hello* to *prod* succeeded in 4.2s: 3 deploy, 1 unchanged
+func (notification Notification) SlackText() string {
+	text := "wskdeploy " + notification.Command
+	if notification.Project != "" {
+		text += " of *" + notification.Project + "*"
+	}
+	if notification.Environment != "" {
+		text += " to *" + notification.Environment + "*"
+	}
+	if notification.Success {
+		text = ":white_check_mark: " + text + " succeeded"
+	} else {
+		text = ":x: " + text + " failed"
+	}
+	text += " in " + strconv.FormatFloat(float64(notification.Duration)/1000, 'f', 1, 64) + "s"
+
+	operations := make([]string, 0, len(notification.Counts))
+	for operation := range notification.Counts {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	counts := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		counts = append(counts, strconv.Itoa(notification.Counts[operation])+" "+operation)
+	}
+	if len(counts) > 0 {
+		text += ": " + strings.Join(counts, ", ")
+	}
+	if notification.Error != "" {
+		text += "\n> " + notification.Error
+	}
+	return text
+}
+
+// PostNotification posts the notification to the webhook, as the JSON
+// document or as a Slack message. The webhook is reached through the proxy
+// and with the certificate authorities the API is.
+func PostNotification(url string, format string, notification Notification) error {
+	var body interface{}
+	switch format {
+	case "", NotifyJSON:
+		body = notification
+	case NotifySlack:
+		body = map[string]string{"text": notification.SlackText()}
+	default:
+		return errors.New("unknown webhook format " + format + ", expected " + NotifyJSON + " or " + NotifySlack)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	transport, err := utils.NewTransport(utils.TransportOptions{
+		CACert:         utils.Flags.CACert,
+		ConnectTimeout: utils.Flags.ConnectTimeout,
+		ReadTimeout:    utils.Flags.ReadTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: transport, Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("unexpected status " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// file the API routes of an exported package are written to
+const ExportApiFileName = "openapi.json"
+
+// OpenApiDocument is an OpenAPI 3.0 document describing API Gateway routes
+type OpenApiDocument struct {
+	OpenApi string                                 `json:"openapi"`
+	Info    OpenApiInfo                            `json:"info"`
+	Servers []OpenApiServer                        `json:"servers"`
+	Paths   map[string]map[string]OpenApiOperation `json:"paths"`
+}
+
+type OpenApiInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenApiServer struct {
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenApiOperation struct {
+	OperationId string             `json:"operationId,omitempty"`
+	Responses   interface{}        `json:"responses"`
+	XOpenWhisk  *OpenApiXOpenWhisk `json:"x-openwhisk,omitempty"`
+}
+
+// the action serving an operation, as read back by the apis block
+type OpenApiXOpenWhisk struct {
+	Namespace string `json:"namespace,omitempty"`
+	Package   string `json:"package,omitempty"`
+	Action    string `json:"action"`
+}
+
+// ExportApis reads the API Gateway routes served by the actions of the
+// package. With a single base path the server url is that base path and the
+// paths are relative to it; otherwise the paths are absolute.
+func (exporter *Exporter) ExportApis(pkgname string) (*OpenApiDocument, error) {
+	pkg, _, err := exporter.Client.Packages.Get(pkgname)
+	if err != nil {
+		return nil, errors.New("Unable to read package " + pkgname + ": " + err.Error())
+	}
+
+	options := whisk.ApiListRequestOptions{ApiOptions: apiOptions(exporter.Client.Config), Docs: true}
+	retApis, _, err := exporter.Client.Apis.List(&options)
+	if err != nil {
+		return nil, errors.New("Unable to list the APIs: " + err.Error())
+	}
+
+	// base path -> relative path -> verb -> operation
+	routes := make(map[string]map[string]map[string]OpenApiOperation)
+	gatewayUrls := make(map[string]string)
+	for _, item := range retApis.Apis {
+		if item.ApiValue == nil || item.ApiValue.Swagger == nil {
+			continue
+		}
+		basePath := item.ApiValue.Swagger.BasePath
+		for relPath, swaggerPath := range item.ApiValue.Swagger.Paths {
+			for verb, operation := range swaggerOperationMap(swaggerPath) {
+				action, ok := packageAction(operation.XOpenWhisk, pkgname)
+				if !ok {
+					continue
+				}
+				if routes[basePath] == nil {
+					routes[basePath] = make(map[string]map[string]OpenApiOperation)
+					gatewayUrls[basePath] = item.ApiValue.BaseUrl
+				}
+				if routes[basePath][relPath] == nil {
+					routes[basePath][relPath] = make(map[string]OpenApiOperation)
+				}
+				responses := operation.Responses
+				if responses == nil {
+					responses = map[string]interface{}{"200": map[string]string{"description": "Successful response"}}
+				}
+				routes[basePath][relPath][strings.ToLower(verb)] = OpenApiOperation{
+					OperationId: operation.OperationId,
+					Responses:   responses,
+					XOpenWhisk:  &OpenApiXOpenWhisk{Namespace: operation.XOpenWhisk.Namespace, Package: pkgname, Action: action},
+				}
+			}
+		}
+	}
+
+	version := pkg.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+	doc := &OpenApiDocument{
+		OpenApi: "3.0.0",
+		Info:    OpenApiInfo{Title: pkgname, Version: version},
+		Servers: make([]OpenApiServer, 0),
+		Paths:   make(map[string]map[string]OpenApiOperation),
+	}
+
+	if len(routes) == 1 {
+		for basePath, relPaths := range routes {
+			doc.Servers = append(doc.Servers, OpenApiServer{Url: basePath, Description: "Base path on the API Gateway"})
+			if gatewayUrls[basePath] != "" {
+				doc.Servers = append(doc.Servers, OpenApiServer{Url: gatewayUrls[basePath]})
+			}
+			doc.Paths = relPaths
+		}
+		return doc, nil
+	}
+
+	doc.Servers = append(doc.Servers, OpenApiServer{Url: "/"})
+	for basePath, relPaths := range routes {
+		for relPath, operations := range relPaths {
+			doc.Paths[strings.TrimRight(basePath, "/")+relPath] = operations
+		}
+	}
+	return doc, nil
+}
+
+// the name of the action within pkgname that serves an operation
+func packageAction(ext *whisk.ApiSwaggerOpXOpenWhisk, pkgname string) (string, bool) {
+	if ext == nil {
+		return "", false
+	}
+	if ext.Package == pkgname {
+		return ext.ActionName, true
+	}
+	if ext.Package == "" && strings.HasPrefix(ext.ActionName, pkgname+"/") {
+		return strings.TrimPrefix(ext.ActionName, pkgname+"/"), true
+	}
+	return "", false
+}
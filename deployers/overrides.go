@@ -0,0 +1,128 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// LoadParameterOverrides reads the inputs given on the command line: the
+// JSON object of the --param-file first, then the key=value pairs of
+// --param, later ones overriding earlier ones. Values of --param are parsed
+// as JSON when they can be, as the wsk CLI does, and are strings otherwise.
+func LoadParameterOverrides(file string, pairs []string) (map[string]interface{}, error) {
+	overrides := make(map[string]interface{})
+	if file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.New("Unable to read the parameter file " + file + ": " + err.Error())
+		}
+		if err := json.Unmarshal(content, &overrides); err != nil {
+			return nil, errors.New("The parameter file " + file + " must hold a JSON object: " + err.Error())
+		}
+	}
+
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.New("parameter " + pair + " must be given as --param KEY VALUE")
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(kv[1]), &value); err != nil {
+			value = kv[1]
+		}
+		overrides[kv[0]] = value
+	}
+	return overrides, nil
+}
+
+// applyParameterOverrides sets the inputs given on the command line, which
+// take precedence over deployment.yaml and the manifest. An ACTION/KEY input
+// is set on that action only. A plain KEY input is set wherever the package
+// or its actions declare it, and on the package when nothing declares it so
+// all its actions receive it.
+func (deployer *ServiceDeployer) applyParameterOverrides(overrides map[string]interface{}) error {
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyValue := whisk.KeyValue{Key: key, Value: overrides[key]}
+
+		if slash := strings.Index(key, "/"); slash >= 0 {
+			actionName := key[:slash]
+			keyValue.Key = key[slash+1:]
+			found := false
+			for _, pack := range deployer.Deployment.Packages {
+				if record, exists := pack.Actions[actionName]; exists {
+					record.Action.Parameters = setKeyValue(record.Action.Parameters, keyValue, true)
+					found = true
+				}
+			}
+			if !found {
+				return errors.New("Parameter " + key + " is given for action " + actionName + " which the manifest does not declare")
+			}
+			continue
+		}
+
+		for _, pack := range deployer.Deployment.Packages {
+			declared := false
+			for _, record := range pack.Actions {
+				if hasKey(record.Action.Parameters, key) {
+					record.Action.Parameters = setKeyValue(record.Action.Parameters, keyValue, false)
+					declared = true
+				}
+			}
+			if pack.Package != nil && (hasKey(pack.Package.Parameters, key) || !declared) {
+				pack.Package.Parameters = setKeyValue(pack.Package.Parameters, keyValue, true)
+			}
+		}
+	}
+	return nil
+}
+
+func hasKey(keyValues whisk.KeyValueArr, key string) bool {
+	for _, keyValue := range keyValues {
+		if keyValue.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// replace the value of the key, appending it when missing and add is set
+func setKeyValue(keyValues whisk.KeyValueArr, keyValue whisk.KeyValue, add bool) whisk.KeyValueArr {
+	for i := range keyValues {
+		if keyValues[i].Key == keyValue.Key {
+			keyValues[i].Value = keyValue.Value
+			return keyValues
+		}
+	}
+	if add {
+		keyValues = append(keyValues, keyValue)
+	}
+	return keyValues
+}
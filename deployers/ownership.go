@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// checkOwnership fails before anything is deployed when the deployment would
+// overwrite an action, trigger or rule this project does not manage, so one
+// team's deployment does not clobber the entities of another sharing the
+// namespace. --force overwrites them.
+func (deployer *ServiceDeployer) checkOwnership() error {
+	if utils.Flags.Force {
+		return nil
+	}
+
+	problems := make([]string, 0)
+	for _, pack := range deployer.Deployment.Packages {
+		listPackage := ""
+		if deployer.DeployActionInPackage {
+			listPackage = pack.Package.Name
+		}
+		deployed := deployer.deployedAnnotations(listPackage)
+
+		// sequences are actions too
+		names := append(sortedActionNames(pack.Actions), sortedActionNames(pack.Sequences)...)
+		for _, name := range names {
+			if annotations, exists := deployed[name]; exists && !deployer.isManaged(annotations) {
+				problems = append(problems, "action "+deployer.actionPath(pack.Package.Name, name)+" "+owner(annotations))
+			}
+		}
+	}
+
+	for _, name := range sortedTriggerNames(deployer.Deployment.Triggers) {
+		existing, resp, err := deployer.Client.Triggers.Get(name)
+		if err != nil {
+			if isNotFound(resp) {
+				continue
+			}
+			return err
+		}
+		if !deployer.isManaged(existing.Annotations) {
+			problems = append(problems, "trigger "+name+" "+owner(existing.Annotations))
+		}
+	}
+
+	for _, name := range sortedRuleNames(deployer.Deployment.Rules) {
+		existing, resp, err := deployer.Client.Rules.Get(name)
+		if err != nil {
+			if isNotFound(resp) {
+				continue
+			}
+			return err
+		}
+		if !deployer.isManaged(existing.Annotations) {
+			problems = append(problems, "rule "+name+" "+owner(existing.Annotations))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Refusing to overwrite entities that project " + deployer.Project() + " does not manage, pass --force to overwrite them:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func owner(annotations whisk.KeyValueArr) string {
+	if project := ManagedValue(annotations, "projectName"); project != "" {
+		return "is managed by project " + project
+	}
+	return "was not deployed by wskdeploy"
+}
@@ -0,0 +1,95 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// page size used when listing entities, the most OpenWhisk returns at once
+const listPageSize = 200
+
+// ListAllPackages lists every package of the namespace, page by page, since
+// a single listing stops at the page size.
+func ListAllPackages(client *whisk.Client) ([]whisk.Package, error) {
+	all := make([]whisk.Package, 0)
+	options := &whisk.PackageListOptions{Limit: listPageSize}
+	for {
+		page, _, err := client.Packages.List(options)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, page...)
+		if len(page) < listPageSize {
+			return all, nil
+		}
+		options.Skip += listPageSize
+	}
+}
+
+// ListAllActions lists every action of a package, or of the namespace when
+// pkgname is empty, page by page.
+func ListAllActions(client *whisk.Client, pkgname string) ([]whisk.Action, error) {
+	all := make([]whisk.Action, 0)
+	options := &whisk.ActionListOptions{Limit: listPageSize}
+	for {
+		page, _, err := client.Actions.List(pkgname, options)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, page...)
+		if len(page) < listPageSize {
+			return all, nil
+		}
+		options.Skip += listPageSize
+	}
+}
+
+// ListAllTriggers lists every trigger of the namespace, page by page.
+func ListAllTriggers(client *whisk.Client) ([]whisk.Trigger, error) {
+	all := make([]whisk.Trigger, 0)
+	options := &whisk.TriggerListOptions{Limit: listPageSize}
+	for {
+		page, _, err := client.Triggers.List(options)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, page...)
+		if len(page) < listPageSize {
+			return all, nil
+		}
+		options.Skip += listPageSize
+	}
+}
+
+// ListAllRules lists every rule of the namespace, page by page.
+func ListAllRules(client *whisk.Client) ([]whisk.Rule, error) {
+	all := make([]whisk.Rule, 0)
+	options := &whisk.RuleListOptions{Limit: listPageSize}
+	for {
+		page, _, err := client.Rules.List(options)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, page...)
+		if len(page) < listPageSize {
+			return all, nil
+		}
+		options.Skip += listPageSize
+	}
+}
@@ -0,0 +1,464 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanDelete = "delete"
+	PlanNoop   = "no-op"
+)
+
+// PlanItem is the change wskdeploy would make to a single entity
+type PlanItem struct {
+	Kind    string // package, action, sequence, trigger or rule
+	Name    string
+	Change  string
+	Details []string // what differs from the deployed entity, for updates
+}
+
+// Plan lists the changes a deployment or undeployment would make, computed
+// by comparing the deployment plan with what is deployed in the namespace.
+type Plan struct {
+	Items []PlanItem
+}
+
+func (plan *Plan) add(kind string, name string, change string, details []string) {
+	plan.Items = append(plan.Items, PlanItem{kind, name, change, details})
+}
+
+// Count returns the number of items with the given change
+func (plan *Plan) Count(change string) int {
+	count := 0
+	for _, item := range plan.Items {
+		if item.Change == change {
+			count++
+		}
+	}
+	return count
+}
+
+func (plan *Plan) Print() {
+	symbols := map[string]string{PlanCreate: "+", PlanUpdate: "~", PlanDelete: "-", PlanNoop: "="}
+
+	fmt.Println("Deployment plan:")
+	for _, item := range plan.Items {
+		fmt.Printf("  %s %s %s (%s)\n", symbols[item.Change], item.Kind, item.Name, item.Change)
+		for _, detail := range item.Details {
+			fmt.Println("      " + detail)
+		}
+	}
+	fmt.Printf("\nPlan: %d to create, %d to update, %d to delete, %d unchanged.\n",
+		plan.Count(PlanCreate), plan.Count(PlanUpdate), plan.Count(PlanDelete), plan.Count(PlanNoop))
+}
+
+// ErrDriftDetected is returned by Deploy in diff mode when the namespace
+// does not match the manifest
+var ErrDriftDetected error = &utils.ExitError{Code: utils.ExitDrift, Err: errors.New("drift detected between the manifest and the namespace")}
+
+// Drift returns the items whose deployed entity is missing or differs from
+// the manifest.
+func (plan *Plan) Drift() []PlanItem {
+	drift := make([]PlanItem, 0)
+	for _, item := range plan.Items {
+		if item.Change != PlanNoop {
+			drift = append(drift, item)
+		}
+	}
+	return drift
+}
+
+func (plan *Plan) PrintDrift() {
+	drift := plan.Drift()
+	if len(drift) == 0 {
+		fmt.Printf("No drift detected, %d entities match the manifest.\n", len(plan.Items))
+		return
+	}
+
+	descriptions := map[string]string{PlanCreate: "not deployed", PlanUpdate: "differs", PlanDelete: "not in the manifest"}
+	for _, item := range drift {
+		fmt.Printf("%s %s: %s\n", item.Kind, item.Name, descriptions[item.Change])
+		for _, detail := range item.Details {
+			fmt.Println("    " + detail)
+		}
+	}
+	fmt.Printf("\n%d of %d entities drifted.\n", len(drift), len(plan.Items))
+}
+
+// DeploymentPlan fetches the entities of the deployment from the namespace
+// and works out which of them deploying would create or update.
+func (deployer *ServiceDeployer) DeploymentPlan() (*Plan, error) {
+	plan := &Plan{}
+
+	for _, name := range sortedPackageNames(deployer.Deployment.Packages) {
+		pack := deployer.Deployment.Packages[name]
+		if err := deployer.planPackage(plan, pack.Package); err != nil {
+			return nil, err
+		}
+
+		for _, actionName := range sortedActionNames(pack.Actions) {
+			if err := deployer.planAction(plan, "action", pack.Package.Name, pack.Actions[actionName].Action); err != nil {
+				return nil, err
+			}
+		}
+		for _, actionName := range sortedActionNames(pack.Sequences) {
+			if err := deployer.planAction(plan, "sequence", pack.Package.Name, pack.Sequences[actionName].Action); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, name := range sortedTriggerNames(deployer.Deployment.Triggers) {
+		if err := deployer.planTrigger(plan, deployer.Deployment.Triggers[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range sortedRuleNames(deployer.Deployment.Rules) {
+		if err := deployer.planRule(plan, deployer.Deployment.Rules[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if utils.Flags.Sync || utils.Flags.RemoveOrphans {
+		orphans, err := deployer.FindOrphans()
+		if err != nil {
+			return nil, err
+		}
+		for _, orphan := range orphans {
+			plan.add(orphan.Kind, orphan.Name, PlanDelete, []string{"no longer in the manifest"})
+		}
+	}
+
+	return plan, nil
+}
+
+// UnDeploymentPlan lists the entities of the plan undeploying would delete.
+func (deployer *ServiceDeployer) UnDeploymentPlan(verifiedPlan *DeploymentApplication) (*Plan, error) {
+	plan := &Plan{}
+
+	change := func(found bool) string {
+		if found {
+			return PlanDelete
+		}
+		return PlanNoop
+	}
+
+	for _, name := range sortedRuleNames(verifiedPlan.Rules) {
+		_, resp, err := deployer.Client.Rules.Get(name)
+		if err != nil && !isNotFound(resp) {
+			return nil, err
+		}
+		plan.add("rule", name, change(err == nil), nil)
+	}
+
+	for _, name := range sortedTriggerNames(verifiedPlan.Triggers) {
+		_, resp, err := deployer.Client.Triggers.Get(name)
+		if err != nil && !isNotFound(resp) {
+			return nil, err
+		}
+		plan.add("trigger", name, change(err == nil), nil)
+	}
+
+	for _, name := range sortedPackageNames(verifiedPlan.Packages) {
+		pack := verifiedPlan.Packages[name]
+		actions := append(sortedActionNames(pack.Sequences), sortedActionNames(pack.Actions)...)
+		for _, actionName := range actions {
+			qualified := deployer.actionPath(pack.Package.Name, actionName)
+			_, resp, err := deployer.Client.Actions.Get(qualified)
+			if err != nil && !isNotFound(resp) {
+				return nil, err
+			}
+			plan.add("action", qualified, change(err == nil), nil)
+		}
+
+		_, resp, err := deployer.Client.Packages.Get(pack.Package.Name)
+		if err != nil && !isNotFound(resp) {
+			return nil, err
+		}
+		plan.add("package", pack.Package.Name, change(err == nil), nil)
+	}
+
+	return plan, nil
+}
+
+func (deployer *ServiceDeployer) planPackage(plan *Plan, pkg *whisk.Package) error {
+	existing, resp, err := deployer.Client.Packages.Get(pkg.Name)
+	if err != nil {
+		if isNotFound(resp) {
+			plan.add("package", pkg.Name, PlanCreate, nil)
+			return nil
+		}
+		return err
+	}
+
+	details := diffKeyValues("parameter", pkg.Parameters, existing.Parameters)
+	details = append(details, diffKeyValues("annotation", pkg.Annotations, existing.Annotations)...)
+	plan.add("package", pkg.Name, changeFor(details), details)
+	return nil
+}
+
+func (deployer *ServiceDeployer) planAction(plan *Plan, kind string, pkgname string, action *whisk.Action) error {
+	name := deployer.actionPath(pkgname, action.Name)
+	existing, resp, err := deployer.Client.Actions.Get(name)
+	if err != nil {
+		if isNotFound(resp) {
+			plan.add(kind, name, PlanCreate, nil)
+			return nil
+		}
+		return err
+	}
+
+	if hash := ManagedValue(existing.Annotations, "hash"); hash != "" && hash == ActionHash(action) {
+		plan.add(kind, name, PlanNoop, nil)
+		return nil
+	}
+
+	details := make([]string, 0)
+	if action.Exec != nil && existing.Exec != nil {
+		if action.Exec.Kind != "" && action.Exec.Kind != existing.Exec.Kind {
+			details = append(details, "kind: "+existing.Exec.Kind+" -> "+action.Exec.Kind)
+		}
+		if action.Exec.Code != nil && (existing.Exec.Code == nil || *action.Exec.Code != *existing.Exec.Code) {
+			if hash := ManagedValue(existing.Annotations, "hash"); hash != "" {
+				details = append(details, "code hash: "+shortHash(hash)+" -> "+shortHash(ActionHash(action)))
+			} else {
+				details = append(details, "code changed")
+			}
+		}
+		if action.Exec.Main != existing.Exec.Main {
+			details = append(details, "main: "+existing.Exec.Main+" -> "+action.Exec.Main)
+		}
+		if strings.Join(action.Exec.Components, ",") != strings.Join(existing.Exec.Components, ",") {
+			details = append(details, "components changed")
+		}
+	}
+	details = append(details, diffLimits(action.Limits, existing.Limits)...)
+	details = append(details, diffKeyValues("parameter", action.Parameters, existing.Parameters)...)
+	details = append(details, diffKeyValues("annotation", action.Annotations, existing.Annotations)...)
+	plan.add(kind, name, changeFor(details), details)
+	return nil
+}
+
+func (deployer *ServiceDeployer) planTrigger(plan *Plan, trigger *whisk.Trigger) error {
+	existing, resp, err := deployer.Client.Triggers.Get(trigger.Name)
+	if err != nil {
+		if isNotFound(resp) {
+			plan.add("trigger", trigger.Name, PlanCreate, nil)
+			return nil
+		}
+		return err
+	}
+
+	details := make([]string, 0)
+	// feed parameters are handed to the feed action, not stored on the trigger
+	if _, isFeed := utils.IsFeedAction(trigger); !isFeed {
+		details = diffKeyValues("parameter", trigger.Parameters, existing.Parameters)
+	} else if ManagedValue(existing.Annotations, "feedHash") != FeedParametersHash(trigger.Parameters) {
+		details = append(details, "feed parameters changed")
+	}
+	details = append(details, diffKeyValues("annotation", trigger.Annotations, existing.Annotations)...)
+	plan.add("trigger", trigger.Name, changeFor(details), details)
+	return nil
+}
+
+func (deployer *ServiceDeployer) planRule(plan *Plan, rule *whisk.Rule) error {
+	existing, resp, err := deployer.Client.Rules.Get(rule.Name)
+	if err != nil {
+		if isNotFound(resp) {
+			plan.add("rule", rule.Name, PlanCreate, nil)
+			return nil
+		}
+		return err
+	}
+
+	details := make([]string, 0)
+	if local, remote := entityName(rule.Trigger), entityName(existing.Trigger); local != remote {
+		details = append(details, "trigger: "+remote+" -> "+local)
+	}
+	if local, remote := entityName(rule.Action), entityName(existing.Action); local != remote {
+		details = append(details, "action: "+remote+" -> "+local)
+	}
+	if local := RuleStatus(rule); existing.Status != "" && existing.Status != local {
+		details = append(details, "status: "+existing.Status+" -> "+local)
+	}
+	details = append(details, diffKeyValues("annotation", rule.Annotations, existing.Annotations)...)
+	plan.add("rule", rule.Name, changeFor(details), details)
+	return nil
+}
+
+func (deployer *ServiceDeployer) actionPath(pkgname string, name string) string {
+	if deployer.DeployActionInPackage && !strings.HasPrefix(name, pkgname+"/") {
+		return pkgname + "/" + name
+	}
+	return name
+}
+
+func changeFor(details []string) string {
+	if len(details) == 0 {
+		return PlanNoop
+	}
+	return PlanUpdate
+}
+
+func isNotFound(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// the unqualified name of a rule's trigger or action, which the server may
+// return either as a path or as a {"name", "path"} object
+func entityName(ref interface{}) string {
+	switch value := ref.(type) {
+	case string:
+		return value[strings.LastIndex(value, "/")+1:]
+	case map[string]interface{}:
+		if name, ok := value["name"].(string); ok {
+			return name
+		}
+	}
+	return fmt.Sprint(ref)
+}
+
+// only the limits set in the manifest are compared, the others are the
+// server defaults
+func diffLimits(local *whisk.Limits, remote *whisk.Limits) []string {
+	details := make([]string, 0)
+	if local == nil {
+		return details
+	}
+	if remote == nil {
+		remote = &whisk.Limits{}
+	}
+
+	compare := func(name string, local *int, remote *int) {
+		if local == nil {
+			return
+		}
+		if remote == nil {
+			details = append(details, "limit "+name+": default -> "+strconv.Itoa(*local))
+		} else if *local != *remote {
+			details = append(details, "limit "+name+": "+strconv.Itoa(*remote)+" -> "+strconv.Itoa(*local))
+		}
+	}
+	compare("timeout", local.Timeout, remote.Timeout)
+	compare("memory", local.Memory, remote.Memory)
+	compare("logsize", local.Logsize, remote.Logsize)
+	return details
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// annotations the server adds on its own, which never show up in a plan
+var systemAnnotations = map[string]bool{"exec": true, ManagedAnnotation: true}
+
+func diffKeyValues(kind string, local whisk.KeyValueArr, remote whisk.KeyValueArr) []string {
+	details := make([]string, 0)
+	remoteValues := make(map[string]string)
+	for _, kv := range remote {
+		remoteValues[kv.Key] = normalizedJSON(kv.Value)
+	}
+
+	localKeys := make(map[string]bool)
+	for _, kv := range local {
+		localKeys[kv.Key] = true
+		value, exists := remoteValues[kv.Key]
+		if !exists {
+			details = append(details, kind+" "+kv.Key+" added")
+		} else if value != normalizedJSON(kv.Value) {
+			details = append(details, kind+" "+kv.Key+" changed")
+		}
+	}
+
+	removed := make([]string, 0)
+	for key := range remoteValues {
+		if !localKeys[key] && !(kind == "annotation" && systemAnnotations[key]) {
+			removed = append(removed, kind+" "+key+" removed")
+		}
+	}
+	sort.Strings(removed)
+	return append(details, removed...)
+}
+
+// values read from yaml and values decoded from the server differ in their
+// go types, compare their JSON forms instead
+func normalizedJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return string(data)
+	}
+	data, _ = json.Marshal(decoded)
+	return string(data)
+}
+
+func sortedPackageNames(packages map[string]*DeploymentPackage) []string {
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTriggerNames(triggers map[string]*whisk.Trigger) []string {
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedRuleNames(rules map[string]*whisk.Rule) []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedActionNames(actions map[string]utils.ActionRecord) []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
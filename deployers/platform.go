@@ -0,0 +1,353 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+const megabyte = 1024 * 1024
+
+// size in megabytes the code of an action can have when the installation
+// does not report its own limit
+const DefaultMaxCodeSize = 48
+
+// number of files listed when an archive is too large
+const largestFilesReported = 5
+
+// PlatformInfo is what the OpenWhisk installation reports at /api/v1: the
+// limits of actions and the runtimes it supports, by family.
+type PlatformInfo struct {
+	Limits   map[string]int               `json:"limits"`
+	Runtimes map[string][]PlatformRuntime `json:"runtimes"`
+}
+
+// PlatformRuntime is a runtime kind the installation supports, e.g. nodejs:8
+type PlatformRuntime struct {
+	Kind       string `json:"kind"`
+	Default    bool   `json:"default"`
+	Deprecated bool   `json:"deprecated"`
+}
+
+// the kinds that are not runtimes the installation lists
+var builtinKinds = map[string]bool{"blackbox": true, "sequence": true}
+
+// Kinds returns the sorted runtime kinds the installation supports.
+func (info *PlatformInfo) Kinds() []string {
+	kinds := make([]string, 0)
+	for family, runtimes := range info.Runtimes {
+		// images of docker actions, not kinds
+		if family == "blackboxes" {
+			continue
+		}
+		for _, runtime := range runtimes {
+			kinds = append(kinds, runtime.Kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// SupportsKind reports whether actions of a kind can be deployed to the
+// installation. A family, e.g. nodejs, or its :default kind is supported
+// when the installation has a runtime of the family. Every kind is supported
+// by installations that do not report their runtimes.
+func (info *PlatformInfo) SupportsKind(kind string) bool {
+	if info == nil || len(info.Runtimes) == 0 || builtinKinds[kind] {
+		return true
+	}
+	family := strings.Split(kind, ":")[0]
+	for _, runtime := range info.Runtimes[family] {
+		if runtime.Kind == kind || kind == family || kind == family+":default" {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveKind returns the kind a family, e.g. nodejs, or its :default kind
+// stands for on the installation: the runtime of the family it marks as the
+// default. Other kinds, and every kind on installations that do not report
+// their runtimes, are returned as given.
+func (info *PlatformInfo) ResolveKind(kind string) string {
+	if info == nil {
+		return kind
+	}
+	family := strings.Split(kind, ":")[0]
+	if kind != family && kind != family+":default" {
+		return kind
+	}
+	for _, runtime := range info.Runtimes[family] {
+		if runtime.Default {
+			return runtime.Kind
+		}
+	}
+	return kind
+}
+
+// Deprecated reports whether the installation marks a kind as deprecated.
+func (info *PlatformInfo) Deprecated(kind string) bool {
+	if info == nil {
+		return false
+	}
+	for _, runtime := range info.Runtimes[strings.Split(kind, ":")[0]] {
+		if runtime.Kind == kind {
+			return runtime.Deprecated
+		}
+	}
+	return false
+}
+
+// PlatformLimits reads the action limits the OpenWhisk installation reports
+// at /api/v1, such as "max_action_memory" in bytes or "max_action_duration"
+// in milliseconds.
+func PlatformLimits(config *whisk.Config) (map[string]int, error) {
+	info, err := ReadPlatformInfo(config)
+	if err != nil {
+		return nil, err
+	}
+	return info.Limits, nil
+}
+
+// ReadPlatformInfo reads the limits and runtimes the OpenWhisk installation
+// reports at /api/v1.
+func ReadPlatformInfo(config *whisk.Config) (*PlatformInfo, error) {
+	if config == nil || config.BaseURL == nil {
+		return nil, errors.New("no API host is set")
+	}
+
+	transport, ok := clientTransports[config]
+	if !ok {
+		var err error
+		if transport, err = utils.NewTransport(transportOptions(config, utils.Flags.Cert, utils.Flags.Key)); err != nil {
+			return nil, err
+		}
+	}
+	transport, err := traced(transport)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{Transport: &utils.TimeoutTransport{Transport: transport, Timeout: utils.Flags.RequestTimeout}}
+	resp, err := httpClient.Get(strings.TrimSuffix(config.BaseURL.String(), "/") + "/v1")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + resp.Status)
+	}
+
+	info := &PlatformInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// the platform information of the target, read once; nil when the
+// installation does not report it
+func (deployer *ServiceDeployer) platformInfo() *PlatformInfo {
+	deployer.platformOnce.Do(func() {
+		info, err := ReadPlatformInfo(deployer.ClientConfig)
+		if err != nil {
+			whisk.Debug(whisk.DbgInfo, "Platform information is not available: %v\n", err)
+			return
+		}
+		deployer.platform = info
+	})
+	return deployer.platform
+}
+
+// platformRanges narrows the default limit ranges to what the installation
+// reports. Memory and log sizes are reported in bytes.
+func platformRanges(limits map[string]int) map[string]parsers.LimitRange {
+	ranges := map[string]parsers.LimitRange{
+		"timeout":    parsers.TimeoutRange,
+		"memorySize": parsers.MemorySizeRange,
+		"logSize":    parsers.LogSizeRange,
+	}
+
+	narrow := func(name string, minKey string, maxKey string, unit int) {
+		valid := ranges[name]
+		if min, ok := limits[minKey]; ok {
+			valid.Min = min / unit
+		}
+		if max, ok := limits[maxKey]; ok {
+			valid.Max = max / unit
+		}
+		ranges[name] = valid
+	}
+	narrow("timeout", "min_action_duration", "max_action_duration", 1)
+	narrow("memorySize", "min_action_memory", "max_action_memory", megabyte)
+	narrow("logSize", "min_action_logs", "max_action_logs", megabyte)
+	return ranges
+}
+
+// resolveKinds sets the kinds of the actions given as a family or :default
+// kind to the default of the runtimes file, or else to the default runtime of
+// the target installation, so what is deployed, planned and hashed is the
+// version that actually runs.
+func (deployer *ServiceDeployer) resolveKinds() {
+	info := deployer.platformInfo()
+	for _, pack := range deployer.Deployment.Packages {
+		for _, record := range pack.Actions {
+			exec := record.Action.Exec
+			if exec == nil {
+				continue
+			}
+			if kind, ok := utils.DefaultKind(exec.Kind); ok {
+				exec.Kind = kind
+			} else {
+				exec.Kind = info.ResolveKind(exec.Kind)
+			}
+		}
+	}
+}
+
+// checkPlatformLimits fails before anything is deployed when an action asks
+// for limits the target installation does not allow, or is of a kind it has
+// no runtime for. Installations that do not report their limits or runtimes
+// are not checked.
+// The code size is always checked, against the limit the runtimes file sets
+// for the kind, the max_action_code_size the installation reports or else
+// the --max-code-size flag.
+func (deployer *ServiceDeployer) checkPlatformLimits() error {
+	info := deployer.platformInfo()
+	var limits map[string]int
+	if info != nil {
+		limits = info.Limits
+	}
+	ranges := platformRanges(limits)
+
+	maxCodeSize := utils.Flags.MaxCodeSize * megabyte
+	if maxCodeSize <= 0 {
+		maxCodeSize = DefaultMaxCodeSize * megabyte
+	}
+	if max, ok := limits["max_action_code_size"]; ok {
+		maxCodeSize = max
+	}
+
+	problems := make([]string, 0)
+	check := func(action string, name string, value *int) {
+		valid := ranges[name]
+		if len(limits) > 0 && value != nil && (*value < valid.Min || *value > valid.Max) {
+			problems = append(problems, fmt.Sprintf("%s of action %s must be between %d and %d on this platform, got %d",
+				name, action, valid.Min, valid.Max, *value))
+		}
+	}
+
+	for _, pkgname := range sortedPackageNames(deployer.Deployment.Packages) {
+		pack := deployer.Deployment.Packages[pkgname]
+		for _, name := range sortedActionNames(pack.Actions) {
+			action := pack.Actions[name].Action
+			qualified := deployer.actionPath(pkgname, name)
+			limit := maxCodeSize
+			if action.Exec != nil {
+				if size, ok := utils.MaxCodeSize(action.Exec.Kind); ok {
+					limit = size * megabyte
+				}
+			}
+			if problem := checkCodeSize(qualified, action.Exec, limit); problem != "" {
+				problems = append(problems, problem)
+			}
+			if action.Exec != nil && !info.SupportsKind(action.Exec.Kind) {
+				problems = append(problems, "kind "+action.Exec.Kind+" of action "+qualified+" is not supported on this platform, use one of: "+strings.Join(info.Kinds(), ", "))
+			} else if action.Exec != nil && info.Deprecated(action.Exec.Kind) {
+				log.Println("Warning: kind " + action.Exec.Kind + " of action " + qualified + " is deprecated on this platform")
+			}
+			if action.Limits == nil {
+				continue
+			}
+			check(qualified, "timeout", action.Limits.Timeout)
+			check(qualified, "memorySize", action.Limits.Memory)
+			check(qualified, "logSize", action.Limits.Logsize)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// report an action whose code, as uploaded, is larger than maxCodeSize
+// bytes, with the largest files of its archive
+func checkCodeSize(action string, exec *whisk.Exec, maxCodeSize int) string {
+	if exec == nil || exec.Code == nil || len(*exec.Code) <= maxCodeSize {
+		return ""
+	}
+
+	problem := fmt.Sprintf("code of action %s is %s, above the %s limit of this platform",
+		action, formatSize(uint64(len(*exec.Code))), formatSize(uint64(maxCodeSize)))
+	content, err := base64.StdEncoding.DecodeString(*exec.Code)
+	if err != nil {
+		return problem
+	}
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return problem
+	}
+
+	files := make(bySize, 0, len(archive.File))
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	sort.Sort(files)
+	if len(files) > largestFilesReported {
+		files = files[:largestFilesReported]
+	}
+	problem += ", largest files:"
+	for _, file := range files {
+		problem += "\n    " + file.Name + " " + formatSize(file.UncompressedSize64)
+	}
+	return problem
+}
+
+// zip entries, largest first
+type bySize []*zip.File
+
+func (files bySize) Len() int      { return len(files) }
+func (files bySize) Swap(i, j int) { files[i], files[j] = files[j], files[i] }
+func (files bySize) Less(i, j int) bool {
+	if files[i].UncompressedSize64 != files[j].UncompressedSize64 {
+		return files[i].UncompressedSize64 > files[j].UncompressedSize64
+	}
+	return files[i].Name < files[j].Name
+}
+
+func formatSize(size uint64) string {
+	if size < megabyte {
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/megabyte)
+}
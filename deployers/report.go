@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// output formats of the project report
+const (
+	ReportTable = "table"
+	ReportJSON  = "json"
+)
+
+// ReportEntry describes one entity deployed by a project
+type ReportEntry struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Runtime   string `json:"runtime,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// ProjectReport lists the packages, actions, triggers and rules in the
+// namespace that are tagged with the deployer's project. Action sizes are
+// the size of their code in bytes.
+func (deployer *ServiceDeployer) ProjectReport() ([]ReportEntry, error) {
+	entries := make([]ReportEntry, 0)
+
+	packages, err := deployer.listPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{""}
+	for _, pkg := range packages {
+		if !deployer.isManaged(pkg.Annotations) {
+			continue
+		}
+		entries = append(entries, ReportEntry{Kind: "package", Name: pkg.Name, Version: pkg.Version,
+			Timestamp: ManagedValue(pkg.Annotations, "timestamp")})
+		if pkg.Binding == nil || pkg.Binding.Name == "" {
+			scopes = append(scopes, pkg.Name)
+		}
+	}
+
+	for _, scope := range scopes {
+		actions, err := deployer.listActions(scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range actions {
+			if !deployer.isManaged(summary.Annotations) {
+				continue
+			}
+			name := strings.TrimPrefix(scope+"/"+summary.Name, "/")
+			action, _, err := deployer.Client.Actions.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			entry := ReportEntry{Kind: "action", Name: name, Version: action.Version,
+				Timestamp: ManagedValue(action.Annotations, "timestamp")}
+			if action.Exec != nil {
+				entry.Runtime = action.Exec.Kind
+				if action.Exec.Code != nil {
+					entry.Size = len(*action.Exec.Code)
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	triggers, err := deployer.listTriggers()
+	if err != nil {
+		return nil, err
+	}
+	for _, trigger := range triggers {
+		if deployer.isManaged(trigger.Annotations) {
+			entries = append(entries, ReportEntry{Kind: "trigger", Name: trigger.Name, Version: trigger.Version,
+				Timestamp: ManagedValue(trigger.Annotations, "timestamp")})
+		}
+	}
+
+	rules, err := deployer.listRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if deployer.isManaged(rule.Annotations) {
+			entries = append(entries, ReportEntry{Kind: "rule", Name: rule.Name, Version: rule.Version,
+				Timestamp: ManagedValue(rule.Annotations, "timestamp")})
+		}
+	}
+
+	return entries, nil
+}
+
+// PrintReport writes the report entries as a table or as a JSON array
+func PrintReport(w io.Writer, entries []ReportEntry, format string) error {
+	switch format {
+	case ReportJSON:
+		output, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(output))
+	case ReportTable, "":
+		fmt.Fprintf(w, "%-8s %-50s %-8s %-10s %10s %s\n", "KIND", "NAME", "VERSION", "RUNTIME", "SIZE", "DEPLOYED")
+		for _, entry := range entries {
+			size := ""
+			if entry.Kind == "action" {
+				size = strconv.Itoa(entry.Size)
+			}
+			fmt.Fprintf(w, "%-8s %-50s %-8s %-10s %10s %s\n", entry.Kind, entry.Name, entry.Version, entry.Runtime, size, entry.Timestamp)
+		}
+	default:
+		return errors.New("unknown report format " + format + ", expected table or json")
+	}
+	return nil
+}
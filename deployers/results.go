@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// output formats of the deploy and undeploy commands
+const (
+	ResultsText = "text"
+	ResultsJSON = "json"
+)
+
+// operations performed on an entity
+const (
+	OperationDeploy    = "deploy"
+	OperationUnchanged = "unchanged"
+	OperationDelete    = "delete"
+	OperationVerify    = "verify" // an action invoked after deploying, as a test
+)
+
+// EntityResult describes what a run did to one entity. Durations are in
+// milliseconds.
+type EntityResult struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Target    string `json:"target,omitempty"`
+	Operation string `json:"operation"`
+	Duration  int64  `json:"durationMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Results collects the entities a deploy or undeploy run touched, for
+// pipelines that read the outcome as JSON instead of parsing the log.
+type Results struct {
+	Command  string         `json:"command"`
+	Project  string         `json:"project,omitempty"`
+	Success  bool           `json:"success"`
+	Failed   int            `json:"failed"` // entities whose operation failed
+	Duration int64          `json:"durationMs"`
+	Error    string         `json:"error,omitempty"`
+	Entities []EntityResult `json:"entities"`
+
+	mu     sync.Mutex
+	start  time.Time
+	target string
+}
+
+// NewResults starts collecting the results of a command
+func NewResults(command string) *Results {
+	return &Results{Command: command, Entities: make([]EntityResult, 0), start: time.Now()}
+}
+
+// SetTarget names the target of the deployment file the entities recorded
+// from now on are deployed to
+func (results *Results) SetTarget(target string) {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+	results.target = target
+}
+
+func (results *Results) add(entry EntityResult) {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+	entry.Target = results.target
+	if entry.Error != "" {
+		results.Failed++
+	}
+	results.Entities = append(results.Entities, entry)
+}
+
+// Finish records the outcome of the command and how long it took. The
+// command did not succeed when it failed or any entity failed.
+func (results *Results) Finish(project string, err error) {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+	results.Project = project
+	results.Success = err == nil && results.Failed == 0
+	if err != nil {
+		results.Error = err.Error()
+	}
+	results.Duration = milliseconds(time.Since(results.start))
+}
+
+// Print writes the results as a JSON document
+func (results *Results) Print(w io.Writer) error {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+	output, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(output))
+	return err
+}
+
+// recordResult adds what was done to an entity since start to the results,
+// when they are collected
+func (deployer *ServiceDeployer) recordResult(kind string, name string, operation string, start time.Time, err error) {
+	if deployer.Results == nil {
+		return
+	}
+	entry := EntityResult{Kind: kind, Name: name, Operation: operation, Duration: milliseconds(time.Since(start))}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	deployer.Results.add(entry)
+}
+
+func milliseconds(duration time.Duration) int64 {
+	return int64(duration / time.Millisecond)
+}
@@ -0,0 +1,145 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// default number of actions deployed at the same time
+const DefaultConcurrency = 4
+
+// runTasks runs the tasks on at most concurrency workers. Once a task fails
+// no further tasks are started, and the first error is returned.
+func runTasks(concurrency int, tasks []func() error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	queue := make(chan func() error)
+
+	for i := 0; i < concurrency && i < len(tasks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				if err := task(); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		queue <- task
+	}
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
+
+// a sequence waiting to be deployed
+type sequenceTask struct {
+	pkgname string
+	action  *whisk.Action
+}
+
+func isSequence(record utils.ActionRecord) bool {
+	return record.Action.Exec != nil && record.Action.Exec.Kind == "sequence"
+}
+
+// sequenceWaves orders the sequences of all packages so that a sequence is
+// deployed only after the sequences it is composed of. Sequences within a
+// wave do not depend on each other and can be deployed concurrently.
+func sequenceWaves(packages map[string]*DeploymentPackage) ([][]sequenceTask, error) {
+	pending := make(map[string]sequenceTask)
+	for _, pack := range packages {
+		// sequences read from the manifest are kept with the actions
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for name, record := range records {
+				if isSequence(record) {
+					pending[pack.Package.Name+"/"+name] = sequenceTask{pack.Package.Name, record.Action}
+				}
+			}
+		}
+	}
+
+	waves := make([][]sequenceTask, 0)
+	for len(pending) > 0 {
+		ready := make([]string, 0)
+		for key, task := range pending {
+			blocked := false
+			for _, component := range task.action.Exec.Components {
+				if _, isPending := pending[componentKey(component)]; isPending && componentKey(component) != key {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				ready = append(ready, key)
+			}
+		}
+
+		if len(ready) == 0 {
+			keys := make([]string, 0, len(pending))
+			for key := range pending {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return nil, errors.New("Sequences " + strings.Join(keys, ", ") + " depend on each other in a cycle")
+		}
+
+		sort.Strings(ready)
+		wave := make([]sequenceTask, 0, len(ready))
+		for _, key := range ready {
+			wave = append(wave, pending[key])
+			delete(pending, key)
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// "package/action" part of a fully qualified component "/namespace/package/action"
+func componentKey(component string) string {
+	parts := strings.Split(strings.Trim(component, "/"), "/")
+	if len(parts) < 2 {
+		return component
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
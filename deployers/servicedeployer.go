@@ -19,12 +19,16 @@ package deployers
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/openwhisk/openwhisk-client-go/whisk"
 	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
@@ -82,6 +86,32 @@ type ServiceDeployer struct {
 	InteractiveChoice     bool
 	ClientConfig          *whisk.Config
 	DependencyMaster      map[string]utils.DependencyRecord
+	// number of actions deployed at the same time
+	Concurrency int
+	// name deployed entities are tagged with, defaults to the root package name
+	ProjectName string
+	// package the manifest package is deployed as, used for dependencies
+	PackageName string
+	// inputs given on the command line, overriding the deployment file
+	ParameterOverrides map[string]interface{}
+	// dependency of the project this deployment was cloned for
+	dependency string
+	// commits the github dependencies are pinned to, shared with the dependencies
+	lock *utils.LockFile
+	// annotations of the deployed actions, by package and action name
+	deployedActions map[string]map[string]whisk.KeyValueArr
+	// prior state of the entities changed in this run, kept when rolling back on failure
+	journal *Journal
+	// hash of the manifest entities are stamped with, read once
+	manifestHash     string
+	manifestHashOnce sync.Once
+	// directory successful deployments are recorded in, none when empty
+	HistoryDir string
+	// limits and runtimes of the target installation, read once
+	platform     *PlatformInfo
+	platformOnce sync.Once
+	// what the run did to each entity, collected for --format json when set
+	Results *Results
 }
 
 // NewServiceDeployer is a Factory to create a new ServiceDeployer
@@ -91,20 +121,26 @@ func NewServiceDeployer() *ServiceDeployer {
 	dep.IsInteractive = true
 	dep.DeployActionInPackage = true
 	dep.DependencyMaster = make(map[string]utils.DependencyRecord)
+	dep.Concurrency = DefaultConcurrency
 
 	return &dep
 }
 
 // Check if the manifest yaml could be parsed by Manifest Parser.
 // Check if the deployment yaml could be parsed by Manifest Parser.
-func (deployer *ServiceDeployer) Check() {
+// The errors of both files are reported together.
+func (deployer *ServiceDeployer) Check() error {
+	var errs utils.Errors
 	ps := parsers.NewYAMLParser()
-	if utils.FileExists(deployer.DeploymentPath) {
-		ps.ParseDeployment(deployer.DeploymentPath)
+	if parsers.DeploymentExists(deployer.DeploymentPath) {
+		_, err := ps.ParseDeployment(deployer.DeploymentPath)
+		errs.Add(err)
 	}
-	ps.ParseManifest(deployer.ManifestPath)
+	_, err := ps.ParseManifest(deployer.ManifestPath)
+	errs.Add(err)
 	// add more schema check or manifest/deployment consistency checks here if
 	// necessary
+	return errs.Err()
 }
 
 func (deployer *ServiceDeployer) ConstructDeploymentPlan() error {
@@ -112,33 +148,45 @@ func (deployer *ServiceDeployer) ConstructDeploymentPlan() error {
 	var manifestReader = NewManfiestReader(deployer)
 	manifestReader.IsUndeploy = false
 	manifest, manifestParser, err := manifestReader.ParseManifest()
-	utils.Check(err)
+	if err != nil {
+		return err
+	}
 
 	deployer.RootPackageName = manifest.Package.Packagename
+	if enabled, err := manifest.Package.Condition.Enabled(); err != nil || !enabled {
+		return packageSkipped(manifest, err)
+	}
 
-	manifestReader.InitRootPackage(manifestParser, manifest)
+	if err := manifestReader.InitRootPackage(manifestParser, manifest); err != nil {
+		return err
+	}
 
 	if deployer.IsDefault == true && !utils.Flags.WithinOpenWhisk {
 		fileReader := NewFileSystemReader(deployer)
 		fileActions, err := fileReader.ReadProjectDirectory(manifest)
-		utils.Check(err)
+		if err != nil {
+			return err
+		}
 
-		fileReader.SetFileActions(fileActions)
+		if err := fileReader.SetFileActions(fileActions); err != nil {
+			return err
+		}
 	}
 
 	// process manifest file
-	err = manifestReader.HandleYaml(deployer, manifestParser, manifest)
-	utils.Check(err)
+	if err := manifestReader.HandleYaml(deployer, manifestParser, manifest); err != nil {
+		return err
+	}
 
 	// process deploymet file
-	if utils.FileExists(deployer.DeploymentPath) {
-		var deploymentReader = NewDeploymentReader(deployer)
-		deploymentReader.HandleYaml()
-
-		deploymentReader.BindAssets()
+	if err := deployer.bindDeployment(); err != nil {
+		return err
 	}
 
-	return nil
+	if err := deployer.applyParameterOverrides(deployer.ParameterOverrides); err != nil {
+		return err
+	}
+	return deployer.checkInputs(manifest)
 }
 
 func (deployer *ServiceDeployer) ConstructUnDeploymentPlan() (*DeploymentApplication, error) {
@@ -146,31 +194,39 @@ func (deployer *ServiceDeployer) ConstructUnDeploymentPlan() (*DeploymentApplica
 	var manifestReader = NewManfiestReader(deployer)
 	manifestReader.IsUndeploy = true
 	manifest, manifestParser, err := manifestReader.ParseManifest()
-	utils.Check(err)
+	if err != nil {
+		return deployer.Deployment, err
+	}
+	if enabled, err := manifest.Package.Condition.Enabled(); err != nil || !enabled {
+		return deployer.Deployment, packageSkipped(manifest, err)
+	}
 
-	manifestReader.InitRootPackage(manifestParser, manifest)
+	if err := manifestReader.InitRootPackage(manifestParser, manifest); err != nil {
+		return deployer.Deployment, err
+	}
 
 	// process file system
 	if deployer.IsDefault == true {
 		fileReader := NewFileSystemReader(deployer)
 		fileActions, err := fileReader.ReadProjectDirectory(manifest)
-		utils.Check(err)
+		if err != nil {
+			return deployer.Deployment, err
+		}
 
-		err = fileReader.SetFileActions(fileActions)
-		utils.Check(err)
+		if err := fileReader.SetFileActions(fileActions); err != nil {
+			return deployer.Deployment, err
+		}
 
 	}
 
 	// process manifest file
-	err = manifestReader.HandleYaml(deployer, manifestParser, manifest)
-	utils.Check(err)
+	if err := manifestReader.HandleYaml(deployer, manifestParser, manifest); err != nil {
+		return deployer.Deployment, err
+	}
 
 	// process deploymet file
-	if utils.FileExists(deployer.DeploymentPath) {
-		var deploymentReader = NewDeploymentReader(deployer)
-		deploymentReader.HandleYaml()
-
-		deploymentReader.BindAssets()
+	if err := deployer.bindDeployment(); err != nil {
+		return deployer.Deployment, err
 	}
 
 	verifiedPlan := deployer.Deployment
@@ -178,17 +234,76 @@ func (deployer *ServiceDeployer) ConstructUnDeploymentPlan() (*DeploymentApplica
 	return verifiedPlan, nil
 }
 
+// bind the inputs and annotations of the deployment file, when there is one,
+// to the entities of the manifest
+func (deployer *ServiceDeployer) bindDeployment() error {
+	if !parsers.DeploymentExists(deployer.DeploymentPath) {
+		return nil
+	}
+	var deploymentReader = NewDeploymentReader(deployer)
+	if err := deploymentReader.HandleYaml(); err != nil {
+		return err
+	}
+	return deploymentReader.BindAssets()
+}
+
+// a package whose conditions do not hold is neither deployed nor undeployed
+func packageSkipped(manifest *parsers.ManifestYAML, err error) error {
+	if err != nil {
+		return errors.New("Package " + manifest.Package.Packagename + ": " + err.Error())
+	}
+	log.Println("Package " + manifest.Package.Packagename + " is skipped, its ifdef: or when: condition does not hold")
+	return nil
+}
+
 // Use relfect util to deploy everything in this service deployer
 // according some planning?
 func (deployer *ServiceDeployer) Deploy() error {
 
+	deployer.resolveKinds()
+
+	if utils.Flags.Preview {
+		plan, err := deployer.DeploymentPlan()
+		if err != nil {
+			return err
+		}
+		plan.Print()
+		return nil
+	}
+
+	if utils.Flags.Diff {
+		plan, err := deployer.DeploymentPlan()
+		if err != nil {
+			return err
+		}
+		plan.PrintDrift()
+		if len(plan.Drift()) > 0 {
+			return ErrDriftDetected
+		}
+		return nil
+	}
+
+	if utils.Flags.Promote {
+		return deployer.Promote()
+	}
+	if utils.Flags.Abort {
+		return deployer.Abort()
+	}
+	switch utils.Flags.Strategy {
+	case "", StrategyInPlace:
+	case StrategyBlueGreen:
+		return deployer.DeployBlueGreen()
+	default:
+		return errors.New("Unknown deployment strategy " + utils.Flags.Strategy + ", use " + StrategyInPlace + " or " + StrategyBlueGreen)
+	}
+
 	if deployer.IsInteractive == true && !utils.Flags.WithinOpenWhisk {
 		deployer.printDeploymentAssets(deployer.Deployment)
 		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("Do you really want to deploy this? (y/N): ")
-
-		text, _ := reader.ReadString('\n')
-		text = strings.TrimSpace(text)
+		text, err := utils.Prompt(reader, "deploy", "Do you really want to deploy this? (y/N): ")
+		if err != nil {
+			return err
+		}
 
 		if text == "" {
 			text = "n"
@@ -196,8 +311,7 @@ func (deployer *ServiceDeployer) Deploy() error {
 
 		if strings.EqualFold(text, "y") || strings.EqualFold(text, "yes") {
 			deployer.InteractiveChoice = true
-			if err := deployer.deployAssets(); err != nil {
-				log.Println("\nDeployment did not complete sucessfully. Run `wskdeploy undeploy` to remove partially deployed assets")
+			if err := deployer.deployProject(); err != nil {
 				return err
 			}
 
@@ -212,8 +326,7 @@ func (deployer *ServiceDeployer) Deploy() error {
 	}
 
 	// non-interactive
-	if err := deployer.deployAssets(); err != nil {
-		log.Println("\nDeployment did not complete sucessfully. Run `wskdeploy undeploy` to remove partially deployed assets")
+	if err := deployer.deployProject(); err != nil {
 		return err
 	}
 
@@ -222,8 +335,55 @@ func (deployer *ServiceDeployer) Deploy() error {
 
 }
 
+// deploy the plan and remove the orphans asked for, then record the
+// deployment in the history
+func (deployer *ServiceDeployer) deployProject() error {
+	var snapshot *Snapshot
+	if deployer.HistoryDir != "" {
+		var err error
+		if snapshot, err = deployer.Snapshot(); err != nil {
+			return err
+		}
+	}
+
+	if err := deployer.deployAssets(); err != nil {
+		deployer.deploymentFailed()
+		return err
+	}
+
+	if utils.Flags.Sync || utils.Flags.RemoveOrphans {
+		if err := deployer.removeOrphans(); err != nil {
+			return err
+		}
+	}
+
+	if snapshot != nil {
+		// the deployment is done, failing to record it is not fatal
+		if err := deployer.recordSnapshot(snapshot); err != nil {
+			log.Println("Unable to record the deployment in " + deployer.HistoryDir + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
 func (deployer *ServiceDeployer) deployAssets() error {
 
+	if err := deployer.checkPlatformLimits(); err != nil {
+		return err
+	}
+
+	if err := deployer.checkAlarmTriggers(); err != nil {
+		return err
+	}
+
+	if err := deployer.checkOwnership(); err != nil {
+		return err
+	}
+
+	if utils.Flags.RollbackOnFailure && deployer.journal == nil {
+		deployer.journal = &Journal{}
+	}
+
 	if err := deployer.DeployPackages(); err != nil {
 		return err
 	}
@@ -257,6 +417,21 @@ func (deployer *ServiceDeployer) deployAssets() error {
 	return nil
 }
 
+// report a failed deployment, rolling it back when requested
+func (deployer *ServiceDeployer) deploymentFailed() {
+	if deployer.journal == nil {
+		log.Println("\nDeployment did not complete sucessfully. Run `wskdeploy undeploy` to remove partially deployed assets")
+		return
+	}
+
+	log.Println("\nDeployment did not complete sucessfully. Rolling back " + strconv.Itoa(deployer.journal.Len()) + " change(s) ...")
+	if err := deployer.Rollback(); err != nil {
+		log.Println(err)
+		return
+	}
+	log.Println("Rollback completed, the namespace is as it was before the deployment.")
+}
+
 func (deployer *ServiceDeployer) DeployDependencies() error {
 	for _, pack := range deployer.Deployment.Packages {
 		for depName, depRecord := range pack.Dependencies {
@@ -270,20 +445,28 @@ func (deployer *ServiceDeployer) DeployDependencies() error {
 				bindingPackage.Publish = &pub
 
 				qName, err := utils.ParseQualifiedName(depRecord.Location, pack.Package.Namespace)
-				utils.Check(err)
+				if err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
 				bindingPackage.Binding = whisk.Binding{qName.Namespace, qName.EntityName}
 
 				bindingPackage.Parameters = depRecord.Parameters
 				bindingPackage.Annotations = depRecord.Annotations
 
-				deployer.createBinding(bindingPackage)
+				if err := deployer.createBinding(bindingPackage); err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
 
 			} else {
 				depServiceDeployer, err := deployer.getDependentDeployer(depName, depRecord)
-				utils.Check(err)
+				if err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
 
-				err = depServiceDeployer.ConstructDeploymentPlan()
-				utils.Check(err)
+				if err := depServiceDeployer.ConstructDeploymentPlan(); err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
+				depServiceDeployer.resolveKinds()
 
 				if err := depServiceDeployer.deployAssets(); err != nil {
 					log.Println("\nDeployment of dependency " + depName + " did not complete sucessfully. Run `wskdeploy undeploy` to remove partially deployed assets")
@@ -300,44 +483,65 @@ func (deployer *ServiceDeployer) DeployDependencies() error {
 
 func (deployer *ServiceDeployer) DeployPackages() error {
 	for _, pack := range deployer.Deployment.Packages {
-		deployer.createPackage(pack.Package)
+		if err := deployer.createPackage(pack.Package); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// DeployActions into OpenWhisk
+// DeploySequences into OpenWhisk, after the sequences they are composed of
 func (deployer *ServiceDeployer) DeploySequences() error {
+	waves, err := sequenceWaves(deployer.Deployment.Packages)
+	if err != nil {
+		return err
+	}
 
-	for _, pack := range deployer.Deployment.Packages {
-		for _, action := range pack.Sequences {
-			deployer.createAction(pack.Package.Name, action.Action)
+	for _, wave := range waves {
+		tasks := make([]func() error, 0, len(wave))
+		for _, sequence := range wave {
+			sequence := sequence
+			tasks = append(tasks, func() error {
+				return deployer.createAction(sequence.pkgname, sequence.action)
+			})
+		}
+		if err := runTasks(deployer.Concurrency, tasks); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// DeployActions into OpenWhisk
+// DeployActions into OpenWhisk, up to Concurrency actions at a time
 func (deployer *ServiceDeployer) DeployActions() error {
-
+	tasks := make([]func() error, 0)
 	for _, pack := range deployer.Deployment.Packages {
-		for _, action := range pack.Actions {
-			err := deployer.createAction(pack.Package.Name, action.Action)
-			if err != nil {
-				return err
+		for _, record := range pack.Actions {
+			if isSequence(record) {
+				continue
 			}
+			pkgname := pack.Package.Name
+			action := record.Action
+			tasks = append(tasks, func() error {
+				return deployer.createAction(pkgname, action)
+			})
 		}
 	}
-	return nil
+	return runTasks(deployer.Concurrency, tasks)
 }
 
 // Deploy Triggers into OpenWhisk
 func (deployer *ServiceDeployer) DeployTriggers() error {
 	for _, trigger := range deployer.Deployment.Triggers {
 
+		var err error
 		if feedname, isFeed := utils.IsFeedAction(trigger); isFeed {
-			deployer.createFeedAction(trigger, feedname)
+			err = deployer.createFeedAction(trigger, feedname)
 		} else {
-			deployer.createTrigger(trigger)
+			err = deployer.createTrigger(trigger)
+		}
+		if err != nil {
+			return err
 		}
 
 	}
@@ -345,53 +549,180 @@ func (deployer *ServiceDeployer) DeployTriggers() error {
 
 }
 
+// checkAlarmTriggers fails before anything is deployed when an alarms feed
+// would be created with a cron expression or dates it cannot fire with.
+func (deployer *ServiceDeployer) checkAlarmTriggers() error {
+	problems := make([]string, 0)
+	for _, name := range sortedTriggerNames(deployer.Deployment.Triggers) {
+		trigger := deployer.Deployment.Triggers[name]
+		feed, isFeed := utils.IsFeedAction(trigger)
+		if !isFeed || !parsers.IsAlarmFeed(feed) {
+			continue
+		}
+		for _, problem := range parsers.CheckAlarmParameters(feed, keyValueMap(trigger.Parameters)) {
+			problems = append(problems, "trigger "+name+": "+problem)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
 // Deploy Rules into OpenWhisk
 func (deployer *ServiceDeployer) DeployRules() error {
 	for _, rule := range deployer.Deployment.Rules {
-		deployer.createRule(rule)
+		if err := deployer.createRule(rule); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// Deploy Apis into OpenWhisk
+// Deploy Apis into OpenWhisk. The base paths used by the deployment belong
+// to it, so routes under them that are no longer defined are removed.
 func (deployer *ServiceDeployer) DeployApis() error {
-	for _, api := range deployer.Deployment.Apis {
-		deployer.createApi(api)
+	basePaths := make(map[string]bool)
+	for _, key := range sortedApiKeys(deployer.Deployment.Apis) {
+		api := deployer.Deployment.Apis[key]
+		if err := deployer.createApi(api); err != nil {
+			return err
+		}
+		basePaths[api.ApiDoc.GatewayBasePath] = true
+	}
+
+	for basePath := range basePaths {
+		deployer.deleteStaleRoutes(basePath)
 	}
 	return nil
 }
 
-func (deployer *ServiceDeployer) createBinding(packa *whisk.BindingPackage) {
+func sortedApiKeys(apis map[string]*whisk.ApiCreateRequest) []string {
+	keys := make([]string, 0, len(apis))
+	for key := range apis {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// the API Gateway identifies the namespace by the uuid of the auth key; the
+// access token is only checked by gateways requiring one
+func apiOptions(config *whisk.Config) whisk.ApiOptions {
+	return whisk.ApiOptions{
+		SpaceGuid:   strings.Split(config.AuthToken, ":")[0],
+		AccessToken: "DUMMY TOKEN",
+	}
+}
+
+// delete the routes under basePath which the deployment no longer defines
+func (deployer *ServiceDeployer) deleteStaleRoutes(basePath string) {
+	options := apiOptions(deployer.ClientConfig)
+	options.ApiBasePath = basePath
+	getOptions := whisk.ApiGetRequestOptions(options)
+	retApis, _, err := deployer.Client.Apis.Get(&whisk.ApiGetRequest{}, &getOptions)
+	if err != nil {
+		log.Printf("Got error retrieving api %v with error message: %v.\n", basePath, err.Error())
+		return
+	}
+
+	for _, item := range retApis.Apis {
+		if item.ApiValue == nil || item.ApiValue.Swagger == nil {
+			continue
+		}
+		if item.ApiValue.Swagger.BasePath != "" && item.ApiValue.Swagger.BasePath != basePath {
+			continue
+		}
+		for relPath, swaggerPath := range item.ApiValue.Swagger.Paths {
+			for verb := range swaggerOperationMap(swaggerPath) {
+				if _, defined := deployer.Deployment.Apis[verb+" "+basePath+relPath]; !defined {
+					deployer.deleteApi(basePath, relPath, verb)
+				}
+			}
+		}
+	}
+}
+
+// the operations of a swagger path keyed by upper case verb
+func swaggerOperationMap(swaggerPath *whisk.ApiSwaggerPath) map[string]*whisk.ApiSwaggerOperation {
+	operations := make(map[string]*whisk.ApiSwaggerOperation)
+	if swaggerPath == nil {
+		return operations
+	}
+	for verb, operation := range map[string]*whisk.ApiSwaggerOperation{
+		"DELETE":  swaggerPath.Delete,
+		"GET":     swaggerPath.Get,
+		"HEAD":    swaggerPath.Head,
+		"OPTIONS": swaggerPath.Options,
+		"PATCH":   swaggerPath.Patch,
+		"POST":    swaggerPath.Post,
+		"PUT":     swaggerPath.Put,
+	} {
+		if operation != nil {
+			operations[verb] = operation
+		}
+	}
+	return operations
+}
+
+func (deployer *ServiceDeployer) createBinding(packa *whisk.BindingPackage) error {
 	log.Print("Deploying package binding" + packa.Name + " ... ")
+	start := time.Now()
+	packa.Annotations = deployer.stamp(packa.Annotations)
+	deployer.recordPackage(packa.Name)
 	_, _, err := deployer.Client.Packages.Insert(packa, true)
+	deployer.recordResult("package", packa.Name, OperationDeploy, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating package binding with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		return err
 	}
 	log.Println("Done!")
+	return nil
 }
 
-func (deployer *ServiceDeployer) createPackage(packa *whisk.Package) {
+func (deployer *ServiceDeployer) createPackage(packa *whisk.Package) error {
 	log.Print("Deploying package " + packa.Name + " ... ")
+	start := time.Now()
+	packa.Annotations = deployer.stamp(packa.Annotations)
+	deployer.recordPackage(packa.Name)
 	_, _, err := deployer.Client.Packages.Insert(packa, true)
+	deployer.recordResult("package", packa.Name, OperationDeploy, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating package with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		return err
 	}
 	log.Println("Done!")
+	return nil
 }
 
-func (deployer *ServiceDeployer) createTrigger(trigger *whisk.Trigger) {
+func (deployer *ServiceDeployer) createTrigger(trigger *whisk.Trigger) error {
+	start := time.Now()
+	trigger.Annotations = deployer.stamp(trigger.Annotations)
+	deployer.recordTrigger(trigger, "")
 	_, _, err := deployer.Client.Triggers.Insert(trigger, true)
+	deployer.recordResult("trigger", trigger.Name, OperationDeploy, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating trigger with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		return err
 	}
 	fmt.Println("Done!")
+	return nil
 }
 
-func (deployer *ServiceDeployer) createFeedAction(trigger *whisk.Trigger, feedName string) {
+// createFeedAction creates the trigger and invokes its feed with the
+// CREATE lifecycle event. A trigger deployed before keeps its feed when the
+// feed parameters did not change; otherwise the feed is sent an UPDATE, and
+// feeds that do not support updates are deleted and created again.
+func (deployer *ServiceDeployer) createFeedAction(trigger *whisk.Trigger, feedName string) (err error) {
 	log.Println("Deploying trigger feed " + trigger.Name + " ... ")
+	start := time.Now()
+	defer func() {
+		deployer.recordResult("trigger", trigger.Name, OperationDeploy, start, err)
+	}()
+
 	// to hold and modify trigger parameters, not passed by ref?
 	params := make(map[string]interface{})
 
@@ -400,41 +731,78 @@ func (deployer *ServiceDeployer) createFeedAction(trigger *whisk.Trigger, feedNa
 		params[keyVal.Key] = keyVal.Value
 	}
 
-	params["authKey"] = deployer.ClientConfig.AuthToken
-	params["lifecycleEvent"] = "CREATE"
-	params["triggerName"] = "/" + deployer.Client.Namespace + "/" + trigger.Name
-
+	feedHash := FeedParametersHash(trigger.Parameters)
 	pub := true
 	t := &whisk.Trigger{
 		Name:        trigger.Name,
-		Annotations: trigger.Annotations,
+		Annotations: SetManagedValue(deployer.stamp(trigger.Annotations), "feedHash", feedHash),
 		Publish:     &pub,
 	}
 
-	_, _, err := deployer.Client.Triggers.Insert(t, false)
+	deployer.recordTrigger(t, feedName)
+
+	existing, resp, err := deployer.Client.Triggers.Get(trigger.Name)
+	if err != nil && !isNotFound(resp) {
+		log.Printf("Got error reading trigger %s: %v\n", trigger.Name, err)
+		return err
+	}
+	exists := err == nil
+
+	_, _, err = deployer.Client.Triggers.Insert(t, exists)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating trigger with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
-	} else {
-
-		qName, err := utils.ParseQualifiedName(feedName, deployer.ClientConfig.Namespace)
-
-		utils.Check(err)
-
-		namespace := deployer.Client.Namespace
-		deployer.Client.Namespace = qName.Namespace
-		_, _, err = deployer.Client.Actions.Invoke(qName.EntityName, params, true, false)
-		deployer.Client.Namespace = namespace
+		return err
+	}
 
-		if err != nil {
-			wskErr := err.(*whisk.WskError)
+	switch {
+	case !exists:
+		err = deployer.invokeFeed(feedName, trigger.Name, "CREATE", params)
+	case ManagedValue(existing.Annotations, "feedHash") == feedHash:
+		log.Println("Feed parameters of trigger " + trigger.Name + " are unchanged")
+	default:
+		if err = deployer.invokeFeed(feedName, trigger.Name, "UPDATE", params); err != nil {
+			log.Printf("Feed %s did not accept the update of trigger %s, creating the feed again\n", feedName, trigger.Name)
+			if err = deployer.invokeFeed(feedName, trigger.Name, "DELETE", nil); err == nil {
+				err = deployer.invokeFeed(feedName, trigger.Name, "CREATE", params)
+			}
+		}
+	}
+	if err != nil {
+		if wskErr, ok := err.(*whisk.WskError); ok {
 			log.Printf("Got error creating trigger feed with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		} else {
+			log.Printf("Got error creating trigger feed with error message: %v.\n", err.Error())
 		}
+		return err
 	}
 	fmt.Println("Done!")
+	return nil
+}
+
+// invoke the feed action of a trigger with a lifecycle event
+func (deployer *ServiceDeployer) invokeFeed(feedName string, triggerName string, event string, params map[string]interface{}) error {
+	parameters := make(map[string]interface{})
+	for key, value := range params {
+		parameters[key] = value
+	}
+	parameters["authKey"] = deployer.ClientConfig.AuthToken
+	parameters["lifecycleEvent"] = event
+	parameters["triggerName"] = "/" + deployer.Client.Namespace + "/" + triggerName
+
+	qName, err := utils.ParseQualifiedName(feedName, deployer.ClientConfig.Namespace)
+	if err != nil {
+		return err
+	}
+
+	namespace := deployer.Client.Namespace
+	deployer.Client.Namespace = qName.Namespace
+	_, _, err = deployer.Client.Actions.Invoke(qName.EntityName, parameters, true, true)
+	deployer.Client.Namespace = namespace
+	return err
 }
 
-func (deployer *ServiceDeployer) createRule(rule *whisk.Rule) {
+func (deployer *ServiceDeployer) createRule(rule *whisk.Rule) error {
 	// The rule's trigger should include the namespace with pattern /namespace/trigger
 	rule.Trigger = deployer.getQualifiedName(rule.Trigger.(string), deployer.ClientConfig.Namespace)
 	// The rule's action should include the namespace and package with pattern /namespace/package/action
@@ -448,29 +816,64 @@ func (deployer *ServiceDeployer) createRule(rule *whisk.Rule) {
 		rule.Action = deployer.getQualifiedName(strings.Join([]string{deployer.RootPackageName, rule.Action.(string)}, "/"), deployer.ClientConfig.Namespace)
 	}
 	fmt.Print("Deploying rule " + rule.Name + " ... ")
+	start := time.Now()
+	rule.Annotations = deployer.stamp(rule.Annotations)
+	deployer.recordRule(rule.Name)
 	_, _, err := deployer.Client.Rules.Insert(rule, true)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating rule with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		deployer.recordResult("rule", rule.Name, OperationDeploy, start, err)
+		return err
 	}
 
-	_, _, err = deployer.Client.Rules.SetState(rule.Name, "active")
+	_, _, err = deployer.Client.Rules.SetState(rule.Name, RuleStatus(rule))
+	deployer.recordResult("rule", rule.Name, OperationDeploy, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
-		log.Printf("Got error activating rule with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		log.Printf("Got error setting the state of rule with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		return err
 	}
 	fmt.Println("Done!")
+	return nil
+}
+
+// RuleStatus is the state a rule is deployed in, active unless the manifest
+// declares it inactive.
+func RuleStatus(rule *whisk.Rule) string {
+	if rule.Status == "inactive" {
+		return "inactive"
+	}
+	return "active"
 }
 
 // Utility function to call go-whisk framework to make action
 func (deployer *ServiceDeployer) createAction(pkgname string, action *whisk.Action) error {
+	// skip actions whose code and settings did not change since the last deploy
+	start := time.Now()
+	hash := ActionHash(action)
+	action.Annotations = deployer.stamp(SetManagedValue(action.Annotations, "hash", hash))
+
+	listPackage := ""
+	if deployer.DeployActionInPackage {
+		listPackage = pkgname
+	}
+	if deployer.deployedHash(listPackage, action.Name) == hash {
+		name := strings.TrimPrefix(strings.Join([]string{listPackage, action.Name}, "/"), "/")
+		log.Println("Action " + name + " is unchanged, skipping.")
+		deployer.recordResult("action", name, OperationUnchanged, start, nil)
+		return nil
+	}
+
 	// call ActionService Thru Client
 	if deployer.DeployActionInPackage {
 		// the action will be created under package with pattern 'packagename/actionname'
 		action.Name = strings.Join([]string{pkgname, action.Name}, "/")
 	}
 	log.Print("Deploying action " + action.Name + " ... ")
+	deployer.recordAction(action.Name)
 	_, _, err := deployer.Client.Actions.Insert(action, true)
+	deployer.recordResult("action", action.Name, OperationDeploy, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating action with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
@@ -481,24 +884,40 @@ func (deployer *ServiceDeployer) createAction(pkgname string, action *whisk.Acti
 }
 
 // create api gateway
-func (deployer *ServiceDeployer) createApi(api *whisk.ApiCreateRequest) {
-	_, _, err := deployer.Client.Apis.Insert(api, nil, true)
+func (deployer *ServiceDeployer) createApi(api *whisk.ApiCreateRequest) error {
+	route := api.ApiDoc.GatewayMethod + " " + api.ApiDoc.GatewayBasePath + api.ApiDoc.GatewayRelPath
+	log.Print("Deploying api " + route + " ... ")
+	start := time.Now()
+	options := whisk.ApiCreateRequestOptions(apiOptions(deployer.ClientConfig))
+	_, _, err := deployer.Client.Apis.Insert(api, &options, true)
+	deployer.recordResult("api", route, OperationDeploy, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error creating api with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+		return err
 	}
 	log.Println("Done!")
+	return nil
 }
 
 func (deployer *ServiceDeployer) UnDeploy(verifiedPlan *DeploymentApplication) error {
+	if utils.Flags.Preview {
+		plan, err := deployer.UnDeploymentPlan(verifiedPlan)
+		if err != nil {
+			return err
+		}
+		plan.Print()
+		return nil
+	}
+
 	if deployer.IsInteractive == true {
 		deployer.printDeploymentAssets(verifiedPlan)
 		reader := bufio.NewReader(os.Stdin)
 
-		fmt.Print("Do you really want to undeploy this? (y/N): ")
-
-		text, _ := reader.ReadString('\n')
-		text = strings.TrimSpace(text)
+		text, err := utils.Prompt(reader, "undeploy", "Do you really want to undeploy this? (y/N): ")
+		if err != nil {
+			return err
+		}
 
 		if text == "" {
 			text = "n"
@@ -535,19 +954,24 @@ func (deployer *ServiceDeployer) UnDeploy(verifiedPlan *DeploymentApplication) e
 
 func (deployer *ServiceDeployer) unDeployAssets(verifiedPlan *DeploymentApplication) error {
 
-	if err := deployer.UnDeployActions(verifiedPlan); err != nil {
+	if err := deployer.UnDeployApis(verifiedPlan); err != nil {
 		return err
 	}
 
-	if err := deployer.UnDeploySequences(verifiedPlan); err != nil {
+	// rules are disabled and deleted before their triggers and actions
+	if err := deployer.UnDeployRules(verifiedPlan); err != nil {
 		return err
 	}
 
-	if err := deployer.UnDeployTriggers(verifiedPlan); err != nil {
+	if err := deployer.UnDeployActions(verifiedPlan); err != nil {
 		return err
 	}
 
-	if err := deployer.UnDeployRules(verifiedPlan); err != nil {
+	if err := deployer.UnDeploySequences(verifiedPlan); err != nil {
+		return err
+	}
+
+	if err := deployer.UnDeployTriggers(verifiedPlan); err != nil {
 		return err
 	}
 
@@ -569,15 +993,23 @@ func (deployer *ServiceDeployer) UnDeployDependencies() error {
 			fmt.Println("Undeploying dependency " + depName + " ... ")
 
 			if depRecord.IsBinding {
+				start := time.Now()
 				_, err := deployer.Client.Packages.Delete(depName)
-				utils.Check(err)
+				deployer.recordResult("package", depName, OperationDelete, start, err)
+				if err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
 			} else {
 
 				depServiceDeployer, err := deployer.getDependentDeployer(depName, depRecord)
-				utils.Check(err)
+				if err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
 
 				plan, err := depServiceDeployer.ConstructUnDeploymentPlan()
-				utils.Check(err)
+				if err != nil {
+					return utils.EntityErr("dependency", depName, err)
+				}
 
 				if err := depServiceDeployer.unDeployAssets(plan); err != nil {
 					log.Println("\nUndeployment of dependency " + depName + " did not complete sucessfully. Run `wskdeploy undeploy` to remove partially deployed assets")
@@ -648,9 +1080,36 @@ func (deployer *ServiceDeployer) UnDeployRules(deployment *DeploymentApplication
 	return nil
 }
 
+// Remove the api gateway routes of the deployment
+func (deployer *ServiceDeployer) UnDeployApis(deployment *DeploymentApplication) error {
+	for _, key := range sortedApiKeys(deployment.Apis) {
+		api := deployment.Apis[key].ApiDoc
+		deployer.deleteApi(api.GatewayBasePath, api.GatewayRelPath, api.GatewayMethod)
+	}
+	return nil
+}
+
+func (deployer *ServiceDeployer) deleteApi(basePath string, relPath string, verb string) {
+	fmt.Print("Removing api " + verb + " " + basePath + relPath + " ... ")
+	start := time.Now()
+	options := apiOptions(deployer.ClientConfig)
+	options.ApiBasePath = basePath
+	options.ApiRelPath = relPath
+	options.ApiVerb = verb
+	deleteOptions := whisk.ApiDeleteRequestOptions(options)
+	_, err := deployer.Client.Apis.Delete(&whisk.ApiDeleteRequest{}, &deleteOptions)
+	deployer.recordResult("api", verb+" "+basePath+relPath, OperationDelete, start, err)
+	if err != nil {
+		log.Printf("Got error deleting api with error message: %v.\n", err.Error())
+	}
+	fmt.Println("Done!")
+}
+
 func (deployer *ServiceDeployer) deletePackage(packa *whisk.Package) {
 	fmt.Print("Removing package " + packa.Name + " ... ")
+	start := time.Now()
 	_, err := deployer.Client.Packages.Delete(packa.Name)
+	deployer.recordResult("package", packa.Name, OperationDelete, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error deleteing package with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
@@ -660,7 +1119,9 @@ func (deployer *ServiceDeployer) deletePackage(packa *whisk.Package) {
 
 func (deployer *ServiceDeployer) deleteTrigger(trigger *whisk.Trigger) {
 	fmt.Print("Removing trigger " + trigger.Name + " ... ")
+	start := time.Now()
 	_, _, err := deployer.Client.Triggers.Delete(trigger.Name)
+	deployer.recordResult("trigger", trigger.Name, OperationDelete, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error deleting trigger with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
@@ -670,42 +1131,31 @@ func (deployer *ServiceDeployer) deleteTrigger(trigger *whisk.Trigger) {
 
 func (deployer *ServiceDeployer) deleteFeedAction(trigger *whisk.Trigger, feedName string) {
 
-	params := make(whisk.KeyValueArr, 0)
-	params = append(params, whisk.KeyValue{Key: "authKey", Value: deployer.ClientConfig.AuthToken})
-	params = append(params, whisk.KeyValue{Key: "lifecycleEvent", Value: "DELETE"})
-	params = append(params, whisk.KeyValue{Key: "triggerName", Value: "/" + deployer.Client.Namespace + "/" + trigger.Name})
-
 	trigger.Parameters = nil
 
+	start := time.Now()
 	_, _, err := deployer.Client.Triggers.Delete(trigger.Name)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error deleting trigger with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
 	} else {
-		parameters := make(map[string]interface{})
-		for _, keyVal := range params {
-			parameters[keyVal.Key] = keyVal.Value
-		}
-
-		qName, err := utils.ParseQualifiedName(feedName, deployer.ClientConfig.Namespace)
-
-		utils.Check(err)
-
-		namespace := deployer.Client.Namespace
-		deployer.Client.Namespace = qName.Namespace
-		_, _, err = deployer.Client.Actions.Invoke(qName.EntityName, parameters, true, true)
-		deployer.Client.Namespace = namespace
+		err = deployer.invokeFeed(feedName, trigger.Name, "DELETE", nil)
 
 		if err != nil {
-			wskErr := err.(*whisk.WskError)
-			log.Printf("Got error deleting trigger feed with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+			if wskErr, ok := err.(*whisk.WskError); ok {
+				log.Printf("Got error deleting trigger feed with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
+			} else {
+				log.Printf("Got error deleting trigger feed with error message: %v.\n", err.Error())
+			}
 		}
 	}
+	deployer.recordResult("trigger", trigger.Name, OperationDelete, start, err)
 	fmt.Println("Done!")
 }
 
 func (deployer *ServiceDeployer) deleteRule(rule *whisk.Rule) {
 	fmt.Print("Removing rule " + rule.Name + " ... ")
+	start := time.Now()
 	_, _, err := deployer.Client.Rules.SetState(rule.Name, "inactive")
 
 	if err != nil {
@@ -722,6 +1172,7 @@ func (deployer *ServiceDeployer) deleteRule(rule *whisk.Rule) {
 
 		fmt.Println("Done!")
 	}
+	deployer.recordResult("rule", rule.Name, OperationDelete, start, err)
 }
 
 // Utility function to call go-whisk framework to make action
@@ -733,7 +1184,9 @@ func (deployer *ServiceDeployer) deleteAction(pkgname string, action *whisk.Acti
 	}
 
 	fmt.Print("Removing action " + action.Name + " ... ")
+	start := time.Now()
 	_, err := deployer.Client.Actions.Delete(action.Name)
+	deployer.recordResult("action", action.Name, OperationDelete, start, err)
 	if err != nil {
 		wskErr := err.(*whisk.WskError)
 		log.Printf("Got error deleting action with error message: %v and error code: %v.\n", wskErr.Error(), wskErr.ExitCode)
@@ -833,7 +1286,7 @@ func (deployer *ServiceDeployer) printDeploymentAssets(assets *DeploymentApplica
 
 func (deployer *ServiceDeployer) getDependentDeployer(depName string, depRecord utils.DependencyRecord) (*ServiceDeployer, error) {
 	depServiceDeployer := NewServiceDeployer()
-	projectPath := path.Join(depRecord.ProjectPath, depName+"-"+depRecord.Version)
+	projectPath := utils.DependencyPath(depRecord.ProjectPath, depName, depRecord.Version)
 	manifestPath := path.Join(projectPath, ManifestFileNameYml)
 	deploymentPath := path.Join(projectPath, DeploymentFileNameYaml)
 	depServiceDeployer.ProjectPath = projectPath
@@ -843,8 +1296,15 @@ func (deployer *ServiceDeployer) getDependentDeployer(depName string, depRecord
 
 	depServiceDeployer.Client = deployer.Client
 	depServiceDeployer.ClientConfig = deployer.ClientConfig
-
-	depServiceDeployer.DependencyMaster = deployer.DependencyMaster
+	depServiceDeployer.Concurrency = deployer.Concurrency
+	depServiceDeployer.journal = deployer.journal
+	depServiceDeployer.Results = deployer.Results
+
+	// the entities of the dependency are managed by this project
+	depServiceDeployer.ProjectName = deployer.Project()
+	depServiceDeployer.dependency = depName
+	depServiceDeployer.lock = deployer.lock
+	depServiceDeployer.PackageName = depRecord.Package
 
 	// share the master dependency list
 	depServiceDeployer.DependencyMaster = deployer.DependencyMaster
@@ -23,3 +23,5 @@ const ManifestFileNameYaml = "manifest.yaml"
 const ManifestFileNameYml = "manifest.yml"
 const DeploymentFileNameYaml = "deployment.yaml"
 const DeploymentFileNameYml = "deployment.yml"
+const ManifestFileNameJson = "manifest.json"
+const DeploymentFileNameJson = "deployment.json"
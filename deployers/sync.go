@@ -0,0 +1,262 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// Orphan is an entity deployed by this project that is no longer part of
+// the manifest.
+type Orphan struct {
+	Kind string // package, action, trigger or rule
+	Name string
+	feed string
+}
+
+// Project returns the name entities of this deployment are tagged with,
+// the root package name unless a project name was given.
+func (deployer *ServiceDeployer) Project() string {
+	if deployer.ProjectName != "" {
+		return deployer.ProjectName
+	}
+	return deployer.RootPackageName
+}
+
+// tag the annotations of an entity as managed by this project, and record
+// the manifest it was deployed from, when it was deployed and the dependency
+// it was deployed for
+func (deployer *ServiceDeployer) stamp(annotations whisk.KeyValueArr) whisk.KeyValueArr {
+	annotations = SetManagedValue(annotations, "projectName", deployer.Project())
+	if hash := deployer.projectHash(); hash != "" {
+		annotations = SetManagedValue(annotations, "projectHash", hash)
+	}
+	if deployer.ManifestPath != "" {
+		annotations = SetManagedValue(annotations, "file", deployer.ManifestPath)
+	}
+	if deployer.dependency != "" {
+		annotations = SetManagedValue(annotations, "dependency", deployer.dependency)
+	}
+	return SetManagedValue(annotations, "timestamp", time.Now().UTC().Format(time.RFC3339))
+}
+
+// the SHA-256 of the manifest, telling which version of it an entity was
+// deployed from
+func (deployer *ServiceDeployer) projectHash() string {
+	deployer.manifestHashOnce.Do(func() {
+		if deployer.ManifestPath == "" {
+			return
+		}
+		content, err := utils.Read(deployer.ManifestPath)
+		if err != nil {
+			log.Printf("Unable to hash the manifest %s: %v\n", deployer.ManifestPath, err)
+			return
+		}
+		sum := sha256.Sum256(content)
+		deployer.manifestHash = hex.EncodeToString(sum[:])
+	})
+	return deployer.manifestHash
+}
+
+func (deployer *ServiceDeployer) isManaged(annotations whisk.KeyValueArr) bool {
+	return ManagedValue(annotations, "projectName") == deployer.Project()
+}
+
+func (deployer *ServiceDeployer) listPackages() ([]whisk.Package, error) {
+	return ListAllPackages(deployer.Client)
+}
+
+func (deployer *ServiceDeployer) listActions(pkgname string) ([]whisk.Action, error) {
+	return ListAllActions(deployer.Client, pkgname)
+}
+
+func (deployer *ServiceDeployer) listTriggers() ([]whisk.Trigger, error) {
+	return ListAllTriggers(deployer.Client)
+}
+
+func (deployer *ServiceDeployer) listRules() ([]whisk.Rule, error) {
+	return ListAllRules(deployer.Client)
+}
+
+// FindOrphans lists the entities tagged with this project that the
+// deployment no longer contains, rules first so they can be deleted in order.
+func (deployer *ServiceDeployer) FindOrphans() ([]Orphan, error) {
+	wanted := make(map[string]bool)
+	for _, pack := range deployer.Deployment.Packages {
+		wanted["package/"+pack.Package.Name] = true
+		for name := range pack.Actions {
+			wanted["action/"+deployer.actionPath(pack.Package.Name, name)] = true
+		}
+		for name := range pack.Sequences {
+			wanted["action/"+deployer.actionPath(pack.Package.Name, name)] = true
+		}
+		for name := range pack.Dependencies {
+			wanted["package/"+name] = true
+			wanted["dependency/"+name] = true
+		}
+	}
+	for name := range deployer.Deployment.Triggers {
+		wanted["trigger/"+name] = true
+	}
+	for name := range deployer.Deployment.Rules {
+		wanted["rule/"+name] = true
+	}
+
+	// entities of a dependency still listed are left to its own manifest
+	orphaned := func(annotations whisk.KeyValueArr, key string) bool {
+		if !deployer.isManaged(annotations) || wanted[key] {
+			return false
+		}
+		dependency := ManagedValue(annotations, "dependency")
+		return dependency == "" || !wanted["dependency/"+dependency]
+	}
+
+	orphans := make([]Orphan, 0)
+
+	rules, err := deployer.listRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if orphaned(rule.Annotations, "rule/"+rule.Name) {
+			orphans = append(orphans, Orphan{Kind: "rule", Name: rule.Name})
+		}
+	}
+
+	triggers, err := deployer.listTriggers()
+	if err != nil {
+		return nil, err
+	}
+	for _, trigger := range triggers {
+		if orphaned(trigger.Annotations, "trigger/"+trigger.Name) {
+			feed, _ := utils.IsFeedAction(&trigger)
+			orphans = append(orphans, Orphan{Kind: "trigger", Name: trigger.Name, feed: feed})
+		}
+	}
+
+	packages, err := deployer.listPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	// actions of the root namespace and of every package the project owns
+	scopes := []string{""}
+	packageOrphans := make([]Orphan, 0)
+	for _, pkg := range packages {
+		if !deployer.isManaged(pkg.Annotations) && !wanted["package/"+pkg.Name] {
+			continue
+		}
+		if pkg.Binding == nil || pkg.Binding.Name == "" {
+			scopes = append(scopes, pkg.Name)
+		}
+		if orphaned(pkg.Annotations, "package/"+pkg.Name) {
+			packageOrphans = append(packageOrphans, Orphan{Kind: "package", Name: pkg.Name})
+		}
+	}
+
+	for _, scope := range scopes {
+		actions, err := deployer.listActions(scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, action := range actions {
+			name := strings.TrimPrefix(scope+"/"+action.Name, "/")
+			if orphaned(action.Annotations, "action/"+name) {
+				orphans = append(orphans, Orphan{Kind: "action", Name: name})
+			}
+		}
+	}
+
+	sort.Sort(byOrphanName(packageOrphans))
+	return append(orphans, packageOrphans...), nil
+}
+
+type byOrphanName []Orphan
+
+func (o byOrphanName) Len() int           { return len(o) }
+func (o byOrphanName) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o byOrphanName) Less(i, j int) bool { return o[i].Name < o[j].Name }
+
+// removeOrphans finds and deletes the orphans of a successful deployment.
+// Sync deletes them right away, --remove-orphans lists them and asks first
+// unless --force is given.
+func (deployer *ServiceDeployer) removeOrphans() error {
+	orphans, err := deployer.FindOrphans()
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+	if !utils.Flags.Sync && !utils.Flags.Force {
+		fmt.Println("\nThese entities of project " + deployer.Project() + " are no longer in the manifest:")
+		for _, orphan := range orphans {
+			fmt.Println("    " + orphan.Kind + " " + orphan.Name)
+		}
+		text, err := utils.Prompt(bufio.NewReader(os.Stdin), "remove-orphans", "Do you really want to remove them? (y/N): ")
+		if err != nil {
+			return errors.New(err.Error() + ", or pass --force")
+		}
+		if !strings.EqualFold(text, "y") && !strings.EqualFold(text, "yes") {
+			fmt.Println("OK. Keeping them")
+			return nil
+		}
+	}
+	log.Println("Removing entities of project " + deployer.Project() + " that are no longer in the manifest ...")
+	return deployer.RemoveOrphans(orphans)
+}
+
+// RemoveOrphans deletes the given entities from the namespace
+func (deployer *ServiceDeployer) RemoveOrphans(orphans []Orphan) error {
+	for _, orphan := range orphans {
+		switch orphan.Kind {
+		case "rule":
+			deployer.deleteRule(&whisk.Rule{Name: orphan.Name})
+		case "trigger":
+			if orphan.feed != "" {
+				deployer.deleteFeedAction(&whisk.Trigger{Name: orphan.Name}, orphan.feed)
+			} else {
+				deployer.deleteTrigger(&whisk.Trigger{Name: orphan.Name})
+			}
+		case "action":
+			log.Print("Removing action " + orphan.Name + " ... ")
+			start := time.Now()
+			_, err := deployer.Client.Actions.Delete(orphan.Name)
+			deployer.recordResult("action", orphan.Name, OperationDelete, start, err)
+			if err != nil {
+				return err
+			}
+			log.Println("Done!")
+		case "package":
+			deployer.deletePackage(&whisk.Package{Name: orphan.Name})
+		}
+	}
+	return nil
+}
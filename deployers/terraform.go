@@ -0,0 +1,367 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deployers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// TerraformFileName is the configuration export --format terraform writes
+const TerraformFileName = "main.tf"
+
+// the directory of the configuration the code of the actions is written to
+const terraformCodeDir = "code"
+
+// the characters Terraform does not allow in the names of resources
+var terraformNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// the Terraform resources of the entities of the plan, by their names
+type terraformResources struct {
+	actions  map[string]string // by package/action
+	triggers map[string]string
+}
+
+// Terraform writes the deployment plan as the Terraform configuration of
+// its packages, actions, sequences, triggers and rules to dir, as resources
+// of the ibm_function kind of the IBM Cloud provider against the namespace
+// variable. The code of the actions is written to the code directory the
+// configuration refers to. What the provider has no resource for, such as
+// API routes, is returned as warnings.
+func (deployer *ServiceDeployer) Terraform(dir string) ([]string, error) {
+	deployment := deployer.Deployment
+	warnings := make([]string, 0)
+	if len(deployment.Apis) > 0 {
+		warnings = append(warnings, "API routes are not exported, the Terraform provider has no resource for them")
+	}
+
+	namespace := ""
+	if deployer.ClientConfig != nil && deployer.ClientConfig.Namespace != "_" {
+		namespace = deployer.ClientConfig.Namespace
+	}
+	var config bytes.Buffer
+	config.WriteString("# Terraform configuration exported by wskdeploy\n\n")
+	fmt.Fprintf(&config, "variable \"namespace\" {\n  default = %s\n}\n", terraformString(namespace))
+
+	resources := terraformResources{actions: make(map[string]string), triggers: make(map[string]string)}
+	packs := make([]string, 0, len(deployment.Packages))
+	for name := range deployment.Packages {
+		packs = append(packs, name)
+	}
+	sort.Strings(packs)
+	for _, packName := range packs {
+		pack := deployment.Packages[packName]
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			for name := range records {
+				resources.actions[pack.Package.Name+"/"+name] = terraformName(pack.Package.Name, name)
+			}
+		}
+	}
+	for name := range deployment.Triggers {
+		resources.triggers[name] = terraformName(name)
+	}
+
+	for _, packName := range packs {
+		pack := deployment.Packages[packName]
+		block := terraformBlock("ibm_function_package", terraformName(pack.Package.Name))
+		block.attribute("name", pack.Package.Name)
+		block.keyValues("user_defined_parameters", pack.Package.Parameters)
+		block.keyValues("user_defined_annotations", pack.Package.Annotations)
+		config.WriteString(block.String())
+
+		for _, name := range sortedDependencies(pack.Dependencies) {
+			record := pack.Dependencies[name]
+			if !record.IsBinding {
+				warnings = append(warnings, "dependency "+name+" is deployed from "+record.Location+", which is not exported")
+				continue
+			}
+			block := terraformBlock("ibm_function_package", terraformName(name))
+			block.attribute("name", name)
+			block.attribute("bind_package_name", record.Location)
+			block.keyValues("user_defined_parameters", record.Parameters)
+			block.keyValues("user_defined_annotations", record.Annotations)
+			config.WriteString(block.String())
+		}
+
+		for _, records := range []map[string]utils.ActionRecord{pack.Actions, pack.Sequences} {
+			names := make([]string, 0, len(records))
+			for name := range records {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				block, err := resources.action(pack.Package.Name, records[name], dir)
+				if err != nil {
+					return nil, err
+				}
+				config.WriteString(block.String())
+			}
+		}
+	}
+
+	triggers := make([]string, 0, len(deployment.Triggers))
+	for name := range deployment.Triggers {
+		triggers = append(triggers, name)
+	}
+	sort.Strings(triggers)
+	for _, name := range triggers {
+		config.WriteString(resources.trigger(deployment.Triggers[name]).String())
+	}
+
+	rules := make([]string, 0, len(deployment.Rules))
+	for name := range deployment.Rules {
+		rules = append(rules, name)
+	}
+	sort.Strings(rules)
+	for _, name := range rules {
+		config.WriteString(resources.rule(deployment.Rules[name], deployer.RootPackageName).String())
+	}
+
+	return warnings, ioutil.WriteFile(filepath.Join(dir, TerraformFileName), config.Bytes(), 0644)
+}
+
+func (resources terraformResources) action(packName string, record utils.ActionRecord, dir string) (*terraformResource, error) {
+	action := record.Action
+	block := terraformBlock("ibm_function_action", terraformName(packName, action.Name))
+	block.expression("name", `"${ibm_function_package.`+terraformName(packName)+`.name}/`+terraformEscape(action.Name)+`"`)
+
+	exec := block.block("exec")
+	if action.Exec != nil {
+		exec.attribute("kind", action.Exec.Kind)
+		if action.Exec.Image != "" {
+			exec.attribute("image", action.Exec.Image)
+		}
+		if action.Exec.Main != "" {
+			exec.attribute("main", action.Exec.Main)
+		}
+		if action.Exec.Code != nil {
+			codePath, err := writeTerraformCode(dir, packName, record)
+			if err != nil {
+				return nil, err
+			}
+			exec.attribute("code_path", codePath)
+		}
+		if len(action.Exec.Components) > 0 {
+			// the components of the plan are referred to by their resources
+			components := make([]string, 0, len(action.Exec.Components))
+			for _, component := range action.Exec.Components {
+				if resource, ok := resources.actions[componentKey(component)]; ok {
+					components = append(components, `"/${var.namespace}/${ibm_function_action.`+resource+`.name}"`)
+				} else {
+					components = append(components, terraformString(component))
+				}
+			}
+			exec.expression("components", "["+strings.Join(components, ", ")+"]")
+		}
+	}
+
+	if limits := action.Limits; limits != nil {
+		limitsBlock := block.block("limits")
+		if limits.Timeout != nil {
+			limitsBlock.number("timeout", *limits.Timeout)
+		}
+		if limits.Memory != nil {
+			limitsBlock.number("memory", *limits.Memory)
+		}
+		if limits.Logsize != nil {
+			limitsBlock.number("log_size", *limits.Logsize)
+		}
+	}
+	block.keyValues("user_defined_parameters", action.Parameters)
+	block.keyValues("user_defined_annotations", action.Annotations)
+	return block, nil
+}
+
+func (resources terraformResources) trigger(trigger *whisk.Trigger) *terraformResource {
+	block := terraformBlock("ibm_function_trigger", resources.triggers[trigger.Name])
+	block.attribute("name", trigger.Name)
+
+	// the parameters of a trigger with a feed are those of the feed
+	annotations := make(whisk.KeyValueArr, 0, len(trigger.Annotations))
+	feed := ""
+	for _, annotation := range trigger.Annotations {
+		if name, ok := annotation.Value.(string); ok && annotation.Key == "feed" {
+			feed = name
+			continue
+		}
+		annotations = append(annotations, annotation)
+	}
+	if feed != "" {
+		feedBlock := block.block("feed")
+		feedBlock.attribute("name", feed)
+		feedBlock.keyValues("parameters", trigger.Parameters)
+	} else {
+		block.keyValues("user_defined_parameters", trigger.Parameters)
+	}
+	block.keyValues("user_defined_annotations", annotations)
+	return block
+}
+
+func (resources terraformResources) rule(rule *whisk.Rule, rootPackage string) *terraformResource {
+	block := terraformBlock("ibm_function_rule", terraformName(rule.Name))
+	block.attribute("name", rule.Name)
+
+	trigger := fmt.Sprint(rule.Trigger)
+	if resource, ok := resources.triggers[trigger]; ok {
+		block.expression("trigger_name", `"${ibm_function_trigger.`+resource+`.name}"`)
+	} else {
+		block.attribute("trigger_name", trigger)
+	}
+	// actions outside of a package are in the root package
+	action := fmt.Sprint(rule.Action)
+	if !strings.Contains(action, "/") {
+		action = rootPackage + "/" + action
+	}
+	if resource, ok := resources.actions[componentKey(action)]; ok {
+		block.expression("action_name", `"${ibm_function_action.`+resource+`.name}"`)
+	} else {
+		block.attribute("action_name", action)
+	}
+	block.keyValues("user_defined_annotations", rule.Annotations)
+	return block
+}
+
+// the code of the action written to the code directory, binaries decoded
+func writeTerraformCode(dir string, packName string, record utils.ActionRecord) (string, error) {
+	code := []byte(*record.Action.Exec.Code)
+	ext := path.Ext(record.Filepath)
+	if record.Action.Exec.Binary != nil && *record.Action.Exec.Binary {
+		decoded, err := base64.StdEncoding.DecodeString(*record.Action.Exec.Code)
+		if err != nil {
+			return "", err
+		}
+		code = decoded
+		if ext != ".jar" {
+			ext = ".zip"
+		}
+	}
+
+	codePath := path.Join(terraformCodeDir, packName, record.Action.Name+ext)
+	file := filepath.Join(dir, filepath.FromSlash(codePath))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	return codePath, ioutil.WriteFile(file, code, 0644)
+}
+
+func sortedDependencies(dependencies map[string]utils.DependencyRecord) []string {
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// the name of the resource of an entity, e.g. pkg_hello for pkg/hello
+func terraformName(parts ...string) string {
+	name := terraformNameInvalid.ReplaceAllString(strings.Join(parts, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+	return name
+}
+
+// the text escaped so Terraform does not interpolate it
+func terraformEscape(text string) string {
+	text = strings.Replace(text, `\`, `\\`, -1)
+	text = strings.Replace(text, `"`, `\"`, -1)
+	text = strings.Replace(text, "\n", `\n`, -1)
+	text = strings.Replace(text, "${", "$${", -1)
+	return strings.Replace(text, "%{", "%%{", -1)
+}
+
+func terraformString(text string) string {
+	return `"` + terraformEscape(text) + `"`
+}
+
+// a resource of the configuration, or a block of one
+type terraformResource struct {
+	header string
+	lines  []string
+	blocks []*terraformResource
+}
+
+// resources are created in the namespace of the variable
+func terraformBlock(kind string, name string) *terraformResource {
+	return &terraformResource{
+		header: "resource " + terraformString(kind) + " " + terraformString(name),
+		lines:  []string{`namespace = "${var.namespace}"`},
+	}
+}
+
+func (resource *terraformResource) block(name string) *terraformResource {
+	block := &terraformResource{header: name}
+	resource.blocks = append(resource.blocks, block)
+	return block
+}
+
+func (resource *terraformResource) expression(key string, expression string) {
+	resource.lines = append(resource.lines, key+" = "+expression)
+}
+
+func (resource *terraformResource) attribute(key string, value string) {
+	resource.expression(key, terraformString(value))
+}
+
+func (resource *terraformResource) number(key string, value int) {
+	resource.expression(key, fmt.Sprint(value))
+}
+
+// parameters and annotations are given to the provider as JSON, the values
+// of the plan being those the whisk client encodes
+func (resource *terraformResource) keyValues(key string, keyValues whisk.KeyValueArr) {
+	if len(keyValues) == 0 {
+		return
+	}
+	content, err := json.Marshal(keyValues)
+	if err != nil {
+		return
+	}
+	resource.attribute(key, string(content))
+}
+
+func (resource *terraformResource) write(buffer *bytes.Buffer, indent string) {
+	buffer.WriteString(indent + resource.header + " {\n")
+	for _, line := range resource.lines {
+		buffer.WriteString(indent + "  " + line + "\n")
+	}
+	for _, block := range resource.blocks {
+		block.write(buffer, indent+"  ")
+	}
+	buffer.WriteString(indent + "}\n")
+}
+
+func (resource *terraformResource) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	resource.write(&buffer, "")
+	return buffer.String()
+}
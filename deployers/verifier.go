@@ -37,8 +37,7 @@ type Filter interface {
 }
 
 func (vf *Verifier) Query(deployer *ServiceDeployer) (da *DeploymentApplication, err error) {
-	pkgoptions := &whisk.PackageListOptions{false, 0, 0, 0, false}
-	packages, _, err := deployer.Client.Packages.List(pkgoptions)
+	packages, err := ListAllPackages(deployer.Client)
 
 	da = NewDeploymentApplication()
 	for _, pa := range packages {
@@ -19,6 +19,7 @@ package deployers
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -30,33 +31,152 @@ import (
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 )
 
-func NewWhiskClient(proppath string, deploymentPath string, isInteractive bool) (*whisk.Client, *whisk.Config) {
+// the environment variables setting the API host, auth key and namespace
+const (
+	ApiHostEnv   = "WSKDEPLOY_APIHOST"
+	AuthEnv      = "WSKDEPLOY_AUTH"
+	NamespaceEnv = "WSKDEPLOY_NAMESPACE"
+)
+
+// ClientSettings are the API host, auth key and namespace the whisk client
+// uses. The API host is either a host name, or the full BaseUrl of the API
+// as deployment files give it.
+type ClientSettings struct {
+	ApiHost   string
+	BaseUrl   string
+	Auth      string
+	Namespace string
+}
+
+// ResolveClientSettings returns the client settings, each taken from the
+// first of these sources that sets it:
+//
+//   1. the --apihost, --auth and --namespace flags
+//   2. the deployment file, or the target deployed to: baseUrl or apihost,
+//      credential and namespace
+//   3. the WSKDEPLOY_APIHOST, WSKDEPLOY_AUTH and WSKDEPLOY_NAMESPACE
+//      environment variables
+//   4. the property file: APIHOST, AUTH and NAMESPACE
+//
+// The property file is given as utils.LoadConfiguration reads it, and the
+// deployment settings as read from the deployment file or target.
+func ResolveClientSettings(configs []string, deployment ClientSettings) ClientSettings {
+	settings := ClientSettings{Namespace: configs[0], ApiHost: configs[1], Auth: configs[2]}
+	settings.override(ClientSettings{
+		ApiHost:   os.Getenv(ApiHostEnv),
+		Auth:      os.Getenv(AuthEnv),
+		Namespace: os.Getenv(NamespaceEnv),
+	})
+	settings.override(deployment)
+	settings.override(ClientSettings{
+		ApiHost:   utils.Flags.ApiHost,
+		Auth:      utils.Flags.Auth,
+		Namespace: utils.Flags.Namespace,
+	})
+	return settings
+}
+
+// deploymentSettings reads the client settings of a deployment file.
+func deploymentSettings(deploymentPath string) (ClientSettings, error) {
+	if !parsers.DeploymentExists(deploymentPath) {
+		return ClientSettings{}, nil
+	}
+	deployment, err := parsers.NewYAMLParser().ParseDeployment(deploymentPath)
+	if err != nil {
+		return ClientSettings{}, err
+	}
+	app := deployment.Application
+	settings, err := ClientSettings{
+		BaseUrl:   app.BaseUrl,
+		Auth:      app.Credential,
+		Namespace: app.Namespace,
+	}.resolve()
+	return settings, utils.FileErr(deploymentPath, err)
+}
+
+// resolve the $VARS, Vault references and encrypted values of the settings
+func (settings ClientSettings) resolve() (ClientSettings, error) {
+	var errs utils.Errors
+	resolve := func(name string, value *string) {
+		resolved, err := resolveSetting(*value)
+		if err != nil {
+			errs.Add(utils.EntityErr("setting", name, err))
+			return
+		}
+		*value = resolved
+	}
+	resolve("apihost", &settings.ApiHost)
+	resolve("baseUrl", &settings.BaseUrl)
+	resolve("credential", &settings.Auth)
+	resolve("namespace", &settings.Namespace)
+	return settings, errs.Err()
+}
+
+func resolveSetting(value string) (string, error) {
+	resolved, err := utils.ResolveEnvVar(value)
+	if err != nil {
+		return "", err
+	}
+	setting, ok := resolved.(string)
+	if !ok {
+		return "", errors.New("the value of " + value + " is not a string")
+	}
+	return setting, nil
+}
+
+// the settings of layer replace those it sets
+func (settings *ClientSettings) override(layer ClientSettings) {
+	if layer.ApiHost != "" || layer.BaseUrl != "" {
+		settings.ApiHost, settings.BaseUrl = layer.ApiHost, layer.BaseUrl
+	}
+	if layer.Auth != "" {
+		settings.Auth = layer.Auth
+	}
+	if layer.Namespace != "" {
+		settings.Namespace = layer.Namespace
+	}
+}
+
+// URL returns the base URL of the API, nil when no API host is set.
+func (settings ClientSettings) URL() (*url.URL, error) {
+	if settings.BaseUrl != "" {
+		return url.Parse(settings.BaseUrl)
+	}
+	if settings.ApiHost != "" {
+		return utils.GetURLBase(settings.ApiHost)
+	}
+	return nil, nil
+}
+
+func NewWhiskClient(proppath string, deploymentPath string, isInteractive bool) (*whisk.Client, *whisk.Config, error) {
 	var clientConfig *whisk.Config
 
 	configs, err := utils.LoadConfiguration(proppath)
-	utils.Check(err)
-
-	credential := configs[2]
-	if len(utils.Flags.Auth) > 0 {
-		credential = utils.Flags.Auth
+	if err != nil {
+		return nil, nil, err
+	}
+	deployment, err := deploymentSettings(deploymentPath)
+	if err != nil {
+		return nil, nil, err
 	}
-	namespace := configs[0]
+	settings := ResolveClientSettings(configs, deployment)
 
+	credential := settings.Auth
+	namespace := settings.Namespace
 	if namespace == "" {
 		namespace = "_"
 	}
-	//we need to get Apihost from property file which currently not defined in sample deployment file.
 
-	u := configs[1]
-	if len(utils.Flags.ApiHost) > 0 {
-		u = utils.Flags.ApiHost
+	baseURL, err := settings.URL()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var baseURL *url.URL
-
-	if u == "" && isInteractive == true {
-		host, err := promptForValue("\nPlease provide the hostname for OpenWhisk [openwhisk.ng.bluemix.net]: ")
-		utils.Check(err)
+	if baseURL == nil && (isInteractive || utils.Answered("apihost")) {
+		host, err := promptForValue("apihost", "\nPlease provide the hostname for OpenWhisk [openwhisk.ng.bluemix.net]: ")
+		if err != nil {
+			return nil, nil, err
+		}
 		if host == "" {
 			host = "openwhisk.ng.bluemix.net"
 		}
@@ -64,51 +184,59 @@ func NewWhiskClient(proppath string, deploymentPath string, isInteractive bool)
 		fmt.Println("Host set to " + host)
 
 		baseURL, err = utils.GetURLBase(host)
-		utils.Check(err)
-
-	} else if u == "" {
-		// handle some error
-	} else {
-		baseURL, err = utils.GetURLBase(u)
-		utils.Check(err)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	if utils.FileExists(deploymentPath) {
-		mm := parsers.NewYAMLParser()
-		deployment := mm.ParseDeployment(deploymentPath)
-		// We get the first package from the sample deployment file.
-		credentialDep := deployment.Application.Credential
-		namespaceDep := deployment.Application.Namespace
-		baseUrlDep := deployment.Application.BaseUrl
+	if baseURL == nil && utils.Flags.NonInteractive {
+		return nil, nil, utils.WithExitCode(utils.ExitPrompt, errors.New("Missing the API host, set APIHOST in "+proppath+", set "+ApiHostEnv+" or pass --apihost"))
+	}
 
-		if credentialDep != "" {
-			credential = credentialDep
+	// the keychain is only consulted when no other source set the auth key,
+	// the account is kept to store the key asked for when none is stored
+	keychainAccount := ""
+	if utils.Flags.UseKeychain && credential == "" {
+		account := "default"
+		if baseURL != nil {
+			account = baseURL.Host
 		}
-
-		if namespaceDep != "" {
-			namespace = namespaceDep
+		credential, err = utils.KeychainGet(account)
+		if err != nil {
+			return nil, nil, err
 		}
-
-		if baseUrlDep != "" {
-			u, err := url.Parse(baseUrlDep)
-			utils.Check(err)
-
-			baseURL = u
+		if credential == "" {
+			keychainAccount = account
 		}
-
 	}
 
-	if credential == "" && isInteractive == true {
-		cred, err := promptForValue("\nPlease provide an authentication token: ")
-		utils.Check(err)
+	if credential == "" && utils.Flags.IAMApiKey == "" && (isInteractive || utils.Answered("auth")) {
+		cred, err := promptForValue("auth", "\nPlease provide an authentication token: ")
+		if err != nil {
+			return nil, nil, err
+		}
 		credential = cred
 
 		fmt.Println("Authentication token set.")
 	}
 
-	if namespace == "" && isInteractive == true {
-		ns, err := promptForValue("\nPlease provide a namespace [default]: ")
-		utils.Check(err)
+	if credential == "" && utils.Flags.IAMApiKey == "" && utils.Flags.NonInteractive {
+		return nil, nil, utils.WithExitCode(utils.ExitPrompt, errors.New("Missing the authentication token, set AUTH in "+proppath+", set "+AuthEnv+" or pass --auth"))
+	}
+
+	if keychainAccount != "" && credential != "" {
+		err = utils.KeychainSet(keychainAccount, credential)
+		if err != nil {
+			return nil, nil, err
+		}
+		fmt.Println("Authentication token stored in the keychain for " + keychainAccount + ".")
+	}
+
+	if namespace == "" && (isInteractive || utils.Answered("namespace")) {
+		ns, err := promptForValue("namespace", "\nPlease provide a namespace [default]: ")
+		if err != nil {
+			return nil, nil, err
+		}
 
 		if ns == "" {
 			ns = "_"
@@ -123,24 +251,171 @@ func NewWhiskClient(proppath string, deploymentPath string, isInteractive bool)
 		Namespace: namespace,  //Namespace
 		BaseURL:   baseURL,
 		Version:   "v1",
-		Insecure:  true, // true if you want to ignore certificate signing
+		Insecure:  utils.Flags.CACert == "", // true if you want to ignore certificate signing
 
 	}
 
+	cert, key := clientCertificate(configs, "", "")
+	client, err := newClient(clientConfig, cert, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, clientConfig, nil
+
+}
+
+// NewTargetClient returns a client for one of the targets listed in the
+// deployment file. The target takes the place of the deployment file in the
+// precedence of ResolveClientSettings.
+func NewTargetClient(proppath string, target parsers.Target) (*whisk.Client, *whisk.Config, error) {
+	configs, err := utils.LoadConfiguration(proppath)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetSettings, err := ClientSettings{
+		ApiHost:   target.ApiHost,
+		Auth:      target.Credential,
+		Namespace: target.Namespace,
+	}.resolve()
+	if err != nil {
+		return nil, nil, utils.EntityErr("target", target.Name, err)
+	}
+	settings := ResolveClientSettings(configs, targetSettings)
+
+	namespace := settings.Namespace
+	if namespace == "" {
+		namespace = "_"
+	}
+	if settings.ApiHost == "" && settings.BaseUrl == "" {
+		return nil, nil, errors.New("no API host is set for target " + target.Name)
+	}
+	if settings.Auth == "" && utils.Flags.IAMApiKey == "" {
+		return nil, nil, errors.New("no credential is set for target " + target.Name)
+	}
+
+	baseURL, err := settings.URL()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientConfig := &whisk.Config{
+		AuthToken: settings.Auth,
+		Namespace: namespace,
+		Host:      settings.ApiHost,
+		BaseURL:   baseURL,
+		Version:   "v1",
+		Insecure:  utils.Flags.CACert == "",
+	}
+	targetCert, err := resolveSetting(target.Cert)
+	if err != nil {
+		return nil, nil, utils.EntityErr("target", target.Name, utils.EntityErr("setting", "cert", err))
+	}
+	targetKey, err := resolveSetting(target.Key)
+	if err != nil {
+		return nil, nil, utils.EntityErr("target", target.Name, utils.EntityErr("setting", "key", err))
+	}
+	cert, key := clientCertificate(configs, targetCert, targetKey)
+	client, err := newClient(clientConfig, cert, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, clientConfig, nil
+}
+
+// the client certificate of a target, else of the command line, else of the
+// property file
+func clientCertificate(configs []string, cert string, key string) (string, string) {
+	if cert == "" {
+		cert, key = utils.Flags.Cert, utils.Flags.Key
+	}
+	if cert == "" && len(configs) > 4 {
+		cert, key = configs[3], configs[4]
+	}
+	return cert, key
+}
+
+// the transports of the clients by configuration, so other calls to the API
+// present the same client certificate
+var clientTransports = make(map[*whisk.Config]http.RoundTripper)
+
+func newClient(clientConfig *whisk.Config, cert string, key string) (*whisk.Client, error) {
 	// Setup network client
-	client, err := whisk.NewClient(http.DefaultClient, clientConfig)
-	utils.Check(err)
-	return client, clientConfig
+	httpClient := &http.Client{}
+	client, err := whisk.NewClient(httpClient, clientConfig)
+	if err != nil {
+		return nil, err
+	}
 
+	// the transport the whisk client configured ignores proxies, replace it and
+	// wrap it so transient errors are retried, at the rate limit, within the
+	// request timeout
+	transport, err := utils.NewTransport(transportOptions(clientConfig, cert, key))
+	if err != nil {
+		return nil, err
+	}
+	clientTransports[clientConfig] = transport
+	var authorized http.RoundTripper = transport
+	if utils.Flags.IAMApiKey != "" {
+		// IBM Cloud Functions takes IAM tokens, which are refreshed as they expire
+		authorized = utils.NewIAMTransport(transport, utils.Flags.IAMApiKey, utils.Flags.IAMEndpoint)
+	}
+	if authorized, err = traced(authorized); err != nil {
+		return nil, err
+	}
+	retried, err := audited(utils.NewRetryTransport(utils.NewRateLimitTransport(authorized, utils.Flags.RateLimit, utils.Flags.RateBurst), utils.Flags.Retries), clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Transport = &utils.TimeoutTransport{
+		Transport: retried,
+		Timeout:   utils.Flags.RequestTimeout,
+	}
+	return client, nil
 }
 
-func promptForValue(msg string) (string, error) {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(msg)
+// the transport tracing every attempt of a request to the --trace file, the
+// transport itself without --trace
+func traced(transport http.RoundTripper) (http.RoundTripper, error) {
+	if utils.Flags.Trace == "" {
+		return transport, nil
+	}
+	writer, err := utils.TraceWriter(utils.Flags.Trace)
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewTraceTransport(transport, writer), nil
+}
 
-	text, _ := reader.ReadString('\n')
-	text = strings.TrimSpace(text)
+// the transport recording the changes its requests make to the --audit-log
+// file, once however often they are retried; the transport itself without
+// --audit-log
+func audited(transport http.RoundTripper, clientConfig *whisk.Config) (http.RoundTripper, error) {
+	if utils.Flags.AuditLog == "" {
+		return transport, nil
+	}
+	writer, err := utils.AuditWriter(utils.Flags.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+	subject := strings.Split(clientConfig.AuthToken, ":")[0]
+	if subject == "" && utils.Flags.IAMApiKey != "" {
+		subject = "iam"
+	}
+	return utils.NewAuditTransport(transport, writer, subject), nil
+}
 
-	return text, nil
+// the options of the transport of a client, as the command line sets them
+func transportOptions(clientConfig *whisk.Config, cert string, key string) utils.TransportOptions {
+	return utils.TransportOptions{
+		CACert:         utils.Flags.CACert,
+		Cert:           cert,
+		Key:            key,
+		Insecure:       clientConfig.Insecure,
+		ConnectTimeout: utils.Flags.ConnectTimeout,
+		ReadTimeout:    utils.Flags.ReadTimeout,
+	}
+}
 
+func promptForValue(key string, msg string) (string, error) {
+	return utils.Prompt(bufio.NewReader(os.Stdin), key, msg)
 }
@@ -0,0 +1,196 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a field of a cron expression and the values it can take
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string // names of the values from min, e.g. jan for 1
+}
+
+var (
+	cronSecond  = cronField{"second", 0, 59, nil}
+	cronMinute  = cronField{"minute", 0, 59, nil}
+	cronHour    = cronField{"hour", 0, 23, nil}
+	cronDay     = cronField{"day of month", 1, 31, nil}
+	cronMonth   = cronField{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	cronWeekday = cronField{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat", "sun"}}
+)
+
+// the date formats the alarms feed is commonly given
+var alarmDateLayouts = []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// IsAlarmFeed reports whether a trigger source is a feed of the alarms
+// package, /whisk.system/alarms/alarm, once or interval.
+func IsAlarmFeed(source string) bool {
+	parts := strings.Split(strings.Trim(source, "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] != "alarms" {
+		return false
+	}
+	switch parts[len(parts)-1] {
+	case "alarm", "once", "interval":
+		return true
+	}
+	return false
+}
+
+// CheckAlarmParameters returns a message for each parameter of an alarms
+// feed the feed would reject or never fire with.
+func CheckAlarmParameters(source string, params map[string]interface{}) []string {
+	problems := make([]string, 0)
+	feed := source[strings.LastIndex(source, "/")+1:]
+
+	switch feed {
+	case "alarm":
+		if cron, ok := params["cron"]; !ok {
+			problems = append(problems, "cron is required by the alarm feed")
+		} else if err := CheckCron(fmt.Sprint(cron)); err != nil {
+			problems = append(problems, "cron: "+err.Error())
+		}
+	case "once":
+		if date, ok := params["date"]; !ok {
+			problems = append(problems, "date is required by the once feed")
+		} else if _, err := parseAlarmDate(date); err != nil {
+			problems = append(problems, "date: "+err.Error())
+		}
+	case "interval":
+		minutes, err := strconv.Atoi(fmt.Sprint(params["minutes"]))
+		if err != nil || minutes < 1 {
+			problems = append(problems, "minutes of the interval feed must be a positive integer")
+		}
+	}
+
+	var start, stop time.Time
+	if value, ok := params["startDate"]; ok {
+		date, err := parseAlarmDate(value)
+		if err != nil {
+			problems = append(problems, "startDate: "+err.Error())
+		}
+		start = date
+	}
+	if value, ok := params["stopDate"]; ok {
+		date, err := parseAlarmDate(value)
+		if err != nil {
+			problems = append(problems, "stopDate: "+err.Error())
+		}
+		stop = date
+	}
+	if !start.IsZero() && !stop.IsZero() && !stop.After(start) {
+		problems = append(problems, "stopDate must be after startDate")
+	}
+	return problems
+}
+
+// CheckCron checks a crontab expression of five fields, or six with the
+// seconds first.
+func CheckCron(expression string) error {
+	fields := strings.Fields(expression)
+	var layout []cronField
+	switch len(fields) {
+	case 5:
+		layout = []cronField{cronMinute, cronHour, cronDay, cronMonth, cronWeekday}
+	case 6:
+		layout = []cronField{cronSecond, cronMinute, cronHour, cronDay, cronMonth, cronWeekday}
+	default:
+		return errors.New("expression " + strconv.Quote(expression) + " must have 5 fields, or 6 with seconds, got " + strconv.Itoa(len(fields)))
+	}
+
+	for i, field := range fields {
+		if err := layout[i].check(field); err != nil {
+			return errors.New("expression " + strconv.Quote(expression) + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// a field is a comma separated list of *, values and ranges, each with an
+// optional /step
+func (field cronField) check(text string) error {
+	for _, item := range strings.Split(text, ",") {
+		valueRange := item
+		if slash := strings.Index(item, "/"); slash >= 0 {
+			valueRange = item[:slash]
+			if step, err := strconv.Atoi(item[slash+1:]); err != nil || step < 1 {
+				return errors.New("invalid step " + item[slash+1:] + " in " + field.name + " " + text)
+			}
+		}
+		if valueRange == "*" {
+			continue
+		}
+
+		bounds := strings.SplitN(valueRange, "-", 2)
+		low, err := field.value(bounds[0])
+		if err != nil {
+			return err
+		}
+		if len(bounds) == 2 {
+			high, err := field.value(bounds[1])
+			if err != nil {
+				return err
+			}
+			if high < low {
+				return errors.New("range " + valueRange + " of " + field.name + " ends before it starts")
+			}
+		}
+	}
+	return nil
+}
+
+func (field cronField) value(text string) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(text, name) {
+			return field.min + i, nil
+		}
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < field.min || value > field.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, got %s", field.name, field.min, field.max, text)
+	}
+	return value, nil
+}
+
+// dates are ISO 8601 strings or milliseconds since the epoch
+func parseAlarmDate(value interface{}) (time.Time, error) {
+	switch date := value.(type) {
+	case int:
+		return time.Unix(0, int64(date)*int64(time.Millisecond)), nil
+	case float64:
+		return time.Unix(0, int64(date)*int64(time.Millisecond)), nil
+	}
+
+	text := fmt.Sprint(value)
+	if millis, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return time.Unix(0, millis*int64(time.Millisecond)), nil
+	}
+	for _, layout := range alarmDateLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, errors.New(strconv.Quote(text) + " is not an ISO 8601 date such as 2017-05-12T15:00:00Z")
+}
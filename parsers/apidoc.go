@@ -0,0 +1,203 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The apis block is either a mapping of routes or the path of a document.
+func (apis *Apis) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var document string
+	if err := unmarshal(&document); err == nil {
+		apis.Document = document
+		return nil
+	}
+	return unmarshal(&apis.Routes)
+}
+
+func (apis Apis) MarshalYAML() (interface{}, error) {
+	if apis.Document != "" {
+		return apis.Document, nil
+	}
+	return apis.Routes, nil
+}
+
+func (apis Apis) Empty() bool {
+	return apis.Document == "" && len(apis.Routes) == 0
+}
+
+// the fields of a swagger 2.0 or OpenAPI 3.0 document used to build routes
+type apiDocument struct {
+	Swagger  string                                `json:"swagger"`
+	OpenApi  string                                `json:"openapi"`
+	BasePath string                                `json:"basePath"`
+	Servers  []apiServer                           `json:"servers"`
+	Paths    map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type apiServer struct {
+	Url string `json:"url"`
+}
+
+type apiOperation struct {
+	XOpenWhisk *apiOpenWhisk `json:"x-openwhisk"`
+}
+
+// the x-openwhisk extension naming the action behind an operation
+type apiOpenWhisk struct {
+	Action    string `json:"action"`
+	Namespace string `json:"namespace"`
+	Package   string `json:"package"`
+}
+
+// RouteMap returns the routes of the apis block, reading them from the
+// referenced document when there is one. Document paths are relative to
+// the manifest.
+func (pkg *Package) RouteMap(manifestPath string) (map[string]map[string]map[string]string, error) {
+	if pkg.Apis.Document == "" {
+		return pkg.Apis.Routes, nil
+	}
+	docPath := pkg.Apis.Document
+	if !filepath.IsAbs(docPath) {
+		docPath = filepath.Join(filepath.Dir(manifestPath), docPath)
+	}
+	return ReadApiDocument(docPath, pkg.Packagename)
+}
+
+// ReadApiDocument maps the operations of a swagger or OpenAPI document, in
+// JSON or YAML, to the actions of package pkgname named by their
+// x-openwhisk extensions.
+func ReadApiDocument(docPath string, pkgname string) (map[string]map[string]map[string]string, error) {
+	content, err := ioutil.ReadFile(docPath)
+	if err != nil {
+		return nil, errors.New("Unable to read API document " + docPath + ": " + err.Error())
+	}
+
+	switch strings.ToLower(path.Ext(docPath)) {
+	case ".yaml", ".yml":
+		var node interface{}
+		if err := yaml.Unmarshal(content, &node); err != nil {
+			return nil, errors.New("Unable to parse API document " + docPath + ": " + err.Error())
+		}
+		if content, err = json.Marshal(jsonValue(node)); err != nil {
+			return nil, errors.New("Unable to parse API document " + docPath + ": " + err.Error())
+		}
+	}
+
+	doc := apiDocument{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, errors.New("Unable to parse API document " + docPath + ": " + err.Error())
+	}
+	if doc.Swagger == "" && doc.OpenApi == "" {
+		return nil, errors.New("API document " + docPath + " is neither a swagger nor an OpenAPI document")
+	}
+
+	basePath := doc.BasePath
+	if doc.OpenApi != "" && len(doc.Servers) > 0 {
+		serverUrl, err := url.Parse(doc.Servers[0].Url)
+		if err != nil {
+			return nil, errors.New("Invalid server url " + doc.Servers[0].Url + " in API document " + docPath)
+		}
+		basePath = serverUrl.Path
+	}
+	if doc.Swagger != "" && strings.Trim(basePath, "/") == "" {
+		return nil, errors.New("API document " + docPath + " does not define a base path")
+	}
+
+	routes := make(map[string]map[string]map[string]string)
+	for itemPath, item := range doc.Paths {
+		// without a base path the first segment of each path is used
+		routeBase, relPath := basePath, itemPath
+		if strings.Trim(basePath, "/") == "" {
+			parts := strings.SplitN(strings.TrimPrefix(itemPath, "/"), "/", 2)
+			if len(parts) != 2 {
+				return nil, errors.New("Path " + itemPath + " in API document " + docPath + " has no base path")
+			}
+			routeBase, relPath = "/"+parts[0], "/"+parts[1]
+		}
+		for verb, raw := range item {
+			// path items also hold parameters, summaries and extensions
+			if !validMethods[strings.ToLower(verb)] {
+				continue
+			}
+			operation := apiOperation{}
+			if err := json.Unmarshal(raw, &operation); err != nil {
+				return nil, errors.New("Invalid operation " + verb + " " + relPath + " in API document " + docPath + ": " + err.Error())
+			}
+			action, err := operationAction(operation, pkgname)
+			if err != nil {
+				return nil, errors.New("Operation " + strings.ToUpper(verb) + " " + relPath + " in API document " + docPath + ": " + err.Error())
+			}
+			if routes[routeBase] == nil {
+				routes[routeBase] = make(map[string]map[string]string)
+			}
+			if routes[routeBase][relPath] == nil {
+				routes[routeBase][relPath] = make(map[string]string)
+			}
+			routes[routeBase][relPath][verb] = action
+		}
+	}
+
+	return routes, nil
+}
+
+// resolve the name of the package action an operation is bound to
+func operationAction(operation apiOperation, pkgname string) (string, error) {
+	ext := operation.XOpenWhisk
+	if ext == nil || ext.Action == "" {
+		return "", errors.New("no x-openwhisk action is set")
+	}
+	action := ext.Action
+	if ext.Package == "" && strings.Contains(action, "/") {
+		parts := strings.SplitN(action, "/", 2)
+		ext.Package, action = parts[0], parts[1]
+	}
+	if ext.Package != "" && ext.Package != pkgname {
+		return "", errors.New("action " + ext.Package + "/" + action + " is not in package " + pkgname)
+	}
+	return action, nil
+}
+
+// convert decoded YAML to values encoding/json can marshal
+func jsonValue(node interface{}) interface{} {
+	switch value := node.(type) {
+	case map[interface{}]interface{}:
+		mapping := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			mapping[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return mapping
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = jsonValue(item)
+		}
+		return list
+	}
+	return node
+}
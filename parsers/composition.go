@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// the deployable form of a composition written by the composer toolchain,
+// e.g. compose app.js --encode > app.json
+type composerOutput struct {
+	Actions []composerAction `json:"actions"`
+}
+
+type composerAction struct {
+	Name   string       `json:"name"`
+	Action whisk.Action `json:"action"`
+}
+
+// IsComposition reports whether an action location is a composition.
+func IsComposition(location string) bool {
+	return strings.ToLower(path.Ext(location)) == ".json"
+}
+
+// ReadComposition returns the conductor action of a composition, its last
+// action, and the actions bundled with it. Bundled actions must belong to
+// package pkgname since the composition refers to them by name.
+func ReadComposition(file string, pkgname string) (*whisk.Action, []*whisk.Action, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, errors.New("Unable to read composition " + file + ": " + err.Error())
+	}
+
+	output := composerOutput{}
+	if err := json.Unmarshal(content, &output); err != nil {
+		return nil, nil, errors.New("Unable to parse composition " + file + ": " + err.Error())
+	}
+	if len(output.Actions) == 0 {
+		return nil, nil, errors.New("Composition " + file + " has no actions, encode it with the composer first")
+	}
+
+	last := len(output.Actions) - 1
+	conductor := output.Actions[last].Action
+	if conductor.Exec == nil {
+		return nil, nil, errors.New("Conductor action " + output.Actions[last].Name + " of composition " + file + " has no code")
+	}
+
+	bundled := make([]*whisk.Action, 0, last)
+	for _, entry := range output.Actions[:last] {
+		name, err := bundledActionName(entry.Name, pkgname)
+		if err != nil {
+			return nil, nil, errors.New("Composition " + file + ": " + err.Error())
+		}
+		if entry.Action.Exec == nil {
+			return nil, nil, errors.New("Composition " + file + ": bundled action " + entry.Name + " has no code")
+		}
+		action := entry.Action
+		action.Name = name
+		action.Namespace = ""
+		pub := false
+		action.Publish = &pub
+		bundled = append(bundled, &action)
+	}
+	return &conductor, bundled, nil
+}
+
+// "/namespace/package/action" or "package/action" to "action"
+func bundledActionName(name string, pkgname string) (string, error) {
+	qualified := name
+	if strings.HasPrefix(qualified, "/") {
+		parts := strings.SplitN(strings.TrimPrefix(qualified, "/"), "/", 2)
+		if len(parts) != 2 {
+			return "", errors.New("bundled action " + name + " must be in package " + pkgname)
+		}
+		qualified = parts[1]
+	}
+	if !strings.HasPrefix(qualified, pkgname+"/") || strings.Contains(strings.TrimPrefix(qualified, pkgname+"/"), "/") {
+		return "", errors.New("bundled action " + name + " must be in package " + pkgname)
+	}
+	return strings.TrimPrefix(qualified, pkgname+"/"), nil
+}
+
+// MergeKeyValues returns the key values of base overridden by the key values
+// of overrides.
+func MergeKeyValues(base whisk.KeyValueArr, overrides whisk.KeyValueArr) whisk.KeyValueArr {
+	merged := make(whisk.KeyValueArr, 0, len(base)+len(overrides))
+	for _, keyValue := range base {
+		overridden := false
+		for _, override := range overrides {
+			if override.Key == keyValue.Key {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, keyValue)
+		}
+	}
+	merged = append(merged, overrides...)
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
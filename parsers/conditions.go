@@ -0,0 +1,137 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// Enabled reports whether the entity is deployed given its ifdef: and when:
+// conditions. Entities without conditions are always deployed.
+func (condition Condition) Enabled() (bool, error) {
+	if condition.Ifdef != "" && !utils.IsDefined(condition.Ifdef) {
+		return false, nil
+	}
+	if condition.When == "" {
+		return true, nil
+	}
+	return utils.EvalCondition(condition.When)
+}
+
+// drop the actions, sequences, triggers and rules of the package whose
+// conditions do not hold, along with the rules and API routes of the
+// actions and triggers dropped
+func (pkg *Package) applyConditions() error {
+	droppedActions := make(map[string]bool)
+	droppedTriggers := make(map[string]bool)
+
+	for name, action := range pkg.Actions {
+		enabled, err := action.Condition.Enabled()
+		if err != nil {
+			return errors.New("Action " + name + ": " + err.Error())
+		}
+		if !enabled {
+			droppedActions[name] = true
+			delete(pkg.Actions, name)
+		}
+	}
+	for name, sequence := range pkg.Sequences {
+		enabled, err := sequence.Condition.Enabled()
+		if err != nil {
+			return errors.New("Sequence " + name + ": " + err.Error())
+		}
+		if !enabled {
+			droppedActions[name] = true
+			delete(pkg.Sequences, name)
+		}
+	}
+	for name, trigger := range pkg.Triggers {
+		enabled, err := trigger.Condition.Enabled()
+		if err != nil {
+			return errors.New("Trigger " + name + ": " + err.Error())
+		}
+		if !enabled {
+			droppedTriggers[name] = true
+			delete(pkg.Triggers, name)
+		}
+	}
+
+	// the actions of rules and routes may be qualified by the package name
+	dropped := func(action string) bool {
+		return droppedActions[strings.TrimPrefix(action, pkg.Packagename+"/")]
+	}
+	for name, rule := range pkg.Rules {
+		enabled, err := rule.Condition.Enabled()
+		if err != nil {
+			return errors.New("Rule " + name + ": " + err.Error())
+		}
+		if !enabled || droppedTriggers[rule.Trigger] || dropped(rule.Action) {
+			delete(pkg.Rules, name)
+		}
+	}
+	for basePath, relPaths := range pkg.Apis.Routes {
+		for relPath, verbs := range relPaths {
+			for verb, action := range verbs {
+				if dropped(action) {
+					delete(verbs, verb)
+				}
+			}
+			if len(verbs) == 0 {
+				delete(relPaths, relPath)
+			}
+		}
+		if len(relPaths) == 0 {
+			delete(pkg.Apis.Routes, basePath)
+		}
+	}
+	return nil
+}
+
+// drop the packages of the deployment file whose conditions do not hold,
+// and the bindings of the entities whose conditions do not hold
+func (app *Application) applyConditions() error {
+	enabled, err := app.Package.Condition.Enabled()
+	if err != nil {
+		return errors.New("Package " + app.Package.Packagename + ": " + err.Error())
+	}
+	if !enabled {
+		app.Package = Package{}
+	}
+	if err := app.Package.applyConditions(); err != nil {
+		return err
+	}
+
+	for name, pkg := range app.Packages {
+		enabled, err := pkg.Condition.Enabled()
+		if err != nil {
+			return errors.New("Package " + name + ": " + err.Error())
+		}
+		if !enabled {
+			delete(app.Packages, name)
+			continue
+		}
+		if err := pkg.applyConditions(); err != nil {
+			return err
+		}
+		app.Packages[name] = pkg
+	}
+	return nil
+}
@@ -18,7 +18,10 @@
 package parsers
 
 import (
+	"errors"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"gopkg.in/yaml.v2"
@@ -42,14 +45,101 @@ func (dm *YAMLParser) MarshalDeployment(deployment *DeploymentYAML) (output []by
 	return data, nil
 }
 
-func (dm *YAMLParser) ParseDeployment(dply string) *DeploymentYAML {
+// DeploymentPathSeparator separates the deployment files layered in one
+// deployment path, e.g. deployment.yaml,deployment.prod.yaml
+const DeploymentPathSeparator = ","
+
+// DeploymentFiles returns the files of a deployment path in the order they are
+// layered.
+func DeploymentFiles(dply string) []string {
+	files := make([]string, 0)
+	for _, file := range strings.Split(dply, DeploymentPathSeparator) {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// DeploymentExists reports whether a deployment path names a file that
+// exists. A layer of the path that is missing is reported when it is parsed.
+func DeploymentExists(dply string) bool {
+	for _, file := range DeploymentFiles(dply) {
+		if utils.FileExists(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDeployment parses the deployment files of a path, merged in order: a
+// later file overrides the values of earlier ones, maps are merged key by key
+// and lists are replaced.
+func (dm *YAMLParser) ParseDeployment(dply string) (*DeploymentYAML, error) {
 	dplyyaml := DeploymentYAML{}
-	content, err := new(utils.ContentReader).LocalReader.ReadLocal(dply)
-	utils.Check(err)
-	err = dm.UnmarshalDeployment(content, &dplyyaml)
-	utils.Check(err)
+	content, err := readDeployment(dply)
+	if err != nil {
+		return nil, invalidDescriptor(err)
+	}
+	if err := dm.UnmarshalDeployment(content, &dplyyaml); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(dply, err))
+	}
+	if err := dplyyaml.Application.ApplyEnvironment(utils.Flags.Environment); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(dply, err))
+	}
+	if err := dplyyaml.Application.applyConditions(); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(dply, err))
+	}
 	dplyyaml.Filepath = dply
-	return &dplyyaml
+	dplyyaml.Application.Package.locateValueFiles(filepath.Dir(dply))
+	for name, pkg := range dplyyaml.Application.Packages {
+		pkg.locateValueFiles(filepath.Dir(dply))
+		dplyyaml.Application.Packages[name] = pkg
+	}
+	return &dplyyaml, nil
+}
+
+func readDeployment(dply string) ([]byte, error) {
+	files := DeploymentFiles(dply)
+	if len(files) == 1 {
+		content, err := readDescriptor(files[0])
+		if err != nil {
+			return nil, err
+		}
+		return content, CheckStrict(files[0], content, DeploymentYAML{})
+	}
+
+	var merged interface{}
+	for _, file := range files {
+		content, err := readDescriptor(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckStrict(file, content, DeploymentYAML{}); err != nil {
+			return nil, err
+		}
+		var layer interface{}
+		if err := yaml.Unmarshal(content, &layer); err != nil {
+			return nil, errors.New("Unable to parse the deployment file " + file + ": " + err.Error())
+		}
+		merged = mergeLayer(merged, layer)
+	}
+	return yaml.Marshal(merged)
+}
+
+func mergeLayer(base interface{}, layer interface{}) interface{} {
+	if layer == nil {
+		return base
+	}
+	baseMap, ok := base.(map[interface{}]interface{})
+	layerMap, isMap := layer.(map[interface{}]interface{})
+	if !ok || !isMap {
+		return layer
+	}
+	for key, value := range layerMap {
+		baseMap[key] = mergeLayer(baseMap[key], value)
+	}
+	return baseMap
 }
 
 //********************Application functions*************************//
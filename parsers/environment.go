@@ -0,0 +1,115 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ApplyEnvironment merges the named environment into the application: its
+// namespace, credential and base URL replace the application's, and its
+// inputs are layered over the inputs of the packages, actions and triggers
+// they name. An empty name leaves the application as it is.
+func (app *Application) ApplyEnvironment(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	env, ok := app.Environments[name]
+	if !ok {
+		names := make([]string, 0, len(app.Environments))
+		for envName := range app.Environments {
+			names = append(names, envName)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return errors.New("Environment " + name + " is not defined, the deployment file has no environments")
+		}
+		return errors.New("Environment " + name + " is not defined, expected one of: " + strings.Join(names, ", "))
+	}
+
+	if env.Namespace != "" {
+		app.Namespace = env.Namespace
+	}
+	if env.Credential != "" {
+		app.Credential = env.Credential
+	}
+	if env.BaseUrl != "" {
+		app.BaseUrl = env.BaseUrl
+	}
+
+	if app.Packages == nil {
+		overridePackage(&app.Package, env.Inputs, env.Packages[app.Package.Packagename])
+		return nil
+	}
+
+	for pkgName := range env.Packages {
+		if _, exists := app.Packages[pkgName]; !exists {
+			app.Packages[pkgName] = Package{Packagename: pkgName}
+		}
+	}
+	for pkgName, pkg := range app.Packages {
+		overridePackage(&pkg, env.Inputs, env.Packages[pkgName])
+		app.Packages[pkgName] = pkg
+	}
+	return nil
+}
+
+func overridePackage(pkg *Package, inputs map[string]Parameter, override Package) {
+	pkg.Inputs = overrideInputs(overrideInputs(pkg.Inputs, inputs), override.Inputs)
+	if override.Namespace != "" {
+		pkg.Namespace = override.Namespace
+	}
+	if override.Credential != "" {
+		pkg.Credential = override.Credential
+	}
+
+	if len(override.Actions) > 0 && pkg.Actions == nil {
+		pkg.Actions = make(map[string]Action)
+	}
+	for name, action := range override.Actions {
+		existing := pkg.Actions[name]
+		existing.Inputs = overrideInputs(existing.Inputs, action.Inputs)
+		pkg.Actions[name] = existing
+	}
+
+	if len(override.Triggers) > 0 && pkg.Triggers == nil {
+		pkg.Triggers = make(map[string]Trigger)
+	}
+	for name, trigger := range override.Triggers {
+		existing := pkg.Triggers[name]
+		existing.Inputs = overrideInputs(existing.Inputs, trigger.Inputs)
+		pkg.Triggers[name] = existing
+	}
+}
+
+func overrideInputs(base map[string]Parameter, override map[string]Parameter) map[string]Parameter {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(map[string]Parameter, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
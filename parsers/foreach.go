@@ -0,0 +1,241 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// expandForeach replaces every action, sequence, trigger and rule declaring
+// foreach: by one copy per item of the list, with ${item} in its name and
+// values replaced by the item, or ${item.KEY} by a field of an item that is
+// a mapping, e.g. one alarm trigger per region:
+//
+//	tick-${item}:
+//	  foreach: [us-south, eu-de]
+//
+// The list may also be given as a comma separated string, e.g. ${REGIONS}.
+// It returns the section and name of the template of each copy, e.g.
+// triggers.tick-${item} for triggers.tick-eu-de.
+func (pkg *Package) expandForeach() (map[string]string, error) {
+	origins := make(map[string]string)
+	sections := []struct {
+		key     string
+		entity  string
+		entries reflect.Value
+	}{
+		{"actions", "Action", reflect.ValueOf(pkg.Actions)},
+		{"sequences", "Sequence", reflect.ValueOf(pkg.Sequences)},
+		{"triggers", "Trigger", reflect.ValueOf(pkg.Triggers)},
+		{"rules", "Rule", reflect.ValueOf(pkg.Rules)},
+	}
+	for _, section := range sections {
+		entries := section.entries
+		if entries.IsNil() {
+			continue
+		}
+
+		names := make([]string, 0, entries.Len())
+		for _, key := range entries.MapKeys() {
+			names = append(names, key.String())
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			template := entries.MapIndex(reflect.ValueOf(name))
+			foreach := template.FieldByName("Foreach").Interface()
+			if foreach == nil {
+				continue
+			}
+			items, err := foreachItems(foreach)
+			if err != nil {
+				return nil, errors.New(section.entity + " " + name + ": " + err.Error())
+			}
+
+			entries.SetMapIndex(reflect.ValueOf(name), reflect.Value{})
+			for _, item := range items {
+				expander := &foreachExpander{item: item}
+				copyName := expander.text(name)
+				entry := expander.copy(template)
+				entry.FieldByName("Foreach").Set(reflect.Zero(entry.FieldByName("Foreach").Type()))
+				if expander.err != nil {
+					return nil, errors.New(section.entity + " " + name + ": " + expander.err.Error())
+				}
+				if copyName == name {
+					return nil, errors.New(section.entity + " " + name + " repeats with foreach and must use ${item} in its name")
+				}
+				if entries.MapIndex(reflect.ValueOf(copyName)).IsValid() {
+					return nil, errors.New(section.entity + " " + copyName + " of the foreach of " + name + " is declared twice")
+				}
+				entries.SetMapIndex(reflect.ValueOf(copyName), entry)
+				origins[section.key+"."+copyName] = section.key + "." + name
+			}
+		}
+	}
+	return origins, nil
+}
+
+// the items of a foreach: list, or of a comma separated string
+func foreachItems(foreach interface{}) ([]interface{}, error) {
+	switch value := foreach.(type) {
+	case []interface{}:
+		return jsonValue(value).([]interface{}), nil
+	case string:
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(utils.Interpolate(value), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return nil, errors.New("foreach must be a list or a comma separated string")
+}
+
+// copies an entity replacing the ${item} references of its strings
+type foreachExpander struct {
+	item interface{}
+	err  error
+}
+
+// the item or the field of the item a reference names, ok is false when the
+// reference is not to the item
+func (expander *foreachExpander) lookup(ref string) (interface{}, bool) {
+	if ref == "item" {
+		return expander.item, true
+	}
+	if !strings.HasPrefix(ref, "item.") {
+		return nil, false
+	}
+	key := strings.TrimPrefix(ref, "item.")
+	fields, isMapping := expander.item.(map[string]interface{})
+	if !isMapping {
+		expander.err = errors.New("${" + ref + "} needs the items of foreach to be mappings")
+		return nil, true
+	}
+	value, exists := fields[key]
+	if !exists {
+		expander.err = errors.New("${" + ref + "} names a field missing from item " + itemText(expander.item))
+	}
+	return value, true
+}
+
+// replace the references to the item in s by its text
+func (expander *foreachExpander) text(s string) string {
+	result := ""
+	for {
+		start := strings.Index(s, "${item")
+		if start < 0 {
+			return result + s
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return result + s
+		}
+		end += start
+		if value, ok := expander.lookup(s[start+2 : end]); ok {
+			result += s[:start] + itemText(value)
+		} else {
+			result += s[:end+1]
+		}
+		s = s[end+1:]
+	}
+}
+
+func itemText(value interface{}) string {
+	switch value.(type) {
+	case string, nil:
+		return fmt.Sprint(value)
+	case map[string]interface{}, []interface{}:
+		content, _ := json.Marshal(value)
+		return string(content)
+	}
+	return fmt.Sprint(value)
+}
+
+// a deep copy of value, so the copies share no maps, with the references
+// to the item replaced. A value that is a single reference, e.g. an input
+// set to ${item.port}, takes the item or field itself, keeping its type.
+func (expander *foreachExpander) copy(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.String:
+		copied := reflect.New(value.Type()).Elem()
+		copied.SetString(expander.text(value.String()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" {
+				copied.Field(i).Set(expander.copy(value.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type().Elem())
+		copied.Elem().Set(expander.copy(value.Elem()))
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMap(value.Type())
+		for _, key := range value.MapKeys() {
+			copiedKey := key
+			if key.Kind() == reflect.String || key.Kind() == reflect.Interface && key.Elem().Kind() == reflect.String {
+				copiedKey = reflect.ValueOf(expander.text(fmt.Sprint(key.Interface()))).Convert(key.Type())
+			}
+			copied.SetMapIndex(copiedKey, expander.copy(value.MapIndex(key)))
+		}
+		return copied
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(expander.copy(value.Index(i)))
+		}
+		return copied
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type()).Elem()
+		if s, isString := value.Elem().Interface().(string); isString && strings.HasPrefix(s, "${item") && strings.IndexByte(s, '}') == len(s)-1 {
+			if item, ok := expander.lookup(s[2 : len(s)-1]); ok {
+				if item != nil {
+					copied.Set(reflect.ValueOf(item))
+				}
+				return copied
+			}
+		}
+		copied.Set(expander.copy(value.Elem()))
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,205 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the scheme of the valueFrom files of inputs
+const ValueFileScheme = "file://"
+
+// the encodings the content of valueFrom files can be bound with
+var valueEncodings = map[string]bool{"": true, "base64": true}
+
+// the types an input can declare, json taking any value
+var inputTypes = map[string]bool{
+	"string":  true,
+	"integer": true,
+	"float":   true,
+	"boolean": true,
+	"json":    true,
+}
+
+// IsInputType reports whether an input can declare the type
+func IsInputType(typ string) bool {
+	return typ == "" || inputTypes[typ]
+}
+
+// InputPattern compiles the pattern of an input, matched against whole values
+func InputPattern(pattern string) (*regexp.Regexp, error) {
+	// errors are reported on the pattern as written
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// CheckInputValue returns what is wrong with the value bound to an input for
+// the type, the values and the pattern the input declares, or "" when the
+// value satisfies them. Values of inputs without constraints, and missing
+// values, always do.
+func CheckInputValue(param Parameter, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	ok := true
+	switch param.Type {
+	case "string":
+		_, ok = value.(string)
+	case "integer":
+		switch number := value.(type) {
+		case int, int64:
+		case float64:
+			ok = number == math.Trunc(number)
+		default:
+			ok = false
+		}
+	case "float":
+		switch value.(type) {
+		case int, int64, float64:
+		default:
+			ok = false
+		}
+	case "boolean":
+		_, ok = value.(bool)
+	}
+	if !ok {
+		article := "a "
+		if param.Type == "integer" {
+			article = "an "
+		}
+		return "must be " + article + param.Type + ", got " + formatInputValue(value)
+	}
+
+	if len(param.Enum) > 0 {
+		// compared as JSON, numbers read from YAML and from JSON differ in type
+		allowed := make([]string, 0, len(param.Enum))
+		found := false
+		for _, candidate := range param.Enum {
+			allowed = append(allowed, formatInputValue(candidate))
+			found = found || formatInputValue(candidate) == formatInputValue(value)
+		}
+		if !found {
+			return "must be one of " + strings.Join(allowed, ", ") + ", got " + formatInputValue(value)
+		}
+	}
+
+	if param.Pattern != "" {
+		pattern, err := InputPattern(param.Pattern)
+		str, isString := value.(string)
+		if err == nil && (!isString || !pattern.MatchString(str)) {
+			return "must match " + param.Pattern + ", got " + formatInputValue(value)
+		}
+	}
+	return ""
+}
+
+func formatInputValue(value interface{}) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(content)
+}
+
+// sortedInputNames returns the names of inputs in order, for stable reports
+func sortedInputNames(inputs map[string]Parameter) []string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckInputs returns what is wrong with the values bound to the inputs
+// declared for an entity, looked up by name: required inputs must have a
+// value and values must be of the declared type.
+func CheckInputs(entity string, inputs map[string]Parameter, bound func(name string) (interface{}, bool)) []string {
+	problems := make([]string, 0)
+	for _, name := range sortedInputNames(inputs) {
+		param := inputs[name]
+		value, exists := bound(name)
+		if !exists || value == nil {
+			if param.Required {
+				problems = append(problems, "input "+name+" of "+entity+" is required")
+			}
+			continue
+		}
+		if problem := CheckInputValue(param, value); problem != "" {
+			problems = append(problems, "input "+name+" of "+entity+" "+problem)
+		}
+	}
+	return problems
+}
+
+// ValueFile returns the path of the file an input takes its value from, and
+// false when its valueFrom is not a file:// URL.
+func ValueFile(param Parameter) (string, bool) {
+	if !strings.HasPrefix(param.ValueFrom, ValueFileScheme) {
+		return "", false
+	}
+	return filepath.FromSlash(strings.TrimPrefix(param.ValueFrom, ValueFileScheme)), true
+}
+
+// read the valueFrom file of an input, as a string or base64 encoded
+func readValueFile(param *Parameter) (interface{}, error) {
+	file, ok := ValueFile(*param)
+	if !ok {
+		return nil, invalidDescriptor(errors.New("valueFrom " + param.ValueFrom + " must be a " + ValueFileScheme + " URL"))
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, invalidDescriptor(err)
+	}
+	if param.Encoding == "base64" {
+		return base64.StdEncoding.EncodeToString(content), nil
+	}
+	return string(content), nil
+}
+
+// make the relative valueFrom files of the inputs of a package relative to
+// dir, the directory of the file declaring them, so they can be read
+// wherever the file was included from
+func (pkg *Package) locateValueFiles(dir string) {
+	locate := func(inputs map[string]Parameter) {
+		for name, param := range inputs {
+			if file, ok := ValueFile(param); ok && !filepath.IsAbs(file) {
+				param.ValueFrom = ValueFileScheme + filepath.ToSlash(filepath.Join(dir, file))
+				inputs[name] = param
+			}
+		}
+	}
+	locate(pkg.Inputs)
+	for _, action := range pkg.Actions {
+		locate(action.Inputs)
+	}
+	for _, trigger := range pkg.Triggers {
+		locate(trigger.Inputs)
+	}
+}
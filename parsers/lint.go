@@ -0,0 +1,223 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// severities of lint findings
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintInfo    = "info"
+)
+
+// LintMaxMemory is the memory limit in MB above which lint asks whether an
+// action really needs it, as memory is what actions are billed for
+const LintMaxMemory = 512
+
+// runtime kinds the OpenWhisk distributions no longer maintain
+var deprecatedKinds = map[string]bool{
+	"nodejs:6":    true,
+	"nodejs:8":    true,
+	"nodejs:10":   true,
+	"python:2":    true,
+	"python:3.6":  true,
+	"swift:3":     true,
+	"swift:3.1.1": true,
+	"swift:4.1":   true,
+	"php:7.1":     true,
+	"php:7.2":     true,
+	"ruby:2.5":    true,
+	"go:1.11":     true,
+	"dotnet:2.2":  true,
+}
+
+// LintFinding is a likely mistake found in a manifest, unlike a validation
+// violation the manifest still deploys. Rule names the check that found it.
+type LintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (f LintFinding) String() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", f.File, f.Severity, f.Message, f.Rule)
+}
+
+type linter struct {
+	validator *Validator
+	findings  []LintFinding
+}
+
+func (l *linter) report(severity string, rule string, keys []string, format string, args ...interface{}) {
+	file, index := l.validator.locate(keys)
+	line, column := index.Lookup(keys...)
+	l.findings = append(l.findings, LintFinding{file, line, column, severity, rule, fmt.Sprintf(format, args...)})
+}
+
+// LintManifest looks for the mistakes of a manifest that deploys: inputs
+// the code of their action never mentions, actions nothing refers to,
+// function files that are missing, deprecated runtime kinds and large
+// memory limits. The findings are sorted by position.
+func LintManifest(manifestPath string) ([]LintFinding, error) {
+	manifest, validator := loadManifest(manifestPath)
+	if manifest == nil {
+		return nil, errors.New(validator.Violations[0].String())
+	}
+	l := &linter{validator: validator}
+
+	pkg := manifest.Package
+	manifestDir := path.Dir(manifestPath)
+	referenced := referencedActions(&pkg, manifestPath)
+	codes := make(map[string]string)
+	functions := 0
+
+	for _, name := range sortedActionNames(pkg.Actions) {
+		action := pkg.Actions[name]
+		keys := []string{"package", "actions", name}
+		if action.Kind != "" {
+			continue
+		}
+		functions++
+
+		location, key := action.Location, "location"
+		if location == "" {
+			location, key = action.Function, "function"
+		}
+		if action.Code != "" {
+			codes[name] = action.Code
+		} else if location != "" && !utils.IsRemoteArtifact(location) {
+			file := path.Join(manifestDir, location)
+			if !utils.FileExists(file) {
+				l.report(LintError, "missing-file", append(keys, key), "file %s of action %s does not exist", location, name)
+			} else if code, ok := actionCode(file); ok {
+				codes[name] = code
+			}
+		}
+
+		if deprecatedKinds[action.Runtime] {
+			l.report(LintWarning, "deprecated-runtime", append(keys, "runtime"), "runtime %s of action %s is deprecated, move to %s:default", action.Runtime, name, strings.SplitN(action.Runtime, ":", 2)[0])
+		}
+
+		if action.Limits != nil && action.Limits.MemorySize != nil && *action.Limits.MemorySize > LintMaxMemory {
+			l.report(LintWarning, "large-memory", append(keys, "limits", "memorySize"), "action %s asks for %d MB of memory, more than %d MB is rarely needed and is billed", name, *action.Limits.MemorySize, LintMaxMemory)
+		}
+
+		if !referenced[name] && !utils.IsWebExport(action.Webexport) {
+			l.report(LintInfo, "unreferenced-action", keys, "action %s is not used by a rule, sequence or API and is not a web action", name)
+		}
+
+		if code, ok := codes[name]; ok {
+			for _, input := range sortedInputNames(action.Inputs) {
+				if !strings.Contains(code, input) {
+					l.report(LintWarning, "unused-input", append(keys, "inputs", input), "input %s of action %s is not mentioned in its code", input, name)
+				}
+			}
+		}
+	}
+
+	// the code of every action is needed to tell a package input is unused
+	if len(codes) == functions && functions > 0 {
+		for _, input := range sortedInputNames(pkg.Inputs) {
+			used := false
+			for _, code := range codes {
+				used = used || strings.Contains(code, input)
+			}
+			if !used {
+				l.report(LintWarning, "unused-input", []string{"package", "inputs", input}, "input %s of package %s is not mentioned in the code of its actions", input, pkg.Packagename)
+			}
+		}
+	}
+
+	sort.Stable(lintByPosition(l.findings))
+	return l.findings, nil
+}
+
+// the actions rules, sequences and API routes refer to
+func referencedActions(pkg *Package, manifestPath string) map[string]bool {
+	referenced := make(map[string]bool)
+	refer := func(action string) {
+		referenced[strings.TrimPrefix(strings.TrimSpace(action), pkg.Packagename+"/")] = true
+	}
+	for _, rule := range pkg.Rules {
+		refer(rule.Action)
+	}
+	for _, sequence := range pkg.Sequences {
+		for _, component := range strings.Split(sequence.Actions, ",") {
+			refer(component)
+		}
+	}
+	for _, action := range pkg.Actions {
+		if action.Kind != "" {
+			for _, component := range strings.Split(action.Actions, ",") {
+				refer(component)
+			}
+		}
+	}
+	routes, _ := pkg.RouteMap(manifestPath)
+	for _, relPaths := range routes {
+		for _, verbs := range relPaths {
+			for _, action := range verbs {
+				refer(action)
+			}
+		}
+	}
+	return referenced
+}
+
+// the source of an action given as a single file, ok is false for
+// directories, archives and binaries
+func actionCode(file string) (string, bool) {
+	if utils.IsDirectory(file) || utils.IsBinaryArtifact(file) || strings.EqualFold(path.Ext(file), ".zip") {
+		return "", false
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+type lintByPosition []LintFinding
+
+func (f lintByPosition) Len() int      { return len(f) }
+func (f lintByPosition) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f lintByPosition) Less(i, j int) bool {
+	if f[i].File != f[j].File {
+		return f[i].File < f[j].File
+	}
+	if f[i].Line != f[j].Line {
+		return f[i].Line < f[j].Line
+	}
+	return f[i].Column < f[j].Column
+}
@@ -0,0 +1,244 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the sections of a package entries can be added to
+var editableSections = map[string]bool{"actions": true, "sequences": true, "triggers": true, "feeds": true, "rules": true}
+
+// AddManifestEntry adds name: entry to a section of the package of a
+// manifest, e.g. actions. The rest of the file is left as it is, so comments
+// and formatting are kept, and the entry is indented like its neighbours.
+func AddManifestEntry(content []byte, section string, name string, entry interface{}) ([]byte, error) {
+	if !editableSections[section] {
+		return nil, errors.New("Cannot add entries to section " + section)
+	}
+	manifest, err := decodeEdited(content)
+	if err != nil {
+		return nil, err
+	}
+	if entries, ok := manifest.lookup("package", section).(map[interface{}]interface{}); ok {
+		if _, exists := entries[name]; exists {
+			return nil, errors.New(strings.TrimSuffix(section, "s") + " " + name + " is already declared in the manifest")
+		}
+	}
+
+	rendered, err := yaml.Marshal(map[string]interface{}{name: entry})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	pkgLine := findKey(lines, 0, len(lines), 0, "package")
+	if pkgLine < 0 {
+		lines = append(trimTrailingBlankLines(lines), "package:")
+		pkgLine = len(lines) - 1
+	}
+	pkgEnd := blockEnd(lines, pkgLine)
+	unit := childIndent(lines, pkgLine, pkgEnd)
+	if unit < 0 {
+		unit = 2
+	}
+
+	var inserted []string
+	at := 0
+	if sectionLine := findKey(lines, pkgLine+1, pkgEnd, unit, section); sectionLine >= 0 {
+		if _, value, _ := splitKey(strings.TrimLeft(lines[sectionLine], " ")); value != "" && !strings.HasPrefix(value, "#") {
+			if value != "{}" {
+				return nil, errors.New("Cannot add to the " + section + " section written in flow style, " + value)
+			}
+			lines[sectionLine] = lines[sectionLine][:strings.Index(lines[sectionLine], ":")+1]
+		}
+		at = blockEnd(lines, sectionLine)
+		indent := childIndent(lines, sectionLine, at)
+		if indent < 0 {
+			indent = 2 * unit
+		}
+		inserted = indentLines(string(rendered), indent, unit)
+	} else {
+		at = pkgEnd
+		inserted = append([]string{strings.Repeat(" ", unit) + section + ":"}, indentLines(string(rendered), 2*unit, unit)...)
+	}
+
+	return insertLines(lines, at, inserted, func(edited *editedManifest) bool {
+		entries, ok := edited.lookup("package", section).(map[interface{}]interface{})
+		_, added := entries[name]
+		return ok && added
+	})
+}
+
+// AddManifestPackage declares the package of a manifest that has none yet.
+func AddManifestPackage(content []byte, pkg Package) ([]byte, error) {
+	manifest, err := decodeEdited(content)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := manifest.lookup("package", "name").(string); ok && existing != "" {
+		return nil, errors.New("The manifest already declares package " + existing + ", a manifest holds a single package")
+	}
+
+	rendered, err := yaml.Marshal(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	var inserted []string
+	at := 0
+	if pkgLine := findKey(lines, 0, len(lines), 0, "package"); pkgLine >= 0 {
+		end := blockEnd(lines, pkgLine)
+		unit := childIndent(lines, pkgLine, end)
+		if unit < 0 {
+			unit = 2
+		}
+		at = pkgLine + 1
+		inserted = indentLines(string(rendered), unit, unit)
+	} else {
+		lines = trimTrailingBlankLines(lines)
+		at = len(lines)
+		inserted = append([]string{"package:"}, indentLines(string(rendered), 2, 2)...)
+	}
+
+	return insertLines(lines, at, inserted, func(edited *editedManifest) bool {
+		return edited.lookup("package", "name") == pkg.Packagename
+	})
+}
+
+// a manifest decoded without a schema, to look entries up by key
+type editedManifest struct {
+	root interface{}
+}
+
+func decodeEdited(content []byte) (*editedManifest, error) {
+	manifest := &editedManifest{}
+	if err := yaml.Unmarshal(content, &manifest.root); err != nil {
+		return nil, errors.New("Unable to parse the manifest: " + err.Error())
+	}
+	return manifest, nil
+}
+
+func (manifest *editedManifest) lookup(keys ...string) interface{} {
+	node := manifest.root
+	for _, key := range keys {
+		mapping, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+		node = mapping[key]
+	}
+	return node
+}
+
+// insert the lines and check the edited manifest still parses and holds what
+// was added
+func insertLines(lines []string, at int, inserted []string, added func(*editedManifest) bool) ([]byte, error) {
+	edited := make([]string, 0, len(lines)+len(inserted)+1)
+	edited = append(edited, lines[:at]...)
+	edited = append(edited, inserted...)
+	edited = append(edited, lines[at:]...)
+	result := strings.Join(edited, "\n")
+	if !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+
+	manifest, err := decodeEdited([]byte(result))
+	if err != nil || !added(manifest) {
+		return nil, errors.New("Unable to add to the manifest without rewriting it, check its indentation")
+	}
+	return []byte(result), nil
+}
+
+// the indent of a line that is neither blank nor a comment
+func contentIndent(line string) (int, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return 0, false
+	}
+	return len(line) - len(trimmed), true
+}
+
+// the line of the key declared at indent between lines from and to, or -1
+func findKey(lines []string, from int, to int, indent int, key string) int {
+	for i := from; i < to; i++ {
+		if lineIndent, ok := contentIndent(lines[i]); !ok || lineIndent != indent {
+			continue
+		}
+		if found, _, ok := splitKey(strings.TrimLeft(lines[i], " ")); ok && found == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// the line after the last line nested under the key at line
+func blockEnd(lines []string, line int) int {
+	indent, _ := contentIndent(lines[line])
+	end := line + 1
+	for i := line + 1; i < len(lines); i++ {
+		lineIndent, ok := contentIndent(lines[i])
+		if !ok {
+			continue
+		}
+		if lineIndent <= indent {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// the indent of the first line nested under the key at line, or -1
+func childIndent(lines []string, line int, end int) int {
+	for i := line + 1; i < end; i++ {
+		if indent, ok := contentIndent(lines[i]); ok {
+			return indent
+		}
+	}
+	return -1
+}
+
+// indent yaml.v2 output, which nests by two spaces, by base and nest it by
+// unit spaces instead. Output with lists keeps two spaces since the items of
+// a list are aligned on their dash.
+func indentLines(rendered string, base int, unit int) []string {
+	lines := strings.Split(strings.TrimRight(rendered, "\n"), "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "- ") {
+			unit = 2
+		}
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		depth := (len(line) - len(trimmed)) / 2
+		lines[i] = strings.Repeat(" ", base+depth*unit) + trimmed
+	}
+	return lines
+}
+
+func trimTrailingBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
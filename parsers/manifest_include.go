@@ -0,0 +1,286 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// keeps track of the file each merged entity came from, to report conflicts
+type includeOrigins map[string]string
+
+// Merge the manifest fragments listed under include: into the manifest.
+// Fragments are merged in the order they are listed, glob matches in
+// lexical order, and nested includes are processed depth first. The
+// returned origins are the files the merged entities of the manifest came
+// from, so the including manifest can report conflicts against them.
+func (dm *YAMLParser) mergeIncludes(maniyaml *ManifestYAML, visited map[string]bool) (includeOrigins, error) {
+	baseDir := filepath.Dir(maniyaml.Filepath)
+	origins := make(includeOrigins)
+
+	for _, include := range maniyaml.Include {
+		pattern := include
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.New("Invalid include pattern " + include + " in " + maniyaml.Filepath + ": " + err.Error())
+		}
+		if len(matches) == 0 {
+			return nil, errors.New("Include " + include + " in " + maniyaml.Filepath + " does not match any file")
+		}
+
+		for _, match := range matches {
+			absPath, err := filepath.Abs(match)
+			if err != nil {
+				return nil, err
+			}
+			if visited[absPath] {
+				return nil, errors.New("Manifest " + match + " is included more than once or includes itself")
+			}
+			visited[absPath] = true
+
+			fragment := ManifestYAML{}
+			content, err := readDescriptor(match)
+			if err != nil {
+				return nil, err
+			}
+			if err := CheckStrict(match, content, fragment); err != nil {
+				return nil, err
+			}
+			if err := dm.Unmarshal(content, &fragment); err != nil {
+				return nil, errors.New("Unable to parse included manifest " + match + ": " + err.Error())
+			}
+			fragment.Filepath = match
+			if _, err := ManifestSchemaVersion(&fragment); err != nil {
+				return nil, err
+			}
+			if err := collectFunctions(&fragment.Package); err != nil {
+				return nil, errors.New("Included manifest " + match + ": " + err.Error())
+			}
+			fragment.Package.locateValueFiles(filepath.Dir(match))
+
+			fragmentOrigins, err := dm.mergeIncludes(&fragment, visited)
+			if err != nil {
+				return nil, err
+			}
+
+			rebaseLocations(&fragment, filepath.Dir(maniyaml.Filepath))
+
+			if err := mergePackage(&maniyaml.Package, &fragment.Package, maniyaml.Filepath, match, origins, fragmentOrigins); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return origins, nil
+}
+
+// Action locations, docker paths and API documents are relative to the
+// manifest declaring them; rewrite them so they are relative to the
+// including manifest instead.
+func rebaseLocations(fragment *ManifestYAML, targetDir string) {
+	fragmentDir := filepath.Dir(fragment.Filepath)
+	rebase := func(location string) string {
+		if location == "" || filepath.IsAbs(location) {
+			return location
+		}
+		if rel, err := filepath.Rel(targetDir, filepath.Join(fragmentDir, location)); err == nil {
+			return rel
+		}
+		return location
+	}
+
+	for name, action := range fragment.Package.Actions {
+		if !utils.IsRemoteArtifact(action.Location) {
+			action.Location = rebase(action.Location)
+		}
+		action.Docker = rebase(action.Docker)
+		fragment.Package.Actions[name] = action
+	}
+
+	fragment.Package.Apis.Document = rebase(fragment.Package.Apis.Document)
+}
+
+// Merge the package of a fragment into the package including it. origins
+// are the files the entities of dest came from, srcOrigins those of the
+// entities src pulled in from its own includes.
+func mergePackage(dest *Package, src *Package, destFile string, srcFile string, origins includeOrigins, srcOrigins includeOrigins) error {
+	if src.Packagename != "" {
+		if dest.Packagename == "" {
+			dest.Packagename = src.Packagename
+		} else if dest.Packagename != src.Packagename {
+			return errors.New("Included manifest " + srcFile + " declares package " + src.Packagename + " but " + destFile + " declares package " + dest.Packagename)
+		}
+	}
+
+	conflict := func(kind string, name string) error {
+		key := kind + "/" + name
+		from, included := srcOrigins[key]
+		if !included {
+			from = srcFile
+		}
+		if first, exists := origins[key]; exists {
+			return errors.New("Conflict detected for " + kind + " " + name + ": defined in both " + first + " and " + from)
+		}
+		origins[key] = from
+		return nil
+	}
+
+	// record what the including manifest defines itself
+	claim := func(kind string, name string) {
+		key := kind + "/" + name
+		if _, exists := origins[key]; !exists {
+			origins[key] = destFile
+		}
+	}
+
+	if dest.Actions == nil && len(src.Actions) > 0 {
+		dest.Actions = make(map[string]Action)
+	}
+	for name := range dest.Actions {
+		claim("action", name)
+	}
+	for name, action := range src.Actions {
+		if err := conflict("action", name); err != nil {
+			return err
+		}
+		dest.Actions[name] = action
+	}
+
+	if dest.Sequences == nil && len(src.Sequences) > 0 {
+		dest.Sequences = make(map[string]Sequence)
+	}
+	for name := range dest.Sequences {
+		claim("sequence", name)
+	}
+	for name, sequence := range src.Sequences {
+		if err := conflict("sequence", name); err != nil {
+			return err
+		}
+		dest.Sequences[name] = sequence
+	}
+
+	if dest.Triggers == nil && len(src.Triggers) > 0 {
+		dest.Triggers = make(map[string]Trigger)
+	}
+	for name := range dest.Triggers {
+		claim("trigger", name)
+	}
+	for name, trigger := range src.Triggers {
+		if err := conflict("trigger", name); err != nil {
+			return err
+		}
+		dest.Triggers[name] = trigger
+	}
+
+	if dest.Feeds == nil && len(src.Feeds) > 0 {
+		dest.Feeds = make(map[string]Feed)
+	}
+	for name := range dest.Feeds {
+		claim("feed", name)
+	}
+	for name, feed := range src.Feeds {
+		if err := conflict("feed", name); err != nil {
+			return err
+		}
+		dest.Feeds[name] = feed
+	}
+
+	if dest.Rules == nil && len(src.Rules) > 0 {
+		dest.Rules = make(map[string]Rule)
+	}
+	for name := range dest.Rules {
+		claim("rule", name)
+	}
+	for name, rule := range src.Rules {
+		if err := conflict("rule", name); err != nil {
+			return err
+		}
+		dest.Rules[name] = rule
+	}
+
+	if dest.Dependencies == nil && len(src.Dependencies) > 0 {
+		dest.Dependencies = make(map[string]Dependency)
+	}
+	for name := range dest.Dependencies {
+		claim("dependency", name)
+	}
+	for name, dependency := range src.Dependencies {
+		if err := conflict("dependency", name); err != nil {
+			return err
+		}
+		dest.Dependencies[name] = dependency
+	}
+
+	if dest.Inputs == nil && len(src.Inputs) > 0 {
+		dest.Inputs = make(map[string]Parameter)
+	}
+	for name := range dest.Inputs {
+		claim("input", name)
+	}
+	for name, input := range src.Inputs {
+		if err := conflict("input", name); err != nil {
+			return err
+		}
+		dest.Inputs[name] = input
+	}
+
+	if src.Apis.Document != "" || dest.Apis.Document != "" {
+		if !src.Apis.Empty() && !dest.Apis.Empty() {
+			return errors.New("Conflict detected for apis: " + destFile + " and " + srcFile + " both define apis and one of them references a document")
+		}
+		if dest.Apis.Empty() {
+			dest.Apis = src.Apis
+		}
+		return nil
+	}
+	if dest.Apis.Routes == nil && len(src.Apis.Routes) > 0 {
+		dest.Apis.Routes = make(map[string]map[string]map[string]string)
+	}
+	for basePath, relPaths := range dest.Apis.Routes {
+		for relPath, verbs := range relPaths {
+			for verb := range verbs {
+				claim("route", verb+" "+basePath+relPath)
+			}
+		}
+	}
+	for basePath, relPaths := range src.Apis.Routes {
+		if dest.Apis.Routes[basePath] == nil {
+			dest.Apis.Routes[basePath] = make(map[string]map[string]string)
+		}
+		for relPath, verbs := range relPaths {
+			if dest.Apis.Routes[basePath][relPath] == nil {
+				dest.Apis.Routes[basePath][relPath] = make(map[string]string)
+			}
+			for verb, action := range verbs {
+				if err := conflict("route", verb+" "+basePath+relPath); err != nil {
+					return err
+				}
+				dest.Apis.Routes[basePath][relPath][verb] = action
+			}
+		}
+	}
+
+	return nil
+}
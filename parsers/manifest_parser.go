@@ -19,12 +19,17 @@ package parsers
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
+	"encoding/base64"
 	"encoding/json"
 
 	"github.com/openwhisk/openwhisk-client-go/whisk"
@@ -33,27 +38,33 @@ import (
 )
 
 // Read existing manifest file or create new if none exists
-func ReadOrCreateManifest() *ManifestYAML {
+func ReadOrCreateManifest() (*ManifestYAML, error) {
 	maniyaml := ManifestYAML{}
 
 	if _, err := os.Stat("manifest.yaml"); err == nil {
 		dat, _ := ioutil.ReadFile("manifest.yaml")
-		err := NewYAMLParser().Unmarshal(dat, &maniyaml)
-		utils.Check(err)
+		if err := NewYAMLParser().Unmarshal(dat, &maniyaml); err != nil {
+			return nil, utils.FileErr("manifest.yaml", err)
+		}
 	}
-	return &maniyaml
+	return &maniyaml, nil
 }
 
 // Serialize manifest to local file
-func Write(manifest *ManifestYAML, filename string) {
+func Write(manifest *ManifestYAML, filename string) error {
 	output, err := NewYAMLParser().Marshal(manifest)
-	utils.Check(err)
+	if err != nil {
+		return err
+	}
 
 	f, err := os.Create(filename)
-	utils.Check(err)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
 
-	f.Write(output)
+	_, err = f.Write(output)
+	return utils.FileErr(filename, err)
 }
 
 func (dm *YAMLParser) Unmarshal(input []byte, manifest *ManifestYAML) error {
@@ -65,6 +76,31 @@ func (dm *YAMLParser) Unmarshal(input []byte, manifest *ManifestYAML) error {
 	return nil
 }
 
+// JSON manifests and deployment files follow the YAML schema; convert them
+// to YAML so the same struct tags and custom unmarshallers apply.
+func ConvertJSONToYAML(input []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(input, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// Read manifest or deployment file content, converting JSON files to YAML
+func readDescriptor(file string) ([]byte, error) {
+	content, err := utils.Read(file)
+	if err != nil {
+		return nil, err
+	}
+	if content, err = utils.PreProcess(file, content); err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(path.Ext(file), ".json") {
+		return ConvertJSONToYAML(content)
+	}
+	return content, nil
+}
+
 func (dm *YAMLParser) Marshal(manifest *ManifestYAML) (output []byte, err error) {
 	data, err := yaml.Marshal(manifest)
 	if err != nil {
@@ -74,26 +110,141 @@ func (dm *YAMLParser) Marshal(manifest *ManifestYAML) (output []byte, err error)
 	return data, nil
 }
 
-func (dm *YAMLParser) ParseManifest(mani string) *ManifestYAML {
+func (dm *YAMLParser) ParseManifest(mani string) (*ManifestYAML, error) {
 	mm := NewYAMLParser()
 	maniyaml := ManifestYAML{}
 
-	content, err := utils.Read(mani)
-	utils.Check(err)
+	content, err := readDescriptor(mani)
+	if err != nil {
+		return nil, invalidDescriptor(err)
+	}
+	if err := CheckStrict(mani, content, maniyaml); err != nil {
+		return nil, invalidDescriptor(err)
+	}
 
-	err = mm.Unmarshal(content, &maniyaml)
-	utils.Check(err)
+	if err := mm.Unmarshal(content, &maniyaml); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(mani, err))
+	}
 	maniyaml.Filepath = mani
-	return &maniyaml
+	if _, err := ManifestSchemaVersion(&maniyaml); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(mani, err))
+	}
+	if err := collectFunctions(&maniyaml.Package); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(mani, err))
+	}
+	maniyaml.Package.locateValueFiles(filepath.Dir(mani))
+
+	// merge the manifest fragments pulled in with include:
+	if len(maniyaml.Include) > 0 {
+		visited := make(map[string]bool)
+		if absPath, err := filepath.Abs(mani); err == nil {
+			visited[absPath] = true
+		}
+		if _, err := mm.mergeIncludes(&maniyaml, visited); err != nil {
+			return nil, invalidDescriptor(err)
+		}
+	}
+
+	if _, err := maniyaml.Package.expandForeach(); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(mani, err))
+	}
+	if err := maniyaml.Package.applyConditions(); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(mani, err))
+	}
+	if err := collectSequenceActions(&maniyaml.Package); err != nil {
+		return nil, invalidDescriptor(utils.FileErr(mani, err))
+	}
+	return &maniyaml, nil
+}
+
+// failures to read manifest and deployment files exit as invalid projects
+func invalidDescriptor(err error) error {
+	return utils.WithExitCode(utils.ExitInvalid, err)
+}
+
+// function: is another name for location:
+func collectFunctions(pkg *Package) error {
+	for name, action := range pkg.Actions {
+		if action.Function == "" {
+			continue
+		}
+		if action.Location != "" && action.Location != action.Function {
+			return errors.New("Action " + name + " sets both function and location")
+		}
+		action.Location = action.Function
+		action.Function = ""
+		pkg.Actions[name] = action
+	}
+	return nil
+}
+
+// SequenceKind is the kind of actions declared as sequences
+const SequenceKind = "sequence"
+
+// move the actions declared with kind: sequence to the sequences
+func collectSequenceActions(pkg *Package) error {
+	for name, action := range pkg.Actions {
+		if action.Kind == "" {
+			continue
+		}
+		if !strings.EqualFold(action.Kind, SequenceKind) {
+			return errors.New("Action " + name + " has unsupported kind " + action.Kind + ", set the runtime instead")
+		}
+		if _, exists := pkg.Sequences[name]; exists {
+			return errors.New("Sequence " + name + " is declared both as an action and under sequences")
+		}
+		if pkg.Sequences == nil {
+			pkg.Sequences = make(map[string]Sequence)
+		}
+		pkg.Sequences[name] = Sequence{Metadata: action.Metadata, Actions: action.Actions, Annotations: action.Annotations}
+		delete(pkg.Actions, name)
+	}
+	return nil
+}
+
+// CheckSequence returns what is wrong with the components of a sequence:
+// components of the package itself must be defined in the manifest, and a
+// sequence cannot contain itself.
+func CheckSequence(pkg Package, name string, actions string) []string {
+	problems := make([]string, 0)
+	components := 0
+	for _, component := range strings.Split(actions, ",") {
+		component = strings.TrimSpace(component)
+		if component == "" {
+			continue
+		}
+		components++
+
+		local := strings.TrimPrefix(component, pkg.Packagename+"/")
+		if strings.Contains(local, "/") {
+			continue
+		}
+		if local == name {
+			problems = append(problems, "sequence "+name+" cannot contain itself")
+			continue
+		}
+		_, isAction := pkg.Actions[local]
+		_, isSequence := pkg.Sequences[local]
+		if !isAction && !isSequence {
+			problems = append(problems, "sequence "+name+" references undefined action "+component)
+		}
+	}
+	if components == 0 {
+		problems = append(problems, "sequence "+name+" has no actions")
+	}
+	return problems
 }
 
 func (dm *YAMLParser) ComposeDependencies(mani *ManifestYAML, projectPath string) (map[string]utils.DependencyRecord, error) {
 
 	depMap := make(map[string]utils.DependencyRecord)
-	for key, dependency := range mani.Package.Dependencies {
-		version := dependency.Version
-		if version == "" {
-			version = "master"
+	var errs utils.Errors
+	for _, key := range sortedDependencyNames(mani.Package.Dependencies) {
+		dependency := mani.Package.Dependencies[key]
+		version, err := DependencyRef(dependency)
+		if err != nil {
+			errs.Add(utils.EntityErr("dependency", key, err))
+			continue
 		}
 
 		location := dependency.Location
@@ -108,21 +259,27 @@ func (dm *YAMLParser) ComposeDependencies(mani *ManifestYAML, projectPath string
 			isBinding = true
 		} else if utils.LocationIsGithub(location) {
 
-			if !strings.HasPrefix(location, "https://") && !strings.HasPrefix(location, "http://") {
+			if !utils.LocationIsSSH(location) && !strings.HasPrefix(location, "https://") && !strings.HasPrefix(location, "http://") {
 				location = "https://" + dependency.Location
 			}
 
 			isBinding = false
 		} else {
-			return nil, errors.New("Dependency type is unknown.  wskdeploy only supports /whisk.system bindings or github.com packages.")
+			errs.Add(utils.EntityErr("dependency", key, errors.New("Dependency type is unknown.  wskdeploy only supports /whisk.system bindings or github.com packages.")))
+			continue
 		}
 
+		failed := false
 		keyValArrParams := make(whisk.KeyValueArr, 0)
 		for name, param := range dependency.Inputs {
 			var keyVal whisk.KeyValue
 			keyVal.Key = name
 
-			keyVal.Value = ResolveParameter(&param)
+			if keyVal.Value, err = ResolveParameter(&param); err != nil {
+				errs.Add(utils.EntityErr("dependency", key, utils.EntityErr("input", name, err)))
+				failed = true
+				continue
+			}
 
 			if keyVal.Value != nil {
 				keyValArrParams = append(keyValArrParams, keyVal)
@@ -133,18 +290,64 @@ func (dm *YAMLParser) ComposeDependencies(mani *ManifestYAML, projectPath string
 		for name, value := range dependency.Annotations {
 			var keyVal whisk.KeyValue
 			keyVal.Key = name
-			keyVal.Value = utils.GetEnvVar(value)
+			if keyVal.Value, err = utils.ResolveEnvVar(value); err != nil {
+				errs.Add(utils.EntityErr("dependency", key, utils.EntityErr("annotation", name, err)))
+				failed = true
+				continue
+			}
 
 			keyValArrAnot = append(keyValArrAnot, keyVal)
 		}
+		if failed {
+			continue
+		}
 
 		packDir := path.Join(projectPath, "Packages")
-		depMap[key] = utils.DependencyRecord{packDir, mani.Package.Packagename, location, version, keyValArrParams, keyValArrAnot, isBinding}
+		depMap[key] = utils.DependencyRecord{
+			ProjectPath: packDir,
+			Packagename: mani.Package.Packagename,
+			Location:    location,
+			Version:     version,
+			Parameters:  keyValArrParams,
+			Annotations: keyValArrAnot,
+			IsBinding:   isBinding,
+			Package:     dependency.Package,
+		}
 	}
 
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
 	return depMap, nil
 }
 
+func sortedDependencyNames(dependencies map[string]Dependency) []string {
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DependencyRef returns the branch, tag or version of a github dependency
+// to deploy, master when none is given.
+func DependencyRef(dependency Dependency) (string, error) {
+	refs := make([]string, 0, 3)
+	for _, ref := range []string{dependency.Branch, dependency.Tag, dependency.Version} {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) > 1 {
+		return "", errors.New("only one of branch, tag and version can be set")
+	}
+	if len(refs) == 0 {
+		return "master", nil
+	}
+	return refs[0], nil
+}
+
 // Is we consider multi pacakge in one yaml?
 func (dm *YAMLParser) ComposePackage(mani *ManifestYAML) (*whisk.Package, error) {
 	//mani := dm.ParseManifest(manipath)
@@ -155,12 +358,15 @@ func (dm *YAMLParser) ComposePackage(mani *ManifestYAML) (*whisk.Package, error)
 	pub := false
 	pag.Publish = &pub
 
+	var err error
 	keyValArr := make(whisk.KeyValueArr, 0)
 	for name, param := range mani.Package.Inputs {
 		var keyVal whisk.KeyValue
 		keyVal.Key = name
 
-		keyVal.Value = ResolveParameter(&param)
+		if keyVal.Value, err = ResolveParameter(&param); err != nil {
+			return nil, utils.EntityErr("package", pag.Name, utils.EntityErr("input", name, err))
+		}
 
 		if keyVal.Value != nil {
 			keyValArr = append(keyValArr, keyVal)
@@ -170,12 +376,22 @@ func (dm *YAMLParser) ComposePackage(mani *ManifestYAML) (*whisk.Package, error)
 	if len(keyValArr) > 0 {
 		pag.Parameters = keyValArr
 	}
+	if pag.Annotations, err = composeAnnotations(mani.Package.Metadata, mani.Package.Annotations); err != nil {
+		return nil, utils.EntityErr("package", mani.Package.Packagename, err)
+	}
 	return pag, nil
 }
 
 func (dm *YAMLParser) ComposeSequences(namespace string, mani *ManifestYAML) ([]utils.ActionRecord, error) {
 	var s1 []utils.ActionRecord = make([]utils.ActionRecord, 0)
-	for key, sequence := range mani.Package.Sequences {
+	var errs utils.Errors
+	for _, key := range sortedSequenceNames(mani.Package.Sequences) {
+		sequence := mani.Package.Sequences[key]
+		if problems := CheckSequence(mani.Package, key, sequence.Actions); len(problems) > 0 {
+			errs.Add(errors.New("Invalid sequence " + key + ": " + strings.Join(problems, ", ")))
+			continue
+		}
+
 		wskaction := new(whisk.Action)
 		wskaction.Exec = new(whisk.Exec)
 		wskaction.Exec.Kind = "sequence"
@@ -198,131 +414,316 @@ func (dm *YAMLParser) ComposeSequences(namespace string, mani *ManifestYAML) ([]
 		wskaction.Publish = &pub
 		wskaction.Namespace = namespace
 
-		keyValArr := make(whisk.KeyValueArr, 0)
-		for name, value := range sequence.Annotations {
-			var keyVal whisk.KeyValue
-			keyVal.Key = name
-			keyVal.Value = utils.GetEnvVar(value)
-
-			keyValArr = append(keyValArr, keyVal)
-		}
-
-		if len(keyValArr) > 0 {
-			wskaction.Annotations = keyValArr
+		annotations, err := composeAnnotations(sequence.Metadata, sequence.Annotations)
+		if err != nil {
+			errs.Add(utils.EntityErr("sequence", key, err))
+			continue
 		}
+		wskaction.Annotations = annotations
 
 		record := utils.ActionRecord{wskaction, mani.Package.Packagename, key}
 		s1 = append(s1, record)
 	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
 	return s1, nil
 }
 
+func sortedSequenceNames(sequences map[string]Sequence) []string {
+	names := make([]string, 0, len(sequences))
+	for name := range sequences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ComposeActions composes every action of the manifest. An action that
+// cannot be composed does not stop the others: the problems of all of them
+// are returned together.
 func (dm *YAMLParser) ComposeActions(mani *ManifestYAML, manipath string) (ar []utils.ActionRecord, aub []*utils.ActionExposedURLBinding, err error) {
 
 	var s1 []utils.ActionRecord = make([]utils.ActionRecord, 0)
 	var au []*utils.ActionExposedURLBinding = make([]*utils.ActionExposedURLBinding, 0)
+	var errs utils.Errors
 
-	for key, action := range mani.Package.Actions {
-		splitmanipath := strings.Split(manipath, string(os.PathSeparator))
+	for _, key := range sortedActionNames(mani.Package.Actions) {
+		records, aubinding, err := dm.composeAction(mani, manipath, key, mani.Package.Actions[key])
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+		s1 = append(s1, records...)
 
-		wskaction := new(whisk.Action)
-		//bind action, and exposed URL
-		aubinding := new(utils.ActionExposedURLBinding)
-		aubinding.ActionName = key
-		aubinding.ExposedUrl = action.ExposedUrl
+		//only append when the fields are exists
+		if aubinding.ActionName != "" && aubinding.ExposedUrl != "" {
+			au = append(au, aubinding)
+		}
+	}
 
-		wskaction.Exec = new(whisk.Exec)
-		if action.Location != "" {
-			filePath := strings.TrimRight(manipath, splitmanipath[len(splitmanipath)-1]) + action.Location
-
-			if utils.IsDirectory(filePath) {
-				zipName := filePath + ".zip"
-				err := utils.CreateFolderZip(filePath, zipName)
-				defer os.Remove(zipName)
-				utils.Check(err)
-				// To do: support docker and main entry as did by go cli?
-				wskaction.Exec, err = utils.GetExec(zipName, action.Runtime, false, "")
-			} else {
-				action.Location = filePath
-				dat, err := utils.Read(filePath)
-				utils.Check(err)
-				code := string(dat)
-				wskaction.Exec.Code = &code
-
-				ext := path.Ext(filePath)
-				kind := "nodejs:default"
-
-				switch ext {
-				case ".swift":
-					kind = "swift:default"
-				case ".js":
-					kind = "nodejs:default"
-				case ".py":
-					kind = "python"
-				}
+	if err := errs.Err(); err != nil {
+		return nil, nil, err
+	}
+	return s1, au, nil
 
-				wskaction.Exec.Kind = kind
-			}
+}
 
+// composeAction composes an action, with the actions bundled in its
+// composition if it is one
+func (dm *YAMLParser) composeAction(mani *ManifestYAML, manipath string, key string, action Action) (ar []utils.ActionRecord, aub *utils.ActionExposedURLBinding, err error) {
+	var s1 []utils.ActionRecord = make([]utils.ActionRecord, 0)
+	splitmanipath := strings.Split(manipath, string(os.PathSeparator))
+
+	wskaction := new(whisk.Action)
+	//bind action, and exposed URL
+	aubinding := new(utils.ActionExposedURLBinding)
+	aubinding.ActionName = key
+	aubinding.ExposedUrl = action.ExposedUrl
+
+	wskaction.Exec = new(whisk.Exec)
+	// the conductor action of a composition written by the composer
+	var composition *whisk.Action
+	if action.Code != "" {
+		if action.Location != "" {
+			return nil, nil, errors.New("Action " + key + " sets both code and location")
+		}
+		if action.Runtime == "" {
+			return nil, nil, utils.EntityErr("action", key, errors.New("inline code requires the runtime of the action"))
+		}
+		code := action.Code
+		wskaction.Exec.Code = &code
+	} else if utils.IsRemoteArtifact(action.Location) {
+		artifact, err := utils.FetchArtifact(action.Location, action.Sha256)
+		if err != nil {
+			return nil, nil, utils.EntityErr("action", key, err)
+		}
+		wskaction.Exec, err = utils.GetExec(artifact, action.Runtime, action.Docker != "", "")
+		if err != nil {
+			return nil, nil, utils.EntityErr("action", key, err)
 		}
+	} else if action.Location != "" {
+		filePath := strings.TrimRight(manipath, splitmanipath[len(splitmanipath)-1]) + action.Location
 
-		if action.Runtime != "" {
-			wskaction.Exec.Kind = action.Runtime
+		if utils.IsJavaProject(filePath) {
+			jar, err := utils.BuildJar(filePath, action.Build)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			wskaction.Exec, err = utils.GetExec(jar, action.Runtime, false, action.Main)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+		} else if utils.IsDirectory(filePath) {
+			build := action.Build
+			if action.Virtualenv != "" && build == "" {
+				if build, err = utils.VirtualenvBuild(action.Virtualenv, action.Runtime, filePath); err != nil {
+					return nil, nil, utils.EntityErr("action", key, err)
+				}
+			}
+			if err := utils.BuildFunction(filePath, build); err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			zipName, err := utils.CachedFolderZip(filePath, utils.ZipOptions{Include: action.Include, Exclude: action.Exclude, StoreSymlinks: action.Symlinks == "store"})
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			runtime := action.Runtime
+			if runtime == "" && action.Docker == "" {
+				kind, ok := utils.InferDirectoryKind(filePath)
+				if !ok {
+					return nil, nil, utils.EntityErr("action", key, errors.New("the runtime of directory "+action.Location+" cannot be told from its files, set the runtime of the action"))
+				}
+				runtime = kind
+			}
+			// To do: support docker and main entry as did by go cli?
+			wskaction.Exec, err = utils.GetExec(zipName, runtime, action.Docker != "", "")
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+		} else if IsComposition(filePath) {
+			action.Location = filePath
+			var bundled []*whisk.Action
+			composition, bundled, err = ReadComposition(filePath, mani.Package.Packagename)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			wskaction.Exec = composition.Exec
+			wskaction.Limits = composition.Limits
+			for _, bundledAction := range bundled {
+				s1 = append(s1, utils.ActionRecord{bundledAction, mani.Package.Packagename, filePath})
+			}
+		} else if path.Ext(filePath) == ".jar" {
+			action.Location = filePath
+			wskaction.Exec, err = utils.GetExec(filePath, action.Runtime, false, action.Main)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+		} else if utils.IsGo(filePath) {
+			action.Location = filePath
+			code, err := utils.CompileGo(filePath)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			wskaction.Exec.Code = &code
+			// a native action unless the go runtime is asked for
+			wskaction.Exec.Kind = "blackbox"
+			if action.Runtime == "" {
+				wskaction.Exec.Image = "openwhisk/dockerskeleton"
+			}
+		} else if utils.IsTypeScript(filePath) {
+			action.Location = filePath
+			code, err := utils.CompileTypeScript(filePath)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			wskaction.Exec.Code = &code
+			wskaction.Exec.Kind = "nodejs:default"
+		} else {
+			action.Location = filePath
+			dat, err := utils.Read(filePath)
+			if err != nil {
+				return nil, nil, utils.EntityErr("action", key, err)
+			}
+			code := string(dat)
+			if utils.IsBinaryArtifact(filePath) {
+				code = base64.StdEncoding.EncodeToString(dat)
+			}
+			wskaction.Exec.Code = &code
+
+			kind, ok := utils.KindForExtension(path.Ext(filePath))
+			if !ok {
+				kind = "nodejs:default"
+			}
+			wskaction.Exec.Kind = kind
 		}
 
-		keyValArr := make(whisk.KeyValueArr, 0)
-		for name, param := range action.Inputs {
-			var keyVal whisk.KeyValue
-			keyVal.Key = name
+	}
 
-			keyVal.Value = ResolveParameter(&param)
+	// the entry point of any runtime, not only of Java
+	if action.Main != "" && wskaction.Exec != nil {
+		wskaction.Exec.Main = action.Main
+	}
 
-			if keyVal.Value != nil {
-				keyValArr = append(keyValArr, keyVal)
-			}
+	if action.Docker != "" {
+		image, err := utils.BuildImage(filepath.Join(filepath.Dir(manipath), action.Docker), mani.Package.Packagename+"-"+key)
+		if err != nil {
+			return nil, nil, utils.EntityErr("action", key, err)
 		}
+		wskaction.Exec.Kind = "blackbox"
+		wskaction.Exec.Image = image
+	} else if action.Runtime != "" {
+		wskaction.Exec.Kind = action.Runtime
+	}
 
-		if len(keyValArr) > 0 {
-			wskaction.Parameters = keyValArr
+	if action.Limits != nil {
+		// installations may allow other ranges, which are checked against
+		// what the target reports before deploying
+		for _, problem := range CheckLimits(action.Limits) {
+			log.Println("Warning: action " + key + " may be rejected, on a default installation " + problem)
 		}
+		wskaction.Limits = action.Limits.ComposeWskLimits()
+	}
 
-		keyValArr = make(whisk.KeyValueArr, 0)
-		for name, value := range action.Annotations {
-			var keyVal whisk.KeyValue
-			keyVal.Key = name
-			keyVal.Value = utils.GetEnvVar(value)
+	keyValArr := make(whisk.KeyValueArr, 0)
+	for name, param := range action.Inputs {
+		var keyVal whisk.KeyValue
+		keyVal.Key = name
 
-			keyValArr = append(keyValArr, keyVal)
+		if keyVal.Value, err = ResolveParameter(&param); err != nil {
+			return nil, nil, utils.EntityErr("action", key, utils.EntityErr("input", name, err))
 		}
 
-		// only set the webaction when the annotations are not empty.
-		if len(keyValArr) > 0 && action.Webexport == "true" {
-			//wskaction.Annotations = keyValArr
-			wskaction.Annotations, err = utils.WebAction("yes", keyValArr, action.Name, false)
-			utils.Check(err)
+		if keyVal.Value != nil {
+			keyValArr = append(keyValArr, keyVal)
 		}
+	}
 
-		wskaction.Name = key
-		pub := false
-		wskaction.Publish = &pub
+	if len(keyValArr) > 0 {
+		wskaction.Parameters = keyValArr
+	}
+	if composition != nil {
+		wskaction.Parameters = MergeKeyValues(composition.Parameters, wskaction.Parameters)
+	}
 
-		record := utils.ActionRecord{wskaction, mani.Package.Packagename, action.Location}
-		s1 = append(s1, record)
+	if wskaction.Annotations, err = composeAnnotations(action.Metadata, action.Annotations); err != nil {
+		return nil, nil, utils.EntityErr("action", key, err)
+	}
+	if composition != nil {
+		wskaction.Annotations = MergeKeyValues(composition.Annotations, wskaction.Annotations)
+	}
+	if action.Conductor || composition != nil {
+		wskaction.Annotations = utils.ConductorAction(wskaction.Annotations)
+	}
 
-		//only append when the fields are exists
-		if aubinding.ActionName != "" && aubinding.ExposedUrl != "" {
-			au = append(au, aubinding)
+	if action.Webexport != "" {
+		wskaction.Annotations, err = utils.WebAction(action.Webexport, wskaction.Annotations, key, false)
+		if err != nil {
+			return nil, nil, errors.New("Invalid web-export value " + action.Webexport + " for action " + key)
 		}
+	}
 
+	if action.WebSecure != nil {
+		if !utils.IsWebExport(action.Webexport) {
+			return nil, nil, errors.New("web-secure of action " + key + " requires web-export to be enabled")
+		}
+		wskaction.Annotations, err = utils.WebSecure(action.WebSecure, wskaction.Annotations)
+		if err != nil {
+			return nil, nil, errors.New("Invalid web-secure value for action " + key + ": " + err.Error())
+		}
 	}
 
-	return s1, au, nil
+	wskaction.Name = key
+	pub := false
+	wskaction.Publish = &pub
 
+	record := utils.ActionRecord{wskaction, mani.Package.Packagename, action.Location}
+	s1 = append(s1, record)
+
+	return s1, aubinding, nil
+}
+
+// LimitRange is the range of values OpenWhisk accepts for a limit
+type LimitRange struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+// the ranges of a default OpenWhisk installation
+var (
+	TimeoutRange    = LimitRange{"timeout", 100, 300000}
+	MemorySizeRange = LimitRange{"memorySize", 128, 512}
+	LogSizeRange    = LimitRange{"logSize", 0, 10}
+)
+
+// CheckLimits returns a message for each limit out of the range of a
+// default installation.
+func CheckLimits(limits *Limits) []string {
+	problems := make([]string, 0)
+	check := func(value *int, valid LimitRange) {
+		if value != nil && (*value < valid.Min || *value > valid.Max) {
+			problems = append(problems, fmt.Sprintf("%s must be between %d and %d, got %d", valid.Name, valid.Min, valid.Max, *value))
+		}
+	}
+	check(limits.Timeout, TimeoutRange)
+	check(limits.MemorySize, MemorySizeRange)
+	check(limits.LogSize, LogSizeRange)
+	return problems
+}
+
+func (limits *Limits) ComposeWskLimits() *whisk.Limits {
+	return &whisk.Limits{
+		Timeout: limits.Timeout,
+		Memory:  limits.MemorySize,
+		Logsize: limits.LogSize,
+	}
 }
 
 func (dm *YAMLParser) ComposeTriggers(manifest *ManifestYAML) ([]*whisk.Trigger, error) {
 
 	var t1 []*whisk.Trigger = make([]*whisk.Trigger, 0)
+	var errs utils.Errors
 	pkg := manifest.Package
 	for _, trigger := range pkg.GetTriggerList() {
 		wsktrigger := new(whisk.Trigger)
@@ -331,29 +732,41 @@ func (dm *YAMLParser) ComposeTriggers(manifest *ManifestYAML) ([]*whisk.Trigger,
 		pub := false
 		wsktrigger.Publish = &pub
 
-		keyValArr := make(whisk.KeyValueArr, 0)
+		annotations, err := composeAnnotations(trigger.Metadata, trigger.Annotations)
+		if err != nil {
+			errs.Add(utils.EntityErr("trigger", trigger.Name, err))
+			continue
+		}
+		wsktrigger.Annotations = annotations
 		if trigger.Source != "" {
 			var keyVal whisk.KeyValue
 
 			keyVal.Key = "feed"
 			keyVal.Value = trigger.Source
 
-			keyValArr = append(keyValArr, keyVal)
-
-			wsktrigger.Annotations = keyValArr
+			wsktrigger.Annotations = MergeKeyValues(wsktrigger.Annotations, whisk.KeyValueArr{keyVal})
 		}
 
-		keyValArr = make(whisk.KeyValueArr, 0)
+		keyValArr := make(whisk.KeyValueArr, 0)
+
+		failed := false
 		for name, param := range trigger.Inputs {
 			var keyVal whisk.KeyValue
 			keyVal.Key = name
 
-			keyVal.Value = ResolveParameter(&param)
+			if keyVal.Value, err = ResolveParameter(&param); err != nil {
+				errs.Add(utils.EntityErr("trigger", trigger.Name, utils.EntityErr("input", name, err)))
+				failed = true
+				continue
+			}
 
 			if keyVal.Value != nil {
 				keyValArr = append(keyValArr, keyVal)
 			}
 		}
+		if failed {
+			continue
+		}
 
 		if len(keyValArr) > 0 {
 			wsktrigger.Parameters = keyValArr
@@ -361,15 +774,27 @@ func (dm *YAMLParser) ComposeTriggers(manifest *ManifestYAML) ([]*whisk.Trigger,
 
 		t1 = append(t1, wsktrigger)
 	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
 	return t1, nil
 }
 
 func (dm *YAMLParser) ComposeRules(manifest *ManifestYAML) ([]*whisk.Rule, error) {
 
 	var r1 []*whisk.Rule = make([]*whisk.Rule, 0)
+	var errs utils.Errors
 	pkg := manifest.Package
 	for _, rule := range pkg.GetRuleList() {
-		wskrule := rule.ComposeWskRule()
+		if problems := CheckRule(pkg, rule.Name, rule); len(problems) > 0 {
+			errs.Add(errors.New("Invalid rule " + rule.Name + ": " + strings.Join(problems, ", ")))
+			continue
+		}
+		wskrule, err := rule.ComposeWskRule()
+		if err != nil {
+			errs.Add(utils.EntityErr("rule", rule.Name, err))
+			continue
+		}
 
 		act := strings.TrimSpace(wskrule.Action.(string))
 
@@ -382,65 +807,277 @@ func (dm *YAMLParser) ComposeRules(manifest *ManifestYAML) ([]*whisk.Rule, error
 		r1 = append(r1, wskrule)
 	}
 
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
 	return r1, nil
 }
 
+// CheckRule returns what is wrong with the trigger and action of a rule:
+// those of the package itself must be defined in the manifest.
+func CheckRule(pkg Package, name string, rule Rule) []string {
+	problems := make([]string, 0)
+	if rule.Trigger == "" {
+		problems = append(problems, "rule "+name+" has no trigger set")
+	} else if _, exists := pkg.Triggers[rule.Trigger]; !exists && !strings.Contains(rule.Trigger, "/") {
+		problems = append(problems, "rule "+name+" references undefined trigger "+rule.Trigger)
+	}
+
+	local := strings.TrimPrefix(strings.TrimSpace(rule.Action), pkg.Packagename+"/")
+	if local == "" {
+		problems = append(problems, "rule "+name+" has no action set")
+	} else if !strings.Contains(local, "/") {
+		_, isAction := pkg.Actions[local]
+		_, isSequence := pkg.Sequences[local]
+		if !isAction && !isSequence {
+			problems = append(problems, "rule "+name+" references undefined action "+rule.Action)
+		}
+	}
+	return problems
+}
+
+// ComposeApiRoutes lists the API Gateway routes of the apis block, or of
+// the swagger or OpenAPI document it references, sorted by base path,
+// relative path and verb.
+func (dm *YAMLParser) ComposeApiRoutes(manifest *ManifestYAML) ([]utils.ApiRoute, error) {
+	var routes []utils.ApiRoute = make([]utils.ApiRoute, 0)
+	var errs utils.Errors
+	pkg := manifest.Package
+	routeMap, err := pkg.RouteMap(manifest.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	for basePath, relPaths := range routeMap {
+		for relPath, verbs := range relPaths {
+			for verb, action := range verbs {
+				if problem := CheckApiRoute(pkg, verb, action); problem != "" {
+					errs.Add(errors.New("Invalid route " + verb + " " + basePath + relPath + ": " + problem))
+					continue
+				}
+				route := utils.ApiRoute{
+					BasePath:    "/" + strings.Trim(basePath, "/"),
+					RelPath:     "/" + strings.TrimLeft(relPath, "/"),
+					Verb:        strings.ToUpper(verb),
+					ActionName:  action,
+					Packagename: pkg.Packagename,
+				}
+				routes = append(routes, route)
+			}
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	sort.Sort(utils.ApiRoutes(routes))
+	return routes, nil
+}
+
+// CheckApiRoute returns why a route of the apis block is invalid, or "" when
+// it binds a supported verb to a web action or a sequence of the package.
+func CheckApiRoute(pkg Package, verb string, action string) string {
+	if !validMethods[strings.ToLower(verb)] {
+		return "unsupported verb " + verb
+	}
+	if a, exists := pkg.Actions[action]; exists {
+		if !utils.IsWebExport(a.Webexport) {
+			return "action " + action + " is not a web action, set web-export to true"
+		}
+		return ""
+	}
+	if _, exists := pkg.Sequences[action]; exists {
+		return ""
+	}
+	return "action " + action + " is not defined in package " + pkg.Packagename
+}
+
 func (action *Action) ComposeWskAction(manipath string) (*whisk.Action, error) {
 	wskaction, err := utils.CreateActionFromFile(manipath, action.Location)
-	utils.Check(err)
+	if err != nil {
+		return nil, utils.EntityErr("action", action.Name, err)
+	}
 	wskaction.Name = action.Name
 	wskaction.Version = action.Version
 	wskaction.Namespace = action.Namespace
-	return wskaction, err
+	return wskaction, nil
+}
+
+// ComposeAnnotations returns the annotations of an entity in key order,
+// resolving $VARS in their string values. JSON objects and arrays are
+// converted to values the whisk client can encode.
+func ComposeAnnotations(annotations map[string]interface{}) (whisk.KeyValueArr, error) {
+	if len(annotations) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(annotations))
+	for name := range annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keyValArr := make(whisk.KeyValueArr, 0, len(names))
+	for _, name := range names {
+		value := annotations[name]
+		if value != nil {
+			resolved, err := utils.ResolveEnvVar(value)
+			if err != nil {
+				return nil, utils.EntityErr("annotation", name, err)
+			}
+			value = jsonValue(resolved)
+		}
+		keyValArr = append(keyValArr, whisk.KeyValue{Key: name, Value: value})
+	}
+	return keyValArr, nil
+}
+
+// the metadata annotations of an entity, with its own annotations taking
+// precedence
+func composeAnnotations(metadata Metadata, annotations map[string]interface{}) (whisk.KeyValueArr, error) {
+	keyValArr, err := ComposeAnnotations(annotations)
+	if err != nil {
+		return nil, err
+	}
+	return MergeKeyValues(metadata.Annotations(), keyValArr), nil
+}
+
+// Annotations returns the description, author and docs annotations that are
+// set, resolving $VARS in them.
+func (metadata Metadata) Annotations() whisk.KeyValueArr {
+	keyValArr := make(whisk.KeyValueArr, 0)
+	add := func(key string, value string) {
+		if value != "" {
+			keyValArr = append(keyValArr, whisk.KeyValue{Key: key, Value: utils.GetEnvVar(value)})
+		}
+	}
+	add("description", metadata.Description)
+	add("author", metadata.Author)
+	add("docs", metadata.Docs)
+	if len(keyValArr) == 0 {
+		return nil
+	}
+	return keyValArr
 }
 
 // Resolve parameter input
-func ResolveParameter(param *Parameter) interface{} {
-	value := utils.GetEnvVar(param.Value)
+func ResolveParameter(param *Parameter) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch {
+	case param.Value != nil:
+		value, err = resolveValue(param.Value)
+	case param.ValueFrom != "":
+		// the content of the file is taken as it is, $VARS included
+		value, err = readValueFile(param)
+	case param.Default != nil:
+		// the default stands for an input nothing binds
+		value, err = resolveValue(param.Default)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	typ := param.Type
-	if str, ok := value.(string); ok && (len(typ) == 0 || typ != "string") {
+	if str, ok := value.(string); ok && (len(typ) == 0 || typ != "string") && param.Encoding == "" {
 		var parsed interface{}
 		err := json.Unmarshal([]byte(str), &parsed)
 		if err == nil {
-			return parsed
+			return parsed, nil
 		}
 	}
-	return value
+	return value, nil
+}
+
+// resolve the $VARS of the strings a structured value holds too
+func resolveValue(value interface{}) (interface{}, error) {
+	var err error
+	switch structured := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(structured))
+		for key, item := range structured {
+			if resolved[key], err = resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(structured))
+		for i, item := range structured {
+			if resolved[i], err = resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	}
+	return utils.ResolveEnvVar(value)
 }
 
 // Provide custom Parameter marshalling and unmarshalling
 
 type ParsedParameter Parameter
 
+// A mapping of nothing but parameter fields declares the parameter, any
+// other mapping or list is its value, e.g. an object of settings. Values
+// are converted to what encoding/json marshals, so structured values are
+// sent as JSON.
 func (n *Parameter) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var aux ParsedParameter
-	if err := unmarshal(&aux); err == nil {
+	var inline interface{}
+	if err := unmarshal(&inline); err != nil {
+		return err
+	}
+
+	if mapping, ok := inline.(map[interface{}]interface{}); ok && isParameterMapping(mapping) {
+		var aux ParsedParameter
+		if err := unmarshal(&aux); err != nil {
+			return err
+		}
 		n.Type = aux.Type
 		n.Description = aux.Description
-		n.Value = aux.Value
+		n.Value = jsonValue(aux.Value)
 		n.Required = aux.Required
-		n.Default = aux.Default
+		n.Default = jsonValue(aux.Default)
 		n.Status = aux.Status
 		n.Schema = aux.Schema
+		n.Enum = aux.Enum
+		for i := range n.Enum {
+			n.Enum[i] = jsonValue(n.Enum[i])
+		}
+		n.Pattern = aux.Pattern
+		n.ValueFrom = aux.ValueFrom
+		n.Encoding = aux.Encoding
 		return nil
 	}
 
-	var inline interface{}
-	if err := unmarshal(&inline); err != nil {
-		return err
-	}
-
-	n.Value = inline
+	n.Value = jsonValue(inline)
 	return nil
 }
 
-func (n *Parameter) MarshalYAML() (interface{}, error) {
-	if _, ok := n.Value.(string); len(n.Type) == 0 && len(n.Description) == 0 && ok {
-		if !n.Required && len(n.Status) == 0 && n.Schema == nil {
-			return n.Value.(string), nil
+func isParameterMapping(mapping map[interface{}]interface{}) bool {
+	fields := yamlFields(reflect.TypeOf(ParsedParameter{}))
+	for key := range mapping {
+		name, ok := key.(string)
+		if !ok {
+			return false
+		}
+		if _, known := fields[name]; !known {
+			return false
+		}
+	}
+	return true
+}
+
+// Parameters with nothing but a scalar value are written inline. The value
+// receiver makes this apply to parameters stored in maps, and the result is
+// marshalled as a ParsedParameter so it does not recurse.
+func (n Parameter) MarshalYAML() (interface{}, error) {
+	if len(n.Type) == 0 && len(n.Description) == 0 && !n.Required && len(n.Status) == 0 && n.Schema == nil && n.Default == nil && n.Enum == nil && n.Pattern == "" && n.ValueFrom == "" {
+		switch n.Value.(type) {
+		case string, bool, int, int64, float64:
+			return n.Value, nil
 		}
 	}
 
-	return n, nil
+	return ParsedParameter(n), nil
 }
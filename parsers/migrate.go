@@ -0,0 +1,272 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// CurrentSchemaVersion is the version of the manifest layout this wskdeploy
+// reads and writes. Manifests without schema-version: have version 0.
+const CurrentSchemaVersion = 1
+
+// a rewrite of the manifest from the layout before version to that of version
+type schemaMigration struct {
+	version int
+	migrate func(lines []string) ([]string, []string, error)
+}
+
+// the migrations in version order, the next layout adds its own
+var schemaMigrations = []schemaMigration{
+	{1, migrateToVersion1},
+}
+
+// ManifestSchemaVersion returns the schema-version of a manifest, and fails
+// on versions newer than this wskdeploy reads.
+func ManifestSchemaVersion(manifest *ManifestYAML) (int, error) {
+	if manifest.SchemaVersion == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(manifest.SchemaVersion)
+	if err != nil || version < 0 {
+		return 0, errors.New("schema-version " + manifest.SchemaVersion + " of manifest " + manifest.Filepath + " must be a whole number")
+	}
+	if version > CurrentSchemaVersion {
+		return 0, errors.New("Manifest " + manifest.Filepath + " has schema-version " + manifest.SchemaVersion + ", newer than version " +
+			strconv.Itoa(CurrentSchemaVersion) + " this wskdeploy reads, upgrade wskdeploy")
+	}
+	return version, nil
+}
+
+// MigrateManifest rewrites a manifest from its schema-version to the
+// current one, returning the rewritten manifest and a line per change. The
+// rest of the file is left as it is, so comments and formatting are kept.
+func MigrateManifest(file string, content []byte) ([]byte, []string, error) {
+	manifest := ManifestYAML{Filepath: file}
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, nil, errors.New("Unable to parse the manifest " + file + ": " + err.Error())
+	}
+	version, err := ManifestSchemaVersion(&manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == CurrentSchemaVersion {
+		return content, []string{}, nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	changes := make([]string, 0)
+	for _, migration := range schemaMigrations {
+		if migration.version <= version {
+			continue
+		}
+		var changed []string
+		if lines, changed, err = migration.migrate(lines); err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, changed...)
+	}
+
+	current := strconv.Itoa(CurrentSchemaVersion)
+	if line := findKey(lines, 0, len(lines), 0, "schema-version"); line >= 0 {
+		lines[line] = "schema-version: " + current
+	} else {
+		at := findKey(lines, 0, len(lines), 0, "package")
+		if at < 0 {
+			at = len(lines)
+		}
+		lines = append(lines[:at], append([]string{"schema-version: " + current}, lines[at:]...)...)
+	}
+	changes = append(changes, "schema-version set to "+current)
+
+	migrated := []byte(strings.Join(lines, "\n") + "\n")
+	if err := checkMigrated(migrated); err != nil {
+		return nil, nil, err
+	}
+	return migrated, changes, nil
+}
+
+// version 1 routes APIs with the apis: section rather than the exposedUrl:
+// of actions, which must be web actions to be routed, and names the feed of
+// triggers source:, feed: being ignored
+func migrateToVersion1(lines []string) ([]string, []string, error) {
+	changes := make([]string, 0)
+	pkgLine := findKey(lines, 0, len(lines), 0, "package")
+	if pkgLine < 0 {
+		return lines, changes, nil
+	}
+	unit := childIndent(lines, pkgLine, blockEnd(lines, pkgLine))
+
+	for _, entry := range sectionEntries(lines, pkgLine, unit, "triggers") {
+		keyIndent := childIndent(lines, entry.line, entry.end)
+		feed := findKey(lines, entry.line+1, entry.end, keyIndent, "feed")
+		if feed < 0 {
+			continue
+		}
+		if findKey(lines, entry.line+1, entry.end, keyIndent, "source") >= 0 {
+			return nil, nil, errors.New("trigger " + entry.name + " sets both feed and source, keep source")
+		}
+		lines[feed] = strings.Replace(lines[feed], "feed:", "source:", 1)
+		changes = append(changes, "triggers."+entry.name+": feed renamed to source")
+	}
+
+	routes := make(map[string]map[string]map[string]string)
+	for _, entry := range sectionEntries(lines, pkgLine, unit, "actions") {
+		keyIndent := childIndent(lines, entry.line, entry.end)
+		exposed := findKey(lines, entry.line+1, entry.end, keyIndent, "exposedUrl")
+		if exposed < 0 {
+			continue
+		}
+		_, value, _ := splitKey(strings.TrimLeft(lines[exposed], " "))
+		parts := strings.Split(strings.Trim(value, `"'`), "/")
+		if len(parts) != 3 || !validMethods[strings.ToLower(parts[0])] {
+			return nil, nil, errors.New("exposedUrl " + value + " of action " + entry.name + " must have the form method/basepath/relativepath")
+		}
+		basePath, relPath := "/"+parts[1], "/"+parts[2]
+		if routes[basePath] == nil {
+			routes[basePath] = make(map[string]map[string]string)
+		}
+		if routes[basePath][relPath] == nil {
+			routes[basePath][relPath] = make(map[string]string)
+		}
+		routes[basePath][relPath][strings.ToLower(parts[0])] = entry.name
+
+		// the route stands for the exposedUrl, which line says web-export
+		webExport := strings.Repeat(" ", keyIndent) + "web-export: true"
+		if line := findKey(lines, entry.line+1, entry.end, keyIndent, "web-export"); line >= 0 {
+			if _, mode, _ := splitKey(strings.TrimLeft(lines[line], " ")); !utils.IsWebExport(strings.Trim(mode, `"'`)) {
+				lines[line] = webExport
+				changes = append(changes, "actions."+entry.name+": web-export set to true, routed actions are web actions")
+			}
+			lines[exposed] = removedLine
+		} else {
+			lines[exposed] = webExport
+			changes = append(changes, "actions."+entry.name+": web-export set to true, routed actions are web actions")
+		}
+		changes = append(changes, "actions."+entry.name+": exposedUrl moved to apis as "+strings.ToLower(parts[0])+" "+basePath+relPath)
+	}
+	lines = removeLines(lines, removedLine)
+	if len(routes) == 0 {
+		return lines, changes, nil
+	}
+
+	// merge the routes into the apis section, which is rewritten
+	manifest, err := decodeEdited([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, nil, err
+	}
+	switch apis := manifest.lookup("package", "apis").(type) {
+	case nil:
+	case string:
+		return nil, nil, errors.New("the apis of the package are read from " + apis + ", add the routes of exposedUrl to it and remove exposedUrl")
+	default:
+		existing := Apis{}
+		content, _ := yaml.Marshal(apis)
+		if err := yaml.Unmarshal(content, &existing.Routes); err != nil {
+			return nil, nil, errors.New("Unable to read the apis of the package: " + err.Error())
+		}
+		for basePath, relPaths := range existing.Routes {
+			for relPath, verbs := range relPaths {
+				for verb, action := range verbs {
+					if routes[basePath] == nil {
+						routes[basePath] = make(map[string]map[string]string)
+					}
+					if routes[basePath][relPath] == nil {
+						routes[basePath][relPath] = make(map[string]string)
+					}
+					if _, exists := routes[basePath][relPath][verb]; !exists {
+						routes[basePath][relPath][verb] = action
+					}
+				}
+			}
+		}
+		line := findKey(lines, pkgLine+1, blockEnd(lines, pkgLine), unit, "apis")
+		lines = append(lines[:line], lines[blockEnd(lines, line):]...)
+	}
+
+	rendered, err := yaml.Marshal(routes)
+	if err != nil {
+		return nil, nil, err
+	}
+	at := blockEnd(lines, pkgLine)
+	section := append([]string{strings.Repeat(" ", unit) + "apis:"}, indentLines(string(rendered), 2*unit, unit)...)
+	lines = append(lines[:at], append(section, lines[at:]...)...)
+	return lines, changes, nil
+}
+
+// marks the lines a migration removes
+const removedLine = "\x00"
+
+func removeLines(lines []string, marker string) []string {
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line != marker {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+type sectionEntry struct {
+	name string
+	line int
+	end  int
+}
+
+// the entries of a section of the package written in block style
+func sectionEntries(lines []string, pkgLine int, unit int, section string) []sectionEntry {
+	entries := make([]sectionEntry, 0)
+	line := findKey(lines, pkgLine+1, blockEnd(lines, pkgLine), unit, section)
+	if line < 0 {
+		return entries
+	}
+	end := blockEnd(lines, line)
+	indent := childIndent(lines, line, end)
+	for i := line + 1; i < end; i++ {
+		if lineIndent, ok := contentIndent(lines[i]); !ok || lineIndent != indent {
+			continue
+		}
+		if name, _, ok := splitKey(strings.TrimLeft(lines[i], " ")); ok {
+			entries = append(entries, sectionEntry{name, i, blockEnd(lines, i)})
+		}
+	}
+	return entries
+}
+
+// the migrated manifest must parse and hold no layout of older versions,
+// which is left when it is written in flow style
+func checkMigrated(content []byte) error {
+	manifest, err := decodeEdited(content)
+	if err != nil {
+		return errors.New("Unable to migrate the manifest without rewriting it, check its indentation")
+	}
+	for _, old := range []struct{ section, key string }{{"actions", "exposedUrl"}, {"triggers", "feed"}} {
+		entries, _ := manifest.lookup("package", old.section).(map[interface{}]interface{})
+		for name, entry := range entries {
+			if fields, ok := entry.(map[interface{}]interface{}); ok && fields[old.key] != nil {
+				return errors.New("Unable to migrate " + old.key + " of " + strings.TrimSuffix(old.section, "s") + " " + name.(string) + ", rewrite it in block style first")
+			}
+		}
+	}
+	return nil
+}
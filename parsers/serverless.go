@@ -0,0 +1,545 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// ServerlessFileName is the project file of the Serverless Framework
+const ServerlessFileName = "serverless.yml"
+
+// the alarms feed schedule events create triggers with
+const serverlessScheduleFeed = "/whisk.system/alarms/alarm"
+
+// the part of a serverless.yml of the openwhisk provider that converts to
+// manifest.yaml and deployment.yaml
+type serverlessYAML struct {
+	Service   interface{}                   `yaml:"service"` // a name, or a mapping with name
+	Provider  serverlessProvider            `yaml:"provider"`
+	Functions map[string]serverlessFunction `yaml:"functions"`
+	Resources serverlessResources           `yaml:"resources"`
+}
+
+type serverlessProvider struct {
+	Name      string `yaml:"name"`
+	Runtime   string `yaml:"runtime"`
+	Namespace string `yaml:"namespace"`
+	Memory    int    `yaml:"memory"`  // megabytes
+	Timeout   int    `yaml:"timeout"` // seconds
+}
+
+type serverlessFunction struct {
+	Handler     string                   `yaml:"handler"` // file.function
+	Name        string                   `yaml:"name"`
+	Runtime     string                   `yaml:"runtime"`
+	Namespace   string                   `yaml:"namespace"`
+	Memory      int                      `yaml:"memory"`  // megabytes
+	Timeout     int                      `yaml:"timeout"` // seconds
+	Parameters  map[string]interface{}   `yaml:"parameters"`
+	Annotations map[string]interface{}   `yaml:"annotations"`
+	Sequence    []string                 `yaml:"sequence"`
+	Image       string                   `yaml:"image"`
+	Events      []map[string]interface{} `yaml:"events"`
+}
+
+type serverlessTrigger struct {
+	Parameters     map[string]interface{} `yaml:"parameters"`
+	Feed           string                 `yaml:"feed"`
+	FeedParameters map[string]interface{} `yaml:"feed_parameters"`
+}
+
+type serverlessResources struct {
+	Triggers map[string]serverlessTrigger `yaml:"triggers"`
+	Packages map[string]interface{}       `yaml:"packages"`
+}
+
+// the extensions of the handler files of the runtimes serverless supports
+var serverlessExtensions = []string{".js", ".py", ".swift", ".php", ".rb", ".go", ".jar"}
+
+// ConvertServerless translates the serverless.yml of a Serverless Framework
+// project of the openwhisk provider, the file or its directory, into a
+// manifest and a deployment file written to outputDir. The package is named
+// after the service; functions become actions or sequences, http events API
+// routes, trigger and schedule events triggers with their rules. Values of
+// parameters are bound in the deployment file and declared as inputs in the
+// manifest. What has no equivalent in wskdeploy is returned as warnings.
+func ConvertServerless(file string, outputDir string) (*ManifestYAML, *DeploymentYAML, []string, error) {
+	if info, err := os.Stat(file); err == nil && info.IsDir() {
+		file = filepath.Join(file, ServerlessFileName)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, nil, errors.New("Unable to read " + file + ": " + err.Error())
+	}
+	var service serverlessYAML
+	if err := yaml.Unmarshal(content, &service); err != nil {
+		return nil, nil, nil, errors.New("Unable to parse " + file + ": " + err.Error())
+	}
+	if service.Provider.Name != "" && service.Provider.Name != "openwhisk" {
+		return nil, nil, nil, errors.New(file + " is written for provider " + service.Provider.Name + ", only the openwhisk provider converts")
+	}
+
+	name := serviceName(service.Service)
+	if name == "" {
+		return nil, nil, nil, errors.New(file + " must name its service")
+	}
+
+	converter := serverlessConverter{
+		service:   service,
+		sourceDir: filepath.Dir(file),
+		outputDir: outputDir,
+		pkg:       Package{Packagename: name},
+		deployed:  Package{Packagename: name},
+		warnings:  make([]string, 0),
+	}
+	if err := converter.convert(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	manifest := &ManifestYAML{SchemaVersion: strconv.Itoa(CurrentSchemaVersion), Package: converter.pkg}
+	deployment := &DeploymentYAML{Application: Application{
+		Name:      name,
+		Namespace: service.Provider.Namespace,
+		Package:   converter.deployed,
+	}}
+	return manifest, deployment, converter.warnings, nil
+}
+
+// the service is a name, or a mapping with name
+func serviceName(service interface{}) string {
+	switch service := service.(type) {
+	case string:
+		return service
+	case map[interface{}]interface{}:
+		if name, ok := service["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+type serverlessConverter struct {
+	service   serverlessYAML
+	sourceDir string
+	outputDir string
+	// the package of the manifest, and the values of its deployment
+	pkg      Package
+	deployed Package
+	warnings []string
+}
+
+func (converter *serverlessConverter) warn(format string, args ...interface{}) {
+	converter.warnings = append(converter.warnings, fmt.Sprintf(format, args...))
+}
+
+func (converter *serverlessConverter) convert() error {
+	if len(converter.service.Resources.Packages) > 0 {
+		converter.warn("packages of resources are not converted, declare them as dependencies of the manifest")
+	}
+
+	names := make([]string, 0, len(converter.service.Functions))
+	for name := range converter.service.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// actions are named as the functions, or by their name
+	actionNames := make(map[string]string, len(names))
+	for _, name := range names {
+		actionNames[name] = converter.actionName(name, converter.service.Functions[name])
+	}
+
+	for _, name := range names {
+		function := converter.service.Functions[name]
+		actionName := actionNames[name]
+		if len(function.Sequence) > 0 {
+			converter.convertSequence(name, actionName, function, actionNames)
+		} else if err := converter.convertAction(name, actionName, function); err != nil {
+			return err
+		}
+		for _, event := range function.Events {
+			if err := converter.convertEvent(name, actionName, event); err != nil {
+				return err
+			}
+		}
+	}
+
+	triggers := make([]string, 0, len(converter.service.Resources.Triggers))
+	for name := range converter.service.Resources.Triggers {
+		triggers = append(triggers, name)
+	}
+	sort.Strings(triggers)
+	for _, name := range triggers {
+		resource := converter.service.Resources.Triggers[name]
+		// the feed is configured in the manifest
+		trigger := Trigger{Source: resource.Feed}
+		for key, value := range resource.FeedParameters {
+			trigger.Inputs = mergeInputs(trigger.Inputs, map[string]Parameter{key: {Value: value}})
+		}
+		converter.setTrigger(name, trigger, resource.Parameters)
+	}
+	return nil
+}
+
+func (converter *serverlessConverter) actionName(name string, function serverlessFunction) string {
+	if function.Name == "" {
+		return name
+	}
+	// actions are deployed in the package of the service
+	if slash := strings.LastIndex(function.Name, "/"); slash >= 0 {
+		converter.warn("function %s is named %s, deployed as %s in package %s", name, function.Name, function.Name[slash+1:], converter.pkg.Packagename)
+		return function.Name[slash+1:]
+	}
+	return function.Name
+}
+
+func (converter *serverlessConverter) convertAction(name string, actionName string, function serverlessFunction) error {
+	if function.Image != "" {
+		converter.warn("image %s of function %s is not converted, wskdeploy builds images from the docker directory of an action", function.Image, name)
+	}
+	if function.Namespace != "" {
+		converter.warn("namespace %s of function %s is not converted, actions are deployed in the namespace of the application", function.Namespace, name)
+	}
+	if function.Handler == "" {
+		return errors.New("function " + name + " must have a handler or a sequence")
+	}
+
+	runtime := function.Runtime
+	if runtime == "" {
+		runtime = converter.service.Provider.Runtime
+	}
+	action := Action{Runtime: runtime}
+
+	// the handler is file.function, relative to serverless.yml
+	file, main := function.Handler, ""
+	if dot := strings.LastIndex(function.Handler, "."); dot >= 0 {
+		file, main = function.Handler[:dot], function.Handler[dot+1:]
+	}
+	if main != "" && main != "main" {
+		action.Main = main
+	}
+	location, found := converter.handlerFile(file, runtime)
+	if !found {
+		converter.warn("handler %s of function %s has no file, location set to %s", function.Handler, name, location)
+	}
+	action.Location = location
+
+	action.Limits = converter.limits(function)
+	for key, value := range function.Annotations {
+		if key == "web-export" {
+			action.Webexport = fmt.Sprint(value)
+			continue
+		}
+		if action.Annotations == nil {
+			action.Annotations = make(map[string]interface{})
+		}
+		action.Annotations[key] = value
+	}
+
+	declared, values := declareInputs(function.Parameters)
+	action.Inputs = declared
+	if len(values) > 0 {
+		if converter.deployed.Actions == nil {
+			converter.deployed.Actions = make(map[string]Action)
+		}
+		converter.deployed.Actions[actionName] = Action{Inputs: values}
+	}
+	if converter.pkg.Actions == nil {
+		converter.pkg.Actions = make(map[string]Action)
+	}
+	converter.pkg.Actions[actionName] = action
+	return nil
+}
+
+func (converter *serverlessConverter) convertSequence(name string, actionName string, function serverlessFunction, actionNames map[string]string) {
+	actions := make([]string, 0, len(function.Sequence))
+	for _, component := range function.Sequence {
+		// functions of the service are named by their actions
+		if converted, ok := actionNames[component]; ok {
+			component = converted
+		}
+		actions = append(actions, component)
+	}
+	if len(function.Parameters) > 0 {
+		converter.warn("parameters of sequence %s are not converted, sequences take no inputs", name)
+	}
+	sequence := Sequence{Actions: strings.Join(actions, ", "), Annotations: function.Annotations}
+	if converter.pkg.Sequences == nil {
+		converter.pkg.Sequences = make(map[string]Sequence)
+	}
+	converter.pkg.Sequences[actionName] = sequence
+}
+
+// the location of the handler file relative to the output directory, trying
+// the extension of the runtime first
+func (converter *serverlessConverter) handlerFile(file string, runtime string) (string, bool) {
+	family := strings.SplitN(runtime, ":", 2)[0]
+	extensions := make([]string, 0, len(serverlessExtensions)+1)
+	for _, ext := range serverlessExtensions {
+		if kind, ok := utils.KindForExtension(ext); ok && strings.SplitN(kind, ":", 2)[0] == family {
+			extensions = append([]string{ext}, extensions...)
+		} else {
+			extensions = append(extensions, ext)
+		}
+	}
+	// the handler may name the file with its extension, as binaries do
+	extensions = append([]string{""}, extensions...)
+
+	for _, ext := range extensions {
+		candidate := filepath.Join(converter.sourceDir, file+ext)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return converter.relative(candidate), true
+		}
+	}
+	// extensions[1] is the extension of the runtime, when it has one
+	return converter.relative(filepath.Join(converter.sourceDir, file+extensions[1])), false
+}
+
+func (converter *serverlessConverter) relative(file string) string {
+	source, _ := filepath.Abs(file)
+	output, _ := filepath.Abs(converter.outputDir)
+	if relative, err := filepath.Rel(output, source); err == nil {
+		return filepath.ToSlash(relative)
+	}
+	return filepath.ToSlash(source)
+}
+
+// serverless gives the timeout in seconds, the manifest in milliseconds
+func (converter *serverlessConverter) limits(function serverlessFunction) *Limits {
+	memory, timeout := function.Memory, function.Timeout
+	if memory == 0 {
+		memory = converter.service.Provider.Memory
+	}
+	if timeout == 0 {
+		timeout = converter.service.Provider.Timeout
+	}
+	if memory == 0 && timeout == 0 {
+		return nil
+	}
+	limits := &Limits{}
+	if memory != 0 {
+		limits.MemorySize = &memory
+	}
+	if timeout != 0 {
+		timeout *= 1000
+		limits.Timeout = &timeout
+	}
+	return limits
+}
+
+func (converter *serverlessConverter) convertEvent(name string, actionName string, event map[string]interface{}) error {
+	for kind, config := range event {
+		switch kind {
+		case "http":
+			if err := converter.convertHttp(name, actionName, config); err != nil {
+				return err
+			}
+		case "trigger":
+			trigger, rule, params := "", "", map[string]interface{}(nil)
+			switch config := config.(type) {
+			case string:
+				trigger = config
+			case map[interface{}]interface{}:
+				trigger, _ = config["name"].(string)
+				rule, _ = config["rule"].(string)
+				params = stringKeys(config["params"])
+			}
+			if trigger == "" {
+				return errors.New("trigger event of function " + name + " must name its trigger")
+			}
+			if rule == "" {
+				rule = converter.pkg.Packagename + "_" + trigger + "_to_" + name
+			}
+			converter.setTrigger(trigger, converter.pkg.Triggers[trigger], params)
+			converter.setRule(rule, trigger, actionName)
+		case "schedule":
+			cron, trigger, rule, params := "", "", "", map[string]interface{}(nil)
+			switch config := config.(type) {
+			case string:
+				cron = config
+			case map[interface{}]interface{}:
+				cron, _ = config["rate"].(string)
+				trigger, _ = config["trigger"].(string)
+				if trigger == "" {
+					trigger, _ = config["name"].(string)
+				}
+				rule, _ = config["rule"].(string)
+				params = stringKeys(config["params"])
+			}
+			// the rate is cron(expression)
+			cron = strings.TrimSpace(cron)
+			if strings.HasPrefix(cron, "cron(") && strings.HasSuffix(cron, ")") {
+				cron = cron[len("cron(") : len(cron)-1]
+			}
+			if cron == "" {
+				return errors.New("schedule event of function " + name + " must give its rate as cron(expression)")
+			}
+			if trigger == "" {
+				trigger = converter.pkg.Packagename + "_" + name + "_schedule_trigger"
+			}
+			if rule == "" {
+				rule = converter.pkg.Packagename + "_" + name + "_schedule_rule"
+			}
+			converter.setTrigger(trigger, Trigger{
+				Source: serverlessScheduleFeed,
+				Inputs: map[string]Parameter{"cron": {Value: cron}},
+			}, params)
+			converter.setRule(rule, trigger, actionName)
+		default:
+			converter.warn("%s event of function %s is not converted, bind it to a trigger of the manifest", kind, name)
+		}
+	}
+	return nil
+}
+
+// an http event is "METHOD /base/path" or a mapping of method and path, the
+// first segment of the path being the base path of the API
+func (converter *serverlessConverter) convertHttp(name string, actionName string, config interface{}) error {
+	method, route := "", ""
+	switch config := config.(type) {
+	case string:
+		if fields := strings.Fields(config); len(fields) == 2 {
+			method, route = fields[0], fields[1]
+		}
+	case map[interface{}]interface{}:
+		method, _ = config["method"].(string)
+		route, _ = config["path"].(string)
+		if _, ok := config["resp"]; ok {
+			converter.warn("resp of the http event of function %s is not converted, API routes respond with the JSON of the action", name)
+		}
+	}
+	method = strings.ToLower(method)
+	if !validMethods[method] || !strings.HasPrefix(route, "/") {
+		return errors.New("http event of function " + name + " must be given as METHOD /base/path")
+	}
+
+	basePath, relPath := route, "/"
+	if slash := strings.Index(route[1:], "/"); slash >= 0 {
+		basePath, relPath = route[:slash+1], route[slash+1:]
+	}
+	if converter.pkg.Apis.Routes == nil {
+		converter.pkg.Apis.Routes = make(map[string]map[string]map[string]string)
+	}
+	if converter.pkg.Apis.Routes[basePath] == nil {
+		converter.pkg.Apis.Routes[basePath] = make(map[string]map[string]string)
+	}
+	if converter.pkg.Apis.Routes[basePath][relPath] == nil {
+		converter.pkg.Apis.Routes[basePath][relPath] = make(map[string]string)
+	}
+	converter.pkg.Apis.Routes[basePath][relPath][method] = actionName
+
+	// routed actions are web actions
+	if action, ok := converter.pkg.Actions[actionName]; ok && !utils.IsWebExport(action.Webexport) {
+		action.Webexport = "true"
+		converter.pkg.Actions[actionName] = action
+	}
+	return nil
+}
+
+// declare the trigger, binding the values of its parameters in deployment
+func (converter *serverlessConverter) setTrigger(name string, trigger Trigger, params map[string]interface{}) {
+	existing := converter.pkg.Triggers[name]
+	if trigger.Source == "" {
+		trigger.Source = existing.Source
+	}
+	declared, values := declareInputs(params)
+	trigger.Inputs = mergeInputs(existing.Inputs, trigger.Inputs, declared)
+	if converter.pkg.Triggers == nil {
+		converter.pkg.Triggers = make(map[string]Trigger)
+	}
+	converter.pkg.Triggers[name] = trigger
+
+	if len(values) > 0 {
+		if converter.deployed.Triggers == nil {
+			converter.deployed.Triggers = make(map[string]Trigger)
+		}
+		deployed := converter.deployed.Triggers[name]
+		deployed.Inputs = mergeInputs(deployed.Inputs, values)
+		converter.deployed.Triggers[name] = deployed
+	}
+}
+
+func (converter *serverlessConverter) setRule(name string, trigger string, action string) {
+	if converter.pkg.Rules == nil {
+		converter.pkg.Rules = make(map[string]Rule)
+	}
+	converter.pkg.Rules[name] = Rule{Trigger: trigger, Action: action}
+}
+
+// the inputs the manifest declares for parameters, typed by their values,
+// and the values the deployment binds them with
+func declareInputs(params map[string]interface{}) (map[string]Parameter, map[string]Parameter) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	declared := make(map[string]Parameter, len(params))
+	values := make(map[string]Parameter, len(params))
+	for key, value := range params {
+		typ := "json"
+		switch value.(type) {
+		case string:
+			typ = "string"
+		case int, int64:
+			typ = "integer"
+		case float64:
+			typ = "float"
+		case bool:
+			typ = "boolean"
+		}
+		declared[key] = Parameter{Type: typ}
+		values[key] = Parameter{Value: value}
+	}
+	return declared, values
+}
+
+// the inputs of all, later ones replacing earlier ones
+func mergeInputs(all ...map[string]Parameter) map[string]Parameter {
+	var merged map[string]Parameter
+	for _, inputs := range all {
+		for key, param := range inputs {
+			if merged == nil {
+				merged = make(map[string]Parameter)
+			}
+			merged[key] = param
+		}
+	}
+	return merged
+}
+
+// the params of events, a mapping parsed with keys of any type
+func stringKeys(params interface{}) map[string]interface{} {
+	mapping, ok := params.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	converted := make(map[string]interface{}, len(mapping))
+	for key, value := range mapping {
+		converted[fmt.Sprint(key)] = value
+	}
+	return converted
+}
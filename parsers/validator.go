@@ -0,0 +1,881 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Violation is a problem found in a manifest or deployment file. Line and
+// Column are 1-based and zero when the position is unknown.
+type Violation struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", v.File, v.Line, v.Column, v.Message)
+	}
+	return fmt.Sprintf("%s: %s", v.File, v.Message)
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+// PositionIndex maps key paths such as "package.actions.hello.location" to
+// the line and column the key is declared on. It understands block style
+// YAML; keys inside flow collections resolve to their closest parent.
+type PositionIndex struct {
+	positions map[string]position
+}
+
+var yamlLineError = regexp.MustCompile(`line (\d+): (.*)`)
+
+func IndexPositions(content []byte) *PositionIndex {
+	index := &PositionIndex{positions: make(map[string]position)}
+
+	stack := make([]*indexLevel, 0)
+	blockIndent := -1
+
+	for i, raw := range strings.Split(string(content), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(trimmed)
+
+		// skip the content of | and > block scalars
+		if blockIndent >= 0 {
+			if trimmed == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		for strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			item := "0"
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				item = strconv.Itoa(parent.items)
+				parent.items++
+			}
+			stack = append(stack, &indexLevel{indent: indent, key: item})
+			index.record(stack, i+1, indent+1)
+
+			if trimmed == "-" {
+				trimmed = ""
+				break
+			}
+			trimmed = trimmed[2:]
+			stripped := strings.TrimLeft(trimmed, " ")
+			indent += 2 + len(trimmed) - len(stripped)
+			trimmed = stripped
+		}
+
+		key, value, ok := splitKey(trimmed)
+		if !ok {
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, &indexLevel{indent: indent, key: key})
+		index.record(stack, i+1, indent+1)
+
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockIndent = indent
+		}
+	}
+
+	return index
+}
+
+// a key or list item on the path to the current line
+type indexLevel struct {
+	indent int
+	key    string
+	items  int
+}
+
+func (index *PositionIndex) record(stack []*indexLevel, line int, column int) {
+	keys := make([]string, 0, len(stack))
+	for _, l := range stack {
+		keys = append(keys, l.key)
+	}
+	index.positions[strings.Join(keys, ".")] = position{line, column}
+}
+
+// split a "key: value" line, ignoring colons inside quotes
+func splitKey(line string) (string, string, bool) {
+	if line == "" || strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") {
+		return "", "", false
+	}
+
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == '#' && i > 0 && line[i-1] == ' ':
+			return "", "", false
+		case c == ':' && (i+1 == len(line) || line[i+1] == ' '):
+			key := strings.TrimSpace(line[:i])
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			} else if len(key) > 1 && key[0] == '\'' && key[len(key)-1] == '\'' {
+				key = key[1 : len(key)-1]
+			}
+			return key, strings.TrimSpace(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// Lookup returns the position of the deepest declared prefix of keys.
+func (index *PositionIndex) Lookup(keys ...string) (int, int) {
+	for n := len(keys); n > 0; n-- {
+		if pos, ok := index.positions[strings.Join(keys[:n], ".")]; ok {
+			return pos.line, pos.column
+		}
+	}
+	return 0, 0
+}
+
+// alias makes the keys under from resolve to the positions of those under to
+func (index *PositionIndex) alias(from string, to string) {
+	for key, pos := range index.positions {
+		if key == to || strings.HasPrefix(key, to+".") {
+			index.positions[from+strings.TrimPrefix(key, to)] = pos
+		}
+	}
+}
+
+// Validator collects violations for one file, and for the fragments it
+// includes
+type Validator struct {
+	File       string
+	index      *PositionIndex
+	Violations []Violation
+	// the files entities merged from include: come from, and their positions
+	origins   includeOrigins
+	fragments map[string]*PositionIndex
+}
+
+func newValidator(file string, content []byte) *Validator {
+	validator := &Validator{File: file, index: &PositionIndex{positions: make(map[string]position)}}
+	if !strings.EqualFold(path.Ext(file), ".json") {
+		validator.index = IndexPositions(content)
+	}
+	return validator
+}
+
+// Report records a violation located at the given key path
+func (validator *Validator) Report(keys []string, format string, args ...interface{}) {
+	file, index := validator.locate(keys)
+	line, column := index.Lookup(keys...)
+	validator.Violations = append(validator.Violations, Violation{file, line, column, fmt.Sprintf(format, args...)})
+}
+
+// the sections of a package, by the kind mergePackage records origins for
+var includeKinds = map[string]string{
+	"actions":      "action",
+	"sequences":    "sequence",
+	"triggers":     "trigger",
+	"feeds":        "feed",
+	"rules":        "rule",
+	"dependencies": "dependency",
+	"inputs":       "input",
+}
+
+// the file declaring the entity at the given key path, and its positions
+func (validator *Validator) locate(keys []string) (string, *PositionIndex) {
+	if len(keys) < 3 || keys[0] != "package" {
+		return validator.File, validator.index
+	}
+	key := includeKinds[keys[1]] + "/" + keys[2]
+	if keys[1] == "apis" && len(keys) >= 5 {
+		key = "route/" + keys[4] + " " + keys[2] + keys[3]
+	}
+	if file, included := validator.origins[key]; included {
+		if index, ok := validator.fragments[file]; ok {
+			return file, index
+		}
+	}
+	return validator.File, validator.index
+}
+
+// merge the fragments of include: into the manifest, so references between
+// them resolve, and index them to report their violations in place
+func (validator *Validator) mergeIncludes(manifest *ManifestYAML) {
+	visited := make(map[string]bool)
+	if absPath, err := filepath.Abs(manifest.Filepath); err == nil {
+		visited[absPath] = true
+	}
+	origins, err := NewYAMLParser().mergeIncludes(manifest, visited)
+	if err != nil {
+		validator.Report([]string{"include"}, "%s", err.Error())
+		return
+	}
+
+	validator.origins = origins
+	validator.fragments = make(map[string]*PositionIndex)
+	for _, file := range origins {
+		if _, indexed := validator.fragments[file]; indexed || file == validator.File {
+			continue
+		}
+		if content, err := readDescriptor(file); err == nil {
+			validator.fragments[file] = newValidator(file, content).index
+		}
+	}
+}
+
+// record yaml.v2 syntax and type errors, which carry a line number
+func (validator *Validator) reportYAMLError(err error) {
+	found := false
+	for _, msg := range strings.Split(err.Error(), "\n") {
+		if match := yamlLineError.FindStringSubmatch(msg); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			validator.Violations = append(validator.Violations, Violation{validator.File, line, 1, match[2]})
+			found = true
+		}
+	}
+	if !found {
+		validator.Violations = append(validator.Violations, Violation{File: validator.File, Message: err.Error()})
+	}
+}
+
+// CheckKeys reports every key of the decoded document that has no matching
+// field in the given struct type.
+func (validator *Validator) CheckKeys(node interface{}, t reflect.Type, keys []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		// parameters accept inline values of any shape
+		if t == reflect.TypeOf(Parameter{}) {
+			return
+		}
+		// apis hold either routes or the path of a document
+		if t == reflect.TypeOf(Apis{}) {
+			validator.CheckKeys(node, reflect.TypeOf(Apis{}.Routes), keys)
+			return
+		}
+		mapping, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		fields := yamlFields(t)
+		for _, key := range sortedKeys(mapping) {
+			fieldType, known := fields[key]
+			if !known {
+				message := "unknown key '" + key + "'"
+				if suggestion := closestKey(key, fields); suggestion != "" {
+					message += ", did you mean '" + suggestion + "'?"
+				}
+				validator.Report(append(keys, key), "%s", message)
+				continue
+			}
+			validator.CheckKeys(mapping[key], fieldType, append(append([]string{}, keys...), key))
+		}
+	case reflect.Map:
+		if mapping, ok := node.(map[interface{}]interface{}); ok {
+			for _, key := range sortedKeys(mapping) {
+				validator.CheckKeys(mapping[key], t.Elem(), append(append([]string{}, keys...), key))
+			}
+		}
+	case reflect.Slice:
+		if list, ok := node.([]interface{}); ok {
+			for i, item := range list {
+				validator.CheckKeys(item, t.Elem(), append(append([]string{}, keys...), strconv.Itoa(i)))
+			}
+		}
+	}
+}
+
+// CheckStrict fails on the unknown keys of a manifest or deployment file
+// when parsing with --strict, as yaml.v2 ignores them, so a misspelled
+// annotatoins: deploys without its annotations. The schema is the type of
+// target. Files that do not parse are left to the parser to report.
+func CheckStrict(file string, content []byte, target interface{}) error {
+	if !utils.Flags.Strict {
+		return nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil
+	}
+	validator := newValidator(file, content)
+	validator.CheckKeys(doc, reflect.TypeOf(target), []string{})
+	if len(validator.Violations) == 0 {
+		return nil
+	}
+	problems := make([]string, 0, len(validator.Violations))
+	for _, violation := range validator.Violations {
+		problems = append(problems, violation.String())
+	}
+	return errors.New("Unknown keys in " + file + ", which --strict does not ignore:\n" + strings.Join(problems, "\n"))
+}
+
+// the yaml key of each field, following the yaml.v2 naming rules
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		inline := false
+		for _, flag := range parts[1:] {
+			if flag == "inline" {
+				inline = true
+			}
+		}
+		if inline {
+			for name, typ := range yamlFields(field.Type) {
+				fields[name] = typ
+			}
+			continue
+		}
+		name := parts[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func sortedKeys(mapping map[interface{}]interface{}) []string {
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, fmt.Sprint(key))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// closest known key within an edit distance of two, for typo hints
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best := ""
+	bestDistance := 3
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), name); d < bestDistance {
+			best = name
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// decode content both generically (for key checks) and into the target struct
+func (validator *Validator) decode(content []byte, target interface{}) (interface{}, bool) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		validator.reportYAMLError(err)
+		return nil, false
+	}
+	if err := yaml.Unmarshal(content, target); err != nil {
+		validator.reportYAMLError(err)
+		return nil, false
+	}
+	return doc, true
+}
+
+// ValidateManifest checks a manifest file against the schema without
+// contacting the OpenWhisk API host. The parsed manifest is nil when the
+// file could not be decoded.
+func ValidateManifest(manifestPath string) (*ManifestYAML, []Violation) {
+	manifest, validator := loadManifest(manifestPath)
+	if manifest != nil {
+		validator.checkManifest(manifest)
+	}
+	return manifest, validator.Violations
+}
+
+// decode the manifest and check its keys, the manifest is nil when it
+// cannot be decoded
+func loadManifest(manifestPath string) (*ManifestYAML, *Validator) {
+	content, err := readDescriptor(manifestPath)
+	if err != nil {
+		validator := newValidator(manifestPath, nil)
+		validator.Violations = []Violation{{File: manifestPath, Message: err.Error()}}
+		return nil, validator
+	}
+
+	validator := newValidator(manifestPath, content)
+	manifest := ManifestYAML{}
+	doc, ok := validator.decode(content, &manifest)
+	if !ok {
+		return nil, validator
+	}
+	manifest.Filepath = manifestPath
+
+	validator.CheckKeys(doc, reflect.TypeOf(manifest), []string{})
+	if _, err := ManifestSchemaVersion(&manifest); err != nil {
+		validator.Report([]string{"schema-version"}, "%s", err.Error())
+	}
+	if len(manifest.Include) > 0 {
+		validator.mergeIncludes(&manifest)
+	}
+	// the copies of a foreach are reported at their template
+	origins, err := manifest.Package.expandForeach()
+	if err != nil {
+		validator.Report([]string{"package"}, "%s", err.Error())
+	}
+	for name, template := range origins {
+		validator.index.alias("package."+name, "package."+template)
+	}
+	return &manifest, validator
+}
+
+var validWebExport = map[string]bool{"": true, "true": true, "false": true, "yes": true, "no": true, "raw": true}
+var validMethods = map[string]bool{"get": true, "post": true, "put": true, "delete": true, "patch": true, "head": true, "options": true}
+
+func (validator *Validator) checkManifest(manifest *ManifestYAML) {
+	pkg := manifest.Package
+	if pkg.Packagename == "" {
+		validator.Report([]string{"package"}, "package name is required")
+	}
+
+	manifestDir := path.Dir(manifest.Filepath)
+
+	for _, name := range sortedActionNames(pkg.Actions) {
+		action := pkg.Actions[name]
+		keys := []string{"package", "actions", name}
+
+		if action.Kind != "" {
+			if !strings.EqualFold(action.Kind, SequenceKind) {
+				validator.Report(append(keys, "kind"), "kind of action %s must be sequence, set the runtime instead", name)
+			} else if _, exists := pkg.Sequences[name]; exists {
+				validator.Report(append(keys, "kind"), "sequence %s is declared both as an action and under sequences", name)
+			} else {
+				for _, problem := range CheckSequence(pkg, name, action.Actions) {
+					validator.Report(append(keys, "actions"), "%s", problem)
+				}
+			}
+			continue
+		}
+
+		if action.Function != "" {
+			if action.Location != "" && action.Location != action.Function {
+				validator.Report(append(keys, "function"), "action %s sets both function and location", name)
+			}
+			action.Location = action.Function
+		}
+
+		if action.Sha256 != "" {
+			if !utils.IsRemoteArtifact(action.Location) {
+				validator.Report(append(keys, "sha256"), "sha256 of action %s requires an https, s3 or cos location", name)
+			} else if !utils.IsSha256(action.Sha256) {
+				validator.Report(append(keys, "sha256"), "sha256 of action %s must be 64 hexadecimal digits", name)
+			}
+		}
+
+		if action.Docker != "" {
+			if !utils.FileExists(path.Join(manifestDir, action.Docker)) {
+				validator.Report(append(keys, "docker"), "docker %s of action %s does not exist", action.Docker, name)
+			}
+			if action.Runtime != "" {
+				validator.Report(append(keys, "runtime"), "action %s runs in its docker image and cannot set a runtime", name)
+			}
+		}
+
+		if action.Code != "" {
+			if action.Location != "" {
+				validator.Report(append(keys, "code"), "action %s sets both code and location", name)
+			}
+			if action.Runtime == "" {
+				validator.Report(append(keys, "code"), "action %s has inline code and must set its runtime", name)
+			}
+		} else if action.Location == "" {
+			if action.Docker == "" {
+				validator.Report(keys, "action %s has no location set", name)
+			}
+		} else if utils.IsRemoteArtifact(action.Location) {
+			if action.Runtime == "" && action.Docker == "" && path.Ext(strings.SplitN(action.Location, "?", 2)[0]) == ".zip" {
+				validator.Report(append(keys, "runtime"), "action %s downloads a zip from %s and must set its runtime", name, action.Location)
+			}
+		} else {
+			if !utils.FileExists(path.Join(manifestDir, action.Location)) {
+				validator.Report(append(keys, "location"), "location %s of action %s does not exist", action.Location, name)
+			} else if (utils.IsJavaProject(path.Join(manifestDir, action.Location)) || path.Ext(action.Location) == ".jar") && action.Main == "" {
+				validator.Report(append(keys, "main"), "Java action %s must set main to the class of its main method", name)
+			} else if action.Build != "" && !utils.IsDirectory(path.Join(manifestDir, action.Location)) {
+				validator.Report(append(keys, "build"), "build of action %s requires its location to be a directory", name)
+			} else if action.Virtualenv != "" && !utils.FileExists(path.Join(manifestDir, action.Location, "requirements.txt")) {
+				validator.Report(append(keys, "virtualenv"), "virtualenv of action %s requires a directory with a requirements.txt", name)
+			} else if IsComposition(action.Location) {
+				if _, _, err := ReadComposition(path.Join(manifestDir, action.Location), pkg.Packagename); err != nil {
+					validator.Report(append(keys, "location"), "%s", err.Error())
+				}
+			} else if action.Runtime == "" && action.Docker == "" && !utils.IsDirectory(path.Join(manifestDir, action.Location)) {
+				if _, ok := utils.KindForExtension(path.Ext(action.Location)); !ok && !utils.IsTypeScript(action.Location) {
+					validator.Report(append(keys, "location"), "cannot infer the runtime of action %s from %s, set runtime explicitly", name, action.Location)
+				}
+			}
+		}
+
+		if action.Virtualenv != "" {
+			if _, err := utils.VirtualenvBuild(action.Virtualenv, action.Runtime, manifestDir); err != nil {
+				validator.Report(append(keys, "virtualenv"), "%s", err.Error())
+			}
+		}
+
+		switch action.Symlinks {
+		case "", "follow", "store":
+		default:
+			validator.Report(append(keys, "symlinks"), "symlinks of action %s must be follow or store", name)
+		}
+
+		if !validWebExport[strings.ToLower(action.Webexport)] {
+			validator.Report(append(keys, "web-export"), "web-export of action %s must be one of true, false, yes, no or raw", name)
+		}
+
+		if action.WebSecure != nil {
+			if !utils.IsWebExport(action.Webexport) {
+				validator.Report(append(keys, "web-secure"), "web-secure of action %s requires web-export to be enabled", name)
+			} else if _, err := utils.WebSecure(action.WebSecure, nil); err != nil {
+				validator.Report(append(keys, "web-secure"), "%s", err.Error())
+			}
+		}
+
+		if action.ExposedUrl != "" {
+			parts := strings.Split(action.ExposedUrl, "/")
+			if len(parts) != 3 || !validMethods[strings.ToLower(parts[0])] {
+				validator.Report(append(keys, "exposedUrl"), "exposedUrl %s of action %s must have the form method/basepath/relativepath", action.ExposedUrl, name)
+			}
+		}
+	}
+
+	routeMap, err := pkg.RouteMap(manifest.Filepath)
+	if err != nil {
+		validator.Report([]string{"package", "apis"}, "%s", err.Error())
+	}
+	for basePath, relPaths := range routeMap {
+		for relPath, verbs := range relPaths {
+			for verb, action := range verbs {
+				if problem := CheckApiRoute(pkg, verb, action); problem != "" {
+					validator.Report([]string{"package", "apis", basePath, relPath, verb}, "route %s %s%s: %s", verb, basePath, relPath, problem)
+				}
+			}
+		}
+	}
+
+	validator.checkCondition([]string{"package"}, "package "+pkg.Packagename, pkg.Condition)
+	for name, action := range pkg.Actions {
+		validator.checkCondition([]string{"package", "actions", name}, "action "+name, action.Condition)
+	}
+	for name, sequence := range pkg.Sequences {
+		validator.checkCondition([]string{"package", "sequences", name}, "sequence "+name, sequence.Condition)
+	}
+	for name, trigger := range pkg.Triggers {
+		validator.checkCondition([]string{"package", "triggers", name}, "trigger "+name, trigger.Condition)
+	}
+	for name, rule := range pkg.Rules {
+		validator.checkCondition([]string{"package", "rules", name}, "rule "+name, rule.Condition)
+	}
+
+	validator.checkInputs([]string{"package", "inputs"}, pkg.Inputs, manifestDir)
+	for _, name := range sortedActionNames(pkg.Actions) {
+		validator.checkInputs([]string{"package", "actions", name, "inputs"}, pkg.Actions[name].Inputs, manifestDir)
+	}
+	for name, trigger := range pkg.Triggers {
+		validator.checkInputs([]string{"package", "triggers", name, "inputs"}, trigger.Inputs, manifestDir)
+	}
+
+	for name, sequence := range pkg.Sequences {
+		for _, problem := range CheckSequence(pkg, name, sequence.Actions) {
+			validator.Report([]string{"package", "sequences", name, "actions"}, "%s", problem)
+		}
+	}
+
+	for name, trigger := range pkg.Triggers {
+		if !IsAlarmFeed(trigger.Source) {
+			continue
+		}
+		params := make(map[string]interface{})
+		for key, param := range trigger.Inputs {
+			// files are read at deploy time
+			if param.ValueFrom != "" {
+				continue
+			}
+			value, err := ResolveParameter(&param)
+			if err != nil {
+				validator.Report([]string{"package", "triggers", name, "inputs", key}, "trigger %s: %s", name, err.Error())
+				continue
+			}
+			params[key] = value
+		}
+		for _, problem := range CheckAlarmParameters(trigger.Source, params) {
+			validator.Report([]string{"package", "triggers", name, "inputs"}, "trigger %s: %s", name, problem)
+		}
+	}
+
+	for name, rule := range pkg.Rules {
+		keys := []string{"package", "rules", name}
+		if rule.Trigger == "" {
+			validator.Report(keys, "rule %s has no trigger set", name)
+		} else if _, exists := pkg.Triggers[rule.Trigger]; !exists && !strings.Contains(rule.Trigger, "/") {
+			validator.Report(append(keys, "trigger"), "rule %s references undefined trigger %s", name, rule.Trigger)
+		}
+
+		if rule.Status != "" && rule.Status != "active" && rule.Status != "inactive" {
+			validator.Report(append(keys, "status"), "status of rule %s must be active or inactive", name)
+		}
+
+		if rule.Action == "" {
+			validator.Report(keys, "rule %s has no action set", name)
+		} else if !strings.Contains(rule.Action, "/") {
+			_, isAction := pkg.Actions[rule.Action]
+			_, isSequence := pkg.Sequences[rule.Action]
+			if !isAction && !isSequence {
+				validator.Report(append(keys, "action"), "rule %s references undefined action %s", name, rule.Action)
+			}
+		}
+	}
+
+	for name, dependency := range pkg.Dependencies {
+		if !utils.LocationIsBinding(dependency.Location) && !utils.LocationIsGithub(dependency.Location) {
+			validator.Report([]string{"package", "dependencies", name, "location"}, "dependency %s must be a /whisk.system binding or a github.com package", name)
+		}
+		if _, err := DependencyRef(dependency); err != nil {
+			validator.Report([]string{"package", "dependencies", name}, "dependency %s: %s", name, err.Error())
+		}
+		if dependency.Package != "" && utils.LocationIsBinding(dependency.Location) {
+			validator.Report([]string{"package", "dependencies", name, "package"}, "dependency %s is a binding and cannot set a package", name)
+		}
+	}
+
+	sort.Sort(byPosition(validator.Violations))
+}
+
+// ifdef: names a single variable and when: must be a valid expression
+func (validator *Validator) checkCondition(keys []string, entity string, condition Condition) {
+	if strings.ContainsAny(strings.TrimSpace(condition.Ifdef), " \t") {
+		validator.Report(append(keys, "ifdef"), "ifdef of %s must name a single variable", entity)
+	}
+	if condition.When == "" {
+		return
+	}
+	if _, err := utils.EvalCondition(condition.When); err != nil {
+		validator.Report(append(keys, "when"), "%s: %s", entity, err.Error())
+	}
+}
+
+// the inputs must declare known types and valid patterns, with defaults
+// satisfying them, and take values from files of dir that exist
+func (validator *Validator) checkInputs(keys []string, inputs map[string]Parameter, dir string) {
+	for _, name := range sortedInputNames(inputs) {
+		param := inputs[name]
+		inputKeys := append(append([]string{}, keys...), name)
+		if param.ValueFrom != "" {
+			if file, ok := ValueFile(param); !ok {
+				validator.Report(append(inputKeys, "valueFrom"), "valueFrom of input %s must be a %s URL", name, ValueFileScheme)
+			} else if param.Value != nil {
+				validator.Report(append(inputKeys, "valueFrom"), "input %s sets both value and valueFrom", name)
+			} else {
+				if !path.IsAbs(file) {
+					file = path.Join(dir, file)
+				}
+				if !utils.FileExists(file) {
+					validator.Report(append(inputKeys, "valueFrom"), "valueFrom file %s of input %s does not exist", param.ValueFrom, name)
+				}
+			}
+		}
+		if !valueEncodings[param.Encoding] {
+			validator.Report(append(inputKeys, "encoding"), "encoding of input %s must be base64", name)
+		}
+		if param.Pattern != "" {
+			if _, err := InputPattern(param.Pattern); err != nil {
+				validator.Report(append(inputKeys, "pattern"), "pattern of input %s is not a valid regular expression: %s", name, err.Error())
+				continue
+			}
+		}
+		if !IsInputType(param.Type) {
+			validator.Report(append(inputKeys, "type"), "type %s of input %s must be string, integer, float, boolean or json", param.Type, name)
+		} else if param.Default != nil {
+			value, err := ResolveParameter(&Parameter{Type: param.Type, Value: param.Default})
+			if err != nil {
+				validator.Report(append(inputKeys, "default"), "default of input %s: %s", name, err.Error())
+			} else if problem := CheckInputValue(param, value); problem != "" {
+				validator.Report(append(inputKeys, "default"), "default of input %s %s", name, problem)
+			}
+		}
+	}
+}
+
+func sortedActionNames(actions map[string]Action) []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateDeployment checks a deployment file against the schema, and
+// against the manifest it configures when one is given.
+func ValidateDeployment(deploymentPath string, manifest *ManifestYAML) []Violation {
+	content, err := readDescriptor(deploymentPath)
+	if err != nil {
+		return []Violation{{File: deploymentPath, Message: err.Error()}}
+	}
+
+	validator := newValidator(deploymentPath, content)
+	deployment := DeploymentYAML{}
+	doc, ok := validator.decode(content, &deployment)
+	if !ok {
+		return validator.Violations
+	}
+
+	validator.CheckKeys(doc, reflect.TypeOf(deployment), []string{})
+
+	if manifest != nil {
+		app := deployment.Application
+		packages := app.Packages
+		prefix := []string{"application", "packages"}
+		if packages == nil {
+			packages = map[string]Package{app.Package.Packagename: app.Package}
+			prefix = []string{"application", "package"}
+		}
+
+		for key, pack := range packages {
+			keys := prefix
+			if prefix[1] == "packages" {
+				keys = append(append([]string{}, prefix...), key)
+			}
+			if pack.Packagename != "" && pack.Packagename != manifest.Package.Packagename {
+				validator.Report(keys, "package %s is not defined in the manifest", pack.Packagename)
+				continue
+			}
+			for name := range pack.Actions {
+				if _, exists := manifest.Package.Actions[name]; !exists {
+					validator.Report(append(append([]string{}, keys...), "actions", name), "action %s is not defined in the manifest", name)
+				}
+			}
+			for name := range pack.Triggers {
+				if _, exists := manifest.Package.Triggers[name]; !exists {
+					validator.Report(append(append([]string{}, keys...), "triggers", name), "trigger %s is not defined in the manifest", name)
+				}
+			}
+		}
+
+		for envName, env := range app.Environments {
+			for pkgName, pack := range env.Packages {
+				keys := []string{"application", "environments", envName, "packages", pkgName}
+				if pkgName != manifest.Package.Packagename {
+					validator.Report(keys, "package %s is not defined in the manifest", pkgName)
+					continue
+				}
+				for name := range pack.Actions {
+					if _, exists := manifest.Package.Actions[name]; !exists {
+						validator.Report(append(append([]string{}, keys...), "actions", name), "action %s is not defined in the manifest", name)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Sort(byPosition(validator.Violations))
+	return validator.Violations
+}
+
+type byPosition []Violation
+
+func (v byPosition) Len() int      { return len(v) }
+func (v byPosition) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v byPosition) Less(i, j int) bool {
+	if v[i].File != v[j].File {
+		return v[i].File < v[j].File
+	}
+	if v[i].Line != v[j].Line {
+		return v[i].Line < v[j].Line
+	}
+	if v[i].Column != v[j].Column {
+		return v[i].Column < v[j].Column
+	}
+	return v[i].Message < v[j].Message
+}
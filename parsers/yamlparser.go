@@ -18,6 +18,8 @@
 package parsers
 
 import (
+	"sort"
+
 	"github.com/openwhisk/openwhisk-client-go/whisk"
 )
 
@@ -48,34 +50,102 @@ type YAMLParser struct {
 	lastID    uint32
 }
 
+// Metadata describes an entity in the catalog UIs through the description,
+// author and docs annotations.
+type Metadata struct {
+	Description string `yaml:"description,omitempty"` //used in manifest.yaml
+	Author      string `yaml:"author,omitempty"`      //used in manifest.yaml
+	Docs        string `yaml:"docs,omitempty"`        //used in manifest.yaml
+}
+
+// Condition decides whether an entity is deployed: ifdef: names a variable
+// that must be set, when: is an expression over the variables, e.g.
+// ${ENV} == dev. An entity with both is deployed when both hold.
+type Condition struct {
+	Ifdef string `yaml:"ifdef,omitempty"`
+	When  string `yaml:"when,omitempty"`
+}
+
 type Action struct {
+	Metadata  `yaml:",inline"`
+	Condition `yaml:",inline"`
+	Foreach   interface{} `yaml:"foreach,omitempty"` //used in manifest.yaml
 	//mapping to wsk.Action.Version
-	Version  string `yaml:"version"`  //used in manifest.yaml
-	Location string `yaml:"location"` //used in manifest.yaml
-	Runtime  string `yaml:"runtime"`  //used in manifest.yaml
+	Version  string `yaml:"version,omitempty"`  //used in manifest.yaml
+	Location string `yaml:"location,omitempty"` //used in manifest.yaml
+	Runtime  string `yaml:"runtime,omitempty"`  //used in manifest.yaml
 	//mapping to wsk.Action.Namespace
-	Namespace  string                 `yaml:"namespace"`  //used in deployment.yaml
-	Credential string                 `yaml:"credential"` //used in deployment.yaml
-	Inputs     map[string]Parameter   `yaml:"inputs"`     //used in both manifest.yaml and deployment.yaml
-	Outputs    map[string]interface{} `yaml:"outputs"`    //used in manifest.yaml
+	Namespace  string                 `yaml:"namespace,omitempty"`  //used in deployment.yaml
+	Credential string                 `yaml:"credential,omitempty"` //used in deployment.yaml
+	Inputs     map[string]Parameter   `yaml:"inputs,omitempty"`     //used in both manifest.yaml and deployment.yaml
+	Outputs    map[string]interface{} `yaml:"outputs,omitempty"`    //used in manifest.yaml
 	//mapping to wsk.Action.Name
-	Name        string
+	Name        string                 `yaml:"name,omitempty"`
 	Annotations map[string]interface{} `yaml:"annotations,omitempty"`
 	//Parameters  map[string]interface{} `yaml:parameters` // used in manifest.yaml
-	ExposedUrl string `yaml:"exposedUrl"` // used in manifest.yaml
-	Webexport  string `yaml:"web-export"` // used in manifest.yaml
+	ExposedUrl string   `yaml:"exposedUrl,omitempty"` // used in manifest.yaml
+	Webexport  string   `yaml:"web-export,omitempty"` // used in manifest.yaml
+	Include    []string `yaml:"include,omitempty"`    // used in manifest.yaml
+	Exclude    []string `yaml:"exclude,omitempty"`    // used in manifest.yaml
+	Limits     *Limits  `yaml:"limits,omitempty"`     // used in manifest.yaml
+	// true, false, a token string or an integer, used in manifest.yaml
+	WebSecure interface{} `yaml:"web-secure,omitempty"`
+	// kind: sequence makes the action a sequence of the comma separated
+	// actions, as if it was listed under sequences, used in manifest.yaml
+	Kind    string `yaml:"kind,omitempty"`
+	Actions string `yaml:"actions,omitempty"`
+	// sets the conductor annotation, used in manifest.yaml
+	Conductor bool `yaml:"conductor,omitempty"`
+	// same as location, used in manifest.yaml
+	Function string `yaml:"function,omitempty"`
+	// source of the action written in the manifest instead of a location,
+	// run in its runtime, used in manifest.yaml
+	Code string `yaml:"code,omitempty"`
+	// checksum of the code downloaded from a remote location, used in manifest.yaml
+	Sha256 string `yaml:"sha256,omitempty"`
+	// Dockerfile, or build context holding one, of the image the action
+	// runs in, used in manifest.yaml
+	Docker string `yaml:"docker,omitempty"`
+	// command run in a function directory before it is zipped, none to skip
+	// installing the dependencies of its package.json or requirements.txt
+	// and compiling its tsconfig.json, used in manifest.yaml
+	Build string `yaml:"build,omitempty"`
+	// entry point of the action: the class of the main method of a Java
+	// action, the function other runtimes call instead of main, used in
+	// manifest.yaml
+	Main string `yaml:"main,omitempty"`
+	// true or docker to install the requirements.txt of a Python function
+	// directory into a virtualenv, in docker to build binary wheels in the
+	// runtime image, used in manifest.yaml
+	Virtualenv string `yaml:"virtualenv,omitempty"`
+	// follow to zip what the symlinks of a function directory point to,
+	// store to zip them as links, used in manifest.yaml
+	Symlinks string `yaml:"symlinks,omitempty"`
+}
+
+// Limits are the resource limits of an action, mapping to whisk.Limits
+type Limits struct {
+	Timeout    *int `yaml:"timeout,omitempty"`    // milliseconds
+	MemorySize *int `yaml:"memorySize,omitempty"` // megabytes
+	LogSize    *int `yaml:"logSize,omitempty"`    // megabytes
 }
 
 type Sequence struct {
-	Actions     string                 `yaml:"actions"` //used in manifest.yaml
+	Metadata    `yaml:",inline"`
+	Condition   `yaml:",inline"`
+	Actions     string                 `yaml:"actions,omitempty"` //used in manifest.yaml
 	Annotations map[string]interface{} `yaml:"annotations,omitempty"`
+	Foreach     interface{}            `yaml:"foreach,omitempty"` //used in manifest.yaml
 }
 
 type Dependency struct {
-	Version     string                 `yaml: "version, omitempty"`
-	Location    string                 `yaml: "location, omitempty"`
-	Inputs      map[string]Parameter   `yaml:"inputs"`
-	Annotations map[string]interface{} `yaml:"annotations"`
+	Version     string                 `yaml:"version,omitempty"`
+	Branch      string                 `yaml:"branch,omitempty"`
+	Tag         string                 `yaml:"tag,omitempty"`
+	Location    string                 `yaml:"location,omitempty"`
+	Package     string                 `yaml:"package,omitempty"` // deploy a github project into this package
+	Inputs      map[string]Parameter   `yaml:"inputs,omitempty"`
+	Annotations map[string]interface{} `yaml:"annotations,omitempty"`
 }
 
 type Parameter struct {
@@ -86,19 +156,31 @@ type Parameter struct {
 	Default     interface{} `yaml:"default,omitempty"`
 	Status      string      `yaml:"status,omitempty"`
 	Schema      interface{} `yaml:"schema,omitempty"`
+	// the values the input accepts
+	Enum []interface{} `yaml:"enum,omitempty"`
+	// regular expression string values must match as a whole
+	Pattern string `yaml:"pattern,omitempty"`
+	// file://path of the file the value is read from at deploy time,
+	// relative to the file declaring the input
+	ValueFrom string `yaml:"valueFrom,omitempty"`
+	// base64 to bind the content of the valueFrom file base64 encoded
+	Encoding string `yaml:"encoding,omitempty"`
 }
 
 type Trigger struct {
+	Metadata  `yaml:",inline"`
+	Condition `yaml:",inline"`
+	Foreach   interface{} `yaml:"foreach,omitempty"` //used in manifest.yaml
 	//mapping to ????
-	Feed string `yaml:"feed"` //used in manifest.yaml
+	Feed string `yaml:"feed,omitempty"` //used in manifest.yaml
 	//mapping to wsk.Trigger.Namespace
-	Namespace  string               `yaml:"namespace"`  //used in deployment.yaml
-	Credential string               `yaml:"credential"` //used in deployment.yaml
-	Inputs     map[string]Parameter `yaml:"inputs"`     //used in deployment.yaml
+	Namespace  string               `yaml:"namespace,omitempty"`  //used in deployment.yaml
+	Credential string               `yaml:"credential,omitempty"` //used in deployment.yaml
+	Inputs     map[string]Parameter `yaml:"inputs,omitempty"`     //used in deployment.yaml
 	//mapping to wsk.Trigger.Name
-	Name        string
+	Name        string                 `yaml:"name,omitempty"`
 	Annotations map[string]interface{} `yaml:"annotations,omitempty"`
-	Source      string                 `yaml:source` // used in manifest.yaml
+	Source      string                 `yaml:"source,omitempty"` // used in manifest.yaml
 	//Parameters  map[string]interface{} `yaml:parameters` // used in manifest.yaml
 }
 
@@ -114,13 +196,19 @@ type Feed struct {
 }
 
 type Rule struct {
+	Metadata  `yaml:",inline"`
+	Condition `yaml:",inline"`
+	Foreach   interface{} `yaml:"foreach,omitempty"` //used in manifest.yaml
 	//mapping to wsk.Rule.Trigger
-	Trigger string `yaml:"trigger"` //used in manifest.yaml
+	Trigger string `yaml:"trigger,omitempty"` //used in manifest.yaml
 	//mapping to wsk.Rule.Action
-	Action string `yaml:"action"` //used in manifest.yaml
-	Rule   string `yaml:"rule"`   //used in manifest.yaml
+	Action string `yaml:"action,omitempty"` //used in manifest.yaml
+	Rule   string `yaml:"rule,omitempty"`   //used in manifest.yaml
 	//mapping to wsk.Rule.Name
-	Name string
+	Name string `yaml:"name,omitempty"`
+	// active or inactive, active by default, used in manifest.yaml
+	Status      string                 `yaml:"status,omitempty"`
+	Annotations map[string]interface{} `yaml:"annotations,omitempty"` //used in manifest.yaml
 }
 
 type Repository struct {
@@ -130,46 +218,85 @@ type Repository struct {
 }
 
 type Package struct {
+	Metadata  `yaml:",inline"`
+	Condition `yaml:",inline"`
 	//mapping to wsk.SentPackageNoPublish.Name
-	Packagename string `yaml:"name"` //used in manifest.yaml
+	Packagename string `yaml:"name,omitempty"` //used in manifest.yaml
 	//mapping to wsk.SentPackageNoPublish.Version
-	Version           string                `yaml:"version"` //used in manifest.yaml
-	License           string                `yaml:"license"` //used in manifest.yaml
+	Version           string                `yaml:"version,omitempty"` //used in manifest.yaml
+	License           string                `yaml:"license,omitempty"` //used in manifest.yaml
 	Repositories      []Repository          `yaml:"repositories,omitempty"`
-	Dependencies      map[string]Dependency `yaml: dependencies`        // used in manifest.yaml
-	Function          string                `yaml:"function"`           //used in deployment.yaml
-	PackageCredential string                `yaml:"package_credential"` //used in deployment.yaml
+	Dependencies      map[string]Dependency `yaml:"dependencies,omitempty"`       // used in manifest.yaml
+	Function          string                `yaml:"function,omitempty"`           //used in deployment.yaml
+	PackageCredential string                `yaml:"package_credential,omitempty"` //used in deployment.yaml
 	//mapping to wsk.SentPackageNoPublish.Namespace
-	Namespace   string                 `yaml:"namespace"`  //used in deployment.yaml
-	Credential  string                 `yaml:"credential"` //used in deployment.yaml
-	Actions     map[string]Action      `yaml:"actions"`    //used in both manifest.yaml and deployment.yaml
-	Triggers    map[string]Trigger     `yaml:"triggers"`   //used in both manifest.yaml and deployment.yaml
-	Feeds       map[string]Feed        `yaml:"feeds"`      //used in both manifest.yaml and deployment.yaml
-	Rules       map[string]Rule        `yaml:"rules"`      //used in both manifest.yaml and deployment.yaml
-	Inputs      map[string]Parameter   `yaml:"inputs"`     //used in deployment.yaml
-	Sequences   map[string]Sequence    `yaml:"sequences"`
+	Namespace   string                 `yaml:"namespace,omitempty"`  //used in deployment.yaml
+	Credential  string                 `yaml:"credential,omitempty"` //used in deployment.yaml
+	Actions     map[string]Action      `yaml:"actions,omitempty"`    //used in both manifest.yaml and deployment.yaml
+	Triggers    map[string]Trigger     `yaml:"triggers,omitempty"`   //used in both manifest.yaml and deployment.yaml
+	Feeds       map[string]Feed        `yaml:"feeds,omitempty"`      //used in both manifest.yaml and deployment.yaml
+	Rules       map[string]Rule        `yaml:"rules,omitempty"`      //used in both manifest.yaml and deployment.yaml
+	Inputs      map[string]Parameter   `yaml:"inputs,omitempty"`     //used in deployment.yaml
+	Sequences   map[string]Sequence    `yaml:"sequences,omitempty"`
 	Annotations map[string]interface{} `yaml:"annotations,omitempty"`
+	Apis        Apis                   `yaml:"apis,omitempty"`
 	//Parameters  map[string]interface{} `yaml: parameters` // used in manifest.yaml
 }
 
+// Apis is the apis block of a package: either routes mapping base path ->
+// relative path -> verb -> action, or the path of a swagger or OpenAPI
+// document defining them.
+type Apis struct {
+	Routes   map[string]map[string]map[string]string
+	Document string
+}
+
 type Application struct {
-	Name       string             `yaml:"name"`      //used in deployment.yaml
-	Namespace  string             `yaml:"namespace"` //used in deployment.yaml
-	Credential string             `yaml:"credential"`
-	BaseUrl    string             `yaml:"baseUrl"`
-	Version    string             `yaml:"version"`
-	Packages   map[string]Package `yaml:"packages"` //used in deployment.yaml
-	Package    Package            `yaml:"package"`
+	Name       string             `yaml:"name,omitempty"`      //used in deployment.yaml
+	Namespace  string             `yaml:"namespace,omitempty"` //used in deployment.yaml
+	Credential string             `yaml:"credential,omitempty"`
+	BaseUrl    string             `yaml:"baseUrl,omitempty"`
+	Version    string             `yaml:"version,omitempty"`
+	Packages   map[string]Package `yaml:"packages,omitempty"` //used in deployment.yaml
+	Package    Package            `yaml:"package,omitempty"`
+	// named overrides selected with --env, used in deployment.yaml
+	Environments map[string]Environment `yaml:"environments,omitempty"`
+	// OpenWhisk installations to deploy to in one run, used in deployment.yaml
+	Targets []Target `yaml:"targets,omitempty"`
+}
+
+// Target is one OpenWhisk installation the application is deployed to.
+type Target struct {
+	Name       string `yaml:"name,omitempty"`
+	ApiHost    string `yaml:"apihost,omitempty"`
+	Namespace  string `yaml:"namespace,omitempty"`
+	Credential string `yaml:"credential,omitempty"`
+	// PEM files of the client certificate and its key, for an API behind mTLS
+	Cert string `yaml:"cert,omitempty"`
+	Key  string `yaml:"key,omitempty"`
+}
+
+// Environment overrides the credentials, namespace and inputs of an
+// application for one target, e.g. dev, staging or prod.
+type Environment struct {
+	Namespace  string               `yaml:"namespace,omitempty"`
+	Credential string               `yaml:"credential,omitempty"`
+	BaseUrl    string               `yaml:"baseUrl,omitempty"`
+	Inputs     map[string]Parameter `yaml:"inputs,omitempty"` // applied to every package
+	Packages   map[string]Package   `yaml:"packages,omitempty"`
 }
 
 type DeploymentYAML struct {
 	Application Application `yaml:"application"` //used in deployment.yaml
-	Filepath    string      //file path of the yaml file
+	Filepath    string      `yaml:"-"`           //file path of the yaml file
 }
 
 type ManifestYAML struct {
-	Package  Package `yaml:"package"` //used in both manifest.yaml and deployment.yaml
-	Filepath string  //file path of the yaml file
+	// the manifest layout, see CurrentSchemaVersion
+	SchemaVersion string   `yaml:"schema-version,omitempty"`
+	Package       Package  `yaml:"package"`           //used in both manifest.yaml and deployment.yaml
+	Include       []string `yaml:"include,omitempty"` //used in manifest.yaml
+	Filepath      string   `yaml:"-"`                 //file path of the yaml file
 }
 
 //********************Trigger functions*************************//
@@ -185,7 +312,7 @@ func (trigger *Trigger) ComposeWskTrigger(kvarr []whisk.KeyValue) *whisk.Trigger
 }
 
 //********************Rule functions*************************//
-func (rule *Rule) ComposeWskRule() *whisk.Rule {
+func (rule *Rule) ComposeWskRule() (*whisk.Rule, error) {
 	wskrule := new(whisk.Rule)
 	wskrule.Name = rule.Name
 	//wskrule.Namespace = rule.Namespace
@@ -194,7 +321,13 @@ func (rule *Rule) ComposeWskRule() *whisk.Rule {
 	wskrule.Trigger = rule.Trigger
 
 	wskrule.Action = rule.Action
-	return wskrule
+	wskrule.Status = rule.Status
+	annotations, err := composeAnnotations(rule.Metadata, rule.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	wskrule.Annotations = annotations
+	return wskrule, nil
 }
 
 //********************Package functions*************************//
@@ -217,18 +350,34 @@ func (pkg *Package) GetActionList() []Action {
 	return s1
 }
 
+// GetTriggerList returns the triggers of the package, sorted by name.
 func (pkg *Package) GetTriggerList() []Trigger {
+	names := make([]string, 0, len(pkg.Triggers))
+	for trigger_name := range pkg.Triggers {
+		names = append(names, trigger_name)
+	}
+	sort.Strings(names)
+
 	var s1 []Trigger = make([]Trigger, 0)
-	for trigger_name, trigger := range pkg.Triggers {
+	for _, trigger_name := range names {
+		trigger := pkg.Triggers[trigger_name]
 		trigger.Name = trigger_name
 		s1 = append(s1, trigger)
 	}
 	return s1
 }
 
+// GetRuleList returns the rules of the package, sorted by name.
 func (pkg *Package) GetRuleList() []Rule {
+	names := make([]string, 0, len(pkg.Rules))
+	for rule_name := range pkg.Rules {
+		names = append(names, rule_name)
+	}
+	sort.Strings(names)
+
 	var s1 []Rule = make([]Rule, 0)
-	for rule_name, rule := range pkg.Rules {
+	for _, rule_name := range names {
+		rule := pkg.Rules[rule_name]
 		rule.Name = rule_name
 		s1 = append(s1, rule)
 	}
@@ -151,3 +151,16 @@ func TestRootCommand(t *testing.T) {
 	checkValidAuthInfo(t, expected_auth_flags)
 	checkValidInputInfo(t, expected_input)
 }
+
+func TestPlanCommand(t *testing.T) {
+	utils.Flags.Preview = false
+	defer func() { utils.Flags.Preview = false }()
+
+	output := fullSetupTest("plan -p fake_plan_path -m fake_plan_manifest")
+
+	assert.True(t, rootcalled, "Plan should run the deployment.")
+	assert.Nil(t, output.Error)
+	assert.True(t, utils.Flags.Preview, "Plan should only preview the deployment.")
+	assert.Equal(t, "fake_plan_path", cmdImp.ProjectPath, "ProjectPath does not match.")
+	assert.Equal(t, "fake_plan_manifest", cmdImp.ManifestPath, "ManifestPath does not match.")
+}
@@ -0,0 +1,76 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmd"
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/stretchr/testify/assert"
+)
+
+const completedManifest = `package:
+  name: demo
+  actions:
+    hello:
+      function: src/hello.js
+  sequences:
+    greet:
+      actions: hello
+  triggers:
+    every-minute:
+      feed: /whisk.system/alarms/alarm
+  rules:
+    hourly:
+      trigger: every-minute
+      action: greet
+`
+
+func TestManifestNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-completion")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(completedManifest), 0644))
+
+	expected := map[string][]string{
+		cmdImp.CompletePackages: {"demo"},
+		cmdImp.CompleteActions:  {"greet", "hello"},
+		cmdImp.CompleteTriggers: {"every-minute"},
+		cmdImp.CompleteRules:    {"hourly"},
+	}
+	for kind, names := range expected {
+		completed, err := cmdImp.ManifestNames(dir, "", kind)
+		assert.Nil(t, err)
+		assert.Equal(t, names, completed, "Wrong "+kind+" completed")
+	}
+
+	_, err = cmdImp.ManifestNames(dir, "", "apis")
+	assert.NotNil(t, err)
+	_, err = cmdImp.ManifestNames(filepath.Join(dir, "missing"), "", cmdImp.CompleteActions)
+	assert.NotNil(t, err)
+}
+
+func TestGenCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var script bytes.Buffer
+		assert.Nil(t, cmd.GenCompletion(&script, shell))
+		assert.Contains(t, script.String(), "__wskdeploy_manifest_names", "No manifest names completed in "+shell)
+	}
+
+	var script bytes.Buffer
+	assert.Nil(t, cmd.GenCompletion(&script, "bash"))
+	assert.Contains(t, script.String(), "wskdeploy_export)\n            __wskdeploy_manifest_names packages")
+	assert.Contains(t, script.String(), "__wskdeploy_manifest_names actions")
+
+	script.Reset()
+	assert.Nil(t, cmd.GenCompletion(&script, "fish"))
+	assert.Contains(t, script.String(), "complete -c wskdeploy -n '__fish_seen_subcommand_from rule' -l action -x -a '(__wskdeploy_manifest_names actions)'")
+	assert.NotContains(t, script.String(), "__manifest_names -d")
+
+	assert.NotNil(t, cmd.GenCompletion(&script, "tcsh"))
+}
@@ -0,0 +1,105 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/stretchr/testify/assert"
+)
+
+const serverlessProject = `service: weather
+
+provider:
+  name: openwhisk
+  runtime: nodejs:6
+  namespace: forecasts
+  timeout: 30
+
+functions:
+  forecast:
+    handler: src/forecast.fetch
+    memory: 256
+    parameters:
+      units: metric
+      days: 3
+    events:
+      - http: GET /weather/forecast
+      - schedule: cron(0 6 * * *)
+  report:
+    handler: src/report.main
+    events:
+      - trigger: daily
+      - cloudant:
+          db: reports
+  daily_report:
+    sequence:
+      - forecast
+      - report
+
+resources:
+  triggers:
+    daily:
+      feed: /whisk.system/alarms/alarm
+      feed_parameters:
+        cron: "0 7 * * *"
+`
+
+func TestConvertServerless(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-convert")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, parsers.ServerlessFileName), []byte(serverlessProject), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+	for _, name := range []string{"forecast.js", "report.js"} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "src", name), []byte("function main() { return {}; }"), 0644))
+	}
+
+	var output bytes.Buffer
+	assert.Nil(t, cmdImp.Convert(&output, cmdImp.ConvertParams{From: cmdImp.ConvertFromServerless, Path: dir}))
+	manifestPath := filepath.Join(dir, "manifest.yaml")
+	deploymentPath := filepath.Join(dir, "deployment.yaml")
+	assert.Equal(t, "Warning: cloudant event of function report is not converted, bind it to a trigger of the manifest\n"+
+		"Service weather converted to "+manifestPath+" and "+deploymentPath+"\n", output.String())
+
+	manifest, violations := parsers.ValidateManifest(manifestPath)
+	assert.Equal(t, 0, len(violations), "The converted manifest should validate.")
+	pkg := manifest.Package
+	assert.Equal(t, "weather", pkg.Packagename)
+
+	forecast := pkg.Actions["forecast"]
+	assert.Equal(t, "src/forecast.js", forecast.Location)
+	assert.Equal(t, "nodejs:6", forecast.Runtime)
+	assert.Equal(t, "fetch", forecast.Main, "The function of the handler should be the main of the action.")
+	assert.Equal(t, "true", forecast.Webexport, "Routed actions should be web actions.")
+	assert.Equal(t, 256, *forecast.Limits.MemorySize)
+	assert.Equal(t, 30000, *forecast.Limits.Timeout, "Timeouts should convert to milliseconds.")
+	assert.Equal(t, parsers.Parameter{Type: "string"}, forecast.Inputs["units"])
+	assert.Equal(t, parsers.Parameter{Type: "integer"}, forecast.Inputs["days"])
+	assert.Equal(t, "", pkg.Actions["report"].Main)
+	assert.Equal(t, "forecast, report", pkg.Sequences["daily_report"].Actions)
+	assert.Equal(t, "forecast", pkg.Apis.Routes["/weather"]["/forecast"]["get"])
+
+	assert.Equal(t, "/whisk.system/alarms/alarm", pkg.Triggers["weather_forecast_schedule_trigger"].Source)
+	assert.Equal(t, "0 6 * * *", pkg.Triggers["weather_forecast_schedule_trigger"].Inputs["cron"].Value)
+	assert.Equal(t, "0 7 * * *", pkg.Triggers["daily"].Inputs["cron"].Value)
+	assert.Equal(t, parsers.Rule{Trigger: "weather_forecast_schedule_trigger", Action: "forecast"}, pkg.Rules["weather_forecast_schedule_rule"])
+	assert.Equal(t, "daily", pkg.Rules["weather_daily_to_report"].Trigger)
+
+	deployment, _ := parsers.NewYAMLParser().ParseDeployment(deploymentPath)
+	assert.Equal(t, "forecasts", deployment.Application.Namespace)
+	assert.Equal(t, "weather", deployment.Application.Package.Packagename)
+	assert.Equal(t, "metric", deployment.Application.Package.Actions["forecast"].Inputs["units"].Value)
+	assert.Equal(t, 3, deployment.Application.Package.Actions["forecast"].Inputs["days"].Value)
+
+	err = cmdImp.Convert(&output, cmdImp.ConvertParams{From: cmdImp.ConvertFromServerless, Path: dir})
+	assert.Equal(t, "manifest file "+manifestPath+" already exists", err.Error(), "Convert should not overwrite the manifest.")
+	err = cmdImp.Convert(&output, cmdImp.ConvertParams{From: "sam", Path: dir})
+	assert.Equal(t, "Unsupported project format sam, use serverless", err.Error())
+}
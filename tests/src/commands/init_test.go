@@ -0,0 +1,70 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitProject(t *testing.T) {
+	for _, runtime := range cmdImp.InitRuntimeNames() {
+		dir, err := ioutil.TempDir("", "wskdeploy-init")
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+
+		params := cmdImp.InitParams{ProjectPath: dir, Name: "demo", Version: "0.0.1", License: "Apache-2.0", Runtime: runtime, Deployment: true}
+		assert.Nil(t, cmdImp.InitProject(params), "Init failed for runtime "+runtime)
+
+		manifest, violations := parsers.ValidateManifest(filepath.Join(dir, "manifest.yaml"))
+		assert.Empty(t, violations, "Invalid manifest for runtime "+runtime)
+		assert.Equal(t, "demo", manifest.Package.Packagename)
+		hello, ok := manifest.Package.Actions["hello"]
+		assert.True(t, ok, "No hello action for runtime "+runtime)
+		_, err = os.Stat(filepath.Join(dir, hello.Function))
+		assert.Nil(t, err, "No code for runtime "+runtime)
+
+		violations = parsers.ValidateDeployment(filepath.Join(dir, "deployment.yaml"), manifest)
+		assert.Empty(t, violations, "Invalid deployment for runtime "+runtime)
+	}
+}
+
+func TestInitProject_KeepsExistingFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-init")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	manifest := "package:\n  name: old\n  actions:\n    mine:\n      function: src/mine.js\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0644))
+
+	params := cmdImp.InitParams{ProjectPath: dir, Name: "renamed", Version: "1.0.0", License: "MIT", Runtime: "nodejs"}
+	assert.Nil(t, cmdImp.InitProject(params))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "manifest.yaml"))
+	assert.Nil(t, err)
+	updated := parsers.ManifestYAML{}
+	assert.Nil(t, parsers.NewYAMLParser().Unmarshal(content, &updated))
+	assert.Equal(t, "renamed", updated.Package.Packagename)
+	assert.Equal(t, "1.0.0", updated.Package.Version)
+	assert.Equal(t, []string{"mine"}, keysOf(updated.Package.Actions))
+	_, err = os.Stat(filepath.Join(dir, "src", "hello.js"))
+	assert.True(t, os.IsNotExist(err), "Init should not add a hello action to a manifest with actions")
+	_, err = os.Stat(filepath.Join(dir, "deployment.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NotNil(t, cmdImp.InitProject(cmdImp.InitParams{ProjectPath: dir, Runtime: "cobol"}))
+}
+
+func keysOf(actions map[string]parsers.Action) []string {
+	keys := make([]string, 0, len(actions))
+	for key := range actions {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,79 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/stretchr/testify/assert"
+)
+
+const lintedManifest = `package:
+  name: demo
+  inputs:
+    region: us-south
+  actions:
+    hello:
+      function: hello.js
+      runtime: nodejs:8
+      inputs:
+        name: world
+        greeting: hello
+    orphan:
+      function: missing.js
+      limits:
+        memorySize: 2048
+  rules:
+    hourly:
+      trigger: tick
+      action: hello
+`
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-lint")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	manifestPath := filepath.Join(dir, "manifest.yaml")
+	assert.Nil(t, ioutil.WriteFile(manifestPath, []byte(lintedManifest), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "hello.js"), []byte("function main(params) { return {msg: 'hi ' + params.name}; }"), 0644))
+
+	findings, err := parsers.LintManifest(manifestPath)
+	assert.Nil(t, err)
+	lines := make([]string, 0)
+	for _, finding := range findings {
+		lines = append(lines, finding.String())
+	}
+	assert.Equal(t, []string{
+		manifestPath + ":8:7: warning: runtime nodejs:8 of action hello is deprecated, move to nodejs:default [deprecated-runtime]",
+		manifestPath + ":11:9: warning: input greeting of action hello is not mentioned in its code [unused-input]",
+		manifestPath + ":12:5: info: action orphan is not used by a rule, sequence or API and is not a web action [unreferenced-action]",
+		manifestPath + ":13:7: error: file missing.js of action orphan does not exist [missing-file]",
+		manifestPath + ":15:9: warning: action orphan asks for 2048 MB of memory, more than 512 MB is rarely needed and is billed [large-memory]",
+	}, lines, "The package input cannot be told unused while the code of orphan is missing.")
+
+	var output bytes.Buffer
+	err = cmdImp.Lint(&output, cmdImp.LintParams{ProjectPath: dir, Output: cmdImp.LintJSON})
+	assert.Equal(t, "1 finding(s) of severity error or above", err.Error())
+	decoded := make([]parsers.LintFinding, 0)
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &decoded), "Findings should be printed as JSON.")
+	assert.Equal(t, findings, decoded)
+
+	assert.Nil(t, ioutil.WriteFile(manifestPath, []byte(strings.Replace(lintedManifest, "missing.js", "hello.js", 1)), 0644))
+	output.Reset()
+	err = cmdImp.Lint(&output, cmdImp.LintParams{ProjectPath: dir})
+	assert.Nil(t, err, "Warnings should not fail lint by default.")
+	assert.Contains(t, output.String(), "input region of package demo is not mentioned in the code of its actions [unused-input]")
+
+	err = cmdImp.Lint(&output, cmdImp.LintParams{ProjectPath: dir, FailOn: "warning"})
+	assert.Equal(t, "4 finding(s) of severity warning or above", err.Error())
+	err = cmdImp.Lint(&output, cmdImp.LintParams{ProjectPath: dir, FailOn: "fatal"})
+	assert.NotNil(t, err)
+}
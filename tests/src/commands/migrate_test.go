@@ -0,0 +1,93 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/cmdImp"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/stretchr/testify/assert"
+)
+
+const legacyManifest = `# the bot of the team
+package:
+  name: whiskbot
+  actions:
+    fire:
+      location: actions/fire.js
+      exposedUrl: post/whiskbot/slack-event
+    status:
+      location: actions/status.js # answers /status
+      web-export: true
+  triggers:
+    Every12Hours:
+      feed: /whisk.system/github/webhook
+  apis:
+    /whiskbot:
+      /status:
+        get: status
+`
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-migrate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	manifestPath := filepath.Join(dir, "manifest.yaml")
+	assert.Nil(t, ioutil.WriteFile(manifestPath, []byte(legacyManifest), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "actions"), 0755))
+	for _, name := range []string{"fire.js", "status.js"} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "actions", name), []byte("function main() { return {}; }"), 0644))
+	}
+
+	var output bytes.Buffer
+	assert.Nil(t, cmdImp.Migrate(&output, cmdImp.MigrateParams{ProjectPath: dir, DryRun: true}))
+	content, _ := ioutil.ReadFile(manifestPath)
+	assert.Equal(t, legacyManifest, string(content), "A dry run should not write the manifest.")
+	assert.Equal(t, `# the bot of the team
+schema-version: 1
+package:
+  name: whiskbot
+  actions:
+    fire:
+      location: actions/fire.js
+      web-export: true
+    status:
+      location: actions/status.js # answers /status
+      web-export: true
+  triggers:
+    Every12Hours:
+      source: /whisk.system/github/webhook
+  apis:
+    /whiskbot:
+      /slack-event:
+        post: fire
+      /status:
+        get: status
+`, output.String())
+
+	output.Reset()
+	assert.Nil(t, cmdImp.Migrate(&output, cmdImp.MigrateParams{ProjectPath: dir}))
+	assert.Equal(t, manifestPath+": triggers.Every12Hours: feed renamed to source\n"+
+		manifestPath+": actions.fire: web-export set to true, routed actions are web actions\n"+
+		manifestPath+": actions.fire: exposedUrl moved to apis as post /whiskbot/slack-event\n"+
+		manifestPath+": schema-version set to 1\n", output.String())
+	manifest, violations := parsers.ValidateManifest(manifestPath)
+	assert.Equal(t, 0, len(violations), "The migrated manifest should validate.")
+	assert.Equal(t, "/whisk.system/github/webhook", manifest.Package.Triggers["Every12Hours"].Source)
+
+	output.Reset()
+	assert.Nil(t, cmdImp.Migrate(&output, cmdImp.MigrateParams{ProjectPath: dir}))
+	assert.Equal(t, manifestPath+" is at schema-version 1 already\n", output.String())
+
+	assert.Nil(t, ioutil.WriteFile(manifestPath, []byte("schema-version: 7\npackage:\n  name: future\n"), 0644))
+	err = cmdImp.Migrate(&output, cmdImp.MigrateParams{ProjectPath: dir})
+	assert.Equal(t, "Manifest "+manifestPath+" has schema-version 7, newer than version 1 this wskdeploy reads, upgrade wskdeploy", err.Error())
+	_, violations = parsers.ValidateManifest(manifestPath)
+	assert.Equal(t, 1, len(violations))
+	assert.Equal(t, 1, violations[0].Line, "Newer manifests should be reported at their schema-version.")
+}
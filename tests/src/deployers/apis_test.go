@@ -0,0 +1,60 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeployApis(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.PutRoute("GET", "/hello", "/old", "pkg/old")
+	fake.PutRoute("GET", "/other", "/path", "other")
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Client, deployer.ClientConfig = fake.Client()
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+	deployer.Deployment.Packages["pkg"] = pack
+
+	routes := []utils.ApiRoute{
+		{BasePath: "/hello", RelPath: "/world", Verb: "GET", ActionName: "hello", Packagename: "pkg"},
+		{BasePath: "/hello", RelPath: "/world", Verb: "POST", ActionName: "hello", Packagename: "pkg"},
+	}
+	err := deployers.NewManfiestReader(deployer).SetApis(deployer, routes)
+	assert.Nil(t, err, "Setting the routes failed.")
+
+	err = deployer.Deploy()
+	assert.Nil(t, err, "Deploying the routes failed.")
+	assert.Equal(t, []string{"GET /hello/world pkg/hello", "GET /other/path other", "POST /hello/world pkg/hello"}, fake.Routes(),
+		"Routes should be created and stale routes of the base path removed.")
+
+	err = deployer.UnDeployApis(deployer.Deployment)
+	assert.Nil(t, err, "Undeploying the routes failed.")
+	assert.Equal(t, []string{"GET /other/path other"}, fake.Routes(), "Only the routes of the deployment should be removed.")
+}
+
+func TestSetApis_Conflict(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.Client, deployer.ClientConfig = fake.Client()
+
+	routes := []utils.ApiRoute{
+		{BasePath: "/hello", RelPath: "/world", Verb: "GET", ActionName: "hello", Packagename: "pkg"},
+		{BasePath: "/hello", RelPath: "/world", Verb: "GET", ActionName: "goodbye", Packagename: "pkg"},
+	}
+	err := deployers.NewManfiestReader(deployer).SetApis(deployer, routes)
+	assert.NotNil(t, err, "A route bound to two actions should be rejected.")
+}
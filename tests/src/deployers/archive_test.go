@@ -0,0 +1,67 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotArchive(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "pkg"+deployers.SnapshotArchiveSuffix)
+
+	deployer := newRuleDeployer(fake, "")
+	deployer.ClientConfig.Namespace = "staging"
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "staging"}
+	pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+	pack.Actions["hello"].Action.Namespace = "staging"
+	deployer.Deployment.Packages["pkg"] = pack
+	routes := []utils.ApiRoute{{BasePath: "/hello", RelPath: "/world", Verb: "GET", ActionName: "hello", Packagename: "pkg"}}
+	assert.Nil(t, deployers.NewManfiestReader(deployer).SetApis(deployer, routes))
+
+	snapshot, err := deployer.Snapshot()
+	assert.Nil(t, err)
+	assert.Nil(t, deployers.WriteSnapshotArchive(file, snapshot, ""))
+
+	restored, err := deployers.ReadSnapshotArchive(file)
+	assert.Nil(t, err, "Reading the archive failed.")
+	code := restored.Deployment.Packages["pkg"].Actions["hello"].Action.Exec.Code
+	assert.Equal(t, "function main() {}", *code, "The code should be read back from the artifacts of the archive.")
+
+	restored.Retarget("prod.example.com", "prod")
+	assert.Equal(t, "prod", restored.Namespace)
+	assert.Equal(t, "prod", restored.Deployment.Packages["pkg"].Package.Namespace)
+	assert.Equal(t, "prod", restored.Deployment.Packages["pkg"].Actions["hello"].Action.Namespace)
+	api := restored.Deployment.Apis["GET /hello/world"].ApiDoc
+	assert.Equal(t, "https://prod.example.com/api/v1/namespaces/prod/actions/pkg/hello", api.Action.BackendUrl, "Routes should call the actions of the new namespace.")
+	assert.Equal(t, "API:prod:/hello", api.Id)
+
+	restorer := deployers.NewServiceDeployer()
+	restorer.IsInteractive = false
+	restorer.Client, restorer.ClientConfig = fake.Client()
+	restorer.ClientConfig.Namespace = "prod"
+	assert.Nil(t, restorer.UseSnapshot(restored))
+	assert.Nil(t, restorer.Deploy())
+	action, _ := fake.Get("actions/pkg/hello")
+	assert.Equal(t, "function main() {}", action["exec"].(map[string]interface{})["code"])
+	rule, _ := fake.Get("rules/tickRule")
+	assert.Equal(t, "/prod/pkg/hello", rule["action"])
+
+	snapshot, _ = deployer.Snapshot()
+	snapshot.Hashes["pkg/hello"] = "tampered"
+	assert.Nil(t, deployers.WriteSnapshotArchive(file, snapshot, ""))
+	_, err = deployers.ReadSnapshotArchive(file)
+	assert.NotNil(t, err, "Code that does not match the snapshot should be rejected.")
+}
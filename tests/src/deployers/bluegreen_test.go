@@ -0,0 +1,83 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBlueGreenDeployer(fake *fakeWhisk, code string) *deployers.ServiceDeployer {
+	deployer := newRuleDeployer(fake, "")
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", code)
+	deployer.Deployment.Packages["pkg"] = pack
+	routes := []utils.ApiRoute{{BasePath: "/hello", RelPath: "/world", Verb: "GET", ActionName: "hello", Packagename: "pkg"}}
+	deployers.NewManfiestReader(deployer).SetApis(deployer, routes)
+	return deployer
+}
+
+func TestDeploy_BlueGreen(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	defer func() {
+		utils.Flags.Strategy, utils.Flags.Verify = "", nil
+		utils.Flags.Promote, utils.Flags.Abort = false, false
+	}()
+
+	utils.Flags.Strategy = deployers.StrategyBlueGreen
+	utils.Flags.Verify = []string{"hello"}
+	assert.Nil(t, newBlueGreenDeployer(fake, "v1").Deploy())
+	_, exists := fake.Get("actions/pkg-v1/hello")
+	assert.True(t, exists, "The first version should be deployed as pkg-v1.")
+	assert.Equal(t, 1, len(fake.invocations["actions/pkg-v1/hello"]), "The --verify action should be invoked.")
+	_, exists = fake.Get("rules/tickRule")
+	assert.False(t, exists, "Rules should wait for the version to be promoted.")
+	assert.Equal(t, []string{}, fake.Routes(), "Routes should wait for the version to be promoted.")
+
+	utils.Flags.Promote = true
+	assert.Nil(t, newBlueGreenDeployer(fake, "v1").Deploy())
+	binding, _ := fake.Get("packages/pkg")
+	assert.Equal(t, "pkg-v1", binding["binding"].(map[string]interface{})["name"], "The package binding should point at the promoted version.")
+	rule, _ := fake.Get("rules/tickRule")
+	assert.Equal(t, "/guest/pkg-v1/hello", rule["action"])
+	assert.Equal(t, []string{"GET /hello/world pkg-v1/hello"}, fake.Routes())
+	assert.NotNil(t, newBlueGreenDeployer(fake, "v1").Deploy(), "Nothing should be left to promote.")
+	utils.Flags.Promote = false
+
+	utils.Flags.Verify = nil
+	assert.Nil(t, newBlueGreenDeployer(fake, "v2").Deploy())
+	_, exists = fake.Get("actions/pkg-v2/hello")
+	assert.True(t, exists, "The next version should be deployed as pkg-v2.")
+	assert.Equal(t, []string{"GET /hello/world pkg-v1/hello"}, fake.Routes(), "The live version should keep the routes.")
+
+	utils.Flags.Abort = true
+	assert.Nil(t, newBlueGreenDeployer(fake, "v2").Deploy())
+	_, exists = fake.Get("packages/pkg-v2")
+	assert.False(t, exists, "Abort should remove the pending version.")
+	_, exists = fake.Get("actions/pkg-v2/hello")
+	assert.False(t, exists, "Abort should remove the pending version.")
+	_, exists = fake.Get("actions/pkg-v1/hello")
+	assert.True(t, exists, "Abort should leave the live version.")
+	utils.Flags.Abort = false
+
+	utils.Flags.Strategy = "canary"
+	assert.NotNil(t, newBlueGreenDeployer(fake, "v2").Deploy(), "Unknown strategies should be rejected.")
+}
+
+func TestDeploy_BlueGreenInPlacePackage(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg", "annotations": managedBy("pkg")})
+	utils.Flags.Strategy = deployers.StrategyBlueGreen
+	defer func() { utils.Flags.Strategy = "" }()
+
+	err := newBlueGreenDeployer(fake, "v1").Deploy()
+	assert.NotNil(t, err, "A package deployed in place cannot become a binding.")
+	assert.Contains(t, err.Error(), "deployed in place")
+}
@@ -0,0 +1,28 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionHash(t *testing.T) {
+	first := newCodeAction("hello", "function main() {}").Action
+	first.Parameters = whisk.KeyValueArr{{Key: "name", Value: "Amy"}, {Key: "place", Value: "Paris"}}
+	second := newCodeAction("hello", "function main() {}").Action
+	second.Parameters = whisk.KeyValueArr{{Key: "place", Value: "Paris"}, {Key: "name", Value: "Amy"}}
+	changed := newCodeAction("hello", "function main() { return {} }").Action
+	changed.Parameters = whisk.KeyValueArr{{Key: "name", Value: "Amy"}, {Key: "place", Value: "Paris"}}
+
+	assert.Equal(t, deployers.ActionHash(first), deployers.ActionHash(second), "Parameter order should not change the hash.")
+	assert.NotEqual(t, deployers.ActionHash(first), deployers.ActionHash(changed), "Changed code should change the hash.")
+
+	hash := deployers.ActionHash(first)
+	first.Annotations = deployers.SetManagedValue(first.Annotations, "hash", hash)
+	assert.Equal(t, hash, deployers.ManagedValue(first.Annotations, "hash"), "Hash should be stored in the managed annotation.")
+	assert.Equal(t, hash, deployers.ActionHash(first), "The managed annotation should not be part of the hash.")
+}
@@ -0,0 +1,73 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_GithubDependency(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	// a dependency already cloned at branch feature/x
+	projectPath, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(projectPath)
+	depPath := utils.DependencyPath(projectPath, "helper", "feature/x")
+	os.MkdirAll(depPath, os.ModePerm)
+	ioutil.WriteFile(filepath.Join(depPath, "hello.js"), []byte("function main() {}"), 0644)
+	ioutil.WriteFile(filepath.Join(depPath, deployers.ManifestFileNameYml), []byte(`package:
+  name: helperpkg
+  actions:
+    hello:
+      location: hello.js
+`), 0644)
+
+	fake.Put("actions/tools/other", map[string]interface{}{"name": "other", "annotations": []interface{}{map[string]interface{}{
+		"key":   deployers.ManagedAnnotation,
+		"value": map[string]interface{}{"projectName": "pkg", "dependency": "helper"},
+	}}})
+	fake.Put("actions/tools/stale", map[string]interface{}{"name": "stale", "annotations": managedBy("pkg")})
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Dependencies["helper"] = utils.DependencyRecord{
+		ProjectPath: projectPath,
+		Packagename: "pkg",
+		Location:    "https://github.com/example/helper",
+		Version:     "feature/x",
+		Package:     "tools",
+	}
+	deployer.Deployment.Packages["pkg"] = pack
+
+	utils.Flags.Sync = true
+	defer func() { utils.Flags.Sync = false }()
+
+	err = deployer.Deploy()
+	assert.Nil(t, err, "Deploying the dependency should succeed.")
+
+	assert.Equal(t, []string{"actions/tools/hello", "actions/tools/other", "packages/pkg", "packages/tools"}, fake.Keys(),
+		"The dependency should be deployed into its package, and sync should leave its entities alone.")
+
+	hello, _ := fake.Get("actions/tools/hello")
+	var managed map[string]interface{}
+	for _, annotation := range hello["annotations"].([]interface{}) {
+		kv := annotation.(map[string]interface{})
+		if kv["key"] == deployers.ManagedAnnotation {
+			managed = kv["value"].(map[string]interface{})
+		}
+	}
+	assert.Equal(t, "pkg", managed["projectName"], "The dependency should be managed by the project.")
+	assert.Equal(t, "helper", managed["dependency"], "The dependency should be recorded.")
+}
@@ -4,6 +4,7 @@ package tests
 
 import (
 	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -31,6 +32,11 @@ func TestDeployerCheck(t *testing.T) {
 	sd := deployers.NewServiceDeployer()
 	sd.DeploymentPath = "../../../tests/usecases/badyaml/deployment.yaml"
 	sd.ManifestPath = "../../../tests/usecases/badyaml/manifest.yaml"
-	// The system will exit thus the test will fail.
-	// sd.Check()
+	err := sd.Check()
+	assert.NotNil(t, err, "Bad yaml should be reported.")
+	errs, ok := err.(utils.Errors)
+	assert.True(t, ok, "The errors of both files should be reported together.")
+	assert.Equal(t, 2, len(errs))
+	assert.Contains(t, errs[0].Error(), "badyaml/deployment.yaml")
+	assert.Equal(t, utils.ExitInvalid, utils.Cause(errs[1]).(*utils.ExitError).Code)
 }
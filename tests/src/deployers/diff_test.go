@@ -0,0 +1,51 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_Diff(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	hello := newCodeAction("hello", "function main() {}")
+	hello.Action.Parameters = whisk.KeyValueArr{{Key: "name", Value: "world"}}
+	pack.Actions["hello"] = hello
+	deployer.Deployment.Packages["pkg"] = pack
+
+	assert.Nil(t, deployer.Deploy(), "Deployment should succeed.")
+
+	utils.Flags.Diff = true
+	defer func() { utils.Flags.Diff = false }()
+	assert.Nil(t, deployer.Deploy(), "A fresh deployment should not drift.")
+
+	// someone edits the action by hand
+	deployed, _ := fake.Get("actions/pkg/hello")
+	deployed["parameters"] = []interface{}{map[string]interface{}{"key": "name", "value": "moon"}}
+	deployed["exec"] = map[string]interface{}{"kind": "nodejs:6", "code": "function main() { return {} }"}
+	memory := 512
+	hello.Action.Limits = &whisk.Limits{Memory: &memory}
+
+	assert.Equal(t, deployers.ErrDriftDetected, deployer.Deploy(), "Drift should be reported as an error.")
+
+	plan, err := deployer.DeploymentPlan()
+	assert.Nil(t, err)
+	drift := plan.Drift()
+	assert.Equal(t, 1, len(drift), "Only the edited action should drift.")
+	assert.Equal(t, "pkg/hello", drift[0].Name)
+	assert.Equal(t, 3, len(drift[0].Details), "Code hash, limit and parameter should differ.")
+	assert.Contains(t, drift[0].Details[0], "code hash: ")
+	assert.Equal(t, "limit memory: default -> 512", drift[0].Details[1])
+	assert.Equal(t, "parameter name changed", drift[0].Details[2])
+}
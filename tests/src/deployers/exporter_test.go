@@ -0,0 +1,136 @@
+// +build unit
+
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporter_Export(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg",
+		"parameters": []interface{}{map[string]interface{}{"key": "greeting", "value": "hello"}}})
+	fake.Put("actions/pkg/hello", map[string]interface{}{"name": "hello",
+		"exec":        map[string]interface{}{"kind": "nodejs:6", "code": "function main() {}"},
+		"annotations": append(managedBy("pkg"), map[string]interface{}{"key": "web-export", "value": true})})
+	fake.Put("actions/pkg/chain", map[string]interface{}{"name": "chain",
+		"exec": map[string]interface{}{"kind": "sequence", "components": []interface{}{"/guest/pkg/hello", "/guest/utils/echo"}}})
+	fake.Put("triggers/everyMinute", map[string]interface{}{"name": "everyMinute",
+		"annotations": []interface{}{map[string]interface{}{"key": "feed", "value": "/whisk.system/alarms/alarm"}}})
+	fake.Put("rules/helloRule", map[string]interface{}{"name": "helloRule",
+		"trigger": map[string]interface{}{"path": "guest", "name": "everyMinute"},
+		"action":  map[string]interface{}{"path": "guest/pkg", "name": "hello"}})
+	fake.Put("rules/otherRule", map[string]interface{}{"name": "otherRule",
+		"trigger": map[string]interface{}{"path": "guest", "name": "everyMinute"},
+		"action":  map[string]interface{}{"path": "guest/other", "name": "hello"}})
+
+	dir, err := ioutil.TempDir("", "export")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	client, _ := fake.Client()
+	manifest, err := deployers.NewExporter(client).Export("pkg", dir)
+	assert.Nil(t, err, "Export should succeed.")
+
+	assert.Equal(t, "hello", manifest.Package.Inputs["greeting"].Value, "Package parameters should be exported.")
+
+	hello := manifest.Package.Actions["hello"]
+	assert.Equal(t, "nodejs:6", hello.Runtime, "Runtime should be the action kind.")
+	assert.Equal(t, "actions/hello.js", hello.Location, "Code should be written under actions/.")
+	assert.Equal(t, "true", hello.Webexport, "web-export annotation should become the web-export key.")
+	assert.Nil(t, hello.Annotations, "System annotations should not be exported.")
+
+	code, err := ioutil.ReadFile(path.Join(dir, "actions", "hello.js"))
+	assert.Nil(t, err, "Action code should be written.")
+	assert.Equal(t, "function main() {}", string(code))
+
+	assert.Equal(t, "hello, utils/echo", manifest.Package.Sequences["chain"].Actions, "Components should be relative to the package.")
+	assert.Equal(t, 1, len(manifest.Package.Rules), "Only rules firing actions of the package should be exported.")
+	assert.Equal(t, "hello", manifest.Package.Rules["helloRule"].Action)
+	assert.Equal(t, "/whisk.system/alarms/alarm", manifest.Package.Triggers["everyMinute"].Source, "Feed annotation should become the source key.")
+}
+
+func TestExporter_RoundTrip(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg"})
+	fake.Put("actions/pkg/hello", map[string]interface{}{"name": "hello",
+		"exec": map[string]interface{}{"kind": "nodejs:6", "code": "function main() {}"}})
+	fake.Put("triggers/everyMinute", map[string]interface{}{"name": "everyMinute",
+		"annotations": []interface{}{map[string]interface{}{"key": "feed", "value": "/whisk.system/alarms/alarm"}}})
+	fake.Put("rules/helloRule", map[string]interface{}{"name": "helloRule",
+		"trigger": map[string]interface{}{"path": "guest", "name": "everyMinute"},
+		"action":  map[string]interface{}{"path": "guest/pkg", "name": "hello"}})
+
+	dir, err := ioutil.TempDir("", "roundtrip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	client, _ := fake.Client()
+	manifest, err := deployers.NewExporter(client).Export("pkg", dir)
+	assert.Nil(t, err, "Export should succeed.")
+	manifestPath := path.Join(dir, "manifest.yaml")
+	assert.Nil(t, parsers.Write(manifest, manifestPath))
+
+	// the exported manifest deploys the trigger with its feed again
+	mm := parsers.NewYAMLParser()
+	exported, err := mm.ParseManifest(manifestPath)
+	assert.Nil(t, err, "The exported manifest should parse.")
+	triggers, err := mm.ComposeTriggers(exported)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(triggers))
+	feed, isFeed := utils.IsFeedAction(triggers[0])
+	assert.True(t, isFeed, "The exported trigger should keep its feed.")
+	assert.Equal(t, "/whisk.system/alarms/alarm", feed)
+}
+
+func TestExporter_ExportApis(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg", "version": "0.2.0"})
+	fake.PutRoute("GET", "/hello", "/world", "pkg/hello")
+	fake.PutRoute("POST", "/hello", "/world", "pkg/hello")
+	fake.PutRoute("GET", "/other", "/path", "other/hello")
+
+	client, _ := fake.Client()
+	doc, err := deployers.NewExporter(client).ExportApis("pkg")
+	assert.Nil(t, err, "Exporting the APIs should succeed.")
+
+	assert.Equal(t, "3.0.0", doc.OpenApi)
+	assert.Equal(t, "0.2.0", doc.Info.Version, "The package version should be the API version.")
+	assert.Equal(t, "/hello", doc.Servers[0].Url, "A single base path should be the server url.")
+	assert.Equal(t, 1, len(doc.Paths), "Only routes of the package should be exported.")
+	assert.Equal(t, "hello", doc.Paths["/world"]["get"].XOpenWhisk.Action, "Operations should name their action.")
+	assert.Equal(t, "pkg", doc.Paths["/world"]["post"].XOpenWhisk.Package, "Operations should name the package.")
+
+	// the document can be referenced from the apis block of a manifest
+	dir, err := ioutil.TempDir("", "exportapis")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fake.PutRoute("GET", "/bye", "/now", "pkg/hello")
+	doc, err = deployers.NewExporter(client).ExportApis("pkg")
+	assert.Nil(t, err, "Exporting the APIs should succeed.")
+	assert.Equal(t, "/", doc.Servers[0].Url, "Several base paths should be kept in the paths.")
+
+	content, _ := json.Marshal(doc)
+	docPath := path.Join(dir, deployers.ExportApiFileName)
+	ioutil.WriteFile(docPath, content, 0644)
+	routes, err := parsers.ReadApiDocument(docPath, "pkg")
+	assert.Nil(t, err, "The exported document should be readable.")
+	assert.Equal(t, "hello", routes["/hello"]["/world"]["post"])
+	assert.Equal(t, "hello", routes["/bye"]["/now"]["get"])
+}
@@ -0,0 +1,312 @@
+// +build unit
+
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// fakeWhisk is an in-memory OpenWhisk API serving packages, actions,
+// triggers and rules of a single namespace, and the routes of its
+// API Gateway.
+type fakeWhisk struct {
+	mu       sync.Mutex
+	server   *httptest.Server
+	entities map[string]map[string]interface{} // keyed by "collection/name"
+	failPut  map[string]bool                   // keys whose PUT fails with 400
+	limits   map[string]int                    // limits reported at /api/v1
+	runtimes map[string]interface{}            // runtimes reported at /api/v1
+	apis     map[string]map[string]string      // base path -> "VERB relpath" -> action
+	requests []string
+	// bodies of the action invocations, keyed by "actions/package/name"
+	invocations map[string][]map[string]interface{}
+	// feed lifecycle events answered with 400
+	rejectEvents map[string]bool
+}
+
+func newFakeWhisk() *fakeWhisk {
+	fake := &fakeWhisk{
+		entities: make(map[string]map[string]interface{}),
+		failPut:  make(map[string]bool),
+		apis:     make(map[string]map[string]string),
+
+		invocations:  make(map[string][]map[string]interface{}),
+		rejectEvents: make(map[string]bool),
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	return fake
+}
+
+func (fake *fakeWhisk) Close() {
+	fake.server.Close()
+}
+
+// Client returns a whisk client talking to the fake server
+func (fake *fakeWhisk) Client() (*whisk.Client, *whisk.Config) {
+	baseURL, _ := url.Parse(fake.server.URL + "/api")
+	config := &whisk.Config{
+		AuthToken: "user:pass",
+		Namespace: "guest",
+		Host:      strings.TrimPrefix(fake.server.URL, "http://"),
+		BaseURL:   baseURL,
+		Version:   "v1",
+	}
+	client, err := whisk.NewClient(&http.Client{}, config)
+	if err != nil {
+		panic(err)
+	}
+	return client, config
+}
+
+func (fake *fakeWhisk) Put(key string, entity map[string]interface{}) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.entities[key] = entity
+}
+
+func (fake *fakeWhisk) Get(key string) (map[string]interface{}, bool) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	entity, ok := fake.entities[key]
+	return entity, ok
+}
+
+func (fake *fakeWhisk) Keys() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	keys := make([]string, 0, len(fake.entities))
+	for key := range fake.entities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (fake *fakeWhisk) PutRoute(verb string, basePath string, relPath string, action string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.putRoute(verb, basePath, relPath, action)
+}
+
+func (fake *fakeWhisk) putRoute(verb string, basePath string, relPath string, action string) {
+	if fake.apis[basePath] == nil {
+		fake.apis[basePath] = make(map[string]string)
+	}
+	fake.apis[basePath][verb+" "+relPath] = action
+}
+
+// Routes lists the routes as "VERB /basepath/relpath action"
+func (fake *fakeWhisk) Routes() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	routes := make([]string, 0)
+	for basePath, operations := range fake.apis {
+		for operation, action := range operations {
+			parts := strings.SplitN(operation, " ", 2)
+			routes = append(routes, parts[0]+" "+basePath+parts[1]+" "+action)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func (fake *fakeWhisk) handle(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	if strings.HasSuffix(r.URL.Path, "/api/v1") {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"limits": fake.limits, "runtimes": fake.runtimes})
+		return
+	}
+
+	if strings.Contains(r.URL.Path, "/web/whisk.system/apimgmt/") {
+		fake.handleApi(w, r)
+		return
+	}
+
+	route := r.URL.Path[strings.Index(r.URL.Path, "/namespaces/")+len("/namespaces/"):]
+	route = route[strings.Index(route, "/")+1:] // drop the namespace
+	fake.requests = append(fake.requests, r.Method+" "+route)
+
+	// listing: "actions", "actions/pkg/", "triggers", ...
+	if r.Method == "GET" && (!strings.Contains(route, "/") || strings.HasSuffix(route, "/")) {
+		parts := strings.SplitN(route, "/", 2)
+		prefix := parts[0] + "/"
+		if len(parts) == 2 {
+			prefix += parts[1]
+		}
+		list := make([]map[string]interface{}, 0)
+		for _, key := range sortedEntityKeys(fake.entities) {
+			rest := strings.TrimPrefix(key, prefix)
+			if strings.HasPrefix(key, prefix) && !strings.Contains(rest, "/") {
+				list = append(list, fake.entities[key])
+			}
+		}
+		// pages of the listing, as OpenWhisk returns them
+		if skip, err := strconv.Atoi(r.URL.Query().Get("skip")); err == nil {
+			if skip > len(list) {
+				skip = len(list)
+			}
+			list = list[skip:]
+		}
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < len(list) {
+			list = list[:limit]
+		}
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	key := strings.TrimSuffix(route, "/")
+	switch r.Method {
+	case "GET":
+		if entity, ok := fake.entities[key]; ok {
+			writeJSON(w, http.StatusOK, entity)
+		} else {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "The requested resource does not exist.", "code": 1})
+		}
+	case "PUT":
+		if fake.failPut[key] {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "The request content was malformed.", "code": 2})
+			return
+		}
+		entity := make(map[string]interface{})
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &entity)
+		name := key[strings.LastIndex(key, "/")+1:]
+		entity["name"] = name
+		if _, exists := fake.entities[key]; !exists || entity["status"] == nil {
+			if strings.HasPrefix(key, "rules/") {
+				entity["status"] = "active"
+			}
+		}
+		fake.entities[key] = entity
+		writeJSON(w, http.StatusOK, entity)
+	case "DELETE":
+		if entity, ok := fake.entities[key]; ok {
+			delete(fake.entities, key)
+			writeJSON(w, http.StatusOK, entity)
+		} else {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "The requested resource does not exist.", "code": 1})
+		}
+	case "POST":
+		// rule state changes and action invocations
+		if entity, ok := fake.entities[key]; ok && strings.HasPrefix(key, "rules/") {
+			state := make(map[string]interface{})
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &state)
+			entity["status"] = state["status"]
+			writeJSON(w, http.StatusOK, entity)
+			return
+		}
+		if strings.HasPrefix(key, "actions/") {
+			params := make(map[string]interface{})
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &params)
+			fake.invocations[key] = append(fake.invocations[key], params)
+			if event, ok := params["lifecycleEvent"].(string); ok && fake.rejectEvents[event] {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unsupported lifecycle event " + event, "code": 3})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+// Events lists the lifecycle events the feed action was invoked with
+func (fake *fakeWhisk) Events(feed string) []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	events := make([]string, 0)
+	for _, params := range fake.invocations[feed] {
+		events = append(events, params["lifecycleEvent"].(string))
+	}
+	return events
+}
+
+// the apimgmt actions of the API Gateway
+func (fake *fakeWhisk) handleApi(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	basePath := query.Get("basepath")
+	switch path.Base(r.URL.Path) {
+	case "createApi.http":
+		request := whisk.ApiCreateRequest{}
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+		api := request.ApiDoc
+		fake.putRoute(api.GatewayMethod, api.GatewayBasePath, api.GatewayRelPath, api.Action.Name)
+		fake.requests = append(fake.requests, "POST api "+api.GatewayMethod+" "+api.GatewayBasePath+api.GatewayRelPath)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"apidoc": fake.swagger(api.GatewayBasePath)})
+	case "getApi.http":
+		// without a base path every api is listed
+		items := make([]interface{}, 0)
+		for _, base := range sortedBasePaths(fake.apis) {
+			if basePath == "" || basePath == base {
+				items = append(items, map[string]interface{}{
+					"id":    "API:guest:" + base,
+					"value": map[string]interface{}{"gwApiUrl": fake.server.URL + "/api/guest" + base, "apidoc": fake.swagger(base)},
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"apis": items})
+	case "deleteApi.http":
+		delete(fake.apis[basePath], query.Get("operation")+" "+query.Get("relpath"))
+		if len(fake.apis[basePath]) == 0 {
+			delete(fake.apis, basePath)
+		}
+		fake.requests = append(fake.requests, "DELETE api "+query.Get("operation")+" "+basePath+query.Get("relpath"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func sortedBasePaths(apis map[string]map[string]string) []string {
+	basePaths := make([]string, 0, len(apis))
+	for basePath := range apis {
+		basePaths = append(basePaths, basePath)
+	}
+	sort.Strings(basePaths)
+	return basePaths
+}
+
+func (fake *fakeWhisk) swagger(basePath string) map[string]interface{} {
+	paths := make(map[string]interface{})
+	for operation, action := range fake.apis[basePath] {
+		parts := strings.SplitN(operation, " ", 2)
+		relPath := parts[1]
+		operations, ok := paths[relPath].(map[string]interface{})
+		if !ok {
+			operations = make(map[string]interface{})
+			paths[relPath] = operations
+		}
+		operations[strings.ToLower(parts[0])] = map[string]interface{}{
+			"operationId": action,
+			"x-openwhisk": map[string]interface{}{"action": action, "namespace": "guest", "url": fake.server.URL + "/api/v1/namespaces/guest/actions/" + action},
+		}
+	}
+	return map[string]interface{}{"swagger": "2.0", "basePath": basePath, "paths": paths}
+}
+
+func sortedEntityKeys(entities map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(entities))
+	for key := range entities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
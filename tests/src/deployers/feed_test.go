@@ -0,0 +1,82 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFeedDeployer(fake *fakeWhisk, cron string) *deployers.ServiceDeployer {
+	deployer := newFakeDeployer(fake)
+	deployer.Deployment.Triggers["everyMinute"] = &whisk.Trigger{
+		Name:        "everyMinute",
+		Annotations: whisk.KeyValueArr{{Key: "feed", Value: "/whisk.system/alarms/alarm"}},
+		Parameters:  whisk.KeyValueArr{{Key: "cron", Value: cron}},
+	}
+	return deployer
+}
+
+func TestDeploy_FeedLifecycle(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	feed := "actions/alarms/alarm"
+
+	deployer := newFeedDeployer(fake, "* * * * *")
+	assert.Nil(t, deployer.Deploy())
+	assert.Equal(t, []string{"CREATE"}, fake.Events(feed), "The feed should be created with the trigger.")
+	params := fake.invocations[feed][0]
+	assert.Equal(t, "* * * * *", params["cron"], "The feed parameters should be passed on create.")
+	assert.Equal(t, "/guest/everyMinute", params["triggerName"])
+
+	assert.Nil(t, newFeedDeployer(fake, "* * * * *").Deploy())
+	assert.Equal(t, []string{"CREATE"}, fake.Events(feed), "Unchanged feed parameters should not invoke the feed.")
+
+	assert.Nil(t, newFeedDeployer(fake, "0 * * * *").Deploy())
+	assert.Equal(t, []string{"CREATE", "UPDATE"}, fake.Events(feed), "Changed feed parameters should update the feed.")
+	assert.Equal(t, "0 * * * *", fake.invocations[feed][1]["cron"])
+
+	fake.rejectEvents["UPDATE"] = true
+	assert.Nil(t, newFeedDeployer(fake, "0 0 * * *").Deploy())
+	assert.Equal(t, []string{"CREATE", "UPDATE", "UPDATE", "DELETE", "CREATE"}, fake.Events(feed), "Feeds without updates should be created again.")
+	assert.Equal(t, "0 0 * * *", fake.invocations[feed][4]["cron"])
+
+	undeployer := newFeedDeployer(fake, "0 0 * * *")
+	assert.Nil(t, undeployer.UnDeploy(undeployer.Deployment))
+	assert.Equal(t, "DELETE", fake.Events(feed)[5], "Undeploying should delete the feed.")
+	_, exists := fake.Get("triggers/everyMinute")
+	assert.False(t, exists, "The trigger should be deleted.")
+}
+
+func TestDeploy_InvalidCron(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := newFeedDeployer(fake, "* * * 13 *")
+	err := deployer.Deploy()
+	assert.NotNil(t, err, "An invalid cron expression should fail the deployment.")
+	assert.Contains(t, err.Error(), "trigger everyMinute: cron: expression \"* * * 13 *\": month must be between 1 and 12")
+	assert.Equal(t, []string{}, fake.Keys(), "Nothing should be deployed.")
+	assert.Equal(t, []string{}, fake.Events("actions/alarms/alarm"), "The feed should not be invoked.")
+}
+
+func TestDeploy_MalformedFeedName(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := newFeedDeployer(fake, "* * * * *")
+	deployer.Deployment.Triggers["everyMinute"].Annotations = whisk.KeyValueArr{{Key: "feed", Value: "/whisk.system//alarm"}}
+	err := deployer.Deploy()
+	assert.NotNil(t, err, "A malformed feed name should fail the deployment.")
+	assert.Contains(t, err.Error(), "A valid qualified name was not detected")
+
+	fake.Put("triggers/everyMinute", map[string]interface{}{"name": "everyMinute", "namespace": "guest"})
+	undeployer := newFeedDeployer(fake, "* * * * *")
+	undeployer.Deployment.Triggers["everyMinute"].Annotations = whisk.KeyValueArr{{Key: "feed", Value: "/whisk.system//alarm"}}
+	assert.Nil(t, undeployer.UnDeploy(undeployer.Deployment), "A malformed feed name should not stop the undeployment.")
+	_, exists := fake.Get("triggers/everyMinute")
+	assert.False(t, exists, "The trigger should be deleted.")
+}
@@ -0,0 +1,25 @@
+// +build unit
+
+package tests
+
+import (
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+)
+
+// a non-interactive deployer of the pkg project talking to the fake server
+func newFakeDeployer(fake *fakeWhisk) *deployers.ServiceDeployer {
+	deployer := newFakeDeployer(fake)
+	return deployer
+}
+
+// a nodejs action of package pkg running code
+func newCodeAction(name string, code string) utils.ActionRecord {
+	action := new(whisk.Action)
+	action.Name = name
+	action.Exec = new(whisk.Exec)
+	action.Exec.Kind = "nodejs:6"
+	action.Exec.Code = &code
+	return utils.ActionRecord{action, "pkg", name + ".js"}
+}
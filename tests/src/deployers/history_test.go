@@ -0,0 +1,63 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_HistoryRollback(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	dir, _ := ioutil.TempDir("", "history")
+	defer os.RemoveAll(dir)
+
+	newDeployer := func(code string) *deployers.ServiceDeployer {
+		deployer := deployers.NewServiceDeployer()
+		deployer.IsInteractive = false
+		deployer.RootPackageName = "pkg"
+		deployer.HistoryDir = dir
+		deployer.Client, deployer.ClientConfig = fake.Client()
+		pack := deployers.NewDeploymentPackage()
+		pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+		pack.Actions["hello"] = newCodeAction("hello", code)
+		deployer.Deployment.Packages["pkg"] = pack
+		return deployer
+	}
+
+	assert.Nil(t, newDeployer("function main() { return {v: 1} }").Deploy())
+	assert.Nil(t, newDeployer("function main() { return {v: 2} }").Deploy())
+
+	history, err := deployers.ReadHistory(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(history), "Each successful deployment should be recorded.")
+	assert.Equal(t, []int{1, 2}, []int{history[0].Version, history[1].Version})
+	assert.Equal(t, "pkg", history[1].Package)
+	assert.NotEmpty(t, history[1].Hashes["pkg/hello"], "The code hash of each action should be recorded.")
+
+	snapshot, err := deployers.RollbackSnapshot(history, "guest", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, snapshot.Version, "Rollback should default to the deployment before the last.")
+	_, err = deployers.RollbackSnapshot(history, "other", 0)
+	assert.NotNil(t, err, "Deployments to other namespaces should not be rolled back to.")
+	_, err = deployers.RollbackSnapshot(history, "guest", 5)
+	assert.NotNil(t, err)
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.HistoryDir = dir
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	assert.Nil(t, deployer.UseSnapshot(snapshot))
+	assert.Nil(t, deployer.Deploy())
+
+	action, _ := fake.Get("actions/pkg/hello")
+	assert.Equal(t, "function main() { return {v: 1} }", action["exec"].(map[string]interface{})["code"], "The code of the snapshot should be deployed again.")
+	history, _ = deployers.ReadHistory(dir)
+	assert.Equal(t, 3, len(history), "A rollback is a deployment too.")
+}
@@ -0,0 +1,84 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_RollbackOnFailure(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.Put("actions/pkg/existing", map[string]interface{}{
+		"name": "existing", "namespace": "guest/pkg",
+		"exec": map[string]interface{}{"kind": "nodejs:6", "code": "original"},
+	})
+	fake.failPut["actions/pkg/broken"] = true
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Concurrency = 1
+	deployer.Client, deployer.ClientConfig = fake.Client()
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["existing"] = newCodeAction("existing", "changed")
+	pack.Actions["broken"] = newCodeAction("broken", "broken")
+	pack.Actions["fresh"] = newCodeAction("fresh", "fresh")
+	deployer.Deployment.Packages["pkg"] = pack
+
+	utils.Flags.RollbackOnFailure = true
+	defer func() { utils.Flags.RollbackOnFailure = false }()
+
+	err := deployer.Deploy()
+	assert.NotNil(t, err, "Deployment should fail.")
+
+	assert.Equal(t, []string{"actions/pkg/existing"}, fake.Keys(), "Only the entity deployed before the run should remain.")
+	existing, _ := fake.Get("actions/pkg/existing")
+	assert.Equal(t, "original", existing["exec"].(map[string]interface{})["code"], "Updated action should be restored.")
+}
+
+func newRollbackDeployer(fake *fakeWhisk) *deployers.ServiceDeployer {
+	deployer := newFakeDeployer(fake)
+	deployer.Concurrency = 1
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", "hello")
+	deployer.Deployment.Packages["pkg"] = pack
+	deployer.Deployment.Triggers["tick"] = &whisk.Trigger{Name: "tick"}
+	deployer.Deployment.Rules["tickRule"] = &whisk.Rule{Name: "tickRule", Trigger: "tick", Action: "hello"}
+	return deployer
+}
+
+func TestDeploy_RollbackOnTriggerFailure(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.failPut["triggers/tick"] = true
+
+	utils.Flags.RollbackOnFailure = true
+	defer func() { utils.Flags.RollbackOnFailure = false }()
+
+	err := newRollbackDeployer(fake).Deploy()
+	assert.NotNil(t, err, "A failed trigger should fail the deployment.")
+	assert.Empty(t, fake.Keys(), "The package and action deployed before the trigger should be rolled back.")
+}
+
+func TestDeploy_RollbackOnRuleFailure(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.failPut["rules/tickRule"] = true
+
+	utils.Flags.RollbackOnFailure = true
+	defer func() { utils.Flags.RollbackOnFailure = false }()
+
+	err := newRollbackDeployer(fake).Deploy()
+	assert.NotNil(t, err, "A failed rule should fail the deployment.")
+	assert.Empty(t, fake.Keys(), "The entities deployed before the rule should be rolled back.")
+}
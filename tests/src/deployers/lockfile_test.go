@@ -0,0 +1,132 @@
+// +build unit
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// serves the commits API and the zipballs of a single github repository
+type fakeGithub struct {
+	server     *httptest.Server
+	head       string
+	downloaded []string
+	// token the repository is private to, when set
+	token string
+}
+
+func newFakeGithub(head string) *fakeGithub {
+	fake := &fakeGithub{head: head}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fake.token != "" && r.Header.Get("Authorization") != "token "+fake.token {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/example/helper/commits/"):
+			w.Write([]byte(fake.head))
+		case strings.HasPrefix(r.URL.Path, "/repos/example/helper/zipball/"):
+			commit := strings.TrimPrefix(r.URL.Path, "/repos/example/helper/zipball/")
+			fake.downloaded = append(fake.downloaded, commit)
+			buf := new(bytes.Buffer)
+			archive := zip.NewWriter(buf)
+			archive.Create("example-helper-" + commit + "/")
+			file, _ := archive.Create("example-helper-" + commit + "/manifest.yaml")
+			file.Write([]byte("package:\n  name: helper\n"))
+			archive.Close()
+			w.Write(buf.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return fake
+}
+
+func (fake *fakeGithub) resolve(projectPath string) error {
+	deployer := deployers.NewServiceDeployer()
+	deployer.ProjectPath = projectPath
+	pack := deployers.NewDeploymentPackage()
+	deployer.Deployment.Packages["pkg"] = pack
+
+	return deployers.NewManfiestReader(deployer).SetDependencies(map[string]utils.DependencyRecord{
+		"helper": {
+			ProjectPath: path.Join(projectPath, "Packages"),
+			Packagename: "pkg",
+			Location:    fake.server.URL + "/example/helper",
+			Version:     "master",
+		},
+	})
+}
+
+func TestSetDependencies_LockFile(t *testing.T) {
+	fake := newFakeGithub("1111")
+	defer fake.server.Close()
+	utils.GithubApiUrl = fake.server.URL
+	defer func() { utils.GithubApiUrl = "https://api.github.com" }()
+
+	projectPath, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(projectPath)
+	lockPath := path.Join(projectPath, utils.LockFileName)
+
+	assert.Nil(t, fake.resolve(projectPath), "Resolving the dependency should succeed.")
+	lock, err := utils.ReadLockFile(lockPath)
+	assert.Nil(t, err)
+	assert.Equal(t, "1111", lock.Dependencies["helper"].Commit, "The commit of the branch should be locked.")
+	assert.NotEmpty(t, lock.Dependencies["helper"].Sha256, "The checksum of the code should be locked.")
+	assert.True(t, utils.FileExists(path.Join(projectPath, "Packages", "helper-master", "manifest.yaml")), "The dependency should be unpacked.")
+
+	fake.head = "2222"
+	assert.Nil(t, fake.resolve(projectPath), "Resolving the dependency again should succeed.")
+	assert.Equal(t, []string{"1111", "1111"}, fake.downloaded, "The locked commit should be used while the branch moves.")
+
+	utils.Flags.UpdateDeps = true
+	err = fake.resolve(projectPath)
+	utils.Flags.UpdateDeps = false
+	assert.Nil(t, err, "Updating the dependency should succeed.")
+	lock, _ = utils.ReadLockFile(lockPath)
+	assert.Equal(t, "2222", lock.Dependencies["helper"].Commit, "--update-deps should lock the new commit.")
+
+	locked := lock.Dependencies["helper"]
+	locked.Sha256 = "0000"
+	lock.Lock("helper", locked)
+	assert.Nil(t, lock.Save())
+	assert.NotNil(t, fake.resolve(projectPath), "Code not matching the locked checksum should be rejected.")
+}
+
+func TestSetDependencies_PrivateToken(t *testing.T) {
+	fake := newFakeGithub("1111")
+	fake.token = "secret"
+	defer fake.server.Close()
+	utils.GithubApiUrl = fake.server.URL
+	defer func() { utils.GithubApiUrl = "https://api.github.com" }()
+
+	projectPath, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(projectPath)
+
+	defer os.Setenv(utils.GithubTokenEnv, os.Getenv(utils.GithubTokenEnv))
+	os.Unsetenv(utils.GithubTokenEnv)
+	err = fake.resolve(projectPath)
+	assert.Contains(t, err.Error(), "set $"+utils.GithubTokenEnv, "A private repository should suggest setting a token.")
+
+	os.Setenv(utils.GithubTokenEnv, "wrong")
+	err = fake.resolve(projectPath)
+	assert.Contains(t, err.Error(), "Authentication failed", "A rejected token should be reported as such.")
+
+	os.Setenv(utils.GithubTokenEnv, "secret")
+	assert.Nil(t, fake.resolve(projectPath), "The token should give access to the private repository.")
+	assert.Equal(t, []string{"1111"}, fake.downloaded, "The archive should be downloaded with the token.")
+}
@@ -19,7 +19,7 @@ func init() {
 	sd.ManifestPath = manifest_file
 	mr = deployers.NewManfiestReader(sd)
 	ps = parsers.NewYAMLParser()
-	ms = ps.ParseManifest(manifest_file)
+	ms, _ = ps.ParseManifest(manifest_file)
 }
 
 // Test could parse Manifest file successfully
@@ -36,7 +36,7 @@ func TestManifestReader_InitRootPackage(t *testing.T) {
 
 // Test Parameters
 func TestManifestReader_param(t *testing.T) {
-	ms := ps.ParseManifest("../../dat/manifest6.yaml")
+	ms, _ := ps.ParseManifest("../../dat/manifest6.yaml")
 	err := mr.InitRootPackage(ps, ms)
 	assert.Equal(t, err, nil, "Init Root Package failed")
 
@@ -0,0 +1,59 @@
+// +build unit
+
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostNotification(t *testing.T) {
+	bodies := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		decoded := make(map[string]interface{})
+		json.Unmarshal(body, &decoded)
+		bodies = append(bodies, decoded)
+	}))
+	defer server.Close()
+
+	notification := deployers.Notification{
+		Command:     "deploy",
+		Project:     "hello",
+		Environment: "prod",
+		Success:     false,
+		Error:       "action hello/broken failed",
+		Duration:    4200,
+		Counts:      map[string]int{"deploy": 3, "failed": 1},
+	}
+
+	assert.Nil(t, deployers.PostNotification(server.URL, deployers.NotifyJSON, notification))
+	assert.Nil(t, deployers.PostNotification(server.URL, deployers.NotifySlack, notification))
+	assert.NotNil(t, deployers.PostNotification(server.URL, "xml", notification), "Unknown formats should be rejected.")
+
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, "hello", bodies[0]["project"])
+	assert.Equal(t, "prod", bodies[0]["environment"])
+	assert.Equal(t, map[string]interface{}{"deploy": 3.0, "failed": 1.0}, bodies[0]["counts"])
+	assert.Equal(t, ":x: wskdeploy deploy of *hello* to *prod* failed in 4.2s: 3 deploy, 1 failed\n> action hello/broken failed", bodies[1]["text"])
+}
+
+func TestNewNotification(t *testing.T) {
+	results := deployers.NewResults("undeploy")
+	results.Finish("hello", errors.New("boom"))
+
+	notification := deployers.NewNotification(results, "dev")
+	assert.Equal(t, "undeploy", notification.Command)
+	assert.Equal(t, "hello", notification.Project)
+	assert.Equal(t, "dev", notification.Environment)
+	assert.False(t, notification.Success)
+	assert.Equal(t, "boom", notification.Error)
+	assert.Empty(t, notification.Counts)
+}
@@ -0,0 +1,94 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+const overriddenManifest = `package:
+  name: demo
+  inputs:
+    region: eu
+  actions:
+    hello:
+      function: hello.js
+      inputs:
+        name: World
+        greeting: Hello
+    bye:
+      function: hello.js
+      inputs:
+        name: World
+`
+
+const overriddenDeployment = `application:
+  name: demo
+  package:
+    name: demo
+    actions:
+      hello:
+        inputs:
+          name: Deployment
+          greeting: Hi
+`
+
+func parameterValues(parameters whisk.KeyValueArr) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, keyValue := range parameters {
+		values[keyValue.Key] = keyValue.Value
+	}
+	return values
+}
+
+func TestConstructDeploymentPlan_ParameterOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-params")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(overriddenManifest), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(overriddenDeployment), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "hello.js"), []byte("function main() {}"), 0644))
+	paramFile := filepath.Join(dir, "params.json")
+	assert.Nil(t, ioutil.WriteFile(paramFile, []byte(`{"name": "File", "region": "us", "hello/greeting": "Hey"}`), 0644))
+
+	overrides, err := deployers.LoadParameterOverrides(paramFile, []string{"name=Flag", "limit=10", "bye/extra={\"a\":1}"})
+	assert.Nil(t, err)
+
+	fake := newFakeWhisk()
+	defer fake.Close()
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	deployer.ProjectPath = dir
+	deployer.ManifestPath = filepath.Join(dir, "manifest.yaml")
+	deployer.DeploymentPath = filepath.Join(dir, "deployment.yaml")
+	deployer.ParameterOverrides = overrides
+	assert.Nil(t, deployer.ConstructDeploymentPlan())
+
+	pack := deployer.Deployment.Packages["demo"]
+	hello := parameterValues(pack.Actions["hello"].Action.Parameters)
+	assert.Equal(t, "Flag", hello["name"], "--param should override the param file and deployment.yaml")
+	assert.Equal(t, "Hey", hello["greeting"], "An action input of the param file should override deployment.yaml")
+	bye := parameterValues(pack.Actions["bye"].Action.Parameters)
+	assert.Equal(t, "Flag", bye["name"])
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, bye["extra"], "Values of --param are parsed as JSON")
+	assert.Nil(t, hello["limit"], "Inputs no action declares belong to the package")
+
+	pkg := parameterValues(pack.Package.Parameters)
+	assert.Equal(t, "us", pkg["region"])
+	assert.Equal(t, float64(10), pkg["limit"])
+	assert.Nil(t, pkg["name"], "Inputs the actions declare are not added to the package")
+
+	deployer.ParameterOverrides = map[string]interface{}{"missing/name": "x"}
+	assert.NotNil(t, deployer.ConstructDeploymentPlan(), "Inputs of undeclared actions should be rejected")
+
+	_, err = deployers.LoadParameterOverrides("", []string{"novalue"})
+	assert.NotNil(t, err)
+}
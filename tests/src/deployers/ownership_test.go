@@ -0,0 +1,47 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_ProtectsUnmanaged(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.Put("actions/pkg/hello", map[string]interface{}{"name": "hello", "exec": map[string]interface{}{"kind": "nodejs:6", "code": "theirs"}})
+	fake.Put("triggers/tick", map[string]interface{}{"name": "tick", "annotations": managedBy("other")})
+
+	newDeployer := func() *deployers.ServiceDeployer {
+		deployer := newRuleDeployer(fake, "")
+		pack := deployers.NewDeploymentPackage()
+		pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+		pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+		deployer.Deployment.Packages["pkg"] = pack
+		return deployer
+	}
+	defer func() { utils.Flags.Force = false }()
+
+	err := newDeployer().Deploy()
+	assert.NotNil(t, err, "Entities of other projects should not be overwritten.")
+	assert.Contains(t, err.Error(), "action pkg/hello was not deployed by wskdeploy")
+	assert.Contains(t, err.Error(), "trigger tick is managed by project other")
+	action, _ := fake.Get("actions/pkg/hello")
+	assert.Equal(t, "theirs", action["exec"].(map[string]interface{})["code"], "Nothing should be deployed when the check fails.")
+	_, exists := fake.Get("rules/tickRule")
+	assert.False(t, exists, "Nothing should be deployed when the check fails.")
+
+	utils.Flags.Force = true
+	assert.Nil(t, newDeployer().Deploy())
+	action, _ = fake.Get("actions/pkg/hello")
+	assert.Equal(t, "function main() {}", action["exec"].(map[string]interface{})["code"], "--force should overwrite the action.")
+
+	// once deployed, the entities belong to the project
+	utils.Flags.Force = false
+	assert.Nil(t, newDeployer().Deploy())
+}
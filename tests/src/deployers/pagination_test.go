@@ -0,0 +1,38 @@
+// +build unit
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAll_Pages(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	for i := 0; i < 450; i++ {
+		name := fmt.Sprintf("action%03d", i)
+		fake.Put("actions/big/"+name, map[string]interface{}{"name": name})
+		name = fmt.Sprintf("trigger%03d", i)
+		fake.Put("triggers/"+name, map[string]interface{}{"name": name})
+	}
+	client, _ := fake.Client()
+
+	actions, err := deployers.ListAllActions(client, "big")
+	assert.Nil(t, err)
+	assert.Equal(t, 450, len(actions), "Every page of actions should be listed")
+	assert.Equal(t, "action449", actions[449].Name)
+
+	fake.requests = nil
+	triggers, err := deployers.ListAllTriggers(client)
+	assert.Nil(t, err)
+	assert.Equal(t, 450, len(triggers))
+	assert.Equal(t, 3, len(fake.requests), "The triggers should be listed in pages of 200")
+
+	rules, err := deployers.ListAllRules(client)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(rules))
+}
@@ -0,0 +1,94 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentPlan(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	hello := newCodeAction("hello", "function main() { return {}; }")
+	timeout, memory := 120000, 256
+	hello.Action.Limits = &whisk.Limits{Timeout: &timeout, Memory: &memory}
+	pack.Actions["hello"] = hello
+	pack.Actions["same"] = newCodeAction("same", "function main() {}")
+	pack.Actions["bye"] = newCodeAction("bye", "function main() {}")
+	deployer.Deployment.Packages["pkg"] = pack
+	deployer.Deployment.Triggers["tick"] = &whisk.Trigger{Name: "tick"}
+	deployer.Deployment.Rules["tickRule"] = &whisk.Rule{Name: "tickRule", Trigger: "tick", Action: "hello"}
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg"})
+	fake.Put("actions/pkg/hello", map[string]interface{}{"name": "hello",
+		"exec":        map[string]interface{}{"kind": "nodejs:6", "code": "function main() {}"},
+		"limits":      map[string]interface{}{"timeout": 60000},
+		"annotations": []interface{}{map[string]interface{}{"key": "web-export", "value": true}}})
+	fake.Put("actions/pkg/same", map[string]interface{}{"name": "same",
+		"annotations": []interface{}{map[string]interface{}{"key": deployers.ManagedAnnotation,
+			"value": map[string]interface{}{"projectName": "pkg", "hash": deployers.ActionHash(pack.Actions["same"].Action)}}}})
+	fake.Put("actions/pkg/old", map[string]interface{}{"name": "old", "annotations": managedBy("pkg")})
+	fake.Put("triggers/tick", map[string]interface{}{"name": "tick"})
+
+	utils.Flags.Sync = true
+	defer func() { utils.Flags.Sync = false }()
+
+	plan, err := deployer.DeploymentPlan()
+	assert.Nil(t, err, "Planning should succeed.")
+	assert.Equal(t, []deployers.PlanItem{
+		{Kind: "package", Name: "pkg", Change: deployers.PlanNoop, Details: []string{}},
+		{Kind: "action", Name: "pkg/bye", Change: deployers.PlanCreate},
+		{Kind: "action", Name: "pkg/hello", Change: deployers.PlanUpdate, Details: []string{
+			"code changed",
+			"limit timeout: 60000 -> 120000",
+			"limit memory: default -> 256",
+			"annotation web-export removed",
+		}},
+		{Kind: "action", Name: "pkg/same", Change: deployers.PlanNoop},
+		{Kind: "trigger", Name: "tick", Change: deployers.PlanNoop, Details: []string{}},
+		{Kind: "rule", Name: "tickRule", Change: deployers.PlanCreate},
+		{Kind: "action", Name: "pkg/old", Change: deployers.PlanDelete, Details: []string{"no longer in the manifest"}},
+	}, plan.Items)
+	assert.Equal(t, 2, plan.Count(deployers.PlanCreate))
+	assert.Equal(t, []string{"actions/pkg/hello", "actions/pkg/old", "actions/pkg/same", "packages/pkg", "triggers/tick"}, fake.Keys(), "Planning should not change the namespace.")
+}
+
+func TestUnDeploymentPlan(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Client, deployer.ClientConfig = fake.Client()
+
+	undeployed := deployers.NewDeploymentApplication()
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+	undeployed.Packages["pkg"] = pack
+	undeployed.Triggers["tick"] = &whisk.Trigger{Name: "tick"}
+	undeployed.Rules["tickRule"] = &whisk.Rule{Name: "tickRule"}
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg"})
+	fake.Put("actions/pkg/hello", map[string]interface{}{"name": "hello"})
+	fake.Put("rules/tickRule", map[string]interface{}{"name": "tickRule"})
+
+	plan, err := deployer.UnDeploymentPlan(undeployed)
+	assert.Nil(t, err, "Planning should succeed.")
+	assert.Equal(t, []deployers.PlanItem{
+		{Kind: "rule", Name: "tickRule", Change: deployers.PlanDelete},
+		{Kind: "trigger", Name: "tick", Change: deployers.PlanNoop},
+		{Kind: "action", Name: "pkg/hello", Change: deployers.PlanDelete},
+		{Kind: "package", Name: "pkg", Change: deployers.PlanDelete},
+	}, plan.Items, "Only deployed entities should be deleted.")
+}
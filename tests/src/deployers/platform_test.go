@@ -0,0 +1,173 @@
+// +build unit
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_PlatformLimits(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.limits = map[string]int{"max_action_duration": 60000, "max_action_memory": 2048 * 1024 * 1024}
+
+	deployer := newFakeDeployer(fake)
+
+	limits, err := deployers.PlatformLimits(deployer.ClientConfig)
+	assert.Nil(t, err, "Reading the platform limits should succeed.")
+	assert.Equal(t, 60000, limits["max_action_duration"])
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	hello := newCodeAction("hello", "function main() {}")
+	memory, timeout := 1024, 120000
+	hello.Action.Limits = &whisk.Limits{Memory: &memory, Timeout: &timeout}
+	pack.Actions["hello"] = hello
+	deployer.Deployment.Packages["pkg"] = pack
+
+	err = deployer.Deploy()
+	assert.NotNil(t, err, "Limits the platform does not allow should fail the deployment.")
+	assert.Contains(t, err.Error(), "timeout of action pkg/hello must be between 100 and 60000")
+	assert.NotContains(t, err.Error(), "memorySize", "Memory within the platform maximum should be accepted.")
+	assert.Equal(t, []string{}, fake.Keys(), "Nothing should be deployed.")
+}
+
+func TestDeploy_CodeSizeLimit(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.limits = map[string]int{"max_action_code_size": 2048}
+
+	deployer := newFakeDeployer(fake)
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for name, size := range map[string]int{"index.js": 100, "node_modules/big.bin": 4000, "node_modules/small.bin": 1000} {
+		header := &zip.FileHeader{Name: name, Method: zip.Store}
+		writer, _ := archive.CreateHeader(header)
+		noise := make([]byte, size)
+		rand.Read(noise)
+		writer.Write(noise)
+	}
+	archive.Close()
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	hello := newCodeAction("hello", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	pack.Actions["hello"] = hello
+	deployer.Deployment.Packages["pkg"] = pack
+
+	err := deployer.Deploy()
+	assert.NotNil(t, err, "Code above the platform limit should fail the deployment.")
+	assert.Contains(t, err.Error(), "code of action pkg/hello is")
+	assert.Contains(t, err.Error(), "above the 2.0 KB limit")
+	assert.Regexp(t, "node_modules/big.bin 3.9 KB\\s+node_modules/small.bin 1.0 KB\\s+index.js", err.Error(), "The largest files should be listed first.")
+	assert.Equal(t, []string{}, fake.Keys(), "Nothing should be deployed.")
+}
+
+func TestDeploy_RuntimeKinds(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.runtimes = map[string]interface{}{
+		"nodejs":     []interface{}{map[string]interface{}{"kind": "nodejs:8", "deprecated": true}, map[string]interface{}{"kind": "nodejs:10", "default": true}},
+		"python":     []interface{}{map[string]interface{}{"kind": "python:3", "default": true}},
+		"blackboxes": []interface{}{map[string]interface{}{"image": "dockerskeleton"}},
+	}
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	for name, kind := range map[string]string{"old": "nodejs:8", "hello": "nodejs:default", "py": "python:2", "docker": "blackbox"} {
+		action := newCodeAction(name, "function main() {}")
+		action.Action.Exec.Kind = kind
+		pack.Actions[name] = action
+	}
+	deployer.Deployment.Packages["pkg"] = pack
+
+	err := deployer.Deploy()
+	assert.NotNil(t, err, "Kinds the platform has no runtime for should fail the deployment.")
+	assert.Equal(t, "kind python:2 of action pkg/py is not supported on this platform, use one of: nodejs:10, nodejs:8, python:3", err.Error())
+	assert.Equal(t, []string{}, fake.Keys(), "Nothing should be deployed.")
+
+	delete(pack.Actions, "py")
+	assert.Nil(t, deployer.Deploy(), "Supported, default and deprecated kinds should be deployed.")
+}
+
+func TestDeploy_RuntimeAliases(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.runtimes = map[string]interface{}{
+		"nodejs": []interface{}{map[string]interface{}{"kind": "nodejs:8"}, map[string]interface{}{"kind": "nodejs:10", "default": true}},
+		"python": []interface{}{map[string]interface{}{"kind": "python:3", "default": true}},
+	}
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	for name, kind := range map[string]string{"default": "nodejs:default", "family": "python", "pinned": "nodejs:8"} {
+		action := newCodeAction(name, "function main() {}")
+		action.Action.Exec.Kind = kind
+		pack.Actions[name] = action
+	}
+	deployer.Deployment.Packages["pkg"] = pack
+	assert.Nil(t, deployer.Deploy())
+
+	for name, kind := range map[string]string{"default": "nodejs:10", "family": "python:3", "pinned": "nodejs:8"} {
+		action, _ := fake.Get("actions/pkg/" + name)
+		assert.Equal(t, kind, action["exec"].(map[string]interface{})["kind"], "Aliases should resolve to the default runtime of the platform.")
+	}
+}
+
+func TestDeploy_RuntimesFile(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.runtimes = map[string]interface{}{
+		"nodejs": []interface{}{map[string]interface{}{"kind": "nodejs:8"}, map[string]interface{}{"kind": "nodejs:10", "default": true}},
+		"perl":   []interface{}{map[string]interface{}{"kind": "perl:5.28"}},
+	}
+
+	dir, err := ioutil.TempDir("", "wskdeploy-runtimes")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer utils.LoadRuntimes("")
+	file := filepath.Join(dir, "runtimes.yaml")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("defaults:\n  nodejs: nodejs:8\n  perl: perl:5.28\nmaxCodeSize:\n  perl: 1\n"), 0644))
+	assert.Nil(t, utils.LoadRuntimes(file))
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	hello := newCodeAction("hello", "function main() {}")
+	hello.Action.Exec.Kind = "nodejs:default"
+	pack.Actions["hello"] = hello
+	script := newCodeAction("script", strings.Repeat("#", 2*1024*1024))
+	script.Action.Exec.Kind = "perl"
+	pack.Actions["script"] = script
+	deployer.Deployment.Packages["pkg"] = pack
+
+	err = deployer.Deploy()
+	assert.NotNil(t, err, "The code size limit of the runtimes file should apply to its kind.")
+	assert.Equal(t, "code of action pkg/script is 2.0 MB, above the 1.0 MB limit of this platform", err.Error())
+
+	script.Action.Exec.Code = &[]string{"print 'hello'"}[0]
+	assert.Nil(t, deployer.Deploy())
+	for name, kind := range map[string]string{"hello": "nodejs:8", "script": "perl:5.28"} {
+		action, _ := fake.Get("actions/pkg/" + name)
+		assert.Equal(t, kind, action["exec"].(map[string]interface{})["kind"], "The defaults of the runtimes file should take precedence over the platform.")
+	}
+}
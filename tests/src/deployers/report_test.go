@@ -0,0 +1,46 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectReport(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg", "version": "0.0.2", "annotations": managedBy("pkg")})
+	fake.Put("actions/pkg/hello", map[string]interface{}{"name": "hello", "version": "0.0.1", "annotations": managedBy("pkg"),
+		"exec": map[string]interface{}{"kind": "nodejs:6", "code": "function main() {}"}})
+	fake.Put("actions/pkg/manual", map[string]interface{}{"name": "manual"})
+	fake.Put("triggers/other", map[string]interface{}{"name": "other", "annotations": managedBy("other")})
+	fake.Put("rules/helloRule", map[string]interface{}{"name": "helloRule", "status": "active", "annotations": managedBy("pkg")})
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	deployer.ProjectName = "pkg"
+
+	entries, err := deployer.ProjectReport()
+	assert.Nil(t, err, "Report should succeed.")
+	assert.Equal(t, []deployers.ReportEntry{
+		{Kind: "package", Name: "pkg", Version: "0.0.2"},
+		{Kind: "action", Name: "pkg/hello", Version: "0.0.1", Runtime: "nodejs:6", Size: 18},
+		{Kind: "rule", Name: "helloRule"},
+	}, entries, "Only entities of the project should be reported.")
+
+	var buf bytes.Buffer
+	err = deployers.PrintReport(&buf, entries, deployers.ReportJSON)
+	assert.Nil(t, err)
+	decoded := make([]deployers.ReportEntry, 0)
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded), "JSON report should be valid.")
+	assert.Equal(t, entries, decoded)
+
+	err = deployers.PrintReport(&buf, entries, "xml")
+	assert.NotNil(t, err, "Unknown formats should be rejected.")
+}
@@ -0,0 +1,155 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_Results(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.failPut["actions/pkg/broken"] = true
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Concurrency = 1
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	deployer.Results = deployers.NewResults("deploy")
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", "hello")
+	pack.Actions["broken"] = newCodeAction("broken", "broken")
+	deployer.Deployment.Packages["pkg"] = pack
+
+	err := deployer.Deploy()
+	assert.NotNil(t, err, "Deployment should fail.")
+
+	entities := make(map[string]deployers.EntityResult)
+	for _, entity := range deployer.Results.Entities {
+		entities[entity.Kind+" "+entity.Name] = entity
+	}
+	assert.Equal(t, 3, len(entities), "Every entity touched should be listed.")
+	assert.Equal(t, deployers.OperationDeploy, entities["package pkg"].Operation)
+	assert.Empty(t, entities["action pkg/hello"].Error, "Deployed action should have no error.")
+	assert.NotEmpty(t, entities["action pkg/broken"].Error, "Failed action should carry its error.")
+
+	deployer.Results.Finish("pkg", err)
+	var buf bytes.Buffer
+	assert.Nil(t, deployer.Results.Print(&buf))
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded), "Results should be valid JSON.")
+	assert.Equal(t, "deploy", decoded["command"])
+	assert.Equal(t, false, decoded["success"])
+	assert.Equal(t, err.Error(), decoded["error"])
+	assert.Len(t, decoded["entities"], 3)
+}
+
+func TestResults_FailedTrigger(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.failPut["triggers/tick"] = true
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	deployer.Results = deployers.NewResults("deploy")
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	deployer.Deployment.Packages["pkg"] = pack
+	deployer.Deployment.Triggers["tick"] = &whisk.Trigger{Name: "tick"}
+
+	assert.NotNil(t, deployer.Deploy(), "A failed trigger should fail the deployment.")
+
+	// the entities decide, whatever the command returned
+	deployer.Results.Finish("pkg", nil)
+	assert.False(t, deployer.Results.Success, "A run with a failed entity should not succeed.")
+	assert.Equal(t, 1, deployer.Results.Failed)
+
+	var buf bytes.Buffer
+	assert.Nil(t, deployer.Results.Print(&buf))
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, false, decoded["success"])
+	assert.Equal(t, float64(1), decoded["failed"])
+}
+
+func TestResults_Targets(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	results := deployers.NewResults("deploy")
+	results.SetTarget("dev")
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	deployer.Results = results
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	deployer.Deployment.Packages["pkg"] = pack
+	assert.Nil(t, deployer.Deploy())
+
+	results.Finish("pkg", errors.New("deployment failed for 1 of 2 target(s)"))
+	assert.Equal(t, "dev", results.Entities[0].Target, "Entities should name the target they were deployed to.")
+	assert.False(t, results.Success)
+}
+
+func TestResults_JUnit(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.failPut["actions/pkg/broken"] = true
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Concurrency = 1
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	deployer.Results = deployers.NewResults("deploy")
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["broken"] = newCodeAction("broken", "broken")
+	deployer.Deployment.Packages["pkg"] = pack
+
+	err := deployer.Deploy()
+	deployer.Results.Finish("pkg", err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, deployer.Results.WriteJUnit(&buf))
+
+	var report struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				ClassName string    `xml:"classname,attr"`
+				Name      string    `xml:"name,attr"`
+				Failure   *struct{} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	assert.Nil(t, xml.Unmarshal(buf.Bytes(), &report), "Report should be valid XML.")
+	assert.Equal(t, 3, report.Tests, "The package, the action and the run should be test cases.")
+	assert.Equal(t, 2, report.Failures, "The failed action and the run should fail.")
+	assert.Len(t, report.Suites, 1)
+	assert.Equal(t, "pkg", report.Suites[0].Name)
+	assert.Equal(t, "action", report.Suites[0].Cases[1].ClassName)
+	assert.Equal(t, "deploy pkg/broken", report.Suites[0].Cases[1].Name)
+	assert.NotNil(t, report.Suites[0].Cases[1].Failure)
+	assert.Equal(t, "deploy", report.Suites[0].Cases[2].Name)
+}
@@ -0,0 +1,68 @@
+// +build unit
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRuleDeployer(fake *fakeWhisk, status string) *deployers.ServiceDeployer {
+	deployer := newFakeDeployer(fake)
+	deployer.Deployment.Triggers["tick"] = &whisk.Trigger{Name: "tick"}
+	deployer.Deployment.Rules["tickRule"] = &whisk.Rule{Name: "tickRule", Trigger: "tick", Action: "hello", Status: status}
+	return deployer
+}
+
+func TestDeploy_RuleStatus(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	assert.Nil(t, newRuleDeployer(fake, "inactive").Deploy())
+	rule, _ := fake.Get("rules/tickRule")
+	assert.Equal(t, "inactive", rule["status"], "An inactive rule should be disabled after it is created.")
+
+	assert.Nil(t, newRuleDeployer(fake, "").Deploy())
+	rule, _ = fake.Get("rules/tickRule")
+	assert.Equal(t, "active", rule["status"], "Rules should be active by default.")
+
+	fake.requests = nil
+	undeployer := newRuleDeployer(fake, "")
+	assert.Nil(t, undeployer.UnDeploy(undeployer.Deployment))
+	assert.Equal(t, []string{"POST rules/tickRule", "DELETE rules/tickRule", "DELETE triggers/tick"}, fake.requests,
+		"Rules should be disabled and deleted before their triggers.")
+}
+
+func TestPlan_RuleStatus(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	assert.Nil(t, newRuleDeployer(fake, "").Deploy())
+
+	plan, err := newRuleDeployer(fake, "inactive").DeploymentPlan()
+	assert.Nil(t, err)
+	found := false
+	for _, item := range plan.Items {
+		if item.Kind == "rule" {
+			found = true
+			assert.Equal(t, []string{"status: active -> inactive"}, item.Details, "The plan should report the state change.")
+		}
+	}
+	assert.True(t, found, "The rule should be in the plan.")
+}
+
+func TestDeploy_RuleFailureExitCode(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.failPut["rules/tickRule"] = true
+
+	utils.Flags.CI = true
+	defer func() { utils.Flags.CI = false }()
+
+	err := newRuleDeployer(fake, "").Deploy()
+	assert.NotNil(t, err, "A rejected rule should fail the deployment.")
+	assert.Equal(t, utils.ExitAPI, utils.ExitCode(err), "A rejected rule should exit with the api class.")
+}
@@ -0,0 +1,59 @@
+// +build unit
+
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSequence(name string, components ...string) utils.ActionRecord {
+	action := new(whisk.Action)
+	action.Name = name
+	action.Exec = new(whisk.Exec)
+	action.Exec.Kind = "sequence"
+	action.Exec.Components = components
+	return utils.ActionRecord{action, "pkg", name}
+}
+
+func TestDeploySequences_Order(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.IsInteractive = false
+	deployer.Concurrency = 4
+	deployer.Client, deployer.ClientConfig = fake.Client()
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg"}
+	deployer.Deployment.Packages["pkg"] = pack
+
+	pack.Actions["top"] = newSequence("top", "/guest/pkg/outer")
+	pack.Actions["outer"] = newSequence("outer", "/guest/pkg/inner", "/guest/pkg/hello")
+	pack.Actions["inner"] = newSequence("inner", "/guest/pkg/hello")
+	pack.Actions["other"] = newSequence("other", "/guest/pkg/hello")
+
+	err := deployer.DeploySequences()
+	assert.Nil(t, err, "Sequences without a cycle should deploy.")
+	puts := make(map[string]int)
+	for i, request := range fake.requests {
+		if strings.HasPrefix(request, "PUT ") {
+			puts[strings.TrimPrefix(request, "PUT ")] = i
+		}
+	}
+	assert.Equal(t, 4, len(puts), "Every sequence should be created.")
+	assert.True(t, puts["actions/pkg/inner"] < puts["actions/pkg/outer"], "A sequence should be created after the sequences it calls.")
+	assert.True(t, puts["actions/pkg/outer"] < puts["actions/pkg/top"], "A sequence should be created after the sequences it calls.")
+
+	pack.Actions["loop1"] = newSequence("loop1", "/guest/pkg/loop2")
+	pack.Actions["loop2"] = newSequence("loop2", "/guest/pkg/loop1")
+
+	err = deployer.DeploySequences()
+	assert.NotNil(t, err, "A cycle between sequences should be reported.")
+	assert.Contains(t, err.Error(), "pkg/loop1, pkg/loop2", "The cycle should name the sequences involved.")
+}
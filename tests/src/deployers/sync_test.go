@@ -0,0 +1,131 @@
+// +build unit
+
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func managedBy(project string) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"key":   deployers.ManagedAnnotation,
+		"value": map[string]interface{}{"projectName": project},
+	}}
+}
+
+func TestDeploy_Sync(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	fake.Put("packages/pkg", map[string]interface{}{"name": "pkg", "annotations": managedBy("pkg")})
+	fake.Put("actions/pkg/old", map[string]interface{}{"name": "old", "annotations": managedBy("pkg")})
+	fake.Put("actions/pkg/manual", map[string]interface{}{"name": "manual"})
+	fake.Put("actions/foreign", map[string]interface{}{"name": "foreign", "annotations": managedBy("other")})
+	fake.Put("triggers/oldTrigger", map[string]interface{}{"name": "oldTrigger", "annotations": managedBy("pkg")})
+	fake.Put("rules/oldRule", map[string]interface{}{"name": "oldRule", "status": "active", "annotations": managedBy("pkg")})
+
+	deployer := newFakeDeployer(fake)
+
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+	deployer.Deployment.Packages["pkg"] = pack
+
+	utils.Flags.Sync = true
+	defer func() { utils.Flags.Sync = false }()
+
+	err := deployer.Deploy()
+	assert.Nil(t, err, "Sync should succeed.")
+
+	assert.Equal(t, []string{"actions/foreign", "actions/pkg/hello", "actions/pkg/manual", "packages/pkg"}, fake.Keys(),
+		"Only the orphans of the project should be removed.")
+
+	hello, _ := fake.Get("actions/pkg/hello")
+	stamped := false
+	for _, annotation := range hello["annotations"].([]interface{}) {
+		kv := annotation.(map[string]interface{})
+		if kv["key"] == deployers.ManagedAnnotation {
+			stamped = kv["value"].(map[string]interface{})["projectName"] == "pkg"
+		}
+	}
+	assert.True(t, stamped, "Deployed action should be tagged with the project.")
+}
+
+func TestDeploy_ManagedAnnotation(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	manifest := []byte("package:\n  name: pkg\n")
+	manifestPath := path.Join(dir, "manifest.yaml")
+	assert.Nil(t, ioutil.WriteFile(manifestPath, manifest, 0644))
+	sum := sha256.Sum256(manifest)
+
+	deployer := newRuleDeployer(fake, "")
+	deployer.ManifestPath = manifestPath
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+	pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+	deployer.Deployment.Packages["pkg"] = pack
+	assert.Nil(t, deployer.Deploy())
+
+	for _, key := range []string{"packages/pkg", "actions/pkg/hello", "triggers/tick", "rules/tickRule"} {
+		entity, ok := fake.Get(key)
+		assert.True(t, ok, key+" should be deployed")
+		var managed map[string]interface{}
+		for _, annotation := range entity["annotations"].([]interface{}) {
+			kv := annotation.(map[string]interface{})
+			if kv["key"] == deployers.ManagedAnnotation {
+				managed = kv["value"].(map[string]interface{})
+			}
+		}
+		assert.Equal(t, "pkg", managed["projectName"], key+" should be stamped with the project")
+		assert.Equal(t, hex.EncodeToString(sum[:]), managed["projectHash"], key+" should be stamped with the manifest hash")
+		assert.Equal(t, manifestPath, managed["file"])
+		assert.NotEmpty(t, managed["timestamp"])
+	}
+}
+
+func TestDeploy_RemoveOrphans(t *testing.T) {
+	fake := newFakeWhisk()
+	defer fake.Close()
+	fake.Put("actions/pkg/old", map[string]interface{}{"name": "old", "annotations": managedBy("pkg")})
+
+	newDeployer := func() *deployers.ServiceDeployer {
+		deployer := newFakeDeployer(fake)
+		pack := deployers.NewDeploymentPackage()
+		pack.Package = &whisk.Package{Name: "pkg", Namespace: "guest"}
+		pack.Actions["hello"] = newCodeAction("hello", "function main() {}")
+		deployer.Deployment.Packages["pkg"] = pack
+		return deployer
+	}
+
+	utils.Flags.RemoveOrphans = true
+	utils.Flags.NonInteractive = true
+	defer func() { utils.Flags.RemoveOrphans, utils.Flags.NonInteractive, utils.Flags.Force = false, false, false }()
+
+	err := newDeployer().Deploy()
+	assert.NotNil(t, err, "Orphans should not be removed without confirmation.")
+	assert.Contains(t, err.Error(), "--force")
+	_, exists := fake.Get("actions/pkg/old")
+	assert.True(t, exists, "The orphan should be kept until confirmed.")
+
+	utils.Flags.Force = true
+	assert.Nil(t, newDeployer().Deploy())
+	_, exists = fake.Get("actions/pkg/old")
+	assert.False(t, exists, "--force should remove the orphan without asking.")
+	_, exists = fake.Get("actions/pkg/hello")
+	assert.True(t, exists)
+}
@@ -0,0 +1,107 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploy_Terraform(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "terraform")
+	defer os.RemoveAll(dir)
+
+	deployer := deployers.NewServiceDeployer()
+	deployer.RootPackageName = "pkg"
+	deployer.ClientConfig = &whisk.Config{Namespace: "guest"}
+	pack := deployers.NewDeploymentPackage()
+	pack.Package = &whisk.Package{Name: "pkg", Parameters: whisk.KeyValueArr{{Key: "units", Value: "metric"}}}
+	hello := newCodeAction("hello", "function main() { return {greeting: `${name}`} }")
+	memory := 256
+	hello.Action.Limits = &whisk.Limits{Memory: &memory}
+	pack.Actions["hello"] = hello
+	sequence := &whisk.Action{Name: "greet", Exec: &whisk.Exec{Kind: "sequence", Components: []string{"/guest/pkg/hello", "/whisk.system/utils/echo"}}}
+	pack.Sequences["greet"] = utils.ActionRecord{sequence, "pkg", ""}
+	pack.Dependencies["alarms"] = utils.DependencyRecord{Location: "/whisk.system/alarms", IsBinding: true}
+	deployer.Deployment.Packages["pkg"] = pack
+	deployer.Deployment.Triggers["every-hour"] = &whisk.Trigger{
+		Name:        "every-hour",
+		Annotations: whisk.KeyValueArr{{Key: "feed", Value: "/whisk.system/alarms/alarm"}},
+		Parameters:  whisk.KeyValueArr{{Key: "cron", Value: "0 * * * *"}},
+	}
+	deployer.Deployment.Rules["hourly"] = &whisk.Rule{Name: "hourly", Trigger: "every-hour", Action: "greet"}
+	deployer.Deployment.Apis["get /hello"] = &whisk.ApiCreateRequest{}
+
+	warnings, err := deployer.Terraform(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"API routes are not exported, the Terraform provider has no resource for them"}, warnings)
+
+	code, err := ioutil.ReadFile(filepath.Join(dir, "code", "pkg", "hello.js"))
+	assert.Nil(t, err, "The code of the actions should be written next to the configuration.")
+	assert.Equal(t, "function main() { return {greeting: `${name}`} }", string(code))
+
+	config, err := ioutil.ReadFile(filepath.Join(dir, deployers.TerraformFileName))
+	assert.Nil(t, err)
+	assert.Equal(t, `# Terraform configuration exported by wskdeploy
+
+variable "namespace" {
+  default = "guest"
+}
+
+resource "ibm_function_package" "pkg" {
+  namespace = "${var.namespace}"
+  name = "pkg"
+  user_defined_parameters = "[{\"key\":\"units\",\"value\":\"metric\"}]"
+}
+
+resource "ibm_function_package" "alarms" {
+  namespace = "${var.namespace}"
+  name = "alarms"
+  bind_package_name = "/whisk.system/alarms"
+}
+
+resource "ibm_function_action" "pkg_hello" {
+  namespace = "${var.namespace}"
+  name = "${ibm_function_package.pkg.name}/hello"
+  exec {
+    kind = "nodejs:6"
+    code_path = "code/pkg/hello.js"
+  }
+  limits {
+    memory = 256
+  }
+}
+
+resource "ibm_function_action" "pkg_greet" {
+  namespace = "${var.namespace}"
+  name = "${ibm_function_package.pkg.name}/greet"
+  exec {
+    kind = "sequence"
+    components = ["/${var.namespace}/${ibm_function_action.pkg_hello.name}", "/whisk.system/utils/echo"]
+  }
+}
+
+resource "ibm_function_trigger" "every-hour" {
+  namespace = "${var.namespace}"
+  name = "every-hour"
+  feed {
+    name = "/whisk.system/alarms/alarm"
+    parameters = "[{\"key\":\"cron\",\"value\":\"0 * * * *\"}]"
+  }
+}
+
+resource "ibm_function_rule" "hourly" {
+  namespace = "${var.namespace}"
+  name = "hourly"
+  trigger_name = "${ibm_function_trigger.every-hour.name}"
+  action_name = "${ibm_function_action.pkg_greet.name}"
+}
+`, string(config))
+}
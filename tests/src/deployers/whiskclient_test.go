@@ -0,0 +1,106 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/deployers"
+	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTargetClient(t *testing.T) {
+	target := parsers.Target{Name: "eu", ApiHost: "https://eu.example.com", Namespace: "team"}
+	_, config, err := deployers.NewTargetClient("../../dat/wskprops", target)
+	assert.Nil(t, err, "Creating the target client should succeed.")
+	assert.Equal(t, "https://eu.example.com/api", config.BaseURL.String(), "Target API host should be used.")
+	assert.Equal(t, "team", config.Namespace, "Target namespace should be used.")
+	assert.Equal(t, "a4f8c502:123zO3xZCLrMN6v2BKK", config.AuthToken, "Unset credential should come from the property file.")
+
+	_, _, err = deployers.NewTargetClient("", parsers.Target{Name: "empty"})
+	assert.NotNil(t, err, "A target without an API host should be rejected.")
+}
+
+func TestResolveClientSettings(t *testing.T) {
+	configs := []string{"guest", "openwhisk.ng.bluemix.net", "a4f8c502:123zO3xZCLrMN6v2BKK"}
+
+	settings := deployers.ResolveClientSettings(configs, deployers.ClientSettings{})
+	assert.Equal(t, deployers.ClientSettings{ApiHost: "openwhisk.ng.bluemix.net", Auth: "a4f8c502:123zO3xZCLrMN6v2BKK", Namespace: "guest"}, settings, "The property file comes last.")
+
+	os.Setenv(deployers.ApiHostEnv, "env.example.com")
+	os.Setenv(deployers.NamespaceEnv, "env")
+	defer os.Unsetenv(deployers.ApiHostEnv)
+	defer os.Unsetenv(deployers.NamespaceEnv)
+	settings = deployers.ResolveClientSettings(configs, deployers.ClientSettings{})
+	assert.Equal(t, "env.example.com", settings.ApiHost, "The environment should override the property file.")
+	assert.Equal(t, "env", settings.Namespace)
+	assert.Equal(t, "a4f8c502:123zO3xZCLrMN6v2BKK", settings.Auth)
+
+	deployment := deployers.ClientSettings{BaseUrl: "https://deploy.example.com/api", Namespace: "deploy"}
+	settings = deployers.ResolveClientSettings(configs, deployment)
+	assert.Equal(t, "", settings.ApiHost, "The base URL of the deployment file replaces the API host.")
+	assert.Equal(t, "deploy", settings.Namespace, "The deployment file should override the environment.")
+	url, err := settings.URL()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://deploy.example.com/api", url.String())
+
+	utils.Flags.ApiHost = "flag.example.com"
+	utils.Flags.Auth = "flag:key"
+	utils.Flags.Namespace = "flag"
+	defer func() { utils.Flags.ApiHost, utils.Flags.Auth, utils.Flags.Namespace = "", "", "" }()
+	settings = deployers.ResolveClientSettings(configs, deployment)
+	assert.Equal(t, deployers.ClientSettings{ApiHost: "flag.example.com", Auth: "flag:key", Namespace: "flag"}, settings, "The flags should override every other source.")
+	url, err = settings.URL()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://flag.example.com/api", url.String())
+
+	_, config, err := deployers.NewTargetClient("../../dat/wskprops", parsers.Target{Name: "eu", ApiHost: "https://eu.example.com", Namespace: "team"})
+	assert.Nil(t, err)
+	assert.Equal(t, "flag", config.Namespace, "The flags should override the target.")
+	assert.Equal(t, "flag:key", config.AuthToken, "--auth should override the target.")
+	assert.Equal(t, "https://flag.example.com/api", config.BaseURL.String(), "--apihost should override the target.")
+}
+
+func TestNewTargetClient_UnresolvedSetting(t *testing.T) {
+	os.Unsetenv(utils.EncryptionKeyVar)
+	target := parsers.Target{Name: "eu", ApiHost: "https://eu.example.com", Credential: "ENC[AES256_GCM,data:AA==,iv:AA==,tag:AA==]"}
+	_, _, err := deployers.NewTargetClient("../../dat/wskprops", target)
+	assert.NotNil(t, err, "A credential that cannot be resolved should be reported.")
+	assert.Contains(t, err.Error(), "eu", "The error should name the target.")
+}
+
+func TestNewWhiskClient_Keychain(t *testing.T) {
+	consulted := 0
+	goos, run := utils.KeychainOS, utils.RunKeychainCommand
+	utils.KeychainOS = "linux"
+	utils.RunKeychainCommand = func(stdin string, name string, args ...string) (string, string, error) {
+		consulted++
+		return "stored:key", "", nil
+	}
+	utils.Flags.UseKeychain = true
+	defer func() {
+		utils.KeychainOS, utils.RunKeychainCommand = goos, run
+		utils.Flags.UseKeychain = false
+	}()
+
+	_, config, err := deployers.NewWhiskClient("../../dat/wskprops", "", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "a4f8c502:123zO3xZCLrMN6v2BKK", config.AuthToken, "The configured auth key should win over the keychain.")
+	assert.Equal(t, 0, consulted, "The keychain should not be read when the auth key is set.")
+
+	dir, err := ioutil.TempDir("", "keychain")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	props := path.Join(dir, "wskprops")
+	ioutil.WriteFile(props, []byte("APIHOST=openwhisk.ng.bluemix.net\nNAMESPACE=guest\n"), 0644)
+
+	_, config, err = deployers.NewWhiskClient(props, "", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "stored:key", config.AuthToken, "The keychain should provide the missing auth key.")
+	assert.Equal(t, 1, consulted)
+}
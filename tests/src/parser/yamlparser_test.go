@@ -3,10 +3,23 @@
 package tests
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
 	"testing"
 
+	"github.com/openwhisk/openwhisk-client-go/whisk"
 	"github.com/openwhisk/openwhisk-wskdeploy/parsers"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,6 +33,13 @@ var testfile1 = "../../dat/deploy1.yaml"
 var testfile2 = "../../dat/deploy2.yaml"
 var testfile3 = "../../dat/deploy3.yaml"
 var testfile4 = "../../dat/deploy4.yaml"
+var testfile5 = "../../dat/deploy5.yaml"
+var manifestLimits = "../../dat/manifest_limits.yaml"
+var manifestApis = "../../dat/manifest_apis.yaml"
+var manifestApiDoc = "../../dat/manifest_apidoc.yaml"
+var manifestSequences = "../../dat/manifest_sequences.yaml"
+var manifestConductor = "../../dat/manifest_conductor.yaml"
+var manifestDependencies = "../../dat/manifest_dependencies.yaml"
 
 func TestParseManifestYAML(t *testing.T) {
 	data, err := ioutil.ReadFile(manifest_yaml)
@@ -205,7 +225,7 @@ func TestParseManifestYAML_param(t *testing.T) {
 func TestParseDeploymentYAML_Application(t *testing.T) {
 	//var deployment utils.DeploymentYAML
 	mm := parsers.NewYAMLParser()
-	deployment := mm.ParseDeployment(testfile1)
+	deployment, _ := mm.ParseDeployment(testfile1)
 
 	//get and verify application name
 	assert.Equal(t, "wskdeploy-samples", deployment.Application.Name, "Get application name failed.")
@@ -217,7 +237,7 @@ func TestParseDeploymentYAML_Application(t *testing.T) {
 func TestParseDeploymentYAML_Package(t *testing.T) {
 	//var deployment utils.DeploymentYAML
 	mm := parsers.NewYAMLParser()
-	deployment := mm.ParseDeployment(testfile2)
+	deployment, _ := mm.ParseDeployment(testfile2)
 
 	assert.Equal(t, 1, len(deployment.Application.Packages), "Get package list failed.")
 	for pkg_name := range deployment.Application.Packages {
@@ -238,7 +258,7 @@ func TestParseDeploymentYAML_Package(t *testing.T) {
 
 func TestParseDeploymentYAML_Action(t *testing.T) {
 	mm := parsers.NewYAMLParser()
-	deployment := mm.ParseDeployment(testfile2)
+	deployment, _ := mm.ParseDeployment(testfile2)
 
 	for pkg_name := range deployment.Application.Packages {
 
@@ -263,10 +283,73 @@ func TestParseDeploymentYAML_Action(t *testing.T) {
 	}
 }
 
+func TestParseDeploymentYAML_Environment(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	deployment, _ := mm.ParseDeployment(testfile5)
+	app := deployment.Application
+
+	err := app.ApplyEnvironment("prod")
+	assert.Nil(t, err, "Applying a defined environment should succeed.")
+	assert.Equal(t, "prod", app.Namespace, "Environment namespace should replace the application namespace.")
+	assert.Equal(t, "12345678ABCDEF", app.Credential, "Environment credential should be used.")
+
+	pkg := app.Packages["test_package"]
+	assert.Equal(t, "error", pkg.Inputs["level"].Value, "Environment inputs should apply to every package.")
+	hello := pkg.Actions["hello"]
+	assert.Equal(t, "London", hello.Inputs["place"].Value, "Action inputs of the environment should override.")
+	assert.Equal(t, "Bernie", hello.Inputs["name"].Value, "Inputs the environment does not set should be kept.")
+
+	err = deployment.Application.ApplyEnvironment("qa")
+	assert.NotNil(t, err, "Unknown environments should be rejected.")
+	assert.Contains(t, err.Error(), "dev, prod")
+}
+
+func TestParseDeploymentYAML_Layered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	base := path.Join(dir, "deployment.yaml")
+	assert.Nil(t, ioutil.WriteFile(base, []byte(`application:
+  name: demo
+  namespace: dev
+  packages:
+    test_package:
+      inputs:
+        level: debug
+        region: eu
+      actions:
+        hello:
+          inputs:
+            name: Bernie
+`), 0644))
+	prod := path.Join(dir, "deployment.prod.yaml")
+	assert.Nil(t, ioutil.WriteFile(prod, []byte(`application:
+  namespace: prod
+  packages:
+    test_package:
+      inputs:
+        level: error
+`), 0644))
+
+	assert.Equal(t, []string{base, prod}, parsers.DeploymentFiles(base+parsers.DeploymentPathSeparator+prod))
+	assert.True(t, parsers.DeploymentExists(base+parsers.DeploymentPathSeparator+prod))
+	assert.False(t, parsers.DeploymentExists(path.Join(dir, "missing.yaml")))
+
+	deployment, _ := parsers.NewYAMLParser().ParseDeployment(base + parsers.DeploymentPathSeparator + prod)
+	app := deployment.Application
+	assert.Equal(t, "demo", app.Name, "Values the later file does not set should be kept.")
+	assert.Equal(t, "prod", app.Namespace, "The later file should override the earlier one.")
+	pkg := app.Packages["test_package"]
+	assert.Equal(t, "error", pkg.Inputs["level"].Value)
+	assert.Equal(t, "eu", pkg.Inputs["region"].Value, "Maps should be merged key by key.")
+	assert.Equal(t, "Bernie", pkg.Actions["hello"].Inputs["name"].Value)
+}
+
 func TestComposeWskPackage(t *testing.T) {
 	mm := parsers.NewYAMLParser()
-	deployment := mm.ParseDeployment(testfile2)
-	manifest := mm.ParseManifest(manifestfile1)
+	deployment, _ := mm.ParseDeployment(testfile2)
+	manifest, _ := mm.ParseManifest(manifestfile1)
 
 	pkglist := deployment.Application.GetPackageList()
 	for _, pkg := range pkglist {
@@ -283,8 +366,8 @@ func TestComposeWskPackage(t *testing.T) {
 
 func TestComposeWskTrigger(t *testing.T) {
 	mm := parsers.NewYAMLParser()
-	deployment := mm.ParseDeployment(testfile4)
-	manifest := mm.ParseManifest(manifestfile3)
+	deployment, _ := mm.ParseDeployment(testfile4)
+	manifest, _ := mm.ParseManifest(manifestfile3)
 
 	pkg := deployment.Application.GetPackageList()[0]
 	for _, trigger := range pkg.GetTriggerList() {
@@ -309,11 +392,11 @@ func TestComposeWskTrigger(t *testing.T) {
 
 func TestComposeWskRule(t *testing.T) {
 	mm := parsers.NewYAMLParser()
-	manifest := mm.ParseManifest(manifestfile4)
+	manifest, _ := mm.ParseManifest(manifestfile4)
 
 	pkg := manifest.Package
 	for _, rule := range pkg.GetRuleList() {
-		wskrule := rule.ComposeWskRule()
+		wskrule, _ := rule.ComposeWskRule()
 		switch wskrule.Name {
 		case "rule1":
 			assert.Equal(t, "trigger1", wskrule.Trigger, "Get rule trigger failed.")
@@ -323,3 +406,1355 @@ func TestComposeWskRule(t *testing.T) {
 		}
 	}
 }
+
+func TestParseManifestYAML_include(t *testing.T) {
+	manifest, _ := parsers.NewYAMLParser().ParseManifest("../../dat/include/manifest.yaml")
+
+	assert.Equal(t, "includeTest", manifest.Package.Packagename, "Get package name failed.")
+	assert.Equal(t, 2, len(manifest.Package.Actions), "Included actions were not merged.")
+	assert.Equal(t, 1, len(manifest.Package.Triggers), "Included triggers were not merged.")
+	assert.Equal(t, 1, len(manifest.Package.Rules), "Included rules were not merged.")
+	assert.Equal(t, "../../usecases/helloworld/actions/greeting2.js", manifest.Package.Actions["greeting2"].Location, "Included action location should be relative to the including manifest.")
+}
+
+func TestParseManifestYAML_nestedInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte("include:\n  - b.yaml\npackage:\n  name: nested\n  actions:\n    a:\n      location: a.js\n      runtime: nodejs:6\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "b.yaml"), []byte("include:\n  - c.yaml\npackage:\n  actions:\n    b:\n      location: b.js\n      runtime: nodejs:6\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "c.yaml"), []byte("package:\n  actions:\n    c:\n      location: c.js\n      runtime: nodejs:6\n"), 0644)
+
+	manifest, err := parsers.NewYAMLParser().ParseManifest(file)
+	assert.Nil(t, err, "Entities included two levels down should not conflict.")
+	assert.Equal(t, 3, len(manifest.Package.Actions), "Actions of every level should be merged.")
+	assert.Equal(t, "c.js", manifest.Package.Actions["c"].Location)
+
+	// a real conflict is reported against the fragment defining the entity
+	ioutil.WriteFile(path.Join(dir, "c.yaml"), []byte("package:\n  actions:\n    a:\n      location: c.js\n      runtime: nodejs:6\n"), 0644)
+	_, err = parsers.NewYAMLParser().ParseManifest(file)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Conflict detected for action a: defined in both "+file+" and "+path.Join(dir, "c.yaml"))
+}
+
+func TestParseManifestYAML_includeDocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(path.Join(dir, "native", "image"), os.ModePerm)
+	ioutil.WriteFile(path.Join(dir, "native", "image", "Dockerfile"), []byte("FROM openwhisk/dockerskeleton\n"), 0644)
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte("include:\n  - native/actions.yaml\npackage:\n  name: docker\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "native", "actions.yaml"), []byte("package:\n  actions:\n    native:\n      docker: image\n"), 0644)
+
+	manifest, err := parsers.NewYAMLParser().ParseManifest(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "native/image", manifest.Package.Actions["native"].Docker, "Included docker path should be relative to the including manifest.")
+
+	_, violations := parsers.ValidateManifest(file)
+	for _, violation := range violations {
+		assert.NotContains(t, violation.String(), "docker", "The Dockerfile of the included action should be found.")
+	}
+}
+
+func TestParseManifestJSON(t *testing.T) {
+	manifest, _ := parsers.NewYAMLParser().ParseManifest("../../dat/manifest7.json")
+
+	assert.Equal(t, "jsonPackage", manifest.Package.Packagename, "Get package name failed.")
+	action := manifest.Package.Actions["greeting"]
+	assert.Equal(t, "nodejs:6", action.Runtime, "Get action runtime failed.")
+	assert.Equal(t, "string", action.Inputs["name"].Value, "Get inline param value failed.")
+	assert.Equal(t, "Paris", action.Inputs["place"].Value, "Get param value failed.")
+}
+
+func TestValidateManifest(t *testing.T) {
+	file := "../../dat/invalid/manifest.yaml"
+	manifest, violations := parsers.ValidateManifest(file)
+	assert.NotNil(t, manifest, "Manifest should be decoded.")
+
+	messages := make([]string, 0)
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+
+	assert.Equal(t, []string{
+		file + ":6:7: unknown key 'runtim', did you mean 'runtime'?",
+		file + ":8:7: location actions/missing.js of action missing does not exist",
+		file + ":9:7: web-export of action missing must be one of true, false, yes, no or raw",
+		file + ":16:7: rule helloRule references undefined trigger everyHour",
+	}, messages, "Validation reported unexpected problems.")
+}
+
+func TestValidateManifest_SyntaxError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte("package:\n  name: broken\n  actions: [\n"), 0644)
+
+	manifest, violations := parsers.ValidateManifest(file)
+	assert.Nil(t, manifest, "Broken manifest should not be decoded.")
+	assert.Equal(t, 1, len(violations), "Syntax error should be reported.")
+	assert.True(t, violations[0].Line > 0, "Syntax error should carry a line number.")
+}
+
+func TestValidateManifest_Include(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(path.Join(dir, "hello.js"), []byte("function main() {}"), 0644)
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`include:
+  - rules.yaml
+package:
+  name: included
+  actions:
+    hello:
+      function: hello.js
+`), 0644)
+	fragment := path.Join(dir, "rules.yaml")
+	ioutil.WriteFile(fragment, []byte(`package:
+  triggers:
+    tick:
+      feed: /whisk.system/alarms/alarm
+  rules:
+    helloRule:
+      trigger: tick
+      action: hello
+    brokenRule:
+      trigger: tock
+      action: hello
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	assert.Equal(t, 1, len(violations), "References to included entities should resolve.")
+	assert.Equal(t, fragment+":10:7: rule brokenRule references undefined trigger tock", violations[0].String(), "The violation should be reported in the fragment.")
+}
+
+func TestValidateManifest_Extensions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"hello.php", "hello.rb", "hello.rs", "hello.cob"} {
+		ioutil.WriteFile(path.Join(dir, name), []byte("code"), 0644)
+	}
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: polyglot
+  actions:
+    php:
+      function: hello.php
+    ruby:
+      function: hello.rb
+    rust:
+      function: hello.rs
+    cobol:
+      function: hello.cob
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	assert.Equal(t, 1, len(violations), "Only the unknown extension should be reported.")
+	assert.Contains(t, violations[0].String(), "cannot infer the runtime of action cobol from hello.cob")
+}
+
+func TestValidateManifest_InputTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(path.Join(dir, "hello.js"), []byte("function main() {}"), 0644)
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: typed
+  inputs:
+    region:
+      type: text
+  actions:
+    hello:
+      function: hello.js
+      inputs:
+        count:
+          type: integer
+          default: 1.5
+        ratio:
+          type: float
+          default: "0.5"
+        zone:
+          pattern: "[a-z"
+        size:
+          enum: [small, large]
+          default: medium
+        cert:
+          valueFrom: file://missing.pem
+          encoding: hex
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	messages := make([]string, 0)
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	assert.Equal(t, []string{
+		file + ":5:7: type text of input region must be string, integer, float, boolean or json",
+		file + ":12:11: default of input count must be an integer, got 1.5",
+		file + ":17:11: pattern of input zone is not a valid regular expression: error parsing regexp: missing closing ]: `[a-z`",
+		file + `:20:11: default of input size must be one of "small", "large", got "medium"`,
+		file + ":22:11: valueFrom file file://missing.pem of input cert does not exist",
+		file + ":23:11: encoding of input cert must be base64",
+	}, messages, "Unknown types and defaults of another type should be reported.")
+}
+
+func TestComposeActions_Limits(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestLimits)
+
+	actions, _, err := mm.ComposeActions(manifest, manifestLimits)
+	assert.Nil(t, err, "Limits out of the default ranges are left to the platform to check.")
+	assert.Equal(t, 2, len(actions))
+	limits := actions[0].Action.Limits
+	assert.Equal(t, 600000, *actions[1].Action.Limits.Timeout, "Timeout out of the default range should be kept.")
+	assert.Equal(t, 60000, *limits.Timeout, "Timeout should be set on the action.")
+	assert.Equal(t, 256, *limits.Memory, "Memory size should be set on the action.")
+	assert.Equal(t, 5, *limits.Logsize, "Log size should be set on the action.")
+}
+
+func TestComposeApiRoutes(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestApis)
+
+	routes, err := mm.ComposeApiRoutes(manifest)
+	assert.Nil(t, err, "Routes to web actions should be accepted.")
+	keys := make([]string, 0)
+	for _, route := range routes {
+		assert.Equal(t, "hello", route.ActionName, "Route should be bound to the action.")
+		assert.Equal(t, "helloworld", route.Packagename, "Route should record the package.")
+		keys = append(keys, route.Key())
+	}
+	assert.Equal(t, []string{"GET /hello/world", "POST /hello/world", "GET /hello/{name}"}, keys, "Routes should be sorted by path and verb.")
+
+	manifest.Package.Apis.Routes["/hello"]["/private"] = map[string]string{"get": "private"}
+	_, err = mm.ComposeApiRoutes(manifest)
+	assert.NotNil(t, err, "Routes to actions which are not web actions should be rejected.")
+	assert.Contains(t, err.Error(), "not a web action")
+
+	manifest.Package.Apis.Routes["/hello"]["/private"] = map[string]string{"fetch": "hello"}
+	_, err = mm.ComposeApiRoutes(manifest)
+	assert.NotNil(t, err, "Unsupported verbs should be rejected.")
+}
+
+func TestComposeApiRoutes_Document(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestApiDoc)
+	assert.Equal(t, "apis/swagger.json", manifest.Package.Apis.Document, "The apis block should reference the document.")
+
+	routes, err := mm.ComposeApiRoutes(manifest)
+	assert.Nil(t, err, "Reading the swagger document failed.")
+	keys := make([]string, 0)
+	for _, route := range routes {
+		assert.Equal(t, "hello", route.ActionName, "Operation should be bound to the x-openwhisk action.")
+		keys = append(keys, route.Key())
+	}
+	assert.Equal(t, []string{"GET /hello/world", "POST /hello/world"}, keys, "Every operation should become a route.")
+
+	manifest.Package.Apis.Document = "apis/openapi.yaml"
+	routes, err = mm.ComposeApiRoutes(manifest)
+	assert.Nil(t, err, "Reading the OpenAPI document failed.")
+	assert.Equal(t, 1, len(routes), "Path summaries should not become routes.")
+	assert.Equal(t, "GET /hello/world", routes[0].Key(), "Base path should be taken from the server url.")
+
+	_, violations := parsers.ValidateManifest(manifestApiDoc)
+	assert.Equal(t, 0, len(violations), "A manifest referencing an API document should validate.")
+
+	_, err = parsers.ReadApiDocument("../../dat/apis/swagger.json", "other")
+	assert.NotNil(t, err, "Operations bound to another package should be rejected.")
+}
+
+func TestComposeSequences_Kind(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestSequences)
+
+	_, isAction := manifest.Package.Actions["greet"]
+	assert.False(t, isAction, "An action of kind sequence should not be deployed as an action.")
+	assert.Equal(t, "hello, helloworld/twice", manifest.Package.Sequences["greet"].Actions, "kind: sequence should declare a sequence.")
+
+	sequences, err := mm.ComposeSequences("guest", manifest)
+	assert.Nil(t, err, "Composing the sequences failed.")
+	components := make(map[string][]string)
+	for _, record := range sequences {
+		components[record.Action.Name] = record.Action.Exec.Components
+	}
+	assert.Equal(t, []string{"/guest/helloworld/hello", "/guest/helloworld/twice"}, components["greet"], "Components should be fully qualified.")
+
+	manifest.Package.Sequences["broken"] = parsers.Sequence{Actions: "hello, missing"}
+	_, err = mm.ComposeSequences("guest", manifest)
+	assert.NotNil(t, err, "Undefined components should be rejected.")
+	assert.Contains(t, err.Error(), "references undefined action missing")
+
+	_, violations := parsers.ValidateManifest(manifestSequences)
+	assert.Equal(t, 0, len(violations), "Sequences referencing sequences should validate.")
+}
+
+func TestComposeManifest_AllErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errors")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(path.Join(dir, "hello.js"), []byte("function main() {}"), 0644)
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: broken
+  actions:
+    hello:
+      function: hello.js
+    first:
+      function: missing.js
+    second:
+      function: gone.js
+  sequences:
+    greet:
+      actions: hello, nowhere
+    empty:
+      actions: ""
+  triggers:
+    tick:
+  rules:
+    orphan:
+      trigger: never
+      action: ghost
+    tickRule:
+      trigger: tick
+      action: hello
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, err := mm.ParseManifest(file)
+	assert.Nil(t, err)
+
+	_, _, err = mm.ComposeActions(manifest, file)
+	assert.NotNil(t, err, "Missing files should be rejected.")
+	assert.Equal(t, 2, len(err.(utils.Errors)), "Every missing file should be reported.")
+	assert.Contains(t, err.Error(), "action first: ")
+	assert.Contains(t, err.Error(), "action second: ")
+
+	_, err = mm.ComposeSequences("guest", manifest)
+	assert.NotNil(t, err, "Broken sequences should be rejected.")
+	assert.Equal(t, 2, len(err.(utils.Errors)), "Every broken sequence should be reported.")
+	assert.Contains(t, err.Error(), "sequence empty has no actions")
+	assert.Contains(t, err.Error(), "sequence greet references undefined action nowhere")
+
+	_, err = mm.ComposeRules(manifest)
+	assert.NotNil(t, err, "Broken references of rules should be rejected.")
+	assert.Equal(t, "Invalid rule orphan: rule orphan references undefined trigger never, rule orphan references undefined action ghost", err.Error())
+}
+
+func TestComposeActions_Conductor(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestConductor)
+
+	records, _, err := mm.ComposeActions(manifest, manifestConductor)
+	assert.Nil(t, err, "Composing the conductor actions failed.")
+
+	actions := make(map[string]*whisk.Action)
+	for _, record := range records {
+		actions[record.Action.Name] = record.Action
+	}
+	assert.Equal(t, 3, len(actions), "The bundled action should be deployed with the composition.")
+	assert.NotNil(t, actions["authenticate"], "Bundled actions should be deployed in the package.")
+
+	demo := actions["demo"]
+	assert.Equal(t, "nodejs:default", demo.Exec.Kind, "The conductor code should come from the composition.")
+	assert.Equal(t, map[string]interface{}{"type": "if"}, annotationValue(demo.Annotations, "conductor"), "The composer's conductor annotation should be kept.")
+	assert.Equal(t, "composed greeting", annotationValue(demo.Annotations, "description"), "Manifest annotations should be added.")
+	assert.Equal(t, true, annotationValue(actions["guide"].Annotations, "conductor"), "conductor: true should set the annotation.")
+
+	_, _, err = parsers.ReadComposition("../../dat/composition/app.json", "other")
+	assert.NotNil(t, err, "Bundled actions of another package should be rejected.")
+}
+
+func annotationValue(annotations whisk.KeyValueArr, key string) interface{} {
+	for _, annotation := range annotations {
+		if annotation.Key == key {
+			return annotation.Value
+		}
+	}
+	return nil
+}
+
+func TestComposeDependencies_Ref(t *testing.T) {
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestDependencies)
+
+	deps, err := mm.ComposeDependencies(manifest, "/project")
+	assert.Nil(t, err, "Composing the dependencies failed.")
+	assert.Equal(t, "master", deps["hellowhisk"].Version, "A dependency without a ref should deploy master.")
+	assert.Equal(t, "v1.0.0", deps["stable"].Version, "The tag should select the version.")
+	assert.Equal(t, "feature/preview", deps["preview"].Version, "The branch should select the version.")
+	assert.Equal(t, "hellopreview", deps["preview"].Package, "The dependency package should be kept.")
+	assert.Equal(t, "", deps["stable"].Package, "A dependency should deploy into its own package by default.")
+	assert.Equal(t, "https://github.com/paulcastro/hellowhisk", deps["stable"].Location, "Github locations should be urls.")
+	assert.Equal(t, "git@github.com:paulcastro/private.git", deps["private"].Location, "ssh locations should be kept.")
+	assert.True(t, deps["myCloudant"].IsBinding, "whisk.system dependencies are bindings.")
+	assert.Equal(t, "/project/Packages/preview-feature-preview", utils.DependencyPath(deps["preview"].ProjectPath, "preview", deps["preview"].Version),
+		"Branches should be cloned into their own directory.")
+
+	dependency := manifest.Package.Dependencies["stable"]
+	dependency.Branch = "master"
+	manifest.Package.Dependencies["stable"] = dependency
+	_, err = mm.ComposeDependencies(manifest, "/project")
+	assert.NotNil(t, err, "A dependency cannot set both a branch and a tag.")
+}
+
+func TestComposeActions_RemoteArtifact(t *testing.T) {
+	artifact := []byte("PK\x03\x04 prebuilt bundle")
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write(artifact)
+	}))
+	sum := sha256.Sum256(artifact)
+	checksum := hex.EncodeToString(sum[:])
+
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv(utils.ArtifactCacheEnv, os.Getenv(utils.ArtifactCacheEnv))
+	os.Setenv(utils.ArtifactCacheEnv, path.Join(dir, "cache"))
+
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    bundle:
+      function: `+server.URL+`/bundle.zip
+      runtime: nodejs:6
+      sha256: `+checksum+`
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	assert.Equal(t, server.URL+"/bundle.zip", manifest.Package.Actions["bundle"].Location, "function should be read as the location.")
+
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the remote action failed.")
+	assert.Equal(t, base64.StdEncoding.EncodeToString(artifact), *actions[0].Action.Exec.Code, "The downloaded zip should be the action code.")
+	assert.Equal(t, "nodejs:6", actions[0].Action.Exec.Kind)
+
+	server.Close()
+	_, _, err = mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "A verified artifact should be read from the cache.")
+	assert.Equal(t, 1, downloads, "The artifact should be downloaded once.")
+
+	action := manifest.Package.Actions["bundle"]
+	action.Sha256 = hex.EncodeToString(make([]byte, sha256.Size))
+	manifest.Package.Actions["bundle"] = action
+	_, _, err = mm.ComposeActions(manifest, manifestPath)
+	assert.NotNil(t, err, "An artifact that cannot be verified should be rejected.")
+}
+
+func TestComposeActions_Docker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// a docker CLI recording its arguments
+	logPath := path.Join(dir, "docker.log")
+	fakeDocker := path.Join(dir, "docker")
+	ioutil.WriteFile(fakeDocker, []byte(`#!/bin/sh
+echo "$@" >> `+logPath+`
+if [ "$1" = inspect ]; then echo sha256:0123456789abcdef0123; fi
+`), 0755)
+	defer func(command string) { utils.DockerCommand = command }(utils.DockerCommand)
+	utils.DockerCommand = fakeDocker
+	defer func(registry string) { utils.Flags.DockerRegistry = registry }(utils.Flags.DockerRegistry)
+	utils.Flags.DockerRegistry = "registry.example.com/team"
+
+	os.MkdirAll(path.Join(dir, "image"), os.ModePerm)
+	ioutil.WriteFile(path.Join(dir, "image", "Dockerfile"), []byte("FROM openwhisk/dockerskeleton\n"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    Native:
+      docker: image
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the docker action failed.")
+	assert.Equal(t, "blackbox", actions[0].Action.Exec.Kind)
+	assert.Equal(t, "registry.example.com/team/helloworld-native:0123456789ab", actions[0].Action.Exec.Image, "The action should run the pushed image.")
+
+	commands, _ := ioutil.ReadFile(logPath)
+	assert.Equal(t, "build -t registry.example.com/team/helloworld-native:latest -f "+path.Join(dir, "image", "Dockerfile")+" "+path.Join(dir, "image")+"\n"+
+		"inspect --format {{.Id}} registry.example.com/team/helloworld-native:latest\n"+
+		"tag registry.example.com/team/helloworld-native:latest registry.example.com/team/helloworld-native:0123456789ab\n"+
+		"push registry.example.com/team/helloworld-native:0123456789ab\n", string(commands), "The image should be built, tagged and pushed.")
+}
+
+func TestComposeActions_Build(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fnDir := path.Join(dir, "fn")
+	os.MkdirAll(fnDir, os.ModePerm)
+	ioutil.WriteFile(path.Join(fnDir, "index.js"), []byte("function main() {}"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    built:
+      location: fn
+      runtime: nodejs:6
+      build: mkdir -p node_modules && echo built > node_modules/dep.js
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the built action failed.")
+
+	content, _ := base64.StdEncoding.DecodeString(*actions[0].Action.Exec.Code)
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "fn/node_modules/dep.js", "The build output should be zipped.")
+
+	action := manifest.Package.Actions["built"]
+	action.Build = "exit 3"
+	manifest.Package.Actions["built"] = action
+	_, _, err = mm.ComposeActions(manifest, manifestPath)
+	assert.NotNil(t, err, "A failing build should be reported.")
+
+	assert.Equal(t, "", utils.DefaultBuild(fnDir))
+	ioutil.WriteFile(path.Join(fnDir, "package.json"), []byte("{}"), 0644)
+	assert.Equal(t, "npm install --production", utils.DefaultBuild(fnDir), "package.json dependencies should be installed.")
+}
+
+func TestComposeActions_DirectoryRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, files := range map[string][]string{
+		"php":    {"composer.json", "index.php"},
+		"ruby":   {"main.rb", "helpers.rb", "README.md"},
+		"dotnet": {"Hello.csproj", "Hello.cs"},
+		"mixed":  {"main.rb", "main.py"},
+	} {
+		os.MkdirAll(path.Join(dir, name), os.ModePerm)
+		for _, file := range files {
+			ioutil.WriteFile(path.Join(dir, name, file), []byte("{}"), 0644)
+		}
+	}
+	ioutil.WriteFile(path.Join(dir, "ruby", ".wskignore"), []byte("README.md\n"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    php:
+      function: php
+    ruby:
+      function: ruby
+    dotnet:
+      function: dotnet
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "The runtimes of the directories should be inferred.")
+	kinds := make(map[string]string)
+	for _, action := range actions {
+		kinds[action.Action.Name] = action.Action.Exec.Kind
+	}
+	assert.Equal(t, map[string]string{"php": "php:default", "ruby": "ruby:default", "dotnet": "dotnet:default"}, kinds)
+
+	for _, action := range actions {
+		if action.Action.Name != "ruby" {
+			continue
+		}
+		content, _ := base64.StdEncoding.DecodeString(*action.Action.Exec.Code)
+		archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		assert.Nil(t, err, "The directory should be zipped.")
+		for _, file := range archive.File {
+			assert.NotContains(t, file.Name, "README.md", "Ignored files should not be zipped.")
+		}
+	}
+
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    mixed:
+      function: mixed
+`), 0644)
+	manifest, _ = mm.ParseManifest(manifestPath)
+	_, _, err = mm.ComposeActions(manifest, manifestPath)
+	assert.NotNil(t, err, "Directories of several runtimes need an explicit runtime.")
+	assert.Equal(t, "action mixed: the runtime of directory mixed cannot be told from its files, set the runtime of the action", err.Error())
+
+	action := manifest.Package.Actions["mixed"]
+	action.Runtime = "python:3"
+	manifest.Package.Actions["mixed"] = action
+	actions, _, err = mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err)
+	assert.Equal(t, "python:3", actions[0].Action.Exec.Kind, "An explicit runtime should be taken as given.")
+}
+
+func TestComposeActions_InlineCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: glue
+  actions:
+    echo:
+      runtime: nodejs:default
+      code: |
+        function main(params) {
+          return params;
+        }
+      inputs:
+        greeting: hello
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(manifestPath)
+	assert.Equal(t, 0, len(violations), "Inline code should be part of the schema.")
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the inline action failed.")
+	assert.Equal(t, "nodejs:default", actions[0].Action.Exec.Kind)
+	assert.Equal(t, "function main(params) {\n  return params;\n}\n", *actions[0].Action.Exec.Code, "The code should be the block of the manifest.")
+
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: glue
+  actions:
+    echo:
+      code: "def main(args): return args"
+    both:
+      runtime: python:3
+      function: echo.py
+      code: "def main(args): return args"
+`), 0644)
+	_, violations = parsers.ValidateManifest(manifestPath)
+	messages := make([]string, 0)
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	assert.Equal(t, []string{
+		manifestPath + ":5:7: action echo has inline code and must set its runtime",
+		manifestPath + ":9:7: action both sets both code and location",
+	}, messages)
+}
+
+func TestComposeActions_Main(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(path.Join(dir, "fn"), os.ModePerm)
+	ioutil.WriteFile(path.Join(dir, "fn", "handler.py"), []byte("def handle(args):\n    return args\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "hello.js"), []byte("function greet() {}"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: entries
+  actions:
+    zipped:
+      function: fn
+      runtime: python:3
+      main: handler.handle
+    greet:
+      function: hello.js
+      main: greet
+    hello:
+      function: hello.js
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err)
+	mains := make(map[string]string)
+	for _, action := range actions {
+		mains[action.Action.Name] = action.Action.Exec.Main
+	}
+	assert.Equal(t, map[string]string{"zipped": "handler.handle", "greet": "greet", "hello": ""}, mains, "main should set the entry point of every runtime.")
+}
+
+func TestComposeActions_StructuredInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("WSK_STRUCTURED_HOST", "db.example.com")
+	defer os.Unsetenv("WSK_STRUCTURED_HOST")
+	ioutil.WriteFile(path.Join(dir, "hello.js"), []byte("function main() {}"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: structured
+  actions:
+    hello:
+      function: hello.js
+      inputs:
+        database:
+          host: $WSK_STRUCTURED_HOST
+          port: 5432
+          options: [ssl, pool]
+        regions:
+          - us-south
+          - eu-de
+        limits:
+          type: json
+          value:
+            type: strict
+            max: 10
+        name: World
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err)
+	values := make(map[string]interface{})
+	for _, param := range actions[0].Action.Parameters {
+		values[param.Key] = param.Value
+	}
+	assert.Equal(t, map[string]interface{}{
+		"database": map[string]interface{}{"host": "db.example.com", "port": 5432, "options": []interface{}{"ssl", "pool"}},
+		"regions":  []interface{}{"us-south", "eu-de"},
+		"limits":   map[string]interface{}{"type": "strict", "max": 10},
+		"name":     "World",
+	}, values, "Mappings and lists should be the values of the inputs.")
+
+	content, err := json.Marshal(actions[0].Action.Parameters)
+	assert.Nil(t, err, "Structured values should be sent as JSON.")
+	assert.Contains(t, string(content), `{"key":"regions","value":["us-south","eu-de"]}`)
+}
+
+func TestComposeActions_TypeScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// a compiler emitting a fixed module into --outDir
+	fakeTsc := path.Join(dir, "tsc")
+	ioutil.WriteFile(fakeTsc, []byte(`#!/bin/sh
+while [ $# -gt 1 ]; do
+  if [ "$1" = --outDir ]; then out=$2; fi
+  shift
+done
+echo "exports.main = main;" > $out/$(basename $1 .ts).js
+`), 0755)
+	defer func(command string) { utils.TypeScriptCommand = command }(utils.TypeScriptCommand)
+	utils.TypeScriptCommand = fakeTsc
+
+	ioutil.WriteFile(path.Join(dir, "hello.ts"), []byte("export function main() { return {}; }"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    hello:
+      location: hello.ts
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the TypeScript action failed.")
+	assert.Equal(t, "nodejs:default", actions[0].Action.Exec.Kind)
+	assert.Equal(t, "exports.main = main;\n", *actions[0].Action.Exec.Code, "The emitted JavaScript should be deployed.")
+
+	fnDir := path.Join(dir, "fn")
+	os.MkdirAll(fnDir, os.ModePerm)
+	ioutil.WriteFile(path.Join(fnDir, "package.json"), []byte("{}"), 0644)
+	ioutil.WriteFile(path.Join(fnDir, "tsconfig.json"), []byte("{}"), 0644)
+	assert.Equal(t, "npm install --production && "+fakeTsc+" -p .", utils.DefaultBuild(fnDir), "A TypeScript project should be compiled.")
+}
+
+func TestComposeActions_Go(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// a go tool recording the target platform in the binary
+	fakeGo := path.Join(dir, "go")
+	ioutil.WriteFile(fakeGo, []byte(`#!/bin/sh
+echo "$GOOS/$GOARCH $4" > $3
+`), 0755)
+	defer func(command string) { utils.GoCommand = command }(utils.GoCommand)
+	utils.GoCommand = fakeGo
+
+	ioutil.WriteFile(path.Join(dir, "hello.go"), []byte("package main\n"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    native:
+      location: hello.go
+    runtime:
+      location: hello.go
+      runtime: go:1.11
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the Go actions failed.")
+
+	execs := make(map[string]*whisk.Exec)
+	for _, record := range actions {
+		execs[record.Action.Name] = record.Action.Exec
+	}
+	assert.Equal(t, "blackbox", execs["native"].Kind)
+	assert.Equal(t, "openwhisk/dockerskeleton", execs["native"].Image, "A Go action should be native by default.")
+	assert.Equal(t, "go:1.11", execs["runtime"].Kind)
+	assert.Equal(t, "", execs["runtime"].Image, "The go runtime needs no image.")
+
+	content, _ := base64.StdEncoding.DecodeString(*execs["native"].Code)
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	assert.Nil(t, err)
+	assert.Equal(t, "exec", archive.File[0].Name, "The binary should be zipped as exec.")
+	binary, _ := archive.File[0].Open()
+	built, _ := ioutil.ReadAll(binary)
+	assert.Equal(t, "linux/amd64 hello.go\n", string(built), "The function should be cross-compiled for linux/amd64.")
+}
+
+func TestComposeActions_Maven(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// a build producing a fat jar next to its sources jar
+	fakeMaven := path.Join(dir, "mvn")
+	ioutil.WriteFile(fakeMaven, []byte(`#!/bin/sh
+mkdir -p target
+echo "fat jar with dependencies" > target/hello-1.0.jar
+echo "src" > target/hello-1.0-sources.jar
+`), 0755)
+	defer func(command string) { utils.MavenCommand = command }(utils.MavenCommand)
+	utils.MavenCommand = fakeMaven
+
+	projectDir := path.Join(dir, "hello")
+	os.MkdirAll(projectDir, os.ModePerm)
+	ioutil.WriteFile(path.Join(projectDir, "pom.xml"), []byte("<project/>"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    hello:
+      location: hello
+      main: com.example.Hello
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the Maven action failed.")
+	assert.Equal(t, "java:default", actions[0].Action.Exec.Kind)
+	assert.Equal(t, "com.example.Hello", actions[0].Action.Exec.Main)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("fat jar with dependencies\n")), *actions[0].Action.Exec.Code, "The built jar should be deployed.")
+
+	action := manifest.Package.Actions["hello"]
+	action.Main = ""
+	manifest.Package.Actions["hello"] = action
+	_, _, err = mm.ComposeActions(manifest, manifestPath)
+	assert.NotNil(t, err, "A Java action without main should be rejected.")
+}
+
+func TestComposeActions_Virtualenv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// a docker CLI creating the virtualenv in the mounted directory
+	logPath := path.Join(dir, "docker.log")
+	fakeDocker := path.Join(dir, "docker")
+	ioutil.WriteFile(fakeDocker, []byte(`#!/bin/sh
+echo "$9 ${10} ${11}" > `+logPath+`
+fn=${4%:/tmp/fn}
+mkdir -p $fn/virtualenv/bin && touch $fn/virtualenv/bin/activate_this.py
+`), 0755)
+	defer func(command string) { utils.DockerCommand = command }(utils.DockerCommand)
+	utils.DockerCommand = fakeDocker
+
+	fnDir := path.Join(dir, "fn")
+	os.MkdirAll(fnDir, os.ModePerm)
+	ioutil.WriteFile(path.Join(fnDir, "__main__.py"), []byte("def main(args):\n    return args\n"), 0644)
+	ioutil.WriteFile(path.Join(fnDir, "requirements.txt"), []byte("numpy\n"), 0644)
+	manifestPath := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(manifestPath, []byte(`package:
+  name: helloworld
+  actions:
+    numbers:
+      location: fn
+      runtime: python:3
+      virtualenv: docker
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(manifestPath)
+	actions, _, err := mm.ComposeActions(manifest, manifestPath)
+	assert.Nil(t, err, "Composing the virtualenv action failed.")
+
+	commands, _ := ioutil.ReadFile(logPath)
+	assert.Equal(t, "openwhisk/python3action -c virtualenv virtualenv && . virtualenv/bin/activate && pip install -r requirements.txt\n", string(commands),
+		"The virtualenv should be built in the image of the runtime.")
+
+	content, _ := base64.StdEncoding.DecodeString(*actions[0].Action.Exec.Code)
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "fn/virtualenv/bin/activate_this.py", "The virtualenv should be zipped with the function.")
+
+	_, err = utils.VirtualenvBuild("sometimes", "python:3", fnDir)
+	assert.NotNil(t, err, "Unknown virtualenv modes should be rejected.")
+}
+
+func TestCheckCron(t *testing.T) {
+	for _, expression := range []string{"* * * * *", "*/5 1-5 * jan-MAR mon,fri", "0 0 12 1 1 0", "0,30 8-18/2 * * 1-5"} {
+		assert.Nil(t, parsers.CheckCron(expression), "Cron expression "+expression+" should be valid.")
+	}
+	for _, expression := range []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "every hour"} {
+		assert.NotNil(t, parsers.CheckCron(expression), "Cron expression "+expression+" should be invalid.")
+	}
+}
+
+func TestValidateManifest_Alarms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: alarms
+  triggers:
+    hourly:
+      source: /whisk.system/alarms/alarm
+      inputs:
+        cron: "0 */1 * * *"
+        startDate: "2017-05-12T15:00:00Z"
+        stopDate: "2018-05-12T15:00:00Z"
+    broken:
+      source: /whisk.system/alarms/alarm
+      inputs:
+        cron: "61 * * * *"
+        startDate: "2018-05-12T15:00:00Z"
+        stopDate: "2017-05-12"
+    later:
+      source: /whisk.system/alarms/once
+      inputs:
+        date: "next tuesday"
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	messages := make([]string, 0)
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	sort.Strings(messages)
+	assert.Equal(t, []string{
+		"trigger broken: cron: expression \"61 * * * *\": minute must be between 0 and 59, got 61",
+		"trigger broken: stopDate must be after startDate",
+		"trigger later: date: \"next tuesday\" is not an ISO 8601 date such as 2017-05-12T15:00:00Z",
+	}, messages, "Alarm parameters should be checked.")
+}
+
+func TestComposeAnnotations_Entities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "annotations")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: annotated
+  annotations:
+    owner: team-a
+    catalog:
+      tags: [demo, sample]
+  actions:
+    hello:
+      function: hello.js
+  triggers:
+    tick:
+      source: /whisk.system/alarms/alarm
+      annotations:
+        purpose: heartbeat
+  rules:
+    tickRule:
+      trigger: tick
+      action: hello
+      annotations:
+        routing:
+          priority: 1
+`), 0644)
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(file)
+
+	pkg, err := mm.ComposePackage(manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{
+		{Key: "catalog", Value: map[string]interface{}{"tags": []interface{}{"demo", "sample"}}},
+		{Key: "owner", Value: "team-a"},
+	}, pkg.Annotations, "Package annotations should be composed in key order.")
+	_, err = json.Marshal(pkg)
+	assert.Nil(t, err, "JSON object annotations should be encodable.")
+
+	triggers, err := mm.ComposeTriggers(manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{
+		{Key: "purpose", Value: "heartbeat"},
+		{Key: "feed", Value: "/whisk.system/alarms/alarm"},
+	}, triggers[0].Annotations, "Trigger annotations should be kept with the feed annotation.")
+
+	rules, err := mm.ComposeRules(manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{{Key: "routing", Value: map[string]interface{}{"priority": 1}}}, rules[0].Annotations)
+}
+
+func TestComposeAnnotations_Metadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(path.Join(dir, "hello.js"), []byte("function main() {}"), 0644)
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: described
+  description: Greetings as a service
+  author: Team A
+  docs: https://example.com/described
+  actions:
+    hello:
+      function: hello.js
+      description: Says hello
+      annotations:
+        author: Someone else
+  sequences:
+    greet:
+      actions: hello
+      description: Greets twice
+  triggers:
+    tick:
+      description: Fires every minute
+  rules:
+    tickRule:
+      trigger: tick
+      action: hello
+      description: Greets on every tick
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	assert.Equal(t, 0, len(violations), "Metadata fields should be part of the schema.")
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(file)
+
+	pkg, err := mm.ComposePackage(manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{
+		{Key: "description", Value: "Greetings as a service"},
+		{Key: "author", Value: "Team A"},
+		{Key: "docs", Value: "https://example.com/described"},
+	}, pkg.Annotations, "Package metadata should become annotations.")
+
+	actions, _, err := mm.ComposeActions(manifest, file)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{
+		{Key: "description", Value: "Says hello"},
+		{Key: "author", Value: "Someone else"},
+	}, actions[0].Action.Annotations, "Declared annotations should be kept alongside the description.")
+
+	sequences, err := mm.ComposeSequences("", manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{{Key: "description", Value: "Greets twice"}}, sequences[0].Action.Annotations)
+
+	triggers, err := mm.ComposeTriggers(manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{{Key: "description", Value: "Fires every minute"}}, triggers[0].Annotations)
+
+	rules, err := mm.ComposeRules(manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, whisk.KeyValueArr{{Key: "description", Value: "Greets on every tick"}}, rules[0].Annotations)
+}
+
+const editedManifest = `# the demo package
+package:
+    name: demo   # keep me
+    actions:
+        hello:
+            function: src/hello.js
+
+        # the second action
+        bye:
+            function: src/bye.js
+    # rules come last
+    rules:
+        greet:
+            trigger: every-minute
+            action: hello
+`
+
+func TestParseManifest_Conditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conditions")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv("WSK_TEST_STAGE", "prod")
+	defer os.Unsetenv("WSK_TEST_STAGE")
+
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: staged
+  actions:
+    hello:
+      code: "function main() { return {}; }"
+      runtime: nodejs:default
+      web-export: true
+    debug:
+      code: "function main(params) { return params; }"
+      runtime: nodejs:default
+      web-export: true
+      when: ${WSK_TEST_STAGE} == dev
+  sequences:
+    traced:
+      actions: debug, hello
+      ifdef: WSK_TEST_TRACING
+  triggers:
+    tick:
+    debugTick:
+      when: ${WSK_TEST_STAGE} != prod
+  rules:
+    tickRule:
+      trigger: tick
+      action: hello
+    debugRule:
+      trigger: tick
+      action: staged/debug
+    debugTickRule:
+      trigger: debugTick
+      action: hello
+  apis:
+    /staged:
+      /debug:
+        get: debug
+      /hello:
+        get: hello
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	assert.Equal(t, 0, len(violations), "Conditions should be part of the schema.")
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(file)
+	pkg := manifest.Package
+	assert.Equal(t, 1, len(pkg.Actions), "Only the actions whose conditions hold should be kept.")
+	assert.NotNil(t, pkg.Actions["hello"])
+	assert.Equal(t, 0, len(pkg.Sequences), "Sequences of unset ifdef variables should be dropped.")
+	assert.Equal(t, 1, len(pkg.Triggers))
+	assert.Equal(t, []string{"tickRule"}, ruleNames(pkg.Rules), "Rules of dropped actions and triggers should be dropped.")
+	assert.Equal(t, map[string]map[string]map[string]string{"/staged": {"/hello": {"get": "hello"}}}, pkg.Apis.Routes)
+
+	ioutil.WriteFile(file, []byte(`package:
+  name: staged
+  when: ${WSK_TEST_STAGE} ==
+  actions:
+    hello:
+      code: "function main() { return {}; }"
+      runtime: nodejs:default
+      ifdef: two words
+`), 0644)
+	_, violations = parsers.ValidateManifest(file)
+	messages := make([]string, 0)
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	assert.Equal(t, []string{
+		file + ":3:3: package staged: Invalid condition ${WSK_TEST_STAGE} ==: it ends where an operand is expected",
+		file + ":8:7: ifdef of action hello must name a single variable",
+	}, messages)
+}
+
+func TestParseManifest_Foreach(t *testing.T) {
+	dir, err := ioutil.TempDir("", "foreach")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	os.Setenv("WSK_TEST_REGIONS", "us-south, eu-de")
+	defer os.Unsetenv("WSK_TEST_REGIONS")
+
+	file := path.Join(dir, "manifest.yaml")
+	ioutil.WriteFile(file, []byte(`package:
+  name: regional
+  actions:
+    report-${item.region}:
+      foreach:
+        - region: us-south
+          port: 8080
+        - region: eu-de
+          port: 9090
+      code: "function main(params) { return params; }"
+      runtime: nodejs:default
+      inputs:
+        region: ${item.region}
+        port: ${item.port}
+        url: https://${item.region}.example.com:${item.port}
+  triggers:
+    tick-${item}:
+      foreach: ${WSK_TEST_REGIONS}
+      inputs:
+        region: ${item}
+  rules:
+    tickRule-${item}:
+      foreach: [us-south, eu-de]
+      trigger: tick-${item}
+      action: report-${item}
+`), 0644)
+
+	_, violations := parsers.ValidateManifest(file)
+	assert.Equal(t, 0, len(violations), "The copies of foreach should validate.")
+
+	mm := parsers.NewYAMLParser()
+	manifest, _ := mm.ParseManifest(file)
+	pkg := manifest.Package
+	assert.Equal(t, 2, len(pkg.Actions))
+	action := pkg.Actions["report-eu-de"]
+	assert.Nil(t, action.Foreach, "The copies should not repeat again.")
+	assert.Equal(t, "eu-de", action.Inputs["region"].Value)
+	assert.Equal(t, 9090, action.Inputs["port"].Value, "A single reference should keep the type of the field.")
+	assert.Equal(t, "https://eu-de.example.com:9090", action.Inputs["url"].Value)
+	assert.Equal(t, 8080, pkg.Actions["report-us-south"].Inputs["port"].Value, "The copies should not share their inputs.")
+
+	assert.Equal(t, 2, len(pkg.Triggers), "The items may be given as a comma separated variable.")
+	assert.Equal(t, "us-south", pkg.Triggers["tick-us-south"].Inputs["region"].Value)
+	assert.Equal(t, []string{"tickRule-eu-de", "tickRule-us-south"}, ruleNames(pkg.Rules))
+	assert.Equal(t, "tick-eu-de", pkg.Rules["tickRule-eu-de"].Trigger)
+	assert.Equal(t, "report-eu-de", pkg.Rules["tickRule-eu-de"].Action)
+
+	ioutil.WriteFile(file, []byte(`package:
+  name: regional
+  triggers:
+    tick:
+      foreach: [us-south, eu-de]
+`), 0644)
+	_, violations = parsers.ValidateManifest(file)
+	assert.Equal(t, 1, len(violations))
+	assert.Equal(t, file+":1:1: Trigger tick repeats with foreach and must use ${item} in its name", violations[0].String())
+}
+
+func TestCheckStrict(t *testing.T) {
+	manifest := []byte(`package:
+  name: strict
+  actions:
+    hello:
+      function: hello.js
+      annotatoins:
+        owner: team-a
+`)
+	assert.Nil(t, parsers.CheckStrict("manifest.yaml", manifest, parsers.ManifestYAML{}), "Unknown keys should be ignored without --strict.")
+
+	utils.Flags.Strict = true
+	defer func() { utils.Flags.Strict = false }()
+	err := parsers.CheckStrict("manifest.yaml", manifest, parsers.ManifestYAML{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "Unknown keys in manifest.yaml, which --strict does not ignore:\n"+
+		"manifest.yaml:6:7: unknown key 'annotatoins', did you mean 'annotations'?", err.Error())
+
+	deployment := []byte(`application:
+  name: strict
+  packages:
+    strict:
+      inputs:
+        region: us-south
+`)
+	assert.Nil(t, parsers.CheckStrict("deployment.yaml", deployment, parsers.DeploymentYAML{}), "Known keys should pass.")
+	assert.Nil(t, parsers.CheckStrict("deployment.yaml", []byte("application: ["), parsers.DeploymentYAML{}), "Syntax errors are left to the parser.")
+}
+
+func ruleNames(rules map[string]parsers.Rule) []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestAddManifestEntry(t *testing.T) {
+	action := parsers.Action{Function: "src/new.js", Runtime: "nodejs:6"}
+	edited, err := parsers.AddManifestEntry([]byte(editedManifest), "actions", "new", action)
+	assert.Nil(t, err)
+	assert.Equal(t, `# the demo package
+package:
+    name: demo   # keep me
+    actions:
+        hello:
+            function: src/hello.js
+
+        # the second action
+        bye:
+            function: src/bye.js
+        new:
+            runtime: nodejs:6
+            function: src/new.js
+    # rules come last
+    rules:
+        greet:
+            trigger: every-minute
+            action: hello
+`, string(edited))
+
+	trigger := parsers.Trigger{Source: "/whisk.system/alarms/alarm"}
+	edited, err = parsers.AddManifestEntry(edited, "triggers", "every-minute", trigger)
+	assert.Nil(t, err)
+	mm := parsers.NewYAMLParser()
+	manifest := parsers.ManifestYAML{}
+	assert.Nil(t, mm.Unmarshal(edited, &manifest))
+	triggers, err := mm.ComposeTriggers(&manifest)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(triggers))
+	feed, isFeed := utils.IsFeedAction(triggers[0])
+	assert.True(t, isFeed, "The added trigger should be deployed with its feed")
+	assert.Equal(t, "/whisk.system/alarms/alarm", feed)
+	assert.Equal(t, "src/new.js", manifest.Package.Actions["new"].Function)
+	assert.Equal(t, "hello", manifest.Package.Rules["greet"].Action)
+	assert.Contains(t, string(edited), "    # rules come last\n    rules:\n")
+	assert.Contains(t, string(edited), "    triggers:\n        every-minute:\n            source: /whisk.system/alarms/alarm\n")
+
+	_, err = parsers.AddManifestEntry(edited, "rules", "greet", parsers.Rule{Trigger: "t", Action: "a"})
+	assert.NotNil(t, err, "Adding a rule twice should fail")
+	_, err = parsers.AddManifestEntry(edited, "apis", "greet", nil)
+	assert.NotNil(t, err)
+}
+
+func TestAddManifestEntry_EmptyManifest(t *testing.T) {
+	edited, err := parsers.AddManifestEntry([]byte{}, "rules", "greet", parsers.Rule{Trigger: "t", Action: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "package:\n  rules:\n    greet:\n      trigger: t\n      action: a\n", string(edited))
+
+	edited, err = parsers.AddManifestPackage(edited, parsers.Package{Packagename: "demo", Version: "0.0.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "package:\n  name: demo\n  version: 0.0.1\n  rules:\n    greet:\n      trigger: t\n      action: a\n", string(edited))
+
+	_, err = parsers.AddManifestPackage(edited, parsers.Package{Packagename: "other"})
+	assert.NotNil(t, err, "A manifest holds a single package")
+
+	edited, err = parsers.AddManifestEntry([]byte("package:\n  name: demo\n  actions: {}\n"), "actions", "hello", parsers.Action{Function: "hello.js"})
+	assert.Nil(t, err)
+	assert.Equal(t, "package:\n  name: demo\n  actions:\n    hello:\n      function: hello.js\n", string(edited))
+}
@@ -0,0 +1,73 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditTransport(t *testing.T) {
+	entities := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if entity, ok := entities[r.URL.Path]; ok {
+				w.Write([]byte(entity))
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case "PUT":
+			entities[r.URL.Path] = `{"name":"hello","version":"0.0.` + string(rune('0'+len(entities))) + `"}`
+			w.Write([]byte(entities[r.URL.Path]))
+		case "DELETE":
+			delete(entities, r.URL.Path)
+		case "POST":
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	var audit bytes.Buffer
+	client := &http.Client{Transport: utils.NewAuditTransport(nil, &audit, "uuid")}
+
+	action := server.URL + "/api/v1/namespaces/guest/actions/pkg/hello"
+	for _, method := range []string{"PUT", "PUT", "DELETE"} {
+		req, _ := http.NewRequest(method, action+"?overwrite=true", strings.NewReader("{}"))
+		_, err := client.Do(req)
+		assert.Nil(t, err)
+	}
+	// invocations change nothing
+	req, _ := http.NewRequest("POST", action+"?blocking=true", strings.NewReader("{}"))
+	client.Do(req)
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	assert.Len(t, lines, 3, "Every change and only changes should be recorded.")
+	entries := make([]utils.AuditEntry, len(lines))
+	for i, line := range lines {
+		assert.Nil(t, json.Unmarshal([]byte(line), &entries[i]), "Entries should be JSON lines.")
+	}
+
+	assert.Equal(t, "create", entries[0].Operation)
+	assert.Equal(t, "uuid", entries[0].Subject)
+	assert.Equal(t, "guest", entries[0].Namespace)
+	assert.Equal(t, "action", entries[0].Kind)
+	assert.Equal(t, "pkg/hello", entries[0].Name)
+	assert.Empty(t, entries[0].Before)
+	assert.NotEmpty(t, entries[0].After)
+
+	assert.Equal(t, "update", entries[1].Operation)
+	assert.Equal(t, entries[0].After, entries[1].Before, "The hash before an update should be the hash after the create.")
+	assert.NotEqual(t, entries[1].Before, entries[1].After)
+
+	assert.Equal(t, "delete", entries[2].Operation)
+	assert.Equal(t, entries[1].After, entries[2].Before)
+	assert.Empty(t, entries[2].After)
+}
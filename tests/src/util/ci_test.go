@@ -0,0 +1,30 @@
+// +build unit
+
+package tests
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	invalid := utils.WithExitCode(utils.ExitInvalid, errors.New("missing manifest file manifest.yaml"))
+	assert.Equal(t, utils.ExitFailure, utils.ExitCode(invalid), "Every failure should exit with 1 without --ci.")
+
+	utils.Flags.CI = true
+	defer func() { utils.Flags.CI = false }()
+	assert.Equal(t, utils.ExitInvalid, utils.ExitCode(invalid))
+	assert.Equal(t, invalid, utils.WithExitCode(utils.ExitAPI, invalid), "A failure should keep the class it has.")
+	assert.Nil(t, utils.WithExitCode(utils.ExitAPI, nil))
+	assert.Equal(t, utils.ExitFailure, utils.ExitCode(errors.New("unclassified")))
+	assert.Equal(t, utils.ExitNetwork, utils.ExitCode(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.Equal(t, utils.ExitNetwork, utils.ExitCode(&whisk.WskError{RootErr: errors.New("timed out"), TimedOut: true}))
+	assert.Equal(t, utils.ExitAPI, utils.ExitCode(&whisk.WskError{RootErr: errors.New("The requested resource does not exist."), ExitCode: whisk.EXIT_CODE_NOT_FOUND}))
+
+	assert.Equal(t, `level=error class=invalid exit=3 msg="missing manifest file manifest.yaml"`, utils.FailureLine(invalid))
+}
@@ -0,0 +1,49 @@
+// +build unit
+
+package tests
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptValue_RoundTrip(t *testing.T) {
+	encoded, err := utils.GenerateKey()
+	assert.Nil(t, err)
+	key, _ := base64.StdEncoding.DecodeString(encoded)
+
+	encrypted, err := utils.EncryptValue("s3cr3t", key)
+	assert.Nil(t, err, "Encrypting should succeed.")
+	assert.True(t, utils.IsEncrypted(encrypted), "Encrypted values should use the ENC[...] form.")
+	assert.False(t, strings.Contains(encrypted, "s3cr3t"), "The plaintext should not show.")
+
+	plaintext, err := utils.DecryptValue(encrypted, key)
+	assert.Nil(t, err, "Decrypting should succeed.")
+	assert.Equal(t, "s3cr3t", plaintext)
+
+	otherEncoded, _ := utils.GenerateKey()
+	otherKey, _ := base64.StdEncoding.DecodeString(otherEncoded)
+	_, err = utils.DecryptValue(encrypted, otherKey)
+	assert.NotNil(t, err, "Decrypting with another key should fail.")
+
+	os.Setenv(utils.EncryptionKeyVar, encoded)
+	defer os.Unsetenv(utils.EncryptionKeyVar)
+	assert.Equal(t, "s3cr3t", utils.GetEnvVar(encrypted), "Inputs should be decrypted.")
+}
+
+func TestDecryptValue_SOPS(t *testing.T) {
+	// password: hunter2 encrypted the way sops does, with a 32 byte IV and
+	// the path of the value as additional data
+	key, _ := base64.StdEncoding.DecodeString("3EJgT8o999nh8JkbIxv5xDnSqhy9yB2bEce9EhpYr6c=")
+	value := "ENC[AES256_GCM,data:bOd739nqPw==,iv:8RbKmdzKDOToyiWQf8uVPt3wbJCGucR2B2Syyfda5vA=,tag:V5yG46c9IcCDpO751bxRaA==,type:str]"
+
+	assert.True(t, utils.IsEncrypted(value))
+	_, err := utils.DecryptValue(value, key)
+	assert.NotNil(t, err, "Values encrypted by sops cannot be decrypted.")
+	assert.Contains(t, err.Error(), "encrypted by SOPS", "Values encrypted by sops should not be reported as malformed.")
+}
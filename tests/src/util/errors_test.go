@@ -0,0 +1,47 @@
+// +build unit
+
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	var errs utils.Errors
+	errs.Add(nil)
+	assert.Nil(t, errs.Err(), "No error should be nil.")
+
+	missing := utils.FileErr("manifest.yaml", errors.New("unknown kind nodejs:99"))
+	errs.Add(utils.EntityErr("action", "hello", missing))
+	assert.Equal(t, "action hello: manifest.yaml: unknown kind nodejs:99", errs.Err().Error(), "One error should be reported as it is.")
+
+	var more utils.Errors
+	more.Add(utils.EntityErr("rule", "hourly", errors.New("trigger tick is not defined")))
+	more.Add(utils.FileErr("src/missing.js", errors.New("no such file")))
+	errs.Add(more)
+	assert.Equal(t, 3, len(errs), "Errors should be flattened.")
+	assert.Equal(t, `3 errors:
+  - action hello: manifest.yaml: unknown kind nodejs:99
+  - rule hourly: trigger tick is not defined
+  - src/missing.js: no such file`, errs.Err().Error())
+
+	assert.Nil(t, utils.FileErr("manifest.yaml", nil))
+	assert.Nil(t, utils.EntityErr("action", "hello", nil))
+}
+
+func TestErrors_ExitCode(t *testing.T) {
+	utils.Flags.CI = true
+	defer func() { utils.Flags.CI = false }()
+
+	invalid := utils.WithExitCode(utils.ExitInvalid, errors.New("yaml: line 3: mapping values are not allowed"))
+	wrapped := utils.EntityErr("dependency", "kafka", utils.FileErr("manifest.yaml", invalid))
+	assert.Equal(t, utils.ExitInvalid, utils.ExitCode(wrapped), "Wrapped failures should keep their class.")
+	assert.Equal(t, wrapped, utils.WithExitCode(utils.ExitAPI, wrapped))
+
+	errs := utils.Errors{wrapped, utils.WithExitCode(utils.ExitAPI, errors.New("conflict"))}
+	assert.Equal(t, utils.ExitInvalid, utils.ExitCode(errs), "Several failures should exit with the class of the first.")
+}
@@ -0,0 +1,56 @@
+// +build unit
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIAMTransport(t *testing.T) {
+	issued := 0
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("apikey") != "secret" || r.Form.Get("grant_type") != "urn:ibm:params:oauth:grant-type:apikey" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		issued++
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": 3600}`, issued)
+	}))
+	defer iam.Close()
+
+	var authorizations []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	transport := utils.NewIAMTransport(nil, "secret", iam.URL)
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", api.URL, nil)
+		req.SetBasicAuth("user", "pass")
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "Basic dXNlcjpwYXNz", req.Header.Get("Authorization"), "The request given should not be modified")
+	}
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1"}, authorizations, "The token should be reused while valid")
+
+	// a token about to expire is replaced
+	transport.RefreshMargin = 2 * time.Hour
+	resp, err := client.Get(api.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "Bearer token-2", authorizations[2])
+
+	_, err = (&http.Client{Transport: utils.NewIAMTransport(nil, "wrong", iam.URL)}).Get(api.URL)
+	assert.NotNil(t, err, "A rejected API key should fail the request")
+}
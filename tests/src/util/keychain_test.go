@@ -0,0 +1,77 @@
+// +build unit
+
+package tests
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type keychainCall struct {
+	stdin string
+	name  string
+	args  []string
+}
+
+// stub the keychain tools of goos, answering with stdout
+func stubKeychain(goos string, stdout string, err error) (*[]keychainCall, func()) {
+	calls := []keychainCall{}
+	savedOS, savedRun := utils.KeychainOS, utils.RunKeychainCommand
+	utils.KeychainOS = goos
+	utils.RunKeychainCommand = func(stdin string, name string, args ...string) (string, string, error) {
+		calls = append(calls, keychainCall{stdin, name, args})
+		return stdout, "", err
+	}
+	return &calls, func() { utils.KeychainOS, utils.RunKeychainCommand = savedOS, savedRun }
+}
+
+func TestKeychain_Darwin(t *testing.T) {
+	calls, restore := stubKeychain("darwin", "stored:key\n", nil)
+	defer restore()
+
+	key, err := utils.KeychainGet("openwhisk.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "stored:key", key)
+
+	assert.Nil(t, utils.KeychainSet("openwhisk.example.com", "s3cret:key"))
+	set := (*calls)[1]
+	assert.Equal(t, "security", set.name)
+	assert.Equal(t, "-w", set.args[len(set.args)-1], "The secret should be asked for.")
+	assert.NotContains(t, strings.Join(set.args, " "), "s3cret", "The secret should not be on the command line.")
+	assert.Equal(t, "s3cret:key\ns3cret:key\n", set.stdin, "The secret and its confirmation should be on stdin.")
+}
+
+func TestKeychain_Windows(t *testing.T) {
+	calls, restore := stubKeychain("windows", "stored:key\r\n", nil)
+	defer restore()
+
+	key, err := utils.KeychainGet("openwhisk.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "stored:key", key, "The Credential Manager should be read.")
+
+	assert.Nil(t, utils.KeychainSet("openwhisk.example.com", "s3cret:key"))
+	for _, call := range *calls {
+		assert.Equal(t, "powershell", call.name)
+		assert.Equal(t, "-EncodedCommand", call.args[len(call.args)-2])
+	}
+	set := (*calls)[1]
+	assert.NotContains(t, strings.Join(set.args, " "), "s3cret", "The secret should not be on the command line.")
+	assert.Equal(t, "s3cret:key", set.stdin)
+}
+
+func TestKeychain_NotStored(t *testing.T) {
+	_, restore := stubKeychain("linux", "", &exec.ExitError{})
+	defer restore()
+
+	key, err := utils.KeychainGet("openwhisk.example.com")
+	assert.Nil(t, err, "A missing key should not be an error.")
+	assert.Equal(t, "", key)
+
+	utils.KeychainOS = "plan9"
+	_, err = utils.KeychainGet("openwhisk.example.com")
+	assert.NotNil(t, err, "Unsupported systems should be reported.")
+}
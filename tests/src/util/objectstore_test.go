@@ -0,0 +1,81 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setEnv(t *testing.T, values map[string]string) func() {
+	saved := make(map[string]string)
+	for name, value := range values {
+		saved[name] = os.Getenv(name)
+		os.Setenv(name, value)
+	}
+	return func() {
+		for name, value := range saved {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+func TestFetchArtifact_S3(t *testing.T) {
+	var authorization, requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		requested = r.URL.EscapedPath()
+		if r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("bundle"))
+	}))
+	defer server.Close()
+
+	cache, err := ioutil.TempDir("", "cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(cache)
+	defer setEnv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "",
+		"AWS_REGION":            "eu-west-1",
+		"AWS_ENDPOINT_URL":      server.URL,
+		utils.ArtifactCacheEnv:  cache,
+	})()
+
+	assert.True(t, utils.IsRemoteArtifact("s3://builds/app v1.zip"))
+	local, err := utils.FetchArtifact("s3://builds/app v1.zip", "")
+	assert.Nil(t, err, "Downloading the object should succeed.")
+	content, _ := ioutil.ReadFile(local)
+	assert.Equal(t, "bundle", string(content))
+	assert.Equal(t, ".zip", local[len(local)-4:], "The cached artifact should keep its extension.")
+
+	assert.Equal(t, "/builds/app%20v1.zip", requested, "A custom endpoint should address the bucket in the path.")
+	assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), "The request should be signed.")
+	assert.Contains(t, authorization, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=")
+}
+
+func TestObjectStoreRequest_Credentials(t *testing.T) {
+	if utils.FileExists(utils.GetHomeDirectory() + "/.bluemix/cos_credentials") {
+		t.Skip("the home directory holds Cloud Object Storage credentials")
+	}
+	defer setEnv(t, map[string]string{
+		"COS_HMAC_ACCESS_KEY_ID":     "",
+		"COS_HMAC_SECRET_ACCESS_KEY": "",
+	})()
+
+	_, err := utils.ObjectStoreRequest("cos://builds/app.zip")
+	assert.Contains(t, err.Error(), "COS_HMAC_ACCESS_KEY_ID", "Missing credentials should say how to provide them.")
+
+	_, err = utils.ObjectStoreRequest("s3://builds")
+	assert.Contains(t, err.Error(), "s3://bucket/key", "A url without a key should be rejected.")
+}
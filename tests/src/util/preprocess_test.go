@@ -0,0 +1,64 @@
+// +build unit
+
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreProcess(t *testing.T) {
+	manifest := []byte(`package:
+  name: {{ .WSK_PRE_NAME | default "demo" }}
+  actions:
+{{- range split "," .WSK_PRE_REGIONS }}
+    hello-{{ trim . }}:
+      function: hello.js
+{{- end }}
+{{- if eq .WSK_PRE_ENV "dev" }}
+    debug:
+      function: debug.js
+{{- end }}
+  inputs:
+    commit: {{ var "WSK_PRE_UNSET" | quote }}
+`)
+	assert.Equal(t, string(manifest), string(mustPreProcess(t, manifest)), "Files should be parsed as they are without --pre-process.")
+
+	utils.Flags.PreProcess = utils.PreProcessGoTemplate
+	defer func() { utils.Flags.PreProcess = "" }()
+	os.Setenv("WSK_PRE_REGIONS", "us-south, eu-de")
+	os.Setenv("WSK_PRE_ENV", "dev")
+	defer os.Unsetenv("WSK_PRE_REGIONS")
+	defer os.Unsetenv("WSK_PRE_ENV")
+	err := utils.LoadVars([]string{"WSK_PRE_NAME=regional"})
+	assert.Nil(t, err)
+	defer utils.LoadVars([]string{"WSK_PRE_NAME="})
+
+	assert.Equal(t, `package:
+  name: regional
+  actions:
+    hello-us-south:
+      function: hello.js
+    hello-eu-de:
+      function: hello.js
+    debug:
+      function: debug.js
+  inputs:
+    commit: ""
+`, string(mustPreProcess(t, manifest)))
+
+	_, err = utils.PreProcess("manifest.yaml", []byte("name: {{ .WSK_PRE_NAME"))
+	assert.NotNil(t, err, "Invalid templates should fail.")
+	utils.Flags.PreProcess = "jsonnet"
+	_, err = utils.PreProcess("manifest.yaml", manifest)
+	assert.Equal(t, "Unsupported pre-processor jsonnet, use go-template", err.Error())
+}
+
+func mustPreProcess(t *testing.T, content []byte) []byte {
+	processed, err := utils.PreProcess("manifest.yaml", content)
+	assert.Nil(t, err)
+	return processed
+}
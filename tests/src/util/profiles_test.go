@@ -0,0 +1,35 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilePath(t *testing.T) {
+	home, err := ioutil.TempDir("", "profiles")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+
+	dir := path.Join(home, utils.ProfilesDirName)
+	os.MkdirAll(dir, os.ModePerm)
+	ioutil.WriteFile(path.Join(dir, "prod"), []byte("APIHOST=prod.example.com\n"), 0600)
+	ioutil.WriteFile(path.Join(dir, "staging"), []byte("APIHOST=staging.example.com\n"), 0600)
+
+	propPath, err := utils.ProfilePath(home, "prod")
+	assert.Nil(t, err, "Existing profile should be found.")
+	assert.Equal(t, path.Join(dir, "prod"), propPath)
+
+	_, err = utils.ProfilePath(home, "dev")
+	assert.NotNil(t, err, "Missing profile should be reported.")
+	assert.Contains(t, err.Error(), "prod, staging", "Available profiles should be listed.")
+
+	_, err = utils.ProfilePath(home, "../prod")
+	assert.NotNil(t, err, "Profile names must not be paths.")
+}
@@ -0,0 +1,51 @@
+// +build unit
+
+package tests
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnswerKey(t *testing.T) {
+	assert.Equal(t, "name", utils.AnswerKey("Name"))
+	assert.Equal(t, "rule-name", utils.AnswerKey("Rule Name"))
+	assert.Equal(t, "runtime", utils.AnswerKey("Runtime [go, nodejs]"))
+	assert.Equal(t, "create-deployment.yaml", utils.AnswerKey("Create deployment.yaml (y/n)"))
+}
+
+func TestPrompt_Answers(t *testing.T) {
+	file, err := ioutil.TempFile("", "answers")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("name: demo\nRule Name: hourly\ndeploy: yes\nversion: 1.0.0\n")
+	file.Close()
+
+	assert.Nil(t, utils.LoadAnswers(file.Name(), []string{"version=2.0.0"}))
+	assert.NotNil(t, utils.LoadAnswers("", []string{"version"}))
+
+	stdin := bufio.NewReader(strings.NewReader("typed\n"))
+	assert.Equal(t, "demo", utils.Ask(stdin, "Name", "default"))
+	assert.Equal(t, "hourly", utils.Ask(stdin, "Rule Name", ""))
+	assert.Equal(t, "2.0.0", utils.Ask(stdin, "Version", "0.0.1"), "--answer should override the answer file")
+	answer, err := utils.Prompt(stdin, "deploy", "Deploy? ")
+	assert.Nil(t, err)
+	assert.Equal(t, "yes", answer)
+	assert.True(t, utils.Answered("deploy"))
+	assert.False(t, utils.Answered("undeploy"))
+
+	assert.Equal(t, "typed", utils.Ask(stdin, "License", "Apache-2.0"))
+
+	utils.Flags.NonInteractive = true
+	defer func() { utils.Flags.NonInteractive = false }()
+	assert.Equal(t, "Apache-2.0", utils.Ask(stdin, "License", "Apache-2.0"), "Non-interactive mode should take the default")
+	_, err = utils.Prompt(stdin, "undeploy", "Undeploy? ")
+	assert.NotNil(t, err, "Non-interactive mode should not prompt")
+	assert.Contains(t, err.Error(), "--answer undeploy=VALUE")
+}
@@ -0,0 +1,41 @@
+// +build unit
+
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: utils.NewRateLimitTransport(nil, 20, 2)}
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		resp, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, 6, calls)
+	// the burst goes through at once, the other 4 requests at 20 per second
+	assert.True(t, time.Since(start) >= 190*time.Millisecond, "Requests beyond the burst should be spaced out")
+
+	unlimited := &http.Client{Transport: utils.NewRateLimitTransport(nil, 0, 1)}
+	start = time.Now()
+	for i := 0; i < 6; i++ {
+		resp, err := unlimited.Get(server.URL)
+		assert.Nil(t, err)
+		resp.Body.Close()
+	}
+	assert.True(t, time.Since(start) < 190*time.Millisecond, "A rate of 0 should not limit requests")
+}
@@ -0,0 +1,77 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFlakyServer(failures int, status int) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if calls <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(body)
+	}))
+	return server, &calls
+}
+
+func TestRetryTransport_Transient(t *testing.T) {
+	server, calls := newFlakyServer(2, http.StatusBadGateway)
+	defer server.Close()
+
+	transport := utils.NewRetryTransport(nil, 3)
+	transport.BaseDelay = time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	req, _ := http.NewRequest("PUT", server.URL, strings.NewReader("payload"))
+	resp, err := client.Do(req)
+	assert.Nil(t, err, "Request should succeed after retrying.")
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Final response should be successful.")
+	assert.Equal(t, "payload", string(body), "Body should be sent again on every attempt.")
+	assert.Equal(t, 3, *calls, "Request should be sent three times.")
+}
+
+func TestRetryTransport_Budget(t *testing.T) {
+	server, calls := newFlakyServer(10, http.StatusTooManyRequests)
+	defer server.Close()
+
+	transport := utils.NewRetryTransport(nil, 2)
+	transport.BaseDelay = time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "Last response should be returned when the budget is used up.")
+	assert.Equal(t, 3, *calls, "Request should be retried twice.")
+}
+
+func TestRetryTransport_PostNotRetried(t *testing.T) {
+	server, calls := newFlakyServer(1, http.StatusBadGateway)
+	defer server.Close()
+
+	transport := utils.NewRetryTransport(nil, 3)
+	transport.BaseDelay = time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, *calls, "POST should not be retried after a gateway error.")
+}
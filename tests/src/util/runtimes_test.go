@@ -0,0 +1,74 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRuntimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-runtimes")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer utils.LoadRuntimes("")
+
+	file := path.Join(dir, "runtimes.yaml")
+	mapping := `extensions:
+  .PL: perl:default
+  .py: python:3
+  .wasm: wasm:default
+binary: [.wasm]
+defaults:
+  perl: perl:5.28
+maxCodeSize:
+  java: 64
+  nodejs:10: 10
+`
+	assert.Nil(t, ioutil.WriteFile(file, []byte(mapping), 0644))
+	assert.Nil(t, utils.LoadRuntimes(file))
+
+	kind, ok := utils.KindForExtension(".pl")
+	assert.True(t, ok)
+	assert.Equal(t, "perl:default", kind, "extensions should be matched regardless of case")
+	kind, _ = utils.KindForExtension(".py")
+	assert.Equal(t, "python:3", kind, "the mapping should take precedence over the built-in kinds")
+	kind, _ = utils.KindForExtension(".js")
+	assert.Equal(t, "nodejs:default", kind, "built-in kinds should be kept")
+	assert.True(t, utils.IsBinaryArtifact("hello.wasm"), "binary extensions should be encoded")
+	assert.True(t, utils.IsBinaryArtifact("hello.zip"))
+	assert.False(t, utils.IsBinaryArtifact("hello.pl"))
+
+	for _, alias := range []string{"perl", "perl:default"} {
+		kind, ok = utils.DefaultKind(alias)
+		assert.True(t, ok)
+		assert.Equal(t, "perl:5.28", kind, alias+" should resolve to the default of the mapping")
+	}
+	_, ok = utils.DefaultKind("perl:5.26")
+	assert.False(t, ok, "pinned kinds should not be resolved")
+	_, ok = utils.DefaultKind("nodejs")
+	assert.False(t, ok, "families without a default should be left to the platform")
+
+	size, _ := utils.MaxCodeSize("java:8")
+	assert.Equal(t, 64, size, "the limit of the family should apply to its kinds")
+	size, _ = utils.MaxCodeSize("nodejs:10")
+	assert.Equal(t, 10, size, "the limit of the kind should apply")
+	_, ok = utils.MaxCodeSize("nodejs:8")
+	assert.False(t, ok)
+
+	invalid := "extensions:\n  pl: perl\ndefaults:\n  perl: python:3\nmaxCodeSize:\n  java: 0\n"
+	assert.Nil(t, ioutil.WriteFile(file, []byte(invalid), 0644))
+	err = utils.LoadRuntimes(file)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Invalid runtimes file "+file+":\n"+
+		"default python:3 of perl must be a kind of the family\n"+
+		"extension pl must start with a dot and map to a kind\n"+
+		"maxCodeSize of java must be a positive number of megabytes", err.Error())
+	_, ok = utils.KindForExtension(".wasm")
+	assert.False(t, ok, "an invalid mapping should not be used")
+}
@@ -0,0 +1,60 @@
+// +build unit
+
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	client := &http.Client{Transport: utils.NewTraceTransport(nil, &trace)}
+
+	payload := `{"authKey":"uuid:secret","parameters":[{"key":"password","value":"hunter2"},{"key":"name","value":"hello"}]}`
+	req, _ := http.NewRequest("POST", server.URL+"/actions/feed?blocking=true&key=gatewaykey", strings.NewReader(payload))
+	req.SetBasicAuth("uuid", "secret")
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, payload, string(body), "The response should reach the client unchanged.")
+
+	logged := trace.String()
+	assert.Contains(t, logged, "POST "+server.URL+"/actions/feed?blocking=true&key=%5BREDACTED%5D 200 OK")
+	assert.Contains(t, logged, "> Authorization: [REDACTED]")
+	assert.Contains(t, logged, `"name","value":"hello"`, "Other parameters should be traced.")
+	assert.NotContains(t, logged, "secret")
+	assert.NotContains(t, logged, "hunter2")
+	assert.NotContains(t, logged, "gatewaykey")
+}
+
+func TestTraceTransport_Truncate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	transport := utils.NewTraceTransport(nil, &trace)
+	transport.BodyLimit = 10
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Len(t, body, 100)
+	assert.Contains(t, trace.String(), "< xxxxxxxxxx... (100 bytes)")
+}
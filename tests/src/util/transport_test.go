@@ -0,0 +1,139 @@
+//go:build unit
+// +build unit
+
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTransport_CACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	caCert := path.Join(dir, "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]}
+	assert.Nil(t, ioutil.WriteFile(caCert, pem.EncodeToMemory(block), 0644))
+
+	transport, err := utils.NewTransport(utils.TransportOptions{CACert: caCert, Insecure: true})
+	assert.Nil(t, err)
+	assert.NotNil(t, transport.Proxy, "The proxy of the environment should be used")
+	assert.False(t, transport.TLSClientConfig.InsecureSkipVerify, "Certificates should be verified against the given authorities")
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Nil(t, err, "The certificate signed by the given authority should be trusted")
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	transport, err = utils.NewTransport(utils.TransportOptions{})
+	assert.Nil(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	assert.NotNil(t, err, "A self-signed certificate should be rejected without --cacert")
+
+	notPEM := path.Join(dir, "ca.txt")
+	assert.Nil(t, ioutil.WriteFile(notPEM, []byte("not a certificate"), 0644))
+	_, err = utils.NewTransport(utils.TransportOptions{CACert: notPEM})
+	assert.NotNil(t, err)
+	_, err = utils.NewTransport(utils.TransportOptions{CACert: path.Join(dir, "missing.pem")})
+	assert.NotNil(t, err)
+}
+
+func TestNewTransport_ClientCert(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "wskdeploy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "wskdeploy"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certFile := path.Join(dir, "client.pem")
+	keyFile := path.Join(dir, "client.key")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	transport, err := utils.NewTransport(utils.TransportOptions{Cert: certFile, Key: keyFile, Insecure: true})
+	assert.Nil(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Nil(t, err, "The client certificate should be presented to the server")
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	transport, err = utils.NewTransport(utils.TransportOptions{Insecure: true})
+	assert.Nil(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	assert.NotNil(t, err, "The server should reject clients without a certificate")
+
+	_, err = utils.NewTransport(utils.TransportOptions{Cert: certFile, Insecure: true})
+	assert.NotNil(t, err, "Without --key the key is read from the certificate file, which holds none")
+	_, err = utils.NewTransport(utils.TransportOptions{Key: keyFile, Insecure: true})
+	assert.NotNil(t, err, "A key without its certificate should be rejected")
+}
+
+func TestTimeoutTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport, err := utils.NewTransport(utils.TransportOptions{ReadTimeout: 50 * time.Millisecond})
+	assert.Nil(t, err)
+	_, err = (&http.Client{Transport: &utils.TimeoutTransport{Transport: transport}}).Get(server.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "--read-timeout", "The error should tell which timeout to raise")
+
+	transport, err = utils.NewTransport(utils.TransportOptions{})
+	assert.Nil(t, err)
+	_, err = (&http.Client{Transport: &utils.TimeoutTransport{Transport: transport, Timeout: 50 * time.Millisecond}}).Get(server.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "--request-timeout")
+
+	resp, err := (&http.Client{Transport: &utils.TimeoutTransport{Transport: transport, Timeout: time.Second}}).Get(server.URL)
+	assert.Nil(t, err, "Requests within the deadline should succeed")
+	if err == nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Nil(t, err, "The deadline should hold until the response is read")
+		assert.Equal(t, "ok", string(body))
+	}
+}
@@ -1,3 +1,4 @@
+//go:build unit
 // +build unit
 
 package tests
@@ -8,7 +9,11 @@ import (
 	"fmt"
 	"github.com/openwhisk/openwhisk-wskdeploy/utils"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"strings"
+	"time"
 )
 
 var contentReader = new(utils.ContentReader)
@@ -44,3 +49,169 @@ func TestGetEnvVar(t *testing.T) {
 	assert.Equal(t, "5000", utils.GetEnvVar("5000"), "Should be no difference between integer and string")
 	assert.Equal(t, "WithDollarAgain", utils.GetEnvVar("$WithDollarAgain"), "if not found, just return the env")
 }
+
+// The ${VAR} and ${VAR:-default} interpolation test cases.
+func TestGetEnvVar_Interpolation(t *testing.T) {
+	os.Setenv("WSK_REGION", "us-south")
+	os.Setenv("WSK_EMPTY", "")
+	os.Unsetenv("WSK_MISSING")
+	assert.Equal(t, "https://us-south.example.com/api", utils.GetEnvVar("https://${WSK_REGION}.example.com/api"), "reference inside a string should be expanded")
+	assert.Equal(t, "fallback", utils.GetEnvVar("${WSK_MISSING:-fallback}"), "default should be used for unset variables")
+	assert.Equal(t, "fallback", utils.GetEnvVar("${WSK_EMPTY:-fallback}"), "default should be used for empty variables")
+	assert.Equal(t, "us-south", utils.GetEnvVar("${WSK_MISSING:-${WSK_REGION}}"), "default may reference another variable")
+	assert.Equal(t, "-", utils.GetEnvVar("${WSK_MISSING}-"), "unset variable without default should be empty")
+	assert.Equal(t, "cost: $5 in ${WSK_REGION}", utils.GetEnvVar("cost: $$5 in $${WSK_REGION}"), "$$ should produce a literal dollar")
+	assert.Equal(t, 5000, utils.GetEnvVar(5000), "non string values should not change")
+}
+
+func TestGetEnvVar_Builtins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-git")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"checkout", "-q", "-b", "release"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		assert.Nil(t, cmd.Run())
+	}
+	head, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	assert.Nil(t, err)
+	utils.GitVarsDir = dir
+	defer func() { utils.GitVarsDir = "" }()
+
+	uuid := utils.GetEnvVar("${__UUID__}").(string)
+	assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", uuid)
+	assert.Equal(t, uuid, utils.GetEnvVar("$__UUID__"), "every reference should have the value of the deployment")
+	deployed, err := time.Parse(time.RFC3339, utils.GetEnvVar("${__DEPLOY_TIME__}").(string))
+	assert.Nil(t, err, "the deploy time should be an RFC 3339 timestamp")
+	assert.WithinDuration(t, time.Now(), deployed, time.Minute)
+	assert.Equal(t, strings.TrimSpace(string(head)), utils.GetEnvVar("${__GIT_COMMIT__}"))
+	assert.Equal(t, "release-1", utils.GetEnvVar("${__GIT_BRANCH__}-1"))
+
+	os.Setenv("__GIT_BRANCH__", "ci")
+	defer os.Unsetenv("__GIT_BRANCH__")
+	assert.Equal(t, "ci", utils.GetEnvVar("${__GIT_BRANCH__}"), "variables that are set should take precedence")
+}
+
+func TestEvalCondition(t *testing.T) {
+	os.Setenv("WSK_COND_ENV", "dev")
+	os.Setenv("WSK_COND_OFF", "false")
+	defer os.Unsetenv("WSK_COND_ENV")
+	defer os.Unsetenv("WSK_COND_OFF")
+
+	for expr, expected := range map[string]bool{
+		"${WSK_COND_ENV} == dev":                            true,
+		"${WSK_COND_ENV} != 'dev'":                          false,
+		"$WSK_COND_ENV == \"prod\" || $WSK_COND_ENV == dev": true,
+		"${WSK_COND_ENV} == dev && ${WSK_COND_OFF}":         false,
+		"${WSK_COND_ENV} == dev && !${WSK_COND_OFF}":        true,
+		"!(${WSK_COND_UNSET} || ${WSK_COND_ENV} == qa)":     true,
+		"${WSK_COND_UNSET:-dev} == ${WSK_COND_ENV}":         true,
+		"${WSK_COND_UNSET}":                                 false,
+	} {
+		result, err := utils.EvalCondition(expr)
+		assert.Nil(t, err, expr)
+		assert.Equal(t, expected, result, expr)
+	}
+
+	for _, expr := range []string{"", "${WSK_COND_ENV} ==", "(a == b", "a == b)", "'dev", "a && || b"} {
+		_, err := utils.EvalCondition(expr)
+		assert.NotNil(t, err, "%q should be invalid", expr)
+	}
+
+	assert.True(t, utils.IsDefined("WSK_COND_ENV"))
+	assert.True(t, utils.IsDefined("${WSK_COND_ENV}"))
+	assert.False(t, utils.IsDefined("$WSK_COND_UNSET"))
+}
+
+func TestLoadVars(t *testing.T) {
+	os.Setenv("WSK_VAR_REGION", "from environment")
+	err := utils.LoadVars([]string{"WSK_VAR_REGION=eu-de", "WSK_VAR_URL=https://a.example.com/?x=1", "WSK_VAR_EMPTY="})
+	assert.Nil(t, err, "Loading the variables failed.")
+
+	assert.Equal(t, "https://eu-de.example.com", utils.GetEnvVar("https://${WSK_VAR_REGION}.example.com"), "--var should take precedence over the environment")
+	assert.Equal(t, "https://a.example.com/?x=1", utils.GetEnvVar("$WSK_VAR_URL"), "values may hold = signs")
+	assert.Equal(t, "fallback", utils.GetEnvVar("${WSK_VAR_EMPTY:-fallback}"), "empty variables should take the default")
+
+	assert.NotNil(t, utils.LoadVars([]string{"=value"}))
+	assert.NotNil(t, utils.LoadVars([]string{"WSK_VAR_NOVALUE"}))
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "wskdeploy-env")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("# local settings\nexport WSK_ENV_HOST=openwhisk.example.com\nWSK_ENV_QUOTED=\"line one\\nline two\"\nWSK_ENV_SINGLE='${NOT_EXPANDED}'\nWSK_ENV_COMMENT=value # trailing comment\n")
+	file.Close()
+
+	os.Setenv("WSK_ENV_COMMENT", "from environment")
+	err = utils.LoadEnvFile(file.Name())
+	assert.Nil(t, err, "Loading the env file failed.")
+
+	assert.Equal(t, "openwhisk.example.com", utils.GetEnvVar("$WSK_ENV_HOST"), "env file variables should resolve bare $VARS")
+	assert.Equal(t, "https://openwhisk.example.com", utils.GetEnvVar("https://${WSK_ENV_HOST}"), "env file variables should resolve ${VARS}")
+	assert.Equal(t, "line one\nline two", utils.GetEnvVar("$WSK_ENV_QUOTED"), "double quoted values should be unescaped")
+	assert.Equal(t, "${NOT_EXPANDED}", utils.GetEnvVar("$WSK_ENV_SINGLE"), "single quoted values should be literal")
+	assert.Equal(t, "from environment", utils.GetEnvVar("$WSK_ENV_COMMENT"), "the process environment should take precedence")
+}
+
+func TestWebSecure(t *testing.T) {
+	annotations, err := utils.WebAction("true", nil, "hello", false)
+	assert.Nil(t, err)
+
+	secured, err := utils.WebSecure(true, annotations)
+	assert.Nil(t, err)
+	assert.Equal(t, true, secured[len(secured)-1].Value, "true should require OpenWhisk credentials")
+
+	secured, err = utils.WebSecure("s3cr3t", secured)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", secured[len(secured)-1].Value, "a string should be the token")
+	assert.Equal(t, 4, len(secured), "the previous value should be replaced")
+
+	secured, err = utils.WebSecure(1234, secured)
+	assert.Nil(t, err)
+	assert.Equal(t, 1234, secured[len(secured)-1].Value, "an integer should be the token")
+
+	secured, err = utils.WebSecure(false, secured)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(secured), "false should remove the annotation")
+
+	_, err = utils.WebSecure(1.5, secured)
+	assert.NotNil(t, err, "other values should be rejected")
+}
+
+func TestGetExec_Kinds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskdeploy-exec")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	kinds := map[string]string{
+		"hello.php": "php:default",
+		"hello.rb":  "ruby:default",
+		"hello.go":  "go:default",
+		"hello.rs":  "rust:default",
+		"hello.cs":  "dotnet:default",
+		"hello.dll": "dotnet:default",
+		"hello.bal": "ballerina:default",
+	}
+	for name, kind := range kinds {
+		artifact := dir + "/" + name
+		assert.Nil(t, ioutil.WriteFile(artifact, []byte("code"), 0644))
+		exec, err := utils.GetExec(artifact, "", false, "main")
+		assert.Nil(t, err, "the kind of "+name+" should be inferred")
+		assert.Equal(t, kind, exec.Kind, "the kind of "+name+" should be the runtime default")
+		if name == "hello.dll" {
+			assert.Equal(t, "Y29kZQ==", *exec.Code, "assemblies should be base64 encoded")
+		} else {
+			assert.Equal(t, "code", *exec.Code, "sources should be sent as they are")
+		}
+	}
+
+	artifact := dir + "/hello.cob"
+	assert.Nil(t, ioutil.WriteFile(artifact, []byte("code"), 0644))
+	_, err = utils.GetExec(artifact, "", false, "main")
+	assert.NotNil(t, err, "unknown extensions should still be rejected")
+}
@@ -0,0 +1,55 @@
+// +build unit
+
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveVaultReference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/db":
+			w.Write([]byte(`{"data": {"password": "hunter2", "port": 5432}}`))
+		case "/v1/kv/data/api":
+			w.Write([]byte(`{"data": {"data": {"key": "abc"}, "metadata": {"version": 3}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("VAULT_ADDR", server.URL)
+	os.Setenv("VAULT_TOKEN", "s.token")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	value, err := utils.ResolveVaultReference("vault://secret/db#password")
+	assert.Nil(t, err, "Reading a KV v1 secret should succeed.")
+	assert.Equal(t, "hunter2", value)
+
+	value, err = utils.ResolveVaultReference("vault://kv/data/api#key")
+	assert.Nil(t, err, "Reading a KV v2 secret should succeed.")
+	assert.Equal(t, "abc", value)
+
+	assert.Equal(t, "hunter2", utils.GetEnvVar("vault://secret/db#password"), "Inputs should resolve Vault references.")
+
+	_, err = utils.ResolveVaultReference("vault://secret/db#user")
+	assert.NotNil(t, err, "Missing keys should be reported.")
+
+	_, err = utils.ResolveVaultReference("vault://secret/missing#key")
+	assert.NotNil(t, err, "Missing secrets should be reported.")
+
+	_, err = utils.ResolveVaultReference("vault://secret/db")
+	assert.NotNil(t, err, "References without a key should be rejected.")
+}
@@ -0,0 +1,44 @@
+// +build unit
+
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanFiles(t *testing.T) {
+	root, _ := ioutil.TempDir("", "watch")
+	defer os.RemoveAll(root)
+	os.MkdirAll(path.Join(root, "src"), 0755)
+	os.MkdirAll(path.Join(root, ".wskdeploy", "history"), 0755)
+	os.MkdirAll(path.Join(root, "build"), 0755)
+	ioutil.WriteFile(path.Join(root, "manifest.yaml"), []byte("package:\n"), 0644)
+	ioutil.WriteFile(path.Join(root, "src", "hello.js"), []byte("function main() {}"), 0644)
+	ioutil.WriteFile(path.Join(root, ".wskdeploy", "history", "1.json"), []byte("{}"), 0644)
+	ioutil.WriteFile(path.Join(root, "build", "hello.zip"), []byte("zip"), 0644)
+	ioutil.WriteFile(path.Join(root, utils.WskIgnoreFileName), []byte("build/\n"), 0644)
+
+	before, err := utils.ScanFiles(root)
+	assert.Nil(t, err)
+	_, hidden := before[path.Join(root, ".wskdeploy", "history", "1.json")]
+	assert.False(t, hidden, "Hidden directories should not be watched.")
+	_, ignored := before[path.Join(root, "build", "hello.zip")]
+	assert.False(t, ignored, "Paths the .wskignore excludes should not be watched.")
+	_, watched := before[path.Join(root, "src", "hello.js")]
+	assert.True(t, watched)
+
+	ioutil.WriteFile(path.Join(root, "src", "hello.js"), []byte("function main() { return {} }"), 0644)
+	ioutil.WriteFile(path.Join(root, "src", "bye.js"), []byte("function main() {}"), 0644)
+	os.Remove(path.Join(root, "manifest.yaml"))
+	after, err := utils.ScanFiles(root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path.Join(root, "manifest.yaml"), path.Join(root, "src", "bye.js"), path.Join(root, "src", "hello.js")},
+		utils.ChangedFiles(before, after), "Added, changed and removed files should be reported.")
+	assert.Equal(t, []string{}, utils.ChangedFiles(after, after))
+}
@@ -0,0 +1,106 @@
+// +build unit
+
+package tests
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreMatcher_Patterns(t *testing.T) {
+	matcher := utils.NewIgnoreMatcher()
+	err := matcher.AddPatterns("", []byte("# comment\n\n*.log\n!keep.log\ndocs/\n/local.json\nnode_modules/**/test\n"))
+	assert.Nil(t, err, "Parsing ignore patterns failed.")
+
+	assert.True(t, matcher.Match("debug.log", false), "*.log should be ignored.")
+	assert.True(t, matcher.Match("lib/debug.log", false), "*.log should be ignored in subfolders.")
+	assert.False(t, matcher.Match("keep.log", false), "Negated pattern should re-include the file.")
+	assert.True(t, matcher.Match("docs", true), "Directory pattern should match a directory.")
+	assert.False(t, matcher.Match("docs", false), "Directory pattern should not match a file.")
+	assert.True(t, matcher.Match("local.json", false), "Anchored pattern should match at the root.")
+	assert.False(t, matcher.Match("lib/local.json", false), "Anchored pattern should not match in subfolders.")
+	assert.True(t, matcher.Match("node_modules/a/b/test", true), "Double star should match nested directories.")
+	assert.True(t, matcher.Match("node_modules/test", true), "Double star should match zero directories.")
+	assert.False(t, matcher.Match("index.js", false), "Unmatched file should be kept.")
+}
+
+func TestIgnoreMatcher_NestedFile(t *testing.T) {
+	matcher := utils.NewIgnoreMatcher()
+	matcher.AddPatterns("", []byte("*.txt\n"))
+	matcher.AddPatterns("lib", []byte("!readme.txt\nfixture.js\n"))
+
+	assert.True(t, matcher.Match("notes.txt", false), "Root pattern should apply.")
+	assert.False(t, matcher.Match("lib/readme.txt", false), "Nested negation should override the parent.")
+	assert.True(t, matcher.Match("lib/fixture.js", false), "Nested pattern should apply under its folder.")
+	assert.False(t, matcher.Match("fixture.js", false), "Nested pattern should not apply outside its folder.")
+}
+
+func TestCreateFolderZip_WskIgnore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wskignore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "action")
+	os.MkdirAll(path.Join(src, "docs"), os.ModePerm)
+	os.MkdirAll(path.Join(src, "lib"), os.ModePerm)
+	ioutil.WriteFile(path.Join(src, "index.js"), []byte("function main() {}"), 0644)
+	ioutil.WriteFile(path.Join(src, "docs", "api.md"), []byte("docs"), 0644)
+	ioutil.WriteFile(path.Join(src, "lib", "util.js"), []byte("util"), 0644)
+	ioutil.WriteFile(path.Join(src, "lib", "util.test.js"), []byte("test"), 0644)
+	ioutil.WriteFile(path.Join(src, ".wskignore"), []byte("docs/\n"), 0644)
+	ioutil.WriteFile(path.Join(src, "lib", ".wskignore"), []byte("*.test.js\n"), 0644)
+
+	zipName := path.Join(dir, "action.zip")
+	err = utils.CreateFolderZip(src, zipName)
+	assert.Nil(t, err, "Creating the zip failed.")
+
+	reader, err := zip.OpenReader(zipName)
+	assert.Nil(t, err, "Opening the zip failed.")
+	defer reader.Close()
+
+	names := make(map[string]bool)
+	for _, file := range reader.File {
+		names[file.Name] = true
+	}
+
+	assert.True(t, names["action/index.js"], "index.js should be zipped.")
+	assert.True(t, names["action/lib/util.js"], "lib/util.js should be zipped.")
+	assert.False(t, names["action/lib/util.test.js"], "Nested .wskignore should be honored.")
+	assert.False(t, names["action/docs/api.md"], "Ignored folder should be skipped.")
+	assert.False(t, names["action/.wskignore"], "The ignore file itself should not be zipped.")
+}
+
+func TestCreateFolderZipWithOptions_IncludeExclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zipoptions")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "action")
+	os.MkdirAll(path.Join(src, "lib", "fixtures"), os.ModePerm)
+	ioutil.WriteFile(path.Join(src, "index.js"), []byte("function main() {}"), 0644)
+	ioutil.WriteFile(path.Join(src, "README.md"), []byte("readme"), 0644)
+	ioutil.WriteFile(path.Join(src, "lib", "util.js"), []byte("util"), 0644)
+	ioutil.WriteFile(path.Join(src, "lib", "fixtures", "data.js"), []byte("data"), 0644)
+
+	zipName := path.Join(dir, "action.zip")
+	options := utils.ZipOptions{Include: []string{"*.js"}, Exclude: []string{"lib/fixtures/"}}
+	err = utils.CreateFolderZipWithOptions(src, zipName, options)
+	assert.Nil(t, err, "Creating the zip failed.")
+
+	reader, err := zip.OpenReader(zipName)
+	assert.Nil(t, err, "Opening the zip failed.")
+	defer reader.Close()
+
+	names := make([]string, 0)
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+
+	assert.Equal(t, []string{"action/index.js", "action/lib/util.js"}, names, "Only the selected files should be zipped.")
+}
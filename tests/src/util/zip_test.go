@@ -0,0 +1,193 @@
+// +build unit
+
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/openwhisk/openwhisk-wskdeploy/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateFolderZip_Reproducible(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "fn")
+	os.MkdirAll(path.Join(src, "lib"), 0700)
+	ioutil.WriteFile(path.Join(src, "index.js"), []byte("function main() {}"), 0600)
+	ioutil.WriteFile(path.Join(src, "lib", "util.js"), []byte("module.exports = {}"), 0664)
+	ioutil.WriteFile(path.Join(src, "run.sh"), []byte("#!/bin/sh\n"), 0750)
+
+	first := path.Join(dir, "first.zip")
+	assert.Nil(t, utils.CreateFolderZip(src, first))
+
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(path.Join(src, "index.js"), later, later)
+	os.Chmod(path.Join(src, "lib", "util.js"), 0600)
+	second := path.Join(dir, "second.zip")
+	assert.Nil(t, utils.CreateFolderZip(src, second))
+
+	firstContent, _ := ioutil.ReadFile(first)
+	secondContent, _ := ioutil.ReadFile(second)
+	assert.True(t, bytes.Equal(firstContent, secondContent), "Zipping the same files should produce the same archive.")
+
+	archive, err := zip.OpenReader(first)
+	assert.Nil(t, err)
+	defer archive.Close()
+	names := make([]string, 0)
+	modes := make(map[string]os.FileMode)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		modes[file.Name] = file.Mode().Perm()
+	}
+	assert.Equal(t, []string{"fn/", "fn/index.js", "fn/lib/", "fn/lib/util.js", "fn/run.sh"}, names, "Entries should be sorted.")
+	assert.Equal(t, os.FileMode(0644), modes["fn/index.js"], "File permissions should be normalized.")
+	assert.Equal(t, os.FileMode(0755), modes["fn/run.sh"], "Executables should stay executable.")
+	assert.Equal(t, os.FileMode(0755), modes["fn/lib/"], "Directory permissions should be normalized.")
+}
+
+func TestCreateFilesZip_Sorted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(path.Join(dir, "b.txt"), []byte("b"), 0644)
+	ioutil.WriteFile(path.Join(dir, "a.txt"), []byte("a"), 0644)
+	first, second := path.Join(dir, "first.zip"), path.Join(dir, "second.zip")
+	assert.Nil(t, utils.CreateFilesZip(first, []string{path.Join(dir, "b.txt"), path.Join(dir, "a.txt")}))
+	assert.Nil(t, utils.CreateFilesZip(second, []string{path.Join(dir, "a.txt"), path.Join(dir, "b.txt")}))
+
+	firstContent, _ := ioutil.ReadFile(first)
+	secondContent, _ := ioutil.ReadFile(second)
+	assert.True(t, bytes.Equal(firstContent, secondContent), "The order files are given in should not matter.")
+}
+
+func TestCreateFolderZip_Symlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	shared := path.Join(dir, "shared")
+	os.MkdirAll(shared, 0755)
+	ioutil.WriteFile(path.Join(shared, "util.js"), []byte("module.exports = {}"), 0644)
+	src := path.Join(dir, "fn")
+	os.MkdirAll(src, 0755)
+	ioutil.WriteFile(path.Join(src, "index.js"), []byte("function main() {}"), 0644)
+	os.Symlink("index.js", path.Join(src, "main.js"))
+	os.Symlink(shared, path.Join(src, "lib"))
+
+	entries := func(file string) map[string]*zip.File {
+		archive, err := zip.OpenReader(file)
+		assert.Nil(t, err)
+		files := make(map[string]*zip.File)
+		for _, entry := range archive.File {
+			files[entry.Name] = entry
+		}
+		return files
+	}
+
+	followed := path.Join(dir, "followed.zip")
+	assert.Nil(t, utils.CreateFolderZip(src, followed))
+	files := entries(followed)
+	assert.NotNil(t, files["fn/lib/util.js"], "A symlinked folder outside the tree should be zipped.")
+	if assert.NotNil(t, files["fn/main.js"]) {
+		assert.Equal(t, os.FileMode(0), files["fn/main.js"].Mode()&os.ModeSymlink, "A followed symlink should be zipped as a file.")
+		assert.Equal(t, uint64(len("function main() {}")), files["fn/main.js"].UncompressedSize64)
+	}
+
+	stored := path.Join(dir, "stored.zip")
+	assert.Nil(t, utils.CreateFolderZipWithOptions(src, stored, utils.ZipOptions{StoreSymlinks: true}))
+	files = entries(stored)
+	assert.Nil(t, files["fn/lib/util.js"], "A stored symlink should not be followed.")
+	if assert.NotNil(t, files["fn/main.js"]) {
+		assert.NotEqual(t, os.FileMode(0), files["fn/main.js"].Mode()&os.ModeSymlink, "The symlink should be stored as a link.")
+		reader, _ := files["fn/main.js"].Open()
+		target, _ := ioutil.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, "index.js", string(target), "The link target should be stored as content.")
+	}
+
+	os.Symlink(src, path.Join(shared, "loop"))
+	err = utils.CreateFolderZip(src, path.Join(dir, "cycle.zip"))
+	if assert.NotNil(t, err, "A symlink cycle should be reported.") {
+		assert.Contains(t, err.Error(), "Symlink cycle")
+	}
+}
+
+func TestCreateFolderZip_Executables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := path.Join(dir, "fn")
+	os.MkdirAll(src, 0755)
+	// as checked out on a file system without executable bits
+	ioutil.WriteFile(path.Join(src, "exec"), []byte("#!/bin/bash\necho '{}'\n"), 0644)
+	ioutil.WriteFile(path.Join(src, "action"), []byte("\x7fELF\x02\x01\x01"), 0644)
+	ioutil.WriteFile(path.Join(src, "data.txt"), []byte("#"), 0644)
+
+	file := path.Join(dir, "fn.zip")
+	assert.Nil(t, utils.CreateFolderZip(src, file))
+	archive, err := zip.OpenReader(file)
+	assert.Nil(t, err)
+	defer archive.Close()
+	modes := make(map[string]os.FileMode)
+	for _, entry := range archive.File {
+		assert.Equal(t, uint16(3), entry.CreatorVersion>>8, "Entries should carry unix attributes.")
+		modes[entry.Name] = entry.Mode().Perm()
+	}
+	assert.Equal(t, os.FileMode(0755), modes["fn/exec"], "Scripts should be executable.")
+	assert.Equal(t, os.FileMode(0755), modes["fn/action"], "Binaries should be executable.")
+	assert.Equal(t, os.FileMode(0644), modes["fn/data.txt"], "Other files should not be executable.")
+}
+
+func TestCachedFolderZip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv(utils.ArtifactCacheEnv, os.Getenv(utils.ArtifactCacheEnv))
+	os.Setenv(utils.ArtifactCacheEnv, path.Join(dir, "cache"))
+
+	src := path.Join(dir, "fn")
+	os.MkdirAll(src, 0755)
+	ioutil.WriteFile(path.Join(src, "index.js"), []byte("function main() {}"), 0644)
+	ioutil.WriteFile(path.Join(src, "notes.txt"), []byte("draft"), 0644)
+
+	first, err := utils.CachedFolderZip(src, utils.ZipOptions{})
+	assert.Nil(t, err, "Zipping into the cache should succeed.")
+	assert.Equal(t, path.Join(dir, "cache", "zips"), path.Dir(first), "The zip should be stored in the cache.")
+	stat, _ := os.Stat(first)
+
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(path.Join(src, "index.js"), later, later)
+	again, err := utils.CachedFolderZip(src, utils.ZipOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, first, again, "Unchanged files should reuse the cached zip.")
+	againStat, _ := os.Stat(again)
+	assert.Equal(t, stat.ModTime(), againStat.ModTime(), "The cached zip should not be written again.")
+
+	excluded, err := utils.CachedFolderZip(src, utils.ZipOptions{Exclude: []string{"*.txt"}})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, excluded, "Zipping other files should make another zip.")
+
+	ioutil.WriteFile(path.Join(src, "index.js"), []byte("function main() { return {} }"), 0644)
+	changed, err := utils.CachedFolderZip(src, utils.ZipOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, changed, "Changed files should make another zip.")
+
+	archive, err := zip.OpenReader(changed)
+	assert.Nil(t, err)
+	defer archive.Close()
+	reader, _ := archive.File[1].Open()
+	content, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "function main() { return {} }", string(content), "The cached zip should hold the current files.")
+}
@@ -0,0 +1,156 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// environment variable overriding the directory remote artifacts are cached in
+const ArtifactCacheEnv = "WSKDEPLOY_CACHE"
+
+// IsRemoteArtifact reports whether action code is downloaded from a url or
+// an object store bucket
+func IsRemoteArtifact(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") || IsObjectStoreArtifact(location)
+}
+
+// ArtifactCacheDir is the directory remote artifacts are cached in,
+// ~/.wskdeploy/cache unless $WSKDEPLOY_CACHE is set.
+func ArtifactCacheDir() string {
+	if dir := os.Getenv(ArtifactCacheEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(GetHomeDirectory(), ".wskdeploy", "cache")
+}
+
+// IsSha256 reports whether a checksum is a hex encoded SHA-256
+func IsSha256(checksum string) bool {
+	if len(checksum) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(checksum)
+	return err == nil
+}
+
+// FetchArtifact returns the local path of the artifact at artifactUrl,
+// downloading it unless the cache holds it. The cache is keyed by checksum,
+// so with a checksum the artifact is only downloaded once and is verified;
+// without one it is downloaded every time.
+func FetchArtifact(artifactUrl string, checksum string) (string, error) {
+	u, err := url.Parse(artifactUrl)
+	if err != nil {
+		return "", errors.New("Invalid artifact url " + artifactUrl + ": " + err.Error())
+	}
+	// keep the extension, the runtime is inferred from it
+	ext := path.Ext(u.Path)
+	checksum = strings.ToLower(checksum)
+	cacheDir := ArtifactCacheDir()
+
+	if checksum != "" {
+		cached := filepath.Join(cacheDir, checksum+ext)
+		if FileExists(cached) {
+			return cached, nil
+		}
+	}
+
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return "", errors.New("Unable to create the artifact cache " + cacheDir + ": " + err.Error())
+	}
+	var request *http.Request
+	if IsObjectStoreArtifact(artifactUrl) {
+		request, err = ObjectStoreRequest(artifactUrl)
+	} else {
+		request, err = http.NewRequest("GET", artifactUrl, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", errors.New("Unable to download artifact " + artifactUrl + ": " + err.Error())
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", errors.New("Unable to download artifact " + artifactUrl + ": " + response.Status)
+	}
+
+	download, err := ioutil.TempFile(cacheDir, "download")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(download.Name())
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(download, hash), response.Body)
+	download.Close()
+	if err != nil {
+		return "", errors.New("Unable to download artifact " + artifactUrl + ": " + err.Error())
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && actual != checksum {
+		return "", errors.New("Checksum of artifact " + artifactUrl + " is " + actual + ", expected " + checksum)
+	}
+	cached := filepath.Join(cacheDir, actual+ext)
+	if err := os.Rename(download.Name(), cached); err != nil {
+		return "", err
+	}
+	return cached, nil
+}
+
+// CachedFolderZip returns the path of a zip of src in the artifact cache,
+// keyed by the digest of what is zipped so unchanged folders are only
+// zipped once across deployments.
+func CachedFolderZip(src string, options ZipOptions) (string, error) {
+	digest, err := FolderDigest(src, options)
+	if err != nil {
+		return "", err
+	}
+	cacheDir := filepath.Join(ArtifactCacheDir(), "zips")
+	cached := filepath.Join(cacheDir, digest+".zip")
+	if FileExists(cached) {
+		return cached, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return "", errors.New("Unable to create the artifact cache " + cacheDir + ": " + err.Error())
+	}
+	zipFile, err := ioutil.TempFile(cacheDir, "zip")
+	if err != nil {
+		return "", err
+	}
+	zipFile.Close()
+	defer os.Remove(zipFile.Name())
+	if err := CreateFolderZipWithOptions(src, zipFile.Name(), options); err != nil {
+		return "", err
+	}
+	if err := os.Rename(zipFile.Name(), cached); err != nil {
+		return "", err
+	}
+	return cached, nil
+}
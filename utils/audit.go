@@ -0,0 +1,175 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// the kinds of entities by the collection of the API they are written to
+var auditKinds = map[string]string{
+	"actions":  "action",
+	"packages": "package",
+	"triggers": "trigger",
+	"rules":    "rule",
+}
+
+// AuditEntry records one change made to the namespace. Before and After are
+// the SHA-256 of the entity as the API returned it before and after the
+// change, Before is empty when the entity was created and After when it was
+// deleted.
+type AuditEntry struct {
+	Time      string `json:"time"`
+	Subject   string `json:"subject"`
+	Namespace string `json:"namespace"`
+	Operation string `json:"operation"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AuditTransport appends an entry to the audit log for every entity the
+// requests it sends create, update or delete, whether the API accepts the
+// change or not. Invocations are not changes and are not recorded.
+type AuditTransport struct {
+	Transport http.RoundTripper
+	Writer    io.Writer
+	// who makes the changes, the uuid of the auth key
+	Subject string
+}
+
+func NewAuditTransport(transport http.RoundTripper, writer io.Writer, subject string) *AuditTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &AuditTransport{Transport: transport, Writer: writer, Subject: subject}
+}
+
+func (t *AuditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	namespace, kind, name := auditedEntity(req)
+	if kind == "" {
+		return t.Transport.RoundTrip(req)
+	}
+
+	entry := AuditEntry{Subject: t.Subject, Namespace: namespace, Kind: kind, Name: name}
+	entry.Before = t.entityHash(req)
+	switch {
+	case req.Method == "DELETE":
+		entry.Operation = "delete"
+	case entry.Before == "":
+		entry.Operation = "create"
+	default:
+		entry.Operation = "update"
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	entry.Time = time.Now().UTC().Format(time.RFC3339)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		body, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			return nil, readErr
+		}
+		switch {
+		case resp.StatusCode >= 300:
+			entry.Error = strings.TrimSpace(string(body))
+			entry.After = entry.Before
+		case req.Method != "DELETE":
+			entry.After = hashBytes(body)
+		}
+	}
+
+	line, _ := json.Marshal(entry)
+	t.Writer.Write(append(line, '\n'))
+	return resp, err
+}
+
+// the hash of the entity a request changes as it is before the change, empty
+// when it does not exist
+func (t *AuditTransport) entityHash(req *http.Request) string {
+	current := *req.URL
+	current.RawQuery = ""
+	get, err := http.NewRequest("GET", current.String(), nil)
+	if err != nil {
+		return ""
+	}
+	get = get.WithContext(req.Context())
+	for name, values := range req.Header {
+		get.Header[name] = values
+	}
+	resp, err := t.Transport.RoundTrip(get)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	return hashBytes(body)
+}
+
+// the namespace, kind and name of the entity a request changes, no kind when
+// it changes none: PUT and DELETE of an entity, and POST of the state of a
+// rule
+func auditedEntity(req *http.Request) (string, string, string) {
+	if req.Method != "PUT" && req.Method != "DELETE" && req.Method != "POST" {
+		return "", "", ""
+	}
+	path := req.URL.Path
+	index := strings.Index(path, "/namespaces/")
+	if index < 0 {
+		return "", "", ""
+	}
+	parts := strings.SplitN(path[index+len("/namespaces/"):], "/", 3)
+	if len(parts) < 3 || parts[2] == "" {
+		return "", "", ""
+	}
+	kind := auditKinds[parts[1]]
+	if kind == "" || (req.Method == "POST" && kind != "rule") {
+		return "", "", ""
+	}
+	return parts[0], kind, strings.TrimSuffix(parts[2], "/")
+}
+
+func hashBytes(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// AuditWriter returns the writer of the audit log, which entries are only
+// ever appended to
+func AuditWriter(path string) (io.Writer, error) {
+	return sharedFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY)
+}
@@ -0,0 +1,243 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// build: value turning off the build of a function directory
+const NoBuild = "none"
+
+// the TypeScript compiler
+var TypeScriptCommand = "tsc"
+
+// DefaultBuild returns the command installing the dependencies a function
+// directory declares, and compiling it when it has a tsconfig.json.
+func DefaultBuild(dir string) string {
+	commands := make([]string, 0, 2)
+	switch {
+	case FileExists(filepath.Join(dir, "package.json")):
+		commands = append(commands, "npm install --production")
+	case FileExists(filepath.Join(dir, "requirements.txt")):
+		commands = append(commands, "pip install -r requirements.txt -t .")
+	}
+	if FileExists(filepath.Join(dir, "tsconfig.json")) {
+		commands = append(commands, TypeScriptCommand+" -p .")
+	}
+	return strings.Join(commands, " && ")
+}
+
+// IsTypeScript reports whether a function is a TypeScript file
+func IsTypeScript(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".ts"
+}
+
+// CompileTypeScript compiles a TypeScript file to a CommonJS module and
+// returns the emitted JavaScript.
+func CompileTypeScript(file string) (string, error) {
+	outDir, err := ioutil.TempDir("", "wskdeploy-tsc")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(outDir)
+
+	cmd := exec.Command(TypeScriptCommand, "--module", "commonjs", "--target", "es2015", "--outDir", outDir, file)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.New("Unable to compile " + file + ": " + err.Error() + "\n" + strings.TrimSpace(string(output)))
+	}
+
+	emitted := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))+".js")
+	code, err := ioutil.ReadFile(emitted)
+	if err != nil {
+		return "", errors.New("Compiling " + file + " did not emit " + filepath.Base(emitted))
+	}
+	return string(code), nil
+}
+
+// BuildFunction runs the build command of a function directory in it, or
+// the default build when no command is given. With --preview nothing is
+// run.
+func BuildFunction(dir string, command string) error {
+	if command == "" {
+		command = DefaultBuild(dir)
+	}
+	if command == "" || command == NoBuild || Flags.Preview {
+		return nil
+	}
+
+	log.Print("Building " + dir + ": " + command + " ... ")
+	if err := RunCommand(dir, command); err != nil {
+		return err
+	}
+	log.Println("Done!")
+	return nil
+}
+
+// RunCommand runs a shell command in dir, returning its output on failure.
+func RunCommand(dir string, command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New("Build command " + command + " failed in " + dir + ": " + err.Error() + "\n" + strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// the go tool Go functions are compiled with
+var GoCommand = "go"
+
+// IsGo reports whether a function is a Go source file
+func IsGo(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".go"
+}
+
+// CompileGo cross-compiles a Go function for linux/amd64 and returns the
+// base64 encoded zip holding the binary as exec, the layout native actions
+// and the go runtime run.
+func CompileGo(file string) (string, error) {
+	outDir, err := ioutil.TempDir("", "wskdeploy-go")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(outDir)
+
+	binary := filepath.Join(outDir, "exec")
+	cmd := exec.Command(GoCommand, "build", "-o", binary, filepath.Base(file))
+	cmd.Dir = filepath.Dir(file)
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.New("Unable to compile " + file + ": " + err.Error() + "\n" + strings.TrimSpace(string(output)))
+	}
+
+	content, err := ioutil.ReadFile(binary)
+	if err != nil {
+		return "", errors.New("Compiling " + file + " did not produce a binary")
+	}
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	header := &zip.FileHeader{Name: "exec", Method: zip.Deflate}
+	header.SetMode(0755)
+	writer, err := archive.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return "", err
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// the build tools of Java projects
+var MavenCommand = "mvn"
+var GradleCommand = "gradle"
+
+// IsJavaProject reports whether a function directory is a Maven or Gradle
+// project
+func IsJavaProject(dir string) bool {
+	return FileExists(filepath.Join(dir, "pom.xml")) || FileExists(filepath.Join(dir, "build.gradle")) || FileExists(filepath.Join(dir, "build.gradle.kts"))
+}
+
+// BuildJar builds a Maven or Gradle project, with command when one is
+// given, and returns the jar it produced. When the build produces several
+// jars the largest is taken, which is the one bundling the dependencies.
+func BuildJar(dir string, command string) (string, error) {
+	outputDir := filepath.Join(dir, "build", "libs")
+	if FileExists(filepath.Join(dir, "pom.xml")) {
+		outputDir = filepath.Join(dir, "target")
+	}
+	if command == "" {
+		switch {
+		case FileExists(filepath.Join(dir, "pom.xml")):
+			command = MavenCommand + " -q package -DskipTests"
+		case FileExists(filepath.Join(dir, "gradlew")):
+			command = "./gradlew -q build -x test"
+		default:
+			command = GradleCommand + " -q build -x test"
+		}
+	}
+	if command != NoBuild && !Flags.Preview {
+		log.Print("Building " + dir + ": " + command + " ... ")
+		if err := RunCommand(dir, command); err != nil {
+			return "", err
+		}
+		log.Println("Done!")
+	}
+
+	jars, _ := filepath.Glob(filepath.Join(outputDir, "*.jar"))
+	jar := ""
+	var size int64 = -1
+	for _, candidate := range jars {
+		name := filepath.Base(candidate)
+		if strings.HasSuffix(name, "-sources.jar") || strings.HasSuffix(name, "-javadoc.jar") || strings.HasPrefix(name, "original-") {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && info.Size() > size {
+			jar, size = candidate, info.Size()
+		}
+	}
+	if jar == "" {
+		return "", errors.New("Building " + dir + " did not produce a jar in " + outputDir)
+	}
+	return jar, nil
+}
+
+// images of the Python runtimes, binary wheels are built in them
+var PythonRuntimeImages = map[string]string{
+	"python":   "openwhisk/python2action",
+	"python:2": "openwhisk/python2action",
+	"python:3": "openwhisk/python3action",
+}
+
+// VirtualenvBuild returns the command installing the requirements.txt of a
+// function directory into the virtualenv folder the Python runtime
+// activates. In docker mode the virtualenv is created in the image of the
+// runtime so binary wheels match it.
+func VirtualenvBuild(mode string, runtime string, dir string) (string, error) {
+	install := "virtualenv virtualenv && . virtualenv/bin/activate && pip install -r requirements.txt"
+	switch strings.ToLower(mode) {
+	case "true", "yes":
+		return install, nil
+	case "docker":
+		image, ok := PythonRuntimeImages[runtime]
+		if !ok {
+			image = PythonRuntimeImages["python:3"]
+		}
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return "", err
+		}
+		return DockerCommand + " run --rm -v '" + absDir + "':/tmp/fn -w /tmp/fn --entrypoint sh " + image + " -c '" + install + "'", nil
+	case "false", "no":
+		return "", nil
+	}
+	return "", errors.New("virtualenv must be true, false or docker, not " + mode)
+}
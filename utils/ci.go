@@ -0,0 +1,131 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/openwhisk/openwhisk-client-go/whisk"
+)
+
+// The exit codes of the classes of failures --ci tells apart. Without --ci
+// every failure exits with ExitFailure.
+const (
+	ExitFailure = 1 // failures of no other class
+	ExitUsage   = 2 // unknown commands, flags or arguments
+	ExitInvalid = 3 // missing or invalid manifest and deployment files
+	ExitPrompt  = 4 // a value that is neither given nor defaulted would be prompted for
+	ExitNetwork = 5 // the API cannot be reached or does not respond in time
+	ExitAPI     = 6 // the API rejects a request
+	ExitDrift   = 7 // the namespace differs from the manifest
+)
+
+// the names of the classes in the failure lines of --ci
+var exitClasses = map[int]string{
+	ExitFailure: "failure",
+	ExitUsage:   "usage",
+	ExitInvalid: "invalid",
+	ExitPrompt:  "prompt",
+	ExitNetwork: "network",
+	ExitAPI:     "api",
+	ExitDrift:   "drift",
+}
+
+// ExitError is a failure of a class, exiting with its code in --ci mode.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// WithExitCode classifies a failure, keeping the class it already has.
+func WithExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := Cause(err).(*ExitError); ok {
+		return err
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode returns the code wskdeploy exits with after a failure: the code of
+// its class in --ci mode, ExitFailure otherwise. Errors of the whisk client
+// and the network are classified by their kind, several errors by the first.
+func ExitCode(err error) int {
+	if !Flags.CI {
+		return ExitFailure
+	}
+	switch err := Cause(err).(type) {
+	case Errors:
+		if len(err) > 0 {
+			return ExitCode(err[0])
+		}
+	case *ExitError:
+		return err.Code
+	case *whisk.WskError:
+		switch {
+		case err.TimedOut || err.ExitCode == whisk.EXIT_CODE_ERR_NETWORK:
+			return ExitNetwork
+		case err.ExitCode == whisk.EXIT_CODE_ERR_USAGE:
+			return ExitUsage
+		case err.ExitCode == whisk.EXIT_CODE_ERR_HTTP_RESP || err.ExitCode == whisk.EXIT_CODE_NOT_FOUND:
+			return ExitAPI
+		}
+	case net.Error:
+		return ExitNetwork
+	}
+	return ExitFailure
+}
+
+// EnableCIMode sets up --ci: no prompts and log lines without timestamps,
+// the CI system stamping them already.
+func EnableCIMode() {
+	Flags.NonInteractive = true
+	log.SetFlags(0)
+}
+
+// PrintFailure prints why wskdeploy fails, as a key=value line with the
+// class and exit code of the failure on stderr in --ci mode.
+func PrintFailure(err error) {
+	if !Flags.CI {
+		fmt.Println(err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, FailureLine(err))
+}
+
+// FailureLine returns the line --ci reports a failure with, e.g.
+// level=error class=invalid exit=3 msg="missing manifest.yaml file"
+func FailureLine(err error) string {
+	code := ExitCode(err)
+	return "level=error class=" + exitClasses[code] + " exit=" + strconv.Itoa(code) + " msg=" + strconv.Quote(err.Error())
+}
+
+// Exit prints the failure and exits with its code.
+func Exit(err error) {
+	PrintFailure(err)
+	os.Exit(ExitCode(err))
+}
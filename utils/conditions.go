@@ -0,0 +1,214 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// IsDefined reports whether the variable of an ifdef: condition is set to a
+// value that is not empty. The name may be written as NAME, $NAME or ${NAME}.
+func IsDefined(name string) bool {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "${") && strings.HasSuffix(name, "}") {
+		name = name[2 : len(name)-1]
+	}
+	value, _ := LookupVar(strings.TrimPrefix(name, "$"))
+	return value != ""
+}
+
+// EvalCondition evaluates the when: condition of an entity against the
+// variables, e.g. ${ENV} == dev && !${SKIP_DEBUG}. Operands are ${VAR} or
+// $VAR references, quoted strings or bare words; they compare as strings
+// with == and != and combine with !, && and || and parentheses. An operand
+// standing alone is true unless it is empty, false, no, off or 0.
+func EvalCondition(expr string) (bool, error) {
+	tokens, err := conditionTokens(expr)
+	if err != nil {
+		return false, errors.New("Invalid condition " + expr + ": " + err.Error())
+	}
+	if len(tokens) == 0 {
+		return false, errors.New("Invalid condition " + expr + ": it is empty")
+	}
+	parser := &conditionParser{tokens: tokens}
+	result, err := parser.or()
+	if err == nil && parser.pos < len(tokens) {
+		err = errors.New("unexpected " + tokens[parser.pos].text)
+	}
+	if err != nil {
+		return false, errors.New("Invalid condition " + expr + ": " + err.Error())
+	}
+	return result, nil
+}
+
+type conditionToken struct {
+	// an operator or parenthesis, empty for operands
+	op   string
+	text string
+	// the value of an operand, references resolved
+	value string
+}
+
+var conditionOperators = []string{"&&", "||", "==", "!=", "!", "(", ")"}
+
+func conditionTokens(expr string) ([]conditionToken, error) {
+	tokens := make([]conditionToken, 0)
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		if c == ' ' || c == '\t' {
+			i++
+			continue
+		}
+
+		matched := ""
+		for _, op := range conditionOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				matched = op
+				break
+			}
+		}
+		if matched != "" {
+			tokens = append(tokens, conditionToken{op: matched, text: matched})
+			i += len(matched)
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, errors.New("unterminated string " + expr[i:])
+			}
+			text := expr[i : i+end+2]
+			tokens = append(tokens, conditionToken{text: text, value: text[1 : len(text)-1]})
+			i += end + 2
+		case strings.HasPrefix(expr[i:], "${"):
+			end := closingBrace(expr, i+2)
+			if end < 0 {
+				return nil, errors.New("unterminated reference " + expr[i:])
+			}
+			text := expr[i : end+1]
+			tokens = append(tokens, conditionToken{text: text, value: expandReference(expr[i+2 : end])})
+			i = end + 1
+		default:
+			end := i
+			for end < len(expr) && !strings.ContainsRune(" \t'\"!&|=()", rune(expr[end])) {
+				end++
+			}
+			if end == i {
+				return nil, errors.New("unexpected " + expr[i:])
+			}
+			text := expr[i:end]
+			value := text
+			if strings.HasPrefix(text, "$") {
+				value, _ = LookupVar(text[1:])
+			}
+			tokens = append(tokens, conditionToken{text: text, value: value})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// a recursive descent parser evaluating the condition as it goes
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].op == op
+}
+
+func (p *conditionParser) or() (bool, error) {
+	result, err := p.and()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right bool
+		right, err = p.and()
+		result = result || right
+	}
+	return result, err
+}
+
+func (p *conditionParser) and() (bool, error) {
+	result, err := p.unary()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right bool
+		right, err = p.unary()
+		result = result && right
+	}
+	return result, err
+}
+
+func (p *conditionParser) unary() (bool, error) {
+	if p.peek("!") {
+		p.pos++
+		result, err := p.unary()
+		return !result, err
+	}
+	if p.peek("(") {
+		p.pos++
+		result, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		if !p.peek(")") {
+			return false, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return result, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return false, err
+	}
+	if p.peek("==") || p.peek("!=") {
+		op := p.tokens[p.pos].op
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return false, err
+		}
+		return (left == right) == (op == "=="), nil
+	}
+	return truthy(left), nil
+}
+
+func (p *conditionParser) operand() (string, error) {
+	if p.pos == len(p.tokens) {
+		return "", errors.New("it ends where an operand is expected")
+	}
+	token := p.tokens[p.pos]
+	if token.op != "" {
+		return "", errors.New("unexpected " + token.text)
+	}
+	p.pos++
+	return token.value, nil
+}
+
+func truthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "no", "off", "0":
+		return false
+	}
+	return true
+}
@@ -34,20 +34,18 @@ type URLReader struct {
 
 func (urlReader *URLReader) ReadUrl(url string) (content []byte, err error) {
 	resp, err := http.Get(url)
-	Check(err)
-	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
-	Check(err)
-	return b, nil
+	return ioutil.ReadAll(resp.Body)
 }
 
 type LocalReader struct {
 }
 
 func (localReader *LocalReader) ReadLocal(path string) (content []byte, err error) {
-	cont, err := ioutil.ReadFile(path)
-	Check(err)
-	return cont, nil
+	return ioutil.ReadFile(path)
 }
 
 func Read(url string) (content []byte, err error) {
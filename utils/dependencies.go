@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/openwhisk/openwhisk-client-go/whisk"
@@ -15,6 +16,15 @@ type DependencyRecord struct {
 	Parameters  whisk.KeyValueArr
 	Annotations whisk.KeyValueArr
 	IsBinding   bool
+	// package a github project is deployed into, the one its manifest
+	// declares when empty
+	Package string
+}
+
+// DependencyPath is the directory the github dependency name is cloned into
+// for a branch, tag or version.
+func DependencyPath(projectPath string, name string, version string) string {
+	return filepath.Join(projectPath, name+"-"+strings.Replace(version, "/", "-", -1))
 }
 
 func LocationIsBinding(location string) bool {
@@ -30,5 +40,11 @@ func LocationIsGithub(location string) bool {
 		return true
 	}
 
-	return false
+	return LocationIsSSH(location)
+}
+
+// LocationIsSSH reports whether a github dependency is cloned over ssh,
+// e.g. git@github.com:owner/repo.git or ssh://git@github.com/owner/repo.git
+func LocationIsSSH(location string) bool {
+	return strings.HasPrefix(location, "git@github.com:") || strings.HasPrefix(location, "ssh://git@github.com/")
 }
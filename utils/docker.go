@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// environment variable naming the registry images are pushed to when
+// --docker-registry is not given
+const DockerRegistryEnv = "WSKDEPLOY_DOCKER_REGISTRY"
+
+// the docker CLI images are built and pushed with
+var DockerCommand = "docker"
+
+var invalidImageChars = regexp.MustCompile("[^a-z0-9._-]+")
+
+// DockerRegistry returns the registry images of blackbox actions are pushed to
+func DockerRegistry() string {
+	if Flags.DockerRegistry != "" {
+		return Flags.DockerRegistry
+	}
+	return os.Getenv(DockerRegistryEnv)
+}
+
+// BuildImage builds the image of a Dockerfile, or of a build context
+// holding one, and pushes it to the registry. The image is tagged with its
+// id so every change deploys a new tag. With --preview the image is neither
+// built nor pushed.
+func BuildImage(dockerPath string, name string) (string, error) {
+	dockerfile, context := dockerPath, filepath.Dir(dockerPath)
+	if IsDirectory(dockerPath) {
+		dockerfile, context = filepath.Join(dockerPath, "Dockerfile"), dockerPath
+	}
+	if !FileExists(dockerfile) {
+		return "", errors.New("Dockerfile " + dockerfile + " does not exist")
+	}
+
+	registry := strings.TrimSuffix(DockerRegistry(), "/")
+	if registry == "" {
+		return "", errors.New("No registry to push the image of " + name + " to, set --docker-registry or $" + DockerRegistryEnv)
+	}
+	repository := registry + "/" + strings.Trim(invalidImageChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+
+	if Flags.Preview {
+		return repository + ":latest", nil
+	}
+
+	log.Print("Building image " + repository + " from " + dockerfile + " ... ")
+	if _, err := docker("build", "-t", repository+":latest", "-f", dockerfile, context); err != nil {
+		return "", err
+	}
+	id, err := docker("inspect", "--format", "{{.Id}}", repository+":latest")
+	if err != nil {
+		return "", err
+	}
+	id = strings.TrimPrefix(strings.TrimSpace(id), "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	image := repository + ":" + id
+	if _, err := docker("tag", repository+":latest", image); err != nil {
+		return "", err
+	}
+	if _, err := docker("push", image); err != nil {
+		return "", err
+	}
+	log.Println("Done!")
+	return image, nil
+}
+
+func docker(args ...string) (string, error) {
+	output, err := exec.Command(DockerCommand, args...).CombinedOutput()
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		if message == "" {
+			message = err.Error()
+		}
+		return "", errors.New("docker " + args[0] + " failed: " + message)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+// Encrypted values borrow the notation of SOPS:
+// ENC[AES256_GCM,data:<base64>,iv:<base64>,tag:<base64>]
+// but are not compatible with it, SOPS encrypts each value with the data
+// key of its file and binds it to the path of the value.
+const encryptedPrefix = "ENC[AES256_GCM,"
+
+// variable holding the base64 encoded 256 bit key encrypted values are
+// decrypted with, unless --key-file is given
+const EncryptionKeyVar = "WSKDEPLOY_KEY"
+
+const gcmTagSize = 16
+
+// IsEncrypted reports whether value is an ENC[...] encrypted value.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, "]")
+}
+
+// GenerateKey returns a new random key, base64 encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptionKey returns the key read from the --key-file file, or from
+// $WSKDEPLOY_KEY.
+func EncryptionKey() ([]byte, error) {
+	encoded, _ := LookupVar(EncryptionKeyVar)
+	if Flags.KeyFile != "" {
+		content, err := ioutil.ReadFile(Flags.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(content)
+	}
+	if encoded == "" {
+		return nil, errors.New("No key to decrypt values with, set " + EncryptionKeyVar + " or use --key-file")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("The encryption key must be 32 bytes, base64 encoded")
+	}
+	return key, nil
+}
+
+// EncryptValue encrypts plaintext with AES-256-GCM into an ENC[...] value.
+func EncryptValue(plaintext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), nil)
+	data, tag := sealed[:len(sealed)-gcmTagSize], sealed[len(sealed)-gcmTagSize:]
+
+	return encryptedPrefix +
+		"data:" + base64.StdEncoding.EncodeToString(data) +
+		",iv:" + base64.StdEncoding.EncodeToString(iv) +
+		",tag:" + base64.StdEncoding.EncodeToString(tag) + "]", nil
+}
+
+// DecryptValue decrypts an ENC[...] value. The plaintext only ever lives
+// in memory.
+func DecryptValue(value string, key []byte) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("Value is not encrypted")
+	}
+
+	fields := make(map[string][]byte)
+	body := strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), "]")
+	for _, field := range strings.Split(body, ",") {
+		idx := strings.Index(field, ":")
+		if idx < 0 {
+			return "", errors.New("Malformed encrypted value")
+		}
+		// only SOPS records the type of the value
+		if field[:idx] == "type" {
+			return "", errors.New("Value was encrypted by SOPS, which wskdeploy cannot decrypt, decrypt it with sops or encrypt it with wskdeploy encrypt")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(field[idx+1:])
+		if err != nil {
+			return "", errors.New("Malformed encrypted value: " + err.Error())
+		}
+		fields[field[:idx]] = decoded
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(fields["iv"]) != gcm.NonceSize() || len(fields["tag"]) != gcmTagSize {
+		return "", errors.New("Malformed encrypted value")
+	}
+
+	plaintext, err := gcm.Open(nil, fields["iv"], append(fields["data"], fields["tag"]...), nil)
+	if err != nil {
+		return "", errors.New("Unable to decrypt value, the key is wrong or the value was modified")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decrypt an input value read from a manifest or deployment file
+func decryptInput(value string) (string, error) {
+	key, err := EncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return DecryptValue(value, key)
+}
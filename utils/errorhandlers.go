@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // ServerlessErr records errors from the Serverless binary
@@ -33,14 +35,103 @@ func (e *ServerlessErr) Error() string {
 	return e.Msg
 }
 
-// Check is a util function to panic when there is an error.
-func Check(e error) {
-	defer func() {
-		if err := recover(); err != nil {
-			log.Printf("Runtime panic : %v", err)
+// FileError is an error in a manifest, deployment or source file.
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+// EntityError is an error of an entity of the project, e.g. action hello.
+type EntityError struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+func (e *EntityError) Error() string {
+	return e.Kind + " " + e.Name + ": " + e.Err.Error()
+}
+
+// FileErr tells which file an error is in, nil when there is no error.
+func FileErr(file string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FileError{File: file, Err: err}
+}
+
+// EntityErr tells which entity an error is about, nil when there is no
+// error.
+func EntityErr(kind string, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &EntityError{Kind: kind, Name: name, Err: err}
+}
+
+// Cause returns the error the file and entity of err were added to.
+func Cause(err error) error {
+	for {
+		switch wrapped := err.(type) {
+		case *FileError:
+			err = wrapped.Err
+		case *EntityError:
+			err = wrapped.Err
+		default:
+			return err
 		}
-	}()
+	}
+}
+
+// Errors are failures independent of each other, reported at once instead
+// of stopping at the first of them.
+type Errors []error
+
+func (errs Errors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	lines := []string{strconv.Itoa(len(errs)) + " errors:"}
+	for _, err := range errs {
+		lines = append(lines, "  - "+strings.Replace(err.Error(), "\n", "\n    ", -1))
+	}
+	return strings.Join(lines, "\n")
+}
 
+// Add adds err unless it is nil, and the errors it aggregates when it is
+// Errors itself.
+func (errs *Errors) Add(err error) {
+	switch err := err.(type) {
+	case nil:
+	case Errors:
+		*errs = append(*errs, err...)
+	default:
+		*errs = append(*errs, err)
+	}
+}
+
+// Err returns nil when there is no error, the error when there is one, and
+// all of them otherwise.
+func (errs Errors) Err() error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+// Check exits when there is an error. Only commands call it, the packages
+// they use return their errors.
+func Check(e error) {
+	if e != nil && Flags.CI && !Flags.WithinOpenWhisk {
+		Exit(e)
+	}
 	if e != nil {
 		log.Printf("%v", e)
 		erro := errors.New("Error happened during execution, please type 'wskdeploy -h' for help messages.")
@@ -48,7 +139,7 @@ func Check(e error) {
 		if Flags.WithinOpenWhisk {
 			PrintOpenWhiskError(e.Error())
 		} else {
-			os.Exit(1)
+			os.Exit(ExitCode(e))
 		}
 
 	}
@@ -48,23 +48,14 @@ func FileExists(file string) bool {
 	}
 }
 
+// IsDirectory tells whether the path is a directory, false when it cannot
+// be read
 func IsDirectory(filePath string) bool {
-	f, err := os.Open(filePath)
-	Check(err)
-
-	defer f.Close()
-
-	fi, err := f.Stat()
-	Check(err)
-
-	switch mode := fi.Mode(); {
-	case mode.IsDir():
-		return true
-	case mode.IsRegular():
-		return false
-	default:
+	fi, err := os.Stat(filePath)
+	if err != nil {
 		return false
 	}
+	return fi.Mode().IsDir()
 }
 
 func CreateActionFromFile(manipath, filePath string) (*whisk.Action, error) {
@@ -81,18 +72,7 @@ func CreateActionFromFile(manipath, filePath string) (*whisk.Action, error) {
 		filePath = strings.TrimRight(manipath, splitmanipath[len(splitmanipath)-1]) + filePath
 	}
 	// process source code files
-	if ext == ".swift" || ext == ".js" || ext == ".py" {
-
-		kind := "nodejs:default"
-
-		switch ext {
-		case ".swift":
-			kind = "swift:default"
-		case ".js":
-			kind = "nodejs:default"
-		case ".py":
-			kind = "python"
-		}
+	if kind, ok := KindForExtension(ext); ok && !IsBinaryArtifact(filePath) {
 
 		var dat []byte
 		var err error
@@ -103,18 +83,17 @@ func CreateActionFromFile(manipath, filePath string) (*whisk.Action, error) {
 			dat, err = new(ContentReader).URLReader.ReadUrl(filePath)
 		}
 
+		if err != nil {
+			return nil, err
+		}
 		code := string(dat)
 		pub := false
-		Check(err)
 		action.Exec = new(whisk.Exec)
 		action.Exec.Code = &code
 		action.Exec.Kind = kind
 		action.Name = name
 		action.Publish = &pub
 		return action, nil
-		//dat, err := new(ContentReader).URLReader.ReadUrl(filePath)
-		//Check(err)
-
 	}
 	// If the action is not supported, we better to return an error.
 	return nil, errors.New("Unsupported action type.")
@@ -161,17 +140,9 @@ func WriteProps(path string, props map[string]string) error {
 	defer file.Close()
 
 	for k, v := range props {
-		_, err := file.WriteString(k)
-		Check(err)
-
-		_, err = file.WriteString("=")
-		Check(err)
-
-		_, err = file.WriteString(v)
-		Check(err)
-
-		_, err = file.WriteString("\n")
-		Check(err)
+		if _, err := file.WriteString(k + "=" + v + "\n"); err != nil {
+			return FileErr(path, err)
+		}
 	}
 	return nil
 }
@@ -179,9 +150,14 @@ func WriteProps(path string, props map[string]string) error {
 // Load configuration will load properties from a file
 func LoadConfiguration(propPath string) ([]string, error) {
 	props, err := ReadProps(propPath)
-	Check(err)
+	if err != nil {
+		return nil, FileErr(propPath, err)
+	}
 	Namespace := props["NAMESPACE"]
 	Apihost := props["APIHOST"]
 	Authtoken := props["AUTH"]
-	return []string{Namespace, Apihost, Authtoken}, nil
+	// the client certificate and its key, as the wsk CLI reads them
+	Cert := props["CERT"]
+	Key := props["KEY"]
+	return []string{Namespace, Apihost, Authtoken, Cert, Key}, nil
 }
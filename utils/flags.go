@@ -1,10 +1,59 @@
 package utils
 
+import "time"
+
 var Flags struct {
-	WithinOpenWhisk bool   // is this running within an OpenWhisk action?
-	ApiHost         string // OpenWhisk API host
-	Auth            string // OpenWhisk API key
-	ApiVersion      string // OpenWhisk version
+	WithinOpenWhisk   bool          // is this running within an OpenWhisk action?
+	ApiHost           string        // OpenWhisk API host
+	Auth              string        // OpenWhisk API key
+	Namespace         string        // OpenWhisk namespace
+	ApiVersion        string        // OpenWhisk version
+	Preview           bool          // print the deployment plan without applying it
+	Diff              bool          // report how the namespace differs from the manifest
+	Concurrency       int           // number of actions deployed at the same time
+	Retries           int           // number of retries after a transient API error
+	RollbackOnFailure bool          // undo the changes of a deployment that fails midway
+	Sync              bool          // delete managed entities that are no longer in the manifest
+	RemoveOrphans     bool          // delete them on deploy too, once confirmed
+	Force             bool          // overwrite entities of other projects, and do not ask before deleting
+	UpdateDeps        bool          // resolve the github dependencies again instead of using the lock file
+	DockerRegistry    string        // registry the images of blackbox actions are pushed to
+	MaxCodeSize       int           // megabytes the code of an action can have, unless the platform reports its limit
+	Project           string        // project name deployed entities are tagged with
+	Environment       string        // environment of the deployment file to deploy to
+	Profile           string        // credential profile in ~/.wskprops.d to use
+	UseKeychain       bool          // keep the auth key in the OS keychain
+	KeyFile           string        // file holding the key encrypted values are decrypted with
+	NonInteractive    bool          // never prompt, fail when a value is neither given nor defaulted
+	Params            []string      // KEY=VALUE inputs given with --param, overriding the deployment file
+	ParamFile         string        // JSON file of inputs overriding the deployment file, before --param
+	CACert            string        // PEM file of certificate authorities trusted besides the system ones
+	Cert              string        // PEM file of the client certificate, for an API behind mTLS
+	Key               string        // PEM file of the key of the client certificate
+	IAMApiKey         string        // IBM Cloud API key exchanged for IAM tokens, instead of the auth key
+	IAMEndpoint       string        // IAM endpoint the API key is exchanged at
+	ConnectTimeout    time.Duration // to open a connection to the API
+	ReadTimeout       time.Duration // for the API to start responding once a request is sent
+	RequestTimeout    time.Duration // for a whole API call, retries included, 0 for none
+	RateLimit         float64       // API requests per second, 0 for no limit
+	RateBurst         int           // API requests sent at once before the rate limit applies
+	Strategy          string        // in-place, or blue-green to deploy a new version of the package next to the live one
+	Verify            []string      // actions of a blue/green version invoked before it can be promoted
+	Promote           bool          // make the pending blue/green version live
+	Abort             bool          // remove the pending blue/green version
+	HistoryDir        string        // directory deployments are recorded in, for rollback
+	Watch             bool          // deploy again whenever a file of the project changes
+	WatchInterval     time.Duration // how often --watch looks for changed files
+	RuntimesFile      string        // mapping of the runtimes of the distribution: extensions, default kinds and code sizes
+	PreProcess        string        // pre-processor manifest and deployment files go through before parsing, go-template
+	Strict            bool          // fail on the unknown keys of manifest and deployment files instead of ignoring them
+	CI                bool          // no colors, prompts or timestamps, failures as key=value lines exiting with the code of their class
+	Format            string        // output of deploy and undeploy: text, or json for a document of the entities touched
+	JUnit             string        // file the entities touched and actions verified are reported to as JUnit XML
+	Webhook           string        // URL notified when deploy or undeploy completes
+	WebhookFormat     string        // body posted to the webhook: json, or slack for a Slack message
+	Trace             string        // file every API request and response is logged to, credentials redacted
+	AuditLog          string        // JSON lines file every change made to the namespace is appended to
 
 	//action flag definition
 	//from go cli
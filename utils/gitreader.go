@@ -3,10 +3,15 @@ package utils
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -17,8 +22,19 @@ type GitReader struct {
 	Url         string
 	Version     string
 	ProjectPath string
+	// commit to download instead of the head of Version
+	Commit string
+	// sha256 of the downloaded archive; verified when set before cloning
+	Checksum string
 }
 
+// GitHub API commits and archives of public dependencies are fetched from
+var GithubApiUrl = "https://api.github.com"
+
+// environment variable holding the personal access token private github
+// dependencies are fetched with
+const GithubTokenEnv = "GITHUB_TOKEN"
+
 func NewGitReader(projectName string, record DependencyRecord) *GitReader {
 	var gitReader GitReader
 
@@ -32,30 +48,159 @@ func NewGitReader(projectName string, record DependencyRecord) *GitReader {
 
 }
 
+// ResolveCommit returns the commit the branch, tag or version of the
+// dependency currently points at.
+func (reader *GitReader) ResolveCommit() (string, error) {
+	if LocationIsSSH(reader.Url) {
+		return reader.lsRemote()
+	}
+
+	repo, err := reader.repo()
+	if err != nil {
+		return "", err
+	}
+	response, err := reader.get(GithubApiUrl+"/repos/"+repo+"/commits/"+reader.Version, "application/vnd.github.VERSION.sha")
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	sha, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(sha)), nil
+}
+
+// owner/name of the github repository of the dependency
+func (reader *GitReader) repo() (string, error) {
+	u, err := url.Parse(reader.Url)
+	if err != nil {
+		return "", errors.New("Invalid location " + reader.Url + " of dependency " + reader.Name + ": " + err.Error())
+	}
+	return strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git"), nil
+}
+
+// send a GET to the GitHub API, authenticated with the token in the
+// environment when there is one
+func (reader *GitReader) get(requestUrl string, accept string) (*http.Response, error) {
+	request, err := http.NewRequest("GET", requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", accept)
+	token := os.Getenv(GithubTokenEnv)
+	if token != "" {
+		request.Header.Set("Authorization", "token "+token)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, errors.New("Unable to fetch " + reader.Version + " of dependency " + reader.Name + ": " + err.Error())
+	}
+	if response.StatusCode == http.StatusOK {
+		return response, nil
+	}
+	response.Body.Close()
+
+	// GitHub answers 404 rather than 403 for private repositories
+	switch {
+	case token != "" && (response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusNotFound):
+		return nil, errors.New("Authentication failed for dependency " + reader.Name + " at " + reader.Url + ": the token in $" + GithubTokenEnv + " is invalid or has no access to the repository (" + response.Status + ")")
+	case token == "" && (response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusNotFound):
+		return nil, errors.New("Dependency " + reader.Name + " at " + reader.Url + " was not found: if the repository is private, set $" + GithubTokenEnv + " to a personal access token or use an ssh location such as git@github.com:owner/repo.git (" + response.Status + ")")
+	}
+	return nil, errors.New("Unable to fetch " + reader.Version + " of dependency " + reader.Name + " from " + reader.Url + ": " + response.Status)
+}
+
+// resolve the commit of a dependency cloned over ssh
+func (reader *GitReader) lsRemote() (string, error) {
+	output, err := reader.git("", "ls-remote", reader.Url, reader.Version)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && (fields[1] == "refs/heads/"+reader.Version || fields[1] == "refs/tags/"+reader.Version || fields[1] == "HEAD") {
+			return fields[0], nil
+		}
+	}
+	// a commit rather than a branch or tag
+	return reader.Version, nil
+}
+
+// run git with the ssh agent of the environment, turning authentication
+// failures into an actionable error
+func (reader *GitReader) git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return string(output), nil
+	}
+
+	message := strings.TrimSpace(string(output))
+	if strings.Contains(message, "Permission denied") || strings.Contains(message, "Could not read from remote repository") || strings.Contains(message, "Host key verification failed") {
+		hint := "make sure the ssh agent holds a key with access to the repository"
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			hint = "no ssh agent is running, start one with ssh-agent and add a key with access to the repository"
+		}
+		return "", errors.New("SSH authentication failed for dependency " + reader.Name + " at " + reader.Url + ": " + hint + " (" + message + ")")
+	}
+	if message == "" {
+		message = err.Error()
+	}
+	return "", errors.New("git " + args[0] + " failed for dependency " + reader.Name + ": " + message)
+}
+
+// CloneDependency downloads the commit, or else the branch, tag or version,
+// of the dependency and unpacks it to DependencyPath, replacing an earlier
+// clone.
 func (reader *GitReader) CloneDependency() error {
-	zipFileName := reader.Name + "." + reader.Version + ".zip"
-	zipFilePath := reader.Url + "/zipball" + "/" + reader.Version
+	ref := reader.Version
+	if reader.Commit != "" {
+		ref = reader.Commit
+	}
+	if LocationIsSSH(reader.Url) {
+		return reader.cloneSSH(ref)
+	}
+
+	repo, err := reader.repo()
+	if err != nil {
+		return err
+	}
+	zipFileName := reader.Name + "." + strings.Replace(reader.Version, "/", "-", -1) + ".zip"
 
 	os.MkdirAll(reader.ProjectPath, os.ModePerm)
 	output, err := os.Create(path.Join(reader.ProjectPath, zipFileName))
-	Check(err)
+	if err != nil {
+		return err
+	}
 	defer output.Close()
 
-	response, err := http.Get(zipFilePath)
-	Check(err)
+	response, err := reader.get(GithubApiUrl+"/repos/"+repo+"/zipball/"+ref, "application/vnd.github.v3+json")
+	if err != nil {
+		os.Remove(path.Join(reader.ProjectPath, zipFileName))
+		return err
+	}
 	defer response.Body.Close()
 
-	_, err = io.Copy(output, response.Body)
-	Check(err)
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(output, hash), response.Body); err != nil {
+		os.Remove(path.Join(reader.ProjectPath, zipFileName))
+		return err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if reader.Checksum != "" && reader.Checksum != checksum {
+		os.Remove(path.Join(reader.ProjectPath, zipFileName))
+		return errors.New("Checksum of dependency " + reader.Name + " at " + ref + " does not match " + LockFileName + ", run with --update-deps to accept the new code")
+	}
+	reader.Checksum = checksum
 
 	zipReader, err := zip.OpenReader(path.Join(reader.ProjectPath, zipFileName))
-	Check(err)
-
-	u, err := url.Parse(reader.Url)
-	team, project := path.Split(u.Path)
-
-	team = strings.TrimPrefix(team, "/")
-	team = strings.TrimSuffix(team, "/")
+	if err != nil {
+		return err
+	}
 
 	for _, file := range zipReader.File {
 		path := filepath.Join(reader.ProjectPath, file.Name)
@@ -66,11 +211,15 @@ func (reader *GitReader) CloneDependency() error {
 		}
 
 		fileReader, err := file.Open()
-		Check(err)
+		if err != nil {
+			return err
+		}
 		defer fileReader.Close()
 
 		targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		Check(err)
+		if err != nil {
+			return err
+		}
 		defer targetFile.Close()
 
 		if _, err := io.Copy(targetFile, fileReader); err != nil {
@@ -79,9 +228,33 @@ func (reader *GitReader) CloneDependency() error {
 	}
 
 	rootDir := filepath.Join(reader.ProjectPath, zipReader.File[0].Name)
-	depPath := filepath.Join(reader.ProjectPath, project+"-"+reader.Version)
+	depPath := DependencyPath(reader.ProjectPath, reader.Name, reader.Version)
+	os.RemoveAll(depPath)
 	os.Rename(rootDir, depPath)
 	os.Remove(filepath.Join(reader.ProjectPath, zipFileName))
 
 	return nil
 }
+
+// clone a dependency over ssh and check out ref; the commit pins the code so
+// no checksum is recorded
+func (reader *GitReader) cloneSSH(ref string) error {
+	os.MkdirAll(reader.ProjectPath, os.ModePerm)
+	cloneDir, err := ioutil.TempDir(reader.ProjectPath, reader.Name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cloneDir)
+
+	if _, err := reader.git("", "clone", "--quiet", reader.Url, cloneDir); err != nil {
+		return err
+	}
+	if _, err := reader.git(cloneDir, "checkout", "--quiet", ref); err != nil {
+		return err
+	}
+	os.RemoveAll(filepath.Join(cloneDir, ".git"))
+
+	depPath := DependencyPath(reader.ProjectPath, reader.Name, reader.Version)
+	os.RemoveAll(depPath)
+	return os.Rename(cloneDir, depPath)
+}
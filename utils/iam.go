@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the IBM Cloud IAM endpoint API keys are exchanged for tokens at
+const DefaultIAMEndpoint = "https://iam.cloud.ibm.com/identity/token"
+
+// IAMTransport authenticates requests with a bearer token of IBM Cloud IAM
+// instead of the whisk auth key. The token is obtained for the API key and
+// obtained again shortly before it expires, so long deployments carry on.
+type IAMTransport struct {
+	Transport http.RoundTripper
+	APIKey    string
+	Endpoint  string
+	// how long before its expiry the token is replaced
+	RefreshMargin time.Duration
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func NewIAMTransport(transport http.RoundTripper, apiKey string, endpoint string) *IAMTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if endpoint == "" {
+		endpoint = DefaultIAMEndpoint
+	}
+	return &IAMTransport{
+		Transport:     transport,
+		APIKey:        apiKey,
+		Endpoint:      endpoint,
+		RefreshMargin: time.Minute,
+	}
+}
+
+func (t *IAMTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Token()
+	if err != nil {
+		return nil, err
+	}
+	// a round tripper must not modify the request it is given
+	authorized := new(http.Request)
+	*authorized = *req
+	authorized.Header = make(http.Header, len(req.Header))
+	for key, values := range req.Header {
+		authorized.Header[key] = values
+	}
+	authorized.Header.Set("Authorization", "Bearer "+token)
+	return t.Transport.RoundTrip(authorized)
+}
+
+// Token returns the current token, obtaining a new one when it is about to
+// expire.
+func (t *IAMTransport) Token() (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.token != "" && time.Now().Add(t.RefreshMargin).Before(t.expiry) {
+		return t.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", t.APIKey)
+	req, err := http.NewRequest("POST", t.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return "", errors.New("Unable to obtain an IAM token: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("Unable to obtain an IAM token: " + resp.Status)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", errors.New("Unable to read the IAM token: " + err.Error())
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("The IAM endpoint " + t.Endpoint + " returned no token")
+	}
+	t.token = result.AccessToken
+	t.expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return t.token, nil
+}
@@ -0,0 +1,254 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// name of the file holding per-developer variables in the project root
+const EnvFileName = ".env"
+
+// variables loaded from .env files, consulted after the process environment
+var envFileVars = make(map[string]string)
+
+// variables given with --var, consulted before the process environment
+var cliVars = make(map[string]string)
+
+// directory of the project the __GIT_COMMIT__ and __GIT_BRANCH__ variables
+// describe, the working directory when empty
+var GitVarsDir string
+
+// the variables wskdeploy sets itself, computed once per run so every
+// reference to one has the same value
+var builtinVars = map[string]func() string{
+	"__UUID__": newUUID,
+	"__DEPLOY_TIME__": func() string {
+		return time.Now().UTC().Format(time.RFC3339)
+	},
+	"__GIT_COMMIT__": func() string {
+		return gitOutput("rev-parse", "HEAD")
+	},
+	"__GIT_BRANCH__": func() string {
+		return gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+	},
+}
+
+var builtinValues = make(map[string]string)
+var builtinLock sync.Mutex
+
+// LookupVar returns the value of a variable referenced from a manifest or
+// deployment file. The built-in variables, e.g. ${__GIT_COMMIT__}, apply
+// when no other variable of the name is set.
+func LookupVar(name string) (string, bool) {
+	if value, ok := cliVars[name]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	if value, ok := envFileVars[name]; ok {
+		return value, true
+	}
+	return builtinVar(name)
+}
+
+func builtinVar(name string) (string, bool) {
+	compute, ok := builtinVars[name]
+	if !ok {
+		return "", false
+	}
+	builtinLock.Lock()
+	defer builtinLock.Unlock()
+	if _, computed := builtinValues[name]; !computed {
+		builtinValues[name] = compute()
+	}
+	return builtinValues[name], true
+}
+
+// a random, version 4 UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// the output of a git command in the project, empty outside of a repository
+func gitOutput(args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = GitVarsDir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// LoadVars sets the NAME=VALUE variables given on the command line, which
+// take precedence over the environment and .env files.
+func LoadVars(pairs []string) error {
+	for _, pair := range pairs {
+		idx := strings.Index(pair, "=")
+		if idx <= 0 {
+			return errors.New("Invalid variable " + pair + ": expected NAME=VALUE")
+		}
+		cliVars[strings.TrimSpace(pair[:idx])] = pair[idx+1:]
+	}
+	return nil
+}
+
+// LoadEnvFile reads KEY=VALUE pairs from path into the interpolation
+// environment. Blank lines, "#" comments and an "export " prefix are
+// allowed; values may be single or double quoted.
+func LoadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lineNum := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return errors.New("Invalid line " + strconv.Itoa(lineNum) + " in " + path + ": expected KEY=VALUE")
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		value, err := parseEnvValue(strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			return errors.New("Invalid value for " + key + " on line " + strconv.Itoa(lineNum) + " in " + path + ": " + err.Error())
+		}
+		envFileVars[key] = value
+	}
+	return scanner.Err()
+}
+
+func parseEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "\""):
+		end := strings.LastIndex(value, "\"")
+		if end == 0 {
+			return "", errors.New("missing closing quote")
+		}
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		end := strings.LastIndex(value, "'")
+		if end == 0 {
+			return "", errors.New("missing closing quote")
+		}
+		return value[1:end], nil
+	default:
+		// unquoted values end at an inline comment
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = value[:idx]
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
+
+// NeedsInterpolation reports whether s uses the ${VAR} syntax or the $$ escape.
+func NeedsInterpolation(s string) bool {
+	return strings.Contains(s, "${") || strings.Contains(s, "$$")
+}
+
+// Interpolate replaces every ${VAR} and ${VAR:-default} in s. The default
+// is used when VAR is unset or empty; "$$" stands for a literal dollar sign.
+// A reference without a closing brace is kept as is.
+func Interpolate(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i++
+		case '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				buf.WriteString(s[i:])
+				return buf.String()
+			}
+			buf.WriteString(expandReference(s[i+2 : end]))
+			i = end
+		default:
+			buf.WriteByte('$')
+		}
+	}
+	return buf.String()
+}
+
+// find the brace closing a reference, allowing nested references in defaults
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func expandReference(ref string) string {
+	name := ref
+	def := ""
+	hasDefault := false
+	if idx := strings.Index(ref, ":-"); idx >= 0 {
+		name = ref[:idx]
+		def = ref[idx+2:]
+		hasDefault = true
+	}
+
+	value, _ := LookupVar(strings.TrimSpace(name))
+	if value == "" && hasDefault {
+		return Interpolate(def)
+	}
+	return value
+}
@@ -0,0 +1,202 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// service the OpenWhisk auth keys are stored under in the OS keychain
+const KeychainService = "wskdeploy"
+
+// the OS whose keychain is used, and the function running its tools with
+// the given stdin, returning their stdout and stderr
+var KeychainOS = runtime.GOOS
+var RunKeychainCommand = func(stdin string, name string, args ...string) (string, string, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// KeychainGet returns the auth key stored for the account, the API host,
+// or "" when none is stored. The macOS Keychain is read with security(1),
+// libsecret with secret-tool(1) and the Windows Credential Manager with
+// PowerShell.
+func KeychainGet(account string) (string, error) {
+	var stdout, stderr string
+	var err error
+	switch KeychainOS {
+	case "darwin":
+		stdout, stderr, err = RunKeychainCommand("", "security", "find-generic-password", "-s", KeychainService, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd":
+		stdout, stderr, err = RunKeychainCommand("", "secret-tool", "lookup", "service", KeychainService, "account", account)
+	case "windows":
+		stdout, stderr, err = RunKeychainCommand("", "powershell", powershellArgs(credentialScript+"[WskdeployCredential]::Read("+powershellString(credentialTarget(account))+")")...)
+	default:
+		return "", errors.New("The OS keychain is not supported on " + KeychainOS)
+	}
+
+	if err != nil {
+		if _, exited := err.(*exec.ExitError); exited && notStored(stderr) {
+			return "", nil
+		}
+		return "", errors.New("Unable to read the auth key from the keychain: " + strings.TrimSpace(err.Error()+" "+stderr))
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// KeychainSet stores the auth key of the account in the OS keychain,
+// replacing any key stored before. The key is passed on stdin so it never
+// shows up in ps.
+func KeychainSet(account string, secret string) error {
+	var stderr string
+	var err error
+	switch KeychainOS {
+	case "darwin":
+		// -w as last option prompts for the password and its confirmation
+		_, stderr, err = RunKeychainCommand(secret+"\n"+secret+"\n", "security", "add-generic-password", "-U", "-s", KeychainService, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd":
+		_, stderr, err = RunKeychainCommand(secret, "secret-tool", "store", "--label=OpenWhisk auth key for "+account, "service", KeychainService, "account", account)
+	case "windows":
+		_, stderr, err = RunKeychainCommand(secret, "powershell", powershellArgs(credentialScript+"[WskdeployCredential]::Write("+powershellString(credentialTarget(account))+", "+powershellString(account)+", [Console]::In.ReadToEnd())")...)
+	default:
+		return errors.New("The OS keychain is not supported on " + KeychainOS)
+	}
+
+	if err != nil {
+		return errors.New("Unable to store the auth key in the keychain: " + strings.TrimSpace(err.Error()+" "+stderr))
+	}
+	return nil
+}
+
+// security exits with 44 and secret-tool with 1 and no output when
+// nothing is stored for the account
+func notStored(stderr string) bool {
+	stderr = strings.TrimSpace(stderr)
+	return stderr == "" || strings.Contains(stderr, "could not be found")
+}
+
+// the name of the generic credential of the account in the Windows
+// Credential Manager
+func credentialTarget(account string) string {
+	return KeychainService + ":" + account
+}
+
+// the script is passed encoded, so PowerShell leaves stdin to the secret
+func powershellArgs(script string) []string {
+	encoded := utf16.Encode([]rune(script))
+	buf := make([]byte, 2*len(encoded))
+	for i, unit := range encoded {
+		binary.LittleEndian.PutUint16(buf[2*i:], unit)
+	}
+	return []string{"-NoProfile", "-NonInteractive", "-EncodedCommand", base64.StdEncoding.EncodeToString(buf)}
+}
+
+func powershellString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// reads and writes generic credentials with CredRead and CredWrite, a
+// credential that is not stored reads as empty
+const credentialScript = `Add-Type -TypeDefinition @'
+using System;
+using System.ComponentModel;
+using System.Runtime.InteropServices;
+using System.Text;
+
+public static class WskdeployCredential {
+    [StructLayout(LayoutKind.Sequential, CharSet = CharSet.Unicode)]
+    struct CREDENTIAL {
+        public int Flags;
+        public int Type;
+        public string TargetName;
+        public string Comment;
+        public System.Runtime.InteropServices.ComTypes.FILETIME LastWritten;
+        public int CredentialBlobSize;
+        public IntPtr CredentialBlob;
+        public int Persist;
+        public int AttributeCount;
+        public IntPtr Attributes;
+        public string TargetAlias;
+        public string UserName;
+    }
+
+    const int CRED_TYPE_GENERIC = 1;
+    const int CRED_PERSIST_LOCAL_MACHINE = 2;
+    const int ERROR_NOT_FOUND = 1168;
+
+    [DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+    static extern bool CredRead(string target, int type, int flags, out IntPtr credential);
+
+    [DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+    static extern bool CredWrite(ref CREDENTIAL credential, int flags);
+
+    [DllImport("advapi32.dll")]
+    static extern void CredFree(IntPtr buffer);
+
+    public static string Read(string target) {
+        IntPtr buffer;
+        if (!CredRead(target, CRED_TYPE_GENERIC, 0, out buffer)) {
+            int code = Marshal.GetLastWin32Error();
+            if (code == ERROR_NOT_FOUND) {
+                return "";
+            }
+            throw new Win32Exception(code);
+        }
+        try {
+            CREDENTIAL credential = (CREDENTIAL)Marshal.PtrToStructure(buffer, typeof(CREDENTIAL));
+            return Marshal.PtrToStringUni(credential.CredentialBlob, credential.CredentialBlobSize / 2);
+        } finally {
+            CredFree(buffer);
+        }
+    }
+
+    public static void Write(string target, string user, string secret) {
+        byte[] blob = Encoding.Unicode.GetBytes(secret.Trim());
+        CREDENTIAL credential = new CREDENTIAL();
+        credential.Type = CRED_TYPE_GENERIC;
+        credential.TargetName = target;
+        credential.UserName = user;
+        credential.Persist = CRED_PERSIST_LOCAL_MACHINE;
+        credential.CredentialBlobSize = blob.Length;
+        credential.CredentialBlob = Marshal.AllocHGlobal(blob.Length);
+        try {
+            Marshal.Copy(blob, 0, credential.CredentialBlob, blob.Length);
+            if (!CredWrite(ref credential, 0)) {
+                throw new Win32Exception(Marshal.GetLastWin32Error());
+            }
+        } finally {
+            Marshal.FreeHGlobal(credential.CredentialBlob);
+        }
+    }
+}
+'@
+`
@@ -0,0 +1,96 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// name of the file in the project path pinning the github dependencies
+const LockFileName = "wskdeploy.lock"
+
+// LockFile records the commit and checksum each github dependency resolved
+// to, so later deployments fetch the same code until --update-deps is given.
+type LockFile struct {
+	Path         string                      `yaml:"-"`
+	Dependencies map[string]LockedDependency `yaml:"dependencies"`
+	changed      bool
+}
+
+type LockedDependency struct {
+	Location string `yaml:"location"`
+	Version  string `yaml:"version"` // the branch, tag or version asked for
+	Commit   string `yaml:"commit"`
+	Sha256   string `yaml:"sha256"`
+}
+
+// ReadLockFile reads a lock file, which is empty when the file does not exist.
+func ReadLockFile(path string) (*LockFile, error) {
+	lock := &LockFile{Path: path, Dependencies: make(map[string]LockedDependency)}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, errors.New("Unable to read lock file " + path + ": " + err.Error())
+	}
+	if err := yaml.Unmarshal(content, lock); err != nil {
+		return nil, errors.New("Unable to parse lock file " + path + ": " + err.Error())
+	}
+	if lock.Dependencies == nil {
+		lock.Dependencies = make(map[string]LockedDependency)
+	}
+	return lock, nil
+}
+
+// Locked returns the pin of a dependency, unless its location or version
+// changed since it was locked.
+func (lock *LockFile) Locked(name string, record DependencyRecord) (LockedDependency, bool) {
+	locked, ok := lock.Dependencies[name]
+	if !ok || locked.Location != record.Location || locked.Version != record.Version {
+		return LockedDependency{}, false
+	}
+	return locked, true
+}
+
+func (lock *LockFile) Lock(name string, locked LockedDependency) {
+	if lock.Dependencies[name] != locked {
+		lock.Dependencies[name] = locked
+		lock.changed = true
+	}
+}
+
+// Save writes the lock file if a pin was added or changed.
+func (lock *LockFile) Save() error {
+	if !lock.changed {
+		return nil
+	}
+	content, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(lock.Path, content, 0644); err != nil {
+		return errors.New("Unable to write lock file " + lock.Path + ": " + err.Error())
+	}
+	lock.changed = false
+	return nil
+}
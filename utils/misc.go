@@ -20,17 +20,23 @@ package utils
 import (
 	"archive/zip"
 	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/hokaccha/go-prettyjson"
 	"github.com/openwhisk/openwhisk-client-go/whisk"
@@ -62,6 +68,49 @@ type ActionExposedURLBinding struct {
 	ExposedUrl string //exposedUrl in format method/baseurl/relativeurl
 }
 
+// Route converts the exposedUrl of the binding to an API Gateway route.
+func (binding *ActionExposedURLBinding) Route(pkgname string) (ApiRoute, error) {
+	parts := strings.SplitN(binding.ExposedUrl, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return ApiRoute{}, errors.New("exposedUrl " + binding.ExposedUrl + " of action " + binding.ActionName + " must have the form method/basepath/relativepath")
+	}
+	route := ApiRoute{
+		BasePath:    "/" + parts[1],
+		RelPath:     "/" + parts[2],
+		Verb:        strings.ToUpper(parts[0]),
+		ActionName:  binding.ActionName,
+		Packagename: pkgname,
+	}
+	return route, nil
+}
+
+// Bind an action to a route of the API Gateway
+type ApiRoute struct {
+	BasePath    string // e.g. /hello
+	RelPath     string // e.g. /world
+	Verb        string // upper case HTTP method
+	ActionName  string
+	Packagename string
+}
+
+// Key identifies the route, e.g. "GET /hello/world"
+func (route ApiRoute) Key() string {
+	return route.Verb + " " + route.BasePath + route.RelPath
+}
+
+type ApiRoutes []ApiRoute
+
+func (routes ApiRoutes) Len() int      { return len(routes) }
+func (routes ApiRoutes) Swap(i, j int) { routes[i], routes[j] = routes[j], routes[i] }
+func (routes ApiRoutes) Less(i, j int) bool {
+	pathI := routes[i].BasePath + routes[i].RelPath
+	pathJ := routes[j].BasePath + routes[j].RelPath
+	if pathI != pathJ {
+		return pathI < pathJ
+	}
+	return routes[i].Verb < routes[j].Verb
+}
+
 // Utility to convert hostname to URL object
 func GetURLBase(host string) (*url.URL, error) {
 
@@ -76,9 +125,13 @@ func GetURLBase(host string) (*url.URL, error) {
 	return url, err
 }
 
+// GetHomeDirectory returns the home directory of the user, $HOME when the
+// user cannot be looked up.
 func GetHomeDirectory() string {
 	usr, err := user.Current()
-	Check(err)
+	if err != nil {
+		return os.Getenv("HOME")
+	}
 
 	return usr.HomeDir
 }
@@ -113,38 +166,65 @@ func IsJSON(s string) (interface{}, bool) {
 func PrettyJSON(j interface{}) string {
 	formatter := prettyjson.NewFormatter()
 	bytes, err := formatter.Marshal(j)
-	Check(err)
+	if err != nil {
+		return fmt.Sprintf("%v", j)
+	}
 	return string(bytes)
 }
 
 // Common utilities
 
-// Prompt for user input
+// Prompt for user input, taking the default when nothing is typed. In
+// non-interactive mode the default is taken unless the question is answered,
+// and a question without default must be answered.
 func Ask(reader *bufio.Reader, question string, def string) string {
-	fmt.Print(question + " (" + def + "): ")
-	answer, _ := reader.ReadString('\n')
-	len := len(answer)
-	if len == 1 {
+	answer, err := Prompt(reader, AnswerKey(question), question+" ("+def+"): ")
+	if err != nil && def == "" {
+		Check(err)
+	}
+	if answer == "" {
 		return def
 	}
-	return answer[:len-1]
+	return answer
 }
 
-// Get the env variable value by key.
-// Get the env variable if the key is start by $
+// Get the env variable value by key, the key itself when it cannot be
+// resolved.
 func GetEnvVar(key interface{}) interface{} {
+	value, err := ResolveEnvVar(key)
+	if err != nil {
+		log.Printf("Warning: %v\n", err)
+		return key
+	}
+	return value
+}
+
+// ResolveEnvVar gets the env variable if the key is start by $, and expands
+// every ${VAR} or ${VAR:-default} reference inside the string. Values of the
+// form vault://path#key are read from Vault, and ENC[...] values are
+// decrypted.
+func ResolveEnvVar(key interface{}) (interface{}, error) {
 	if reflect.TypeOf(key).String() == "string" {
+		if IsEncrypted(key.(string)) {
+			return decryptInput(key.(string))
+		}
+		if IsVaultReference(key.(string)) {
+			return ResolveVaultReference(key.(string))
+		}
+		if NeedsInterpolation(key.(string)) {
+			return Interpolate(key.(string)), nil
+		}
 		if strings.HasPrefix(key.(string), "$") {
 			envkey := strings.Split(key.(string), "$")[1]
-			value := os.Getenv(envkey)
+			value, _ := LookupVar(envkey)
 			if value != "" {
-				return value
+				return value, nil
 			}
-			return envkey
+			return envkey, nil
 		}
-		return key.(string)
+		return key.(string), nil
 	}
-	return key
+	return key, nil
 }
 
 var kindToJSON []string = []string{"", "boolean", "integer", "integer", "integer", "integer", "integer", "integer", "integer", "integer",
@@ -156,54 +236,224 @@ func GetJSONType(j interface{}) string {
 	return kindToJSON[reflect.TypeOf(j).Kind()]
 }
 
-// zip whole folder to a zip file
+// ZipOptions narrows down which files of a folder end up in the zip.
+// Include and Exclude are glob patterns matched against the slash separated
+// path relative to the folder; patterns without a "/" match the file name
+// at any depth. Symlinks are replaced by what they point to unless
+// StoreSymlinks is set.
+type ZipOptions struct {
+	Include       []string
+	Exclude       []string
+	StoreSymlinks bool
+}
+
+// zip whole folder to a zip file, leaving out anything matched by the
+// .wskignore files found in the folder or its subfolders
 func CreateFolderZip(src, des string) error {
+	return CreateFolderZipWithOptions(src, des, ZipOptions{})
+}
+
+// zip the files of a folder selected by the include/exclude patterns
+func CreateFolderZipWithOptions(src, des string, options ZipOptions) error {
+	include, err := NewGlobList(options.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := NewGlobList(options.Exclude)
+	if err != nil {
+		return err
+	}
+
 	zippedFile, err := os.Create(des)
-	Check(err)
+	if err != nil {
+		return err
+	}
 	defer zippedFile.Close()
 
 	zipWritter := zip.NewWriter(zippedFile)
 	defer zipWritter.Close()
 
 	sinfo, err := os.Stat(src)
-	Check(err)
+	if err != nil {
+		return err
+	}
+
+	return newFolderZipper(zipWritter, sinfo, src, include, exclude, options).walk(src, "")
+}
+
+// FolderDigest is a hex encoded SHA-256 of the entries CreateFolderZipWithOptions
+// would zip: their names, permissions and contents.
+func FolderDigest(src string, options ZipOptions) (string, error) {
+	include, err := NewGlobList(options.Include)
+	if err != nil {
+		return "", err
+	}
+	exclude, err := NewGlobList(options.Exclude)
+	if err != nil {
+		return "", err
+	}
+	sinfo, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	// an uncompressed zip is as unique as its entries and cheap to write
+	hash := sha256.New()
+	zipWritter := zip.NewWriter(hash)
+	zipper := newFolderZipper(zipWritter, sinfo, src, include, exclude, options)
+	zipper.store = true
+	if err := zipper.walk(src, ""); err != nil {
+		return "", err
+	}
+	if err := zipWritter.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
 
+func newFolderZipper(writer *zip.Writer, sinfo os.FileInfo, src string, include *GlobList, exclude *GlobList, options ZipOptions) *folderZipper {
 	var basedir string
 	if sinfo.IsDir() {
 		basedir = filepath.Base(src)
 	}
+	return &folderZipper{
+		writer:  writer,
+		include: include,
+		exclude: exclude,
+		ignore:  NewIgnoreMatcher(),
+		options: options,
+		basedir: basedir,
+		walking: make(map[string]bool),
+	}
+}
 
-	filepath.Walk(src, func(path string, finfo os.FileInfo, err error) error {
-		Check(err)
+type folderZipper struct {
+	writer  *zip.Writer
+	include *GlobList
+	exclude *GlobList
+	ignore  *IgnoreMatcher
+	options ZipOptions
+	basedir string
+	// write the entries uncompressed
+	store bool
+	// real paths of the directories being zipped, to detect symlink cycles
+	walking map[string]bool
+}
 
-		header, err := zip.FileInfoHeader(finfo)
-		Check(err)
+// add the tree at root to the zip; prefix is the path root is zipped at,
+// relative to the zipped folder
+func (zipper *folderZipper) walk(root string, prefix string) error {
+	if realPath, err := filepath.EvalSymlinks(root); err == nil {
+		if zipper.walking[realPath] {
+			return errors.New("Symlink cycle: " + root + " links to a folder containing it")
+		}
+		zipper.walking[realPath] = true
+		defer delete(zipper.walking, realPath)
+	}
+
+	return filepath.Walk(root, func(path string, finfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if prefix != "" {
+			if rel == "." {
+				rel = prefix
+			} else {
+				rel = prefix + "/" + rel
+			}
+		}
+		name := filepath.Base(path)
+
+		isLink := finfo.Mode()&os.ModeSymlink != 0
+		if isLink && !zipper.options.StoreSymlinks {
+			target, err := os.Stat(path)
+			if err != nil {
+				return errors.New("Broken symlink " + path + ": " + err.Error())
+			}
+			if target.IsDir() {
+				realPath, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					return err
+				}
+				return zipper.walk(realPath, rel)
+			}
+			finfo, isLink = target, false
+		}
+
+		if rel != "." {
+			if name == WskIgnoreFileName || zipper.ignore.Match(rel, finfo.IsDir()) || zipper.exclude.Match(rel) {
+				if finfo.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !finfo.IsDir() && !zipper.include.Empty() && !zipper.include.Match(rel) {
+				return nil
+			}
+		}
 
-		if basedir != "" {
-			header.Name = filepath.Join(basedir, strings.TrimPrefix(path, src))
+		if finfo.IsDir() {
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			if err := zipper.ignore.AddIgnoreFile(path, base); err != nil {
+				return err
+			}
+		}
+
+		header := zipHeader(path, finfo, name)
+		if zipper.store {
+			header.Method = zip.Store
+		}
+		if zipper.basedir != "" {
+			header.Name = zipper.basedir
+			if rel != "." {
+				header.Name += "/" + rel
+			}
 		}
 
 		if finfo.IsDir() {
+			// with an include list only the selected files are stored
+			if !zipper.include.Empty() {
+				return nil
+			}
 			header.Name += "/"
-		} else {
-			header.Method = zip.Deflate
+			_, err = zipper.writer.CreateHeader(header)
+			return err
 		}
 
-		writer, err := zipWritter.CreateHeader(header)
-		Check(err)
+		writer, err := zipper.writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if isLink {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_, err = writer.Write([]byte(target))
+			return err
+		}
 
 		file, err := os.Open(path)
-		Check(err)
+		if err != nil {
+			return err
+		}
 		defer file.Close()
 		_, err = io.Copy(writer, file)
-		Check(err)
 		return err
 	})
-
-	return err
 }
 
-// zip given files to a zip file.
+// zip given files to a zip file, in name order
 func CreateFilesZip(filename string, files []string) error {
 	file, err := os.Create(filename)
 	if err != nil {
@@ -212,7 +462,9 @@ func CreateFilesZip(filename string, files []string) error {
 	defer file.Close()
 	zipwriter := zip.NewWriter(file)
 	defer zipwriter.Close()
-	for _, name := range files {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
 		if err := writeFileToZip(zipwriter, name); err != nil {
 			return err
 		}
@@ -230,10 +482,7 @@ func writeFileToZip(zipwriter *zip.Writer, filename string) error {
 	if err != nil {
 		return err
 	}
-	header, err := zip.FileInfoHeader(finfo)
-	if err != nil {
-		return err
-	}
+	header := zipHeader(filename, finfo, finfo.Name())
 	//add some filter logic if necessary
 	//filter(file)
 	writer, err := zipwriter.CreateHeader(header)
@@ -244,6 +493,64 @@ func writeFileToZip(zipwriter *zip.Writer, filename string) error {
 	return err
 }
 
+// time every zip entry is stamped with, the earliest a zip can hold, so
+// identical files make identical archives
+var zipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// the header of a zip entry with a fixed timestamp and normalized
+// permissions: directories and executables 0755, other files 0644. The
+// permissions are stored as unix external attributes whatever the platform
+// the zip is made on, so they survive the unzip on the invoker.
+func zipHeader(path string, finfo os.FileInfo, name string) *zip.FileHeader {
+	header := &zip.FileHeader{Name: name}
+	header.SetModTime(zipModTime)
+	switch {
+	case finfo.IsDir():
+		header.SetMode(os.ModeDir | 0755)
+	case finfo.Mode()&os.ModeSymlink != 0:
+		header.SetMode(os.ModeSymlink | 0777)
+	case isExecutable(path, finfo):
+		header.Method = zip.Deflate
+		header.SetMode(0755)
+	default:
+		header.Method = zip.Deflate
+		header.SetMode(0644)
+	}
+	return header
+}
+
+// first bytes of scripts and of linux and macOS binaries
+var executableMagics = [][]byte{
+	[]byte("#!"),
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce},
+	{0xfe, 0xed, 0xfa, 0xcf},
+	{0xce, 0xfa, 0xed, 0xfe},
+	{0xcf, 0xfa, 0xed, 0xfe},
+}
+
+// a file is executable when any of its executable bits is set, or when it
+// starts like a script or a binary, for file systems without executable
+// bits such as the ones of Windows
+func isExecutable(path string, finfo os.FileInfo) bool {
+	if finfo.Mode()&0111 != 0 {
+		return true
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, 4)
+	count, _ := io.ReadFull(file, head)
+	for _, magic := range executableMagics {
+		if bytes.HasPrefix(head[:count], magic) {
+			return true
+		}
+	}
+	return false
+}
+
 func filter(filename string) interface{} {
 	//To do
 	return nil
@@ -261,7 +568,9 @@ func GetExec(artifact string, kind string, isDocker bool, mainEntry string) (*wh
 
 	if !isDocker || ext == ".zip" {
 		content, err = new(ContentReader).ReadLocal(artifact)
-		Check(err)
+		if err != nil {
+			return nil, err
+		}
 		code = string(content)
 		exec.Code = &code
 	}
@@ -275,15 +584,8 @@ func GetExec(artifact string, kind string, isDocker bool, mainEntry string) (*wh
 		} else {
 			exec.Image = "openwhisk/dockerskeleton"
 		}
-	} else if ext == ".swift" {
-		exec.Kind = "swift:default"
-	} else if ext == ".js" {
-		exec.Kind = "nodejs:default"
-	} else if ext == ".py" {
-		exec.Kind = "python:default"
-	} else if ext == ".jar" {
-		exec.Kind = "java:default"
-		exec.Code = nil
+	} else if extKind, ok := KindForExtension(ext); ok {
+		exec.Kind = extKind
 	} else {
 		if ext == ".zip" {
 			return nil, zipKindError()
@@ -296,13 +598,13 @@ func GetExec(artifact string, kind string, isDocker bool, mainEntry string) (*wh
 	if len(mainEntry) != 0 {
 		exec.Main = mainEntry
 	} else {
-		if exec.Kind == "java" {
+		if strings.HasPrefix(exec.Kind, "java") {
 			return nil, javaEntryError()
 		}
 	}
 
-	// Base64 encode the zip file content
-	if ext == ".zip" {
+	// Base64 encode the zip, jar and dll file content
+	if IsBinaryArtifact(artifact) {
 		code = base64.StdEncoding.EncodeToString([]byte(code))
 		exec.Code = &code
 	}
@@ -332,10 +634,12 @@ func javaEntryError() error {
 	return errors.New(errMsg)
 }
 
-//for web action support, code from wsk cli with tiny adjustments
+// for web action support, code from wsk cli with tiny adjustments
 const WEB_EXPORT_ANNOT = "web-export"
 const RAW_HTTP_ANNOT = "raw-http"
 const FINAL_ANNOT = "final"
+const WEB_SECURE_ANNOT = "require-whisk-auth"
+const CONDUCTOR_ANNOT = "conductor"
 
 func WebAction(webMode string, annotations whisk.KeyValueArr, entityName string, fetch bool) (whisk.KeyValueArr, error) {
 	switch strings.ToLower(webMode) {
@@ -354,6 +658,16 @@ func WebAction(webMode string, annotations whisk.KeyValueArr, entityName string,
 	}
 }
 
+// IsWebExport reports whether a web-export value turns the action into a
+// web action.
+func IsWebExport(webMode string) bool {
+	switch strings.ToLower(webMode) {
+	case "yes", "true", "raw":
+		return true
+	}
+	return false
+}
+
 type WebActionAnnotationMethod func(annotations whisk.KeyValueArr) whisk.KeyValueArr
 
 func webActionAnnotations(
@@ -379,6 +693,7 @@ func addWebAnnotations(annotations whisk.KeyValueArr) whisk.KeyValueArr {
 
 func deleteWebAnnotations(annotations whisk.KeyValueArr) whisk.KeyValueArr {
 	annotations = deleteWebAnnotationKeys(annotations)
+	annotations = deleteKey(WEB_SECURE_ANNOT, annotations)
 	annotations = addKeyValue(WEB_EXPORT_ANNOT, false, annotations)
 	annotations = addKeyValue(RAW_HTTP_ANNOT, false, annotations)
 	annotations = addKeyValue(FINAL_ANNOT, false, annotations)
@@ -395,6 +710,48 @@ func addRawAnnotations(annotations whisk.KeyValueArr) whisk.KeyValueArr {
 	return annotations
 }
 
+// WebSecure sets the require-whisk-auth annotation of a web action from the
+// web-secure value of the manifest: true requires OpenWhisk credentials, a
+// string or an integer is the token callers must send in the
+// X-Require-Whisk-Auth header, and false leaves the action unsecured.
+func WebSecure(webSecure interface{}, annotations whisk.KeyValueArr) (whisk.KeyValueArr, error) {
+	annotations = deleteKey(WEB_SECURE_ANNOT, annotations)
+	switch value := webSecure.(type) {
+	case bool:
+		if value {
+			annotations = addKeyValue(WEB_SECURE_ANNOT, true, annotations)
+		}
+	case int, int64:
+		annotations = addKeyValue(WEB_SECURE_ANNOT, value, annotations)
+	case string:
+		switch strings.ToLower(value) {
+		case "true":
+			annotations = addKeyValue(WEB_SECURE_ANNOT, true, annotations)
+		case "false", "":
+		default:
+			token, err := ResolveEnvVar(value)
+			if err != nil {
+				return nil, err
+			}
+			annotations = addKeyValue(WEB_SECURE_ANNOT, token, annotations)
+		}
+	default:
+		return nil, errors.New("web-secure must be true, false, a token string or an integer")
+	}
+	return annotations, nil
+}
+
+// ConductorAction flags the action as a conductor action, keeping the
+// conductor annotation when one is set already, e.g. by the composer.
+func ConductorAction(annotations whisk.KeyValueArr) whisk.KeyValueArr {
+	for _, keyValue := range annotations {
+		if keyValue.Key == CONDUCTOR_ANNOT {
+			return annotations
+		}
+	}
+	return addKeyValue(CONDUCTOR_ANNOT, true, annotations)
+}
+
 func deleteWebAnnotationKeys(annotations whisk.KeyValueArr) whisk.KeyValueArr {
 	annotations = deleteKey(WEB_EXPORT_ANNOT, annotations)
 	annotations = deleteKey(RAW_HTTP_ANNOT, annotations)
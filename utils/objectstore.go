@@ -0,0 +1,271 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hash of the empty body of a GET
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// IsObjectStoreArtifact reports whether action code is read from an s3://
+// or cos:// bucket
+func IsObjectStoreArtifact(location string) bool {
+	return strings.HasPrefix(location, "s3://") || strings.HasPrefix(location, "cos://")
+}
+
+// credentials and endpoint of an S3 compatible object store
+type objectStore struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	// host of the service; buckets are addressed in the path when set,
+	// and as a subdomain of s3.<region>.amazonaws.com otherwise
+	Endpoint string
+}
+
+// the store an s3:// or cos:// url is read from, with the credentials of
+// the standard environment variables and files of each cloud:
+//
+//   s3://  AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN,
+//          AWS_REGION and AWS_ENDPOINT_URL, or the AWS_PROFILE of
+//          ~/.aws/credentials
+//   cos:// COS_HMAC_ACCESS_KEY_ID, COS_HMAC_SECRET_ACCESS_KEY and
+//          COS_ENDPOINT, or the HMAC keys of ~/.bluemix/cos_credentials
+func storeFor(scheme string) (*objectStore, error) {
+	if scheme == "cos" {
+		return cosStore()
+	}
+	return s3Store()
+}
+
+func s3Store() (*objectStore, error) {
+	store := &objectStore{
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		Region:       os.Getenv("AWS_REGION"),
+		Endpoint:     strings.TrimPrefix(os.Getenv("AWS_ENDPOINT_URL"), "https://"),
+	}
+	if store.Region == "" {
+		store.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if store.Region == "" {
+		store.Region = "us-east-1"
+	}
+
+	if store.AccessKey == "" {
+		profile := os.Getenv("AWS_PROFILE")
+		if profile == "" {
+			profile = "default"
+		}
+		credentialsFile := filepath.Join(GetHomeDirectory(), ".aws", "credentials")
+		values, err := readIniSection(credentialsFile, profile)
+		if err != nil {
+			return nil, err
+		}
+		store.AccessKey = values["aws_access_key_id"]
+		store.SecretKey = values["aws_secret_access_key"]
+		store.SessionToken = values["aws_session_token"]
+	}
+	if store.AccessKey == "" || store.SecretKey == "" {
+		return nil, errors.New("No AWS credentials found, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or add them to ~/.aws/credentials")
+	}
+	return store, nil
+}
+
+func cosStore() (*objectStore, error) {
+	store := &objectStore{
+		AccessKey: os.Getenv("COS_HMAC_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("COS_HMAC_SECRET_ACCESS_KEY"),
+		Region:    os.Getenv("COS_REGION"),
+		Endpoint:  strings.TrimPrefix(os.Getenv("COS_ENDPOINT"), "https://"),
+	}
+	if store.Region == "" {
+		store.Region = "us-standard"
+	}
+	if store.Endpoint == "" {
+		store.Endpoint = "s3.us.cloud-object-storage.appdomain.cloud"
+	}
+
+	if store.AccessKey == "" {
+		credentialsFile := filepath.Join(GetHomeDirectory(), ".bluemix", "cos_credentials")
+		content, err := ioutil.ReadFile(credentialsFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.New("Unable to read " + credentialsFile + ": " + err.Error())
+		}
+		if err == nil {
+			credentials := struct {
+				HmacKeys struct {
+					AccessKeyId     string `json:"access_key_id"`
+					SecretAccessKey string `json:"secret_access_key"`
+				} `json:"cos_hmac_keys"`
+			}{}
+			if err := json.Unmarshal(content, &credentials); err != nil {
+				return nil, errors.New("Unable to parse " + credentialsFile + ": " + err.Error())
+			}
+			store.AccessKey = credentials.HmacKeys.AccessKeyId
+			store.SecretKey = credentials.HmacKeys.SecretAccessKey
+		}
+	}
+	if store.AccessKey == "" || store.SecretKey == "" {
+		return nil, errors.New("No Cloud Object Storage HMAC credentials found, set COS_HMAC_ACCESS_KEY_ID and COS_HMAC_SECRET_ACCESS_KEY or create ~/.bluemix/cos_credentials")
+	}
+	return store, nil
+}
+
+// the key values of a section of an ini file, empty when the file does not exist
+func readIniSection(file string, section string) (map[string]string, error) {
+	values := make(map[string]string)
+	handle, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, errors.New("Unable to read " + file + ": " + err.Error())
+	}
+	defer handle.Close()
+
+	current := ""
+	scanner := bufio.NewScanner(handle)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return values, scanner.Err()
+}
+
+// ObjectStoreRequest returns a GET of an s3://bucket/key or cos://bucket/key
+// url signed with AWS signature version 4.
+func ObjectStoreRequest(location string) (*http.Request, error) {
+	parts := strings.SplitN(location, "://", 2)
+	bucketKey := strings.SplitN(parts[1], "/", 2)
+	if len(bucketKey) != 2 || bucketKey[0] == "" || bucketKey[1] == "" {
+		return nil, errors.New("Invalid artifact location " + location + ", expected " + parts[0] + "://bucket/key")
+	}
+	store, err := storeFor(parts[0])
+	if err != nil {
+		return nil, errors.New("Unable to read " + location + ": " + err.Error())
+	}
+	return store.signedGet(bucketKey[0], bucketKey[1], time.Now().UTC())
+}
+
+func (store *objectStore) signedGet(bucket string, key string, now time.Time) (*http.Request, error) {
+	scheme := "https://"
+	host := store.Endpoint
+	if strings.HasPrefix(host, "http://") {
+		scheme, host = "http://", strings.TrimPrefix(host, "http://")
+	}
+	canonicalPath := "/" + uriEncode(key, false)
+	if host == "" {
+		host = bucket + ".s3." + store.Region + ".amazonaws.com"
+	} else {
+		canonicalPath = "/" + uriEncode(bucket, true) + canonicalPath
+	}
+
+	request, err := http.NewRequest("GET", scheme+host+canonicalPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	amzDate := now.Format("20060102T150405Z")
+	headers := map[string]string{
+		"host":                 host,
+		"x-amz-content-sha256": emptyPayloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if store.SessionToken != "" {
+		headers["x-amz-security-token"] = store.SessionToken
+	}
+	names := make([]string, 0, len(headers))
+	for name, value := range headers {
+		names = append(names, name)
+		if name != "host" {
+			request.Header.Set(name, value)
+		}
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{"GET", canonicalPath, "", canonicalHeaders, signedHeaders, emptyPayloadHash}, "\n")
+
+	scope := now.Format("20060102") + "/" + store.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := []byte("AWS4" + store.SecretKey)
+	for _, part := range []string{now.Format("20060102"), store.Region, "s3", "aws4_request"} {
+		signingKey = hmacSha256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+store.AccessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return request, nil
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// percent-encode everything but the unreserved characters of RFC 3986
+func uriEncode(value string, encodeSlash bool) string {
+	encoded := ""
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded += string(b)
+		case b == '/' && !encodeSlash:
+			encoded += "/"
+		default:
+			encoded += fmt.Sprintf("%%%02X", b)
+		}
+	}
+	return encoded
+}
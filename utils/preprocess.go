@@ -0,0 +1,104 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// PreProcessGoTemplate runs manifest and deployment files through
+// text/template before they are parsed
+const PreProcessGoTemplate = "go-template"
+
+// the functions manifests can call in go-template pre-processing, besides
+// the built-in ones of text/template
+var templateFuncs = template.FuncMap{
+	// the value of a variable, the built-in ones included, e.g. {{ var "__GIT_BRANCH__" }}
+	"var": func(name string) string {
+		value, _ := LookupVar(name)
+		return value
+	},
+	// the value unless it is empty, e.g. {{ .REGION | default "us-south" }}
+	"default": func(def string, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+	"split": func(sep string, s string) []string {
+		if s == "" {
+			return []string{}
+		}
+		return strings.Split(s, sep)
+	},
+	"join": func(sep string, items []string) string {
+		return strings.Join(items, sep)
+	},
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// a quoted YAML string, e.g. description: {{ .NOTES | quote }}
+	"quote": strconv.Quote,
+}
+
+// PreProcess runs the content of a manifest or deployment file through the
+// pre-processor chosen with --pre-process, if any. Go templates see the
+// variables of the deployment as fields, e.g. {{ if eq .ENV "dev" }}, and
+// variables that are not set are empty.
+func PreProcess(file string, content []byte) ([]byte, error) {
+	switch Flags.PreProcess {
+	case "":
+		return content, nil
+	case PreProcessGoTemplate:
+	default:
+		return nil, errors.New("Unsupported pre-processor " + Flags.PreProcess + ", use " + PreProcessGoTemplate)
+	}
+
+	tmpl, err := template.New(file).Funcs(templateFuncs).Option("missingkey=zero").Parse(string(content))
+	if err != nil {
+		return nil, errors.New("Unable to parse the template " + file + ": " + err.Error())
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, Variables()); err != nil {
+		return nil, errors.New("Unable to pre-process " + file + ": " + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// Variables returns the variables set for the deployment: those of .env
+// files, the environment and --var, later ones taking precedence.
+func Variables() map[string]string {
+	vars := make(map[string]string)
+	for name, value := range envFileVars {
+		vars[name] = value
+	}
+	for _, pair := range os.Environ() {
+		if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+			vars[kv[0]] = kv[1]
+		}
+	}
+	for name, value := range cliVars {
+		vars[name] = value
+	}
+	return vars
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// name of the property file in the home directory
+const WskpropsFileName = ".wskprops"
+
+// directory in the home directory holding one property file per profile
+const ProfilesDirName = ".wskprops.d"
+
+// WskpropsPath returns the property file credentials are read from:
+// ~/.wskprops, or ~/.wskprops.d/<profile> when --profile is given.
+func WskpropsPath() (string, error) {
+	home := GetHomeDirectory()
+	if Flags.Profile == "" {
+		return path.Join(home, WskpropsFileName), nil
+	}
+	return ProfilePath(home, Flags.Profile)
+}
+
+// ProfilePath returns the property file of the named profile under home.
+func ProfilePath(home string, profile string) (string, error) {
+	if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+		return "", errors.New("Invalid profile name " + profile)
+	}
+
+	dir := path.Join(home, ProfilesDirName)
+	propPath := path.Join(dir, profile)
+	if FileExists(propPath) {
+		return propPath, nil
+	}
+
+	profiles, err := ListProfiles(home)
+	if err != nil || len(profiles) == 0 {
+		return "", errors.New("Profile " + profile + " not found, no profiles are defined in " + dir)
+	}
+	return "", errors.New("Profile " + profile + " not found in " + dir + ", available profiles: " + strings.Join(profiles, ", "))
+}
+
+// ListProfiles returns the names of the profiles defined under home.
+func ListProfiles(home string) ([]string, error) {
+	files, err := ioutil.ReadDir(path.Join(home, ProfilesDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	profiles := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
+			profiles = append(profiles, file.Name())
+		}
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
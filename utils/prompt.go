@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the answers given with --answers and --answer, keyed by prompt
+var answers = map[string]string{}
+
+// LoadAnswers reads the answers to prompts from a YAML file mapping prompt
+// keys to answers, e.g. name: demo, then applies the key=value answers given
+// on the command line over them.
+func LoadAnswers(file string, pairs []string) error {
+	if file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.New("Unable to read the answer file " + file + ": " + err.Error())
+		}
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return errors.New("Unable to parse the answer file " + file + ": " + err.Error())
+		}
+		for key, value := range values {
+			// yes and no are read as booleans
+			switch value {
+			case true:
+				value = "yes"
+			case false:
+				value = "no"
+			}
+			answers[AnswerKey(key)] = fmt.Sprint(value)
+		}
+	}
+
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return errors.New("answer " + pair + " must be given as key=value")
+		}
+		answers[AnswerKey(kv[0])] = kv[1]
+	}
+	return nil
+}
+
+// AnswerKey returns the key answering a question in answer files: the
+// question up to its first bracket, lower case, with dashes for spaces.
+// "Rule Name" is answered by rule-name.
+func AnswerKey(question string) string {
+	if end := strings.IndexAny(question, "[(?:"); end >= 0 {
+		question = question[:end]
+	}
+	return strings.Replace(strings.ToLower(strings.TrimSpace(question)), " ", "-", -1)
+}
+
+// Answered reports whether an answer was given for key.
+func Answered(key string) bool {
+	_, ok := answers[key]
+	return ok
+}
+
+// Prompt returns the answer given for key, or prints msg and returns the line
+// typed. In non-interactive mode a missing answer is an error instead of a
+// prompt, so automation never blocks on stdin.
+func Prompt(reader *bufio.Reader, key string, msg string) (string, error) {
+	if answer, ok := answers[key]; ok {
+		return answer, nil
+	}
+	if Flags.NonInteractive || Flags.WithinOpenWhisk {
+		return "", WithExitCode(ExitPrompt, errors.New("No answer for "+key+" in non-interactive mode, give it with --answer "+key+"=VALUE or in the --answers file"))
+	}
+
+	fmt.Print(msg)
+	text, _ := reader.ReadString('\n')
+	return strings.TrimSpace(text), nil
+}
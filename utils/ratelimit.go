@@ -0,0 +1,92 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// default number of requests sent at once before the rate limit applies
+const DefaultRateBurst = 10
+
+// RateLimitTransport spaces requests out to at most Rate requests a second,
+// letting Burst of them through at once, so large projects stay below the
+// throttling of the platform instead of running into 429 responses.
+type RateLimitTransport struct {
+	Transport http.RoundTripper
+	Rate      float64 // requests per second, 0 for no limit
+	Burst     int
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimitTransport(transport http.RoundTripper, rate float64, burst int) *RateLimitTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimitTransport{
+		Transport: transport,
+		Rate:      rate,
+		Burst:     burst,
+		tokens:    float64(burst),
+	}
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Rate > 0 {
+		if delay := t.reserve(); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			}
+		}
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// take a token from the bucket, returning how long to wait for it when the
+// bucket is empty
+func (t *RateLimitTransport) reserve() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	if !t.last.IsZero() {
+		t.tokens += now.Sub(t.last).Seconds() * t.Rate
+		if t.tokens > float64(t.Burst) {
+			t.tokens = float64(t.Burst)
+		}
+	}
+	t.last = now
+
+	t.tokens--
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / t.Rate * float64(time.Second))
+}
@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// default number of times a request is retried after a transient error
+const DefaultRetries = 3
+
+// RetryTransport retries requests that failed with a transient error: 429,
+// a gateway error, or a network failure. Requests are retried with jittered
+// exponential backoff until the retry budget is used up.
+type RetryTransport struct {
+	Transport http.RoundTripper
+	Retries   int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func NewRetryTransport(transport http.RoundTripper, retries int) *RetryTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RetryTransport{
+		Transport: transport,
+		Retries:   retries,
+		BaseDelay: 500 * time.Millisecond,
+		MaxDelay:  10 * time.Second,
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body is read once so it can be sent again on every attempt
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := *req
+		if body != nil {
+			attemptReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.Transport.RoundTrip(&attemptReq)
+		if attempt >= t.Retries || !retryable(req.Method, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if err != nil {
+			log.Printf("%s %s failed: %v, retrying in %v\n", req.Method, req.URL.String(), err, delay)
+		} else {
+			log.Printf("%s %s returned %s, retrying in %v\n", req.Method, req.URL.String(), resp.Status, delay)
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		time.Sleep(delay)
+	}
+}
+
+// Requests that may have reached the backend are only retried when they are
+// safe to send twice. POST invokes actions, so it is retried only when the
+// request was rejected before being processed.
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		if opErr, ok := err.(*net.OpError); ok && opErr.Op == "dial" {
+			return true
+		}
+		return method != "POST"
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method != "POST"
+	}
+	return false
+}
+
+// exponential backoff with jitter, honoring Retry-After when the server sends it
+func (t *RetryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay := time.Duration(seconds) * time.Second
+			if delay > t.MaxDelay {
+				delay = t.MaxDelay
+			}
+			return delay
+		}
+	}
+
+	delay := t.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > t.MaxDelay {
+		delay = t.MaxDelay
+	}
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
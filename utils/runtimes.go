@@ -0,0 +1,237 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the kinds of the actions whose code is a file with these extensions, one
+// for each runtime OpenWhisk distributions ship
+var extensionKinds = map[string]string{
+	".js":    "nodejs:default",
+	".swift": "swift:default",
+	".py":    "python:default",
+	".jar":   "java:default",
+	".php":   "php:default",
+	".rb":    "ruby:default",
+	".go":    "go:default",
+	".rs":    "rust:default",
+	".cs":    "dotnet:default",
+	".dll":   "dotnet:default",
+	".bal":   "ballerina:default",
+}
+
+// the extensions of the artifacts deployed base64 encoded
+var binaryExtensions = map[string]bool{
+	".zip": true,
+	".jar": true,
+	".dll": true,
+}
+
+// RuntimesConfig maps the runtimes of a distribution of OpenWhisk, for the
+// in-house runtimes and defaults wskdeploy cannot know of, e.g.
+//
+//	extensions:
+//	  .pl: perl:default
+//	  .wasm: wasm:default
+//	binary: [.wasm]
+//	defaults:
+//	  nodejs: nodejs:12
+//	maxCodeSize:
+//	  java: 64
+type RuntimesConfig struct {
+	// kinds of the actions whose code is a file with the extension
+	Extensions map[string]string `yaml:"extensions"`
+	// extensions of the artifacts deployed base64 encoded
+	Binary []string `yaml:"binary"`
+	// kinds a family, or its :default kind, stand for
+	Defaults map[string]string `yaml:"defaults"`
+	// megabytes the code of the actions of a kind or family can have
+	MaxCodeSize map[string]int `yaml:"maxCodeSize"`
+}
+
+// the mapping given with --runtimes-file, nil for the built-in runtimes only
+var runtimes *RuntimesConfig
+
+// LoadRuntimes reads the runtimes mapping file, whose entries take
+// precedence over the built-in runtimes and what the platform reports. An
+// empty file name clears the mapping read before.
+func LoadRuntimes(file string) error {
+	runtimes = nil
+	if file == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.New("Unable to read the runtimes file " + file + ": " + err.Error())
+	}
+	config := &RuntimesConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return errors.New("Unable to parse the runtimes file " + file + ": " + err.Error())
+	}
+
+	problems := make([]string, 0)
+	extensions := make(map[string]string, len(config.Extensions))
+	for ext, kind := range config.Extensions {
+		if !strings.HasPrefix(ext, ".") || kind == "" {
+			problems = append(problems, "extension "+ext+" must start with a dot and map to a kind")
+		}
+		extensions[strings.ToLower(ext)] = kind
+	}
+	config.Extensions = extensions
+	for i, ext := range config.Binary {
+		if !strings.HasPrefix(ext, ".") {
+			problems = append(problems, "binary extension "+ext+" must start with a dot")
+		}
+		config.Binary[i] = strings.ToLower(ext)
+	}
+	for family, kind := range config.Defaults {
+		name := strings.TrimSuffix(family, ":default")
+		if strings.Contains(name, ":") || !strings.HasPrefix(kind, name+":") {
+			problems = append(problems, "default "+kind+" of "+family+" must be a kind of the family")
+		}
+	}
+	for kind, size := range config.MaxCodeSize {
+		if size <= 0 {
+			problems = append(problems, "maxCodeSize of "+kind+" must be a positive number of megabytes")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New("Invalid runtimes file " + file + ":\n" + strings.Join(problems, "\n"))
+	}
+	runtimes = config
+	return nil
+}
+
+// KindForExtension returns the default kind of the runtime running code
+// files with the extension, and false when no runtime runs them.
+func KindForExtension(ext string) (string, bool) {
+	ext = strings.ToLower(ext)
+	if runtimes != nil {
+		if kind, ok := runtimes.Extensions[ext]; ok {
+			return kind, true
+		}
+	}
+	kind, ok := extensionKinds[ext]
+	return kind, ok
+}
+
+// IsBinaryArtifact reports whether the file is an archive or a compiled
+// assembly, whose content is base64 encoded rather than sent as source.
+func IsBinaryArtifact(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	if runtimes != nil {
+		for _, binary := range runtimes.Binary {
+			if binary == ext {
+				return true
+			}
+		}
+	}
+	return binaryExtensions[ext]
+}
+
+// DefaultKind returns the kind the runtimes file makes the default of a
+// family, given as nodejs or nodejs:default, and false when it sets none.
+func DefaultKind(kind string) (string, bool) {
+	if runtimes == nil {
+		return "", false
+	}
+	family := strings.Split(kind, ":")[0]
+	if kind != family && kind != family+":default" {
+		return "", false
+	}
+	if defaultKind, ok := runtimes.Defaults[family]; ok {
+		return defaultKind, true
+	}
+	defaultKind, ok := runtimes.Defaults[family+":default"]
+	return defaultKind, ok
+}
+
+// MaxCodeSize returns the megabytes the runtimes file allows the code of
+// actions of a kind to have, set for the kind or else its family, and false
+// when it sets no limit for them.
+func MaxCodeSize(kind string) (int, bool) {
+	if runtimes == nil || kind == "" {
+		return 0, false
+	}
+	if size, ok := runtimes.MaxCodeSize[kind]; ok {
+		return size, true
+	}
+	size, ok := runtimes.MaxCodeSize[strings.Split(kind, ":")[0]]
+	return size, ok
+}
+
+// the files that tell the runtime of a function directory, in the order
+// they are looked for
+var runtimeMarkers = []struct {
+	file string
+	kind string
+}{
+	{"package.json", "nodejs:default"},
+	{"requirements.txt", "python:default"},
+	{"__main__.py", "python:default"},
+	{"composer.json", "php:default"},
+	{"Gemfile", "ruby:default"},
+	{"go.mod", "go:default"},
+	{"Cargo.toml", "rust:default"},
+	{"Package.swift", "swift:default"},
+	{"Ballerina.toml", "ballerina:default"},
+}
+
+// InferDirectoryKind returns the kind of the runtime a function directory is
+// written for, told by its manifest of dependencies, e.g. package.json, or
+// else by the extensions of the source files at its top, when they are all
+// of one runtime. It returns false when the runtime cannot be told.
+func InferDirectoryKind(dir string) (string, bool) {
+	for _, marker := range runtimeMarkers {
+		if FileExists(filepath.Join(dir, marker.file)) {
+			return marker.kind, true
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	kind := ""
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if strings.EqualFold(filepath.Ext(file.Name()), ".csproj") {
+			return "dotnet:default", true
+		}
+		fileKind, ok := KindForExtension(filepath.Ext(file.Name()))
+		if !ok {
+			continue
+		}
+		if kind != "" && fileKind != kind {
+			return "", false
+		}
+		kind = fileKind
+	}
+	return kind, kind != ""
+}
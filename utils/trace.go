@@ -0,0 +1,231 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bytes of a request or response body kept in the trace
+const DefaultTraceBodyLimit = 2048
+
+// the value secrets are replaced with in the trace
+const redacted = "[REDACTED]"
+
+// the headers, query parameters and JSON keys holding credentials, in lower
+// case; the auth key of a feed is sent as its authKey parameter
+var traceSecrets = map[string]bool{
+	"authorization": true,
+	"authkey":       true,
+	"apikey":        true,
+	"api_key":       true,
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"x-auth-token":  true,
+}
+
+// query parameters holding credentials besides those, e.g. ?key= of a gateway
+var traceSecretParams = map[string]bool{
+	"key": true,
+}
+
+// TraceTransport logs every request sent to the API and its response: the
+// method, URL, status, latency and the start of both bodies, with the
+// Authorization header and key parameters redacted.
+type TraceTransport struct {
+	Transport http.RoundTripper
+	Writer    io.Writer
+	BodyLimit int
+}
+
+func NewTraceTransport(transport http.RoundTripper, writer io.Writer) *TraceTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &TraceTransport{Transport: transport, Writer: writer, BodyLimit: DefaultTraceBodyLimit}
+}
+
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.Transport.RoundTrip(req)
+	latency := time.Since(start)
+
+	var trace bytes.Buffer
+	fmt.Fprintf(&trace, "%s %s %s", start.UTC().Format(time.RFC3339), req.Method, RedactURL(req.URL))
+	if err != nil {
+		fmt.Fprintf(&trace, " error %v %s\n", err, latency)
+	} else {
+		fmt.Fprintf(&trace, " %s %s\n", resp.Status, latency)
+	}
+	for _, name := range sortedHeaderNames(req.Header) {
+		value := strings.Join(req.Header[name], ", ")
+		if traceSecrets[strings.ToLower(name)] {
+			value = redacted
+		}
+		fmt.Fprintf(&trace, "> %s: %s\n", name, value)
+	}
+	if len(reqBody) > 0 {
+		fmt.Fprintf(&trace, "> %s\n", t.body(reqBody))
+	}
+
+	if err == nil && resp.Body != nil {
+		respBody, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		if readErr != nil {
+			return nil, readErr
+		}
+		if len(respBody) > 0 {
+			fmt.Fprintf(&trace, "< %s\n", t.body(respBody))
+		}
+	}
+
+	t.Writer.Write(trace.Bytes())
+	return resp, err
+}
+
+// a body with its secrets redacted, cut at the body limit
+func (t *TraceTransport) body(body []byte) string {
+	text := RedactJSON(body)
+	if t.BodyLimit > 0 && len(text) > t.BodyLimit {
+		return text[:t.BodyLimit] + fmt.Sprintf("... (%d bytes)", len(text))
+	}
+	return text
+}
+
+// RedactURL returns the URL with the values of its key parameters redacted
+func RedactURL(u *url.URL) string {
+	redactedURL := *u
+	redactedURL.User = nil
+	query := redactedURL.Query()
+	for name := range query {
+		if traceSecrets[strings.ToLower(name)] || traceSecretParams[strings.ToLower(name)] {
+			query.Set(name, redacted)
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.String()
+}
+
+// RedactJSON returns a JSON body with the values of its key fields redacted,
+// at any depth. Bodies that are not JSON are returned as they are.
+func RedactJSON(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	redactedBody, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return string(body)
+	}
+	return string(redactedBody)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if traceSecrets[strings.ToLower(key)] {
+				value[key] = redacted
+			} else {
+				value[key] = redactValue(field)
+			}
+		}
+		// parameters and annotations are lists of {"key", "value"} pairs
+		if key, ok := value["key"].(string); ok && traceSecrets[strings.ToLower(key)] {
+			value["value"] = redacted
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+func sortedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TraceWriter returns the writer of the trace file, created on the first call
+// of the run and shared by the clients after that
+func TraceWriter(path string) (io.Writer, error) {
+	return sharedFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+}
+
+// lockedFile is a file the clients of a run write to at the same time, one
+// whole entry at a time
+type lockedFile struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func (locked *lockedFile) Write(p []byte) (int, error) {
+	locked.mutex.Lock()
+	defer locked.mutex.Unlock()
+	return locked.file.Write(p)
+}
+
+var sharedFiles = struct {
+	sync.Mutex
+	open map[string]*lockedFile
+}{open: make(map[string]*lockedFile)}
+
+// the file at path, opened with flag on the first call of the run
+func sharedFile(path string, flag int) (io.Writer, error) {
+	sharedFiles.Lock()
+	defer sharedFiles.Unlock()
+	if locked, exists := sharedFiles.open[path]; exists {
+		return locked, nil
+	}
+	file, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		return nil, err
+	}
+	locked := &lockedFile{file: file}
+	sharedFiles.open[path] = locked
+	return locked, nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// default timeouts of the whisk client, 0 is no timeout
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultReadTimeout    = 5 * time.Minute
+	DefaultRequestTimeout = 0
+)
+
+// TransportOptions configure the transport of the whisk client.
+type TransportOptions struct {
+	CACert   string // PEM file of certificate authorities trusted besides the system ones
+	Cert     string // PEM file of the client certificate
+	Key      string // PEM file of its key, the Cert file when empty
+	Insecure bool   // skip the verification of the server certificate
+
+	ConnectTimeout time.Duration // to open the connection
+	ReadTimeout    time.Duration // for the response to start once the request is sent
+}
+
+// NewTransport returns the transport of the whisk client. It goes through the
+// proxy set with HTTP_PROXY or HTTPS_PROXY unless NO_PROXY lists the host.
+// Certificates signed by the authorities of the CACert file are trusted
+// besides those of the system, and are verified even when Insecure is set.
+// The client certificate is presented to the API when given.
+func NewTransport(options TransportOptions) (*http.Transport, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.Insecure}
+	if cert, key := options.Cert, options.Key; cert != "" {
+		if key == "" {
+			key = cert
+		}
+		certificate, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.New("Unable to load the client certificate " + cert + ": " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	} else if key != "" {
+		return nil, errors.New("The client key " + key + " is given without its certificate, set --cert")
+	}
+	if caCert := options.CACert; caCert != "" {
+		pem, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, errors.New("Unable to read the certificate authorities " + caCert + ": " + err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No PEM certificate found in " + caCert)
+		}
+		tlsConfig.RootCAs = pool
+		tlsConfig.InsecureSkipVerify = false
+	}
+	dialer := &net.Dialer{Timeout: options.ConnectTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		Dial:                  dialer.Dial,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   options.ConnectTimeout,
+		ResponseHeaderTimeout: options.ReadTimeout,
+	}, nil
+}
+
+// TimeoutTransport bounds the time a request may take, from sending it to
+// reading the whole response, and says which timeout a request ran into.
+type TimeoutTransport struct {
+	Transport http.RoundTripper
+	Timeout   time.Duration // 0 is no deadline
+}
+
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Timeout <= 0 {
+		resp, err := t.Transport.RoundTrip(req)
+		return resp, timeoutError(req, err, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := t.Transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, timeoutError(req, err, ctx)
+	}
+	// the deadline holds until the response is read
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// a request that timed out tells which timeout to raise, as large uploads may
+// need longer ones
+func timeoutError(req *http.Request, err error, ctx context.Context) error {
+	if err == nil {
+		return nil
+	}
+	if ctx != nil && ctx.Err() == context.DeadlineExceeded {
+		return errors.New(req.Method + " " + req.URL.String() + " did not complete within the request timeout, raise it with --request-timeout")
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return errors.New(req.Method + " " + req.URL.String() + " timed out: " + err.Error() + ", raise --connect-timeout or --read-timeout")
+	}
+	return err
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
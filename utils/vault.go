@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// prefix of input values read from a HashiCorp Vault server
+const VaultScheme = "vault://"
+
+// secrets already read from Vault, by path
+var vaultCache = struct {
+	sync.Mutex
+	secrets map[string]map[string]interface{}
+}{secrets: make(map[string]map[string]interface{})}
+
+// IsVaultReference reports whether value has the vault://path#key form.
+func IsVaultReference(value string) bool {
+	return strings.HasPrefix(value, VaultScheme)
+}
+
+// ResolveVaultReference reads the key of a vault://secret/path#key
+// reference from the Vault server at $VAULT_ADDR, authenticating with
+// $VAULT_TOKEN. Both KV version 1 and version 2 secret engines are supported.
+func ResolveVaultReference(ref string) (interface{}, error) {
+	secretPath := strings.TrimPrefix(ref, VaultScheme)
+	idx := strings.LastIndex(secretPath, "#")
+	if idx <= 0 || idx == len(secretPath)-1 {
+		return nil, errors.New("Invalid Vault reference " + ref + ", expected vault://path#key")
+	}
+	key := secretPath[idx+1:]
+	secretPath = strings.Trim(secretPath[:idx], "/")
+
+	secret, err := readVaultSecret(secretPath)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := secret[key]
+	if !ok {
+		return nil, errors.New("Key " + key + " not found in Vault secret " + secretPath)
+	}
+	return value, nil
+}
+
+func readVaultSecret(secretPath string) (map[string]interface{}, error) {
+	vaultCache.Lock()
+	defer vaultCache.Unlock()
+	if secret, ok := vaultCache.secrets[secretPath]; ok {
+		return secret, nil
+	}
+
+	addr, _ := LookupVar("VAULT_ADDR")
+	token, _ := LookupVar("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN must be set to read Vault secret " + secretPath)
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(addr, "/")+"/v1/"+secretPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace, _ := LookupVar("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to read Vault secret %s: %s", secretPath, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.New("Unable to decode Vault secret " + secretPath + ": " + err.Error())
+	}
+
+	secret := body.Data
+	// KV version 2 nests the secret under data.data, next to its metadata
+	if nested, ok := secret["data"].(map[string]interface{}); ok {
+		if _, versioned := secret["metadata"]; versioned {
+			secret = nested
+		}
+	}
+	vaultCache.secrets[secretPath] = secret
+	return secret, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often --watch looks for changed files.
+const DefaultWatchInterval = time.Second
+
+// FileStamp tells whether a file changed between two scans.
+type FileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// ScanFiles stamps the files under root and the extra files given, e.g. a
+// deployment file kept elsewhere. Hidden directories such as .git and the
+// paths the .wskignore file of root excludes are left out.
+func ScanFiles(root string, extra ...string) (map[string]FileStamp, error) {
+	matcher := NewIgnoreMatcher()
+	if err := matcher.AddIgnoreFile(root, ""); err != nil {
+		return nil, err
+	}
+
+	stamps := make(map[string]FileStamp)
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(root, file)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") || matcher.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !matcher.Match(rel, false) {
+			stamps[file] = FileStamp{info.ModTime(), info.Size()}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range extra {
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			stamps[file] = FileStamp{info.ModTime(), info.Size()}
+		}
+	}
+	return stamps, nil
+}
+
+// ChangedFiles returns the sorted files added, changed or removed between
+// two scans.
+func ChangedFiles(before map[string]FileStamp, after map[string]FileStamp) []string {
+	changed := make([]string, 0)
+	for file, stamp := range after {
+		if previous, ok := before[file]; !ok || !previous.ModTime.Equal(stamp.ModTime) || previous.Size != stamp.Size {
+			changed = append(changed, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
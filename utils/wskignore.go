@@ -0,0 +1,228 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// name of the file listing paths to leave out of zipped actions
+const WskIgnoreFileName = ".wskignore"
+
+type ignoreRule struct {
+	base     string // directory of the .wskignore file, relative to the zip root
+	pattern  *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreMatcher evaluates gitignore style patterns read from .wskignore
+// files. Rules from deeper files are evaluated after the rules of their
+// parents, and the last matching rule wins.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+func NewIgnoreMatcher() *IgnoreMatcher {
+	return &IgnoreMatcher{}
+}
+
+// AddPatterns parses the content of an ignore file located in the directory
+// base (slash separated, relative to the zip root, "" for the root itself).
+func (matcher *IgnoreMatcher) AddPatterns(base string, content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		// a slash anywhere but at the end ties the pattern to the ignore file's directory
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		re, err := GlobToRegexp(line)
+		if err != nil {
+			return err
+		}
+		rule.pattern = re
+		matcher.rules = append(matcher.rules, rule)
+	}
+	return scanner.Err()
+}
+
+// AddIgnoreFile reads the .wskignore file in dir, if there is one.
+func (matcher *IgnoreMatcher) AddIgnoreFile(dir string, base string) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, WskIgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return matcher.AddPatterns(base, content)
+}
+
+// Match reports whether the slash separated path rel (relative to the zip
+// root) is excluded.
+func (matcher *IgnoreMatcher) Match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range matcher.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, rule.base+"/")
+		}
+
+		target := sub
+		if !rule.anchored {
+			target = path.Base(sub)
+		}
+
+		if rule.pattern.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// GlobToRegexp converts a glob pattern to an anchored regular expression.
+// "*" and "?" never cross a "/", "**" matches any number of directories.
+func GlobToRegexp(glob string) (*regexp.Regexp, error) {
+	var buf bytes.Buffer
+	buf.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more leading directories
+					i++
+					buf.WriteString("(.*/)?")
+				} else {
+					buf.WriteString(".*")
+				}
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(string(glob[i])))
+			} else {
+				buf.WriteString(`\\`)
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
+// GlobList is a set of include or exclude patterns given in the manifest.
+type GlobList struct {
+	patterns []*regexp.Regexp
+	anchored []bool
+}
+
+func NewGlobList(globs []string) (*GlobList, error) {
+	list := &GlobList{}
+	for _, glob := range globs {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			continue
+		}
+		glob = strings.TrimSuffix(glob, "/")
+		anchored := strings.Contains(glob, "/")
+		re, err := GlobToRegexp(strings.TrimPrefix(strings.TrimPrefix(glob, "./"), "/"))
+		if err != nil {
+			return nil, err
+		}
+		list.patterns = append(list.patterns, re)
+		list.anchored = append(list.anchored, anchored)
+	}
+	return list, nil
+}
+
+func (list *GlobList) Empty() bool {
+	return len(list.patterns) == 0
+}
+
+// Match reports whether the slash separated relative path matches any pattern.
+func (list *GlobList) Match(rel string) bool {
+	for i, re := range list.patterns {
+		target := rel
+		if !list.anchored[i] {
+			target = path.Base(rel)
+		}
+		if re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}